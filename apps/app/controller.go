@@ -162,6 +162,9 @@ func (c *Controller) UpdateApp(ctx *gin.Context) {
 		Status      int    `json:"status"`
 		PublicData  string `json:"public_data"`  // 公有数据
 		PrivateData string `json:"private_data"` // 私有数据
+
+		EncryptionType *int   `json:"encryption_type"` // 加密类型：0-不加密，1-AES加密，不传则不修改
+		EncryptionKey  string `json:"encryption_key"`  // 加密密钥，开启加密且为空时自动生成
 	}
 
 	if bindErr := ctx.ShouldBindJSON(&req); bindErr != nil {
@@ -169,6 +172,12 @@ func (c *Controller) UpdateApp(ctx *gin.Context) {
 		return
 	}
 
+	// 未传加密类型时保持不变
+	encryptionType := -1
+	if req.EncryptionType != nil {
+		encryptionType = *req.EncryptionType
+	}
+
 	// 调用服务层更新应用
 	app, err := c.service.UpdateApp(
 		userID.(uint),
@@ -183,6 +192,8 @@ func (c *Controller) UpdateApp(ctx *gin.Context) {
 		req.Status,
 		req.PublicData,
 		req.PrivateData,
+		encryptionType,
+		req.EncryptionKey,
 	)
 
 	if err != nil {
@@ -255,10 +266,15 @@ func (c *Controller) RegenerateAppSecret(ctx *gin.Context) {
 }
 
 // SetupAppAPIRoutes 设置应用API路由
+// 已废弃：该组接口要求客户端直接携带X-App-Secret，请迁移到 /api/v2/client
 func SetupAppAPIRoutes(r *gin.Engine) {
 	// 应用API路由组
 	api := r.Group("/api/v1/client")
-	api.Use(middleware.AppAuthMiddleware(), middleware.LogMiddleware(3)) // 使用LogTypeApp(3)类型的日志中间件
+	api.Use(
+		middleware.DeprecationMiddleware("/api/v2/client"),
+		middleware.LogMiddleware(dbmodel.LogTypeApp),
+		middleware.AppAuthMiddleware(),
+	)
 	{
 		// 验证应用有效性
 		api.GET("/verify", VerifyApp)
@@ -303,17 +319,9 @@ func VerifyApp(c *gin.Context) {
 		TrialTimeLeft int64     `json:"trial_time_left,omitempty"` // 剩余小时数
 	}
 
-	if app.AllowTrial == 1 && app.TrialAmount > 0 {
+	if trialEndTime, hasTrial := app.TrialEndTime(); hasTrial {
 		trialInfo.HasTrial = true
-		// 根据计费模式处理试用额度
-		var trialDuration time.Duration
-		if app.BillingMode == 0 { // 时长计费模式
-			trialDuration = time.Duration(app.TrialAmount) * time.Hour
-		} else { // 点数计费模式
-			// 点数计费模式下，假设每个点数对应1小时的使用时间
-			trialDuration = time.Duration(app.TrialAmount) * time.Hour
-		}
-		trialInfo.TrialEndTime = app.CreatedAt.Add(trialDuration)
+		trialInfo.TrialEndTime = trialEndTime
 		trialInfo.TrialTimeLeft = int64(time.Until(trialInfo.TrialEndTime).Hours())
 		if trialInfo.TrialTimeLeft < 0 {
 			trialInfo.TrialTimeLeft = 0
@@ -10,43 +10,47 @@ import (
 
 // AppResponse 应用响应模型
 type AppResponse struct {
-	ID          uint      `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	AppKey      string    `json:"app_key"`
-	AppSecret   string    `json:"app_secret,omitempty"` // 仅在创建和重新生成密钥时返回
-	Status      int       `json:"status"`
-	Version     string    `json:"version"`
-	DownloadUrl string    `json:"download_url"`
-	BillingMode int       `json:"billing_mode"`
-	TrialAmount int       `json:"trial_amount"`
-	AllowTrial  int       `json:"allow_trial"`
-	PublicData  string    `json:"public_data"`  // 公有数据，可存储JSON格式的公共配置信息
-	PrivateData string    `json:"private_data"` // 私有数据，可存储JSON格式的私有配置信息
-	UserID      uint      `json:"user_id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID             uint      `json:"id"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description"`
+	AppKey         string    `json:"app_key"`
+	AppSecret      string    `json:"app_secret,omitempty"` // 仅在创建和重新生成密钥时返回
+	Status         int       `json:"status"`
+	Version        string    `json:"version"`
+	DownloadUrl    string    `json:"download_url"`
+	BillingMode    int       `json:"billing_mode"`
+	TrialAmount    int       `json:"trial_amount"`
+	AllowTrial     int       `json:"allow_trial"`
+	PublicData     string    `json:"public_data"`     // 公有数据，可存储JSON格式的公共配置信息
+	PrivateData    string    `json:"private_data"`    // 私有数据，可存储JSON格式的私有配置信息
+	EncryptionType int       `json:"encryption_type"` // 加密类型：0-不加密，1-AES加密
+	EncryptionKey  string    `json:"encryption_key"`  // 加密密钥
+	UserID         uint      `json:"user_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 // NewAppResponse 从数据库模型创建应用响应模型
 func NewAppResponse(app *dbmodel.App) *AppResponse {
 	return &AppResponse{
-		ID:          app.ID,
-		Name:        app.Name,
-		Description: app.Description,
-		AppKey:      app.AppKey,
-		AppSecret:   app.AppSecret,
-		Status:      app.Status,
-		Version:     app.Version,
-		DownloadUrl: app.DownloadUrl,
-		BillingMode: app.BillingMode,
-		TrialAmount: app.TrialAmount,
-		AllowTrial:  app.AllowTrial,
-		PublicData:  app.PublicData,
-		PrivateData: app.PrivateData,
-		UserID:      app.UserID,
-		CreatedAt:   app.CreatedAt,
-		UpdatedAt:   app.UpdatedAt,
+		ID:             app.ID,
+		Name:           app.Name,
+		Description:    app.Description,
+		AppKey:         app.AppKey,
+		AppSecret:      app.AppSecret,
+		Status:         app.Status,
+		Version:        app.Version,
+		DownloadUrl:    app.DownloadUrl,
+		BillingMode:    app.BillingMode,
+		TrialAmount:    app.TrialAmount,
+		AllowTrial:     app.AllowTrial,
+		PublicData:     app.PublicData,
+		PrivateData:    app.PrivateData,
+		EncryptionType: app.EncryptionType,
+		EncryptionKey:  app.EncryptionKey,
+		UserID:         app.UserID,
+		CreatedAt:      app.CreatedAt,
+		UpdatedAt:      app.UpdatedAt,
 	}
 }
 
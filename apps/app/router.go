@@ -2,6 +2,7 @@ package apps
 
 import (
 	"github.com/gin-gonic/gin"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
 	"github.com/skyle1995/DevE-Server/middleware"
 )
 
@@ -14,15 +15,21 @@ func SetupAppsRoutes(r *gin.Engine) {
 	protected := r.Group("/api/v1")
 	protected.Use(middleware.JWTAuthMiddleware())
 	{
-		// 应用相关路由（所有已登录用户可访问）
+		// 应用相关路由（按权限访问，用户只能管理自己的应用）
 		apps := protected.Group("/apps")
 		{
-			apps.POST("", controller.CreateApp)                      // 创建应用
-			apps.GET("", controller.GetAppList)                      // 获取应用列表
-			apps.GET("/:id", controller.GetAppByID)                  // 获取应用详情
-			apps.PUT("/:id", controller.UpdateApp)                   // 更新应用
-			apps.DELETE("/:id", controller.DeleteApp)                // 删除应用
-			apps.POST("/:id/secret", controller.RegenerateAppSecret) // 重新生成应用密钥
+			view := middleware.PermissionMiddleware(dbmodel.PermissionAppView)
+			update := middleware.PermissionMiddleware(dbmodel.PermissionAppUpdate)
+
+			// 限定应用的访问令牌只能访问路径中的应用
+			pathApp := middleware.AppScopeMiddleware(middleware.AppIDFromParam("id"))
+
+			apps.POST("", middleware.PermissionMiddleware(dbmodel.PermissionAppCreate), controller.CreateApp)                // 创建应用
+			apps.GET("", view, controller.GetAppList)                                                                        // 获取应用列表
+			apps.GET("/:id", pathApp, view, controller.GetAppByID)                                                           // 获取应用详情
+			apps.PUT("/:id", pathApp, update, controller.UpdateApp)                                                          // 更新应用
+			apps.DELETE("/:id", pathApp, middleware.PermissionMiddleware(dbmodel.PermissionAppDelete), controller.DeleteApp) // 删除应用
+			apps.POST("/:id/secret", pathApp, update, controller.RegenerateAppSecret)                                        // 重新生成应用密钥
 		}
 
 	}
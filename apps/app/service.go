@@ -157,6 +157,8 @@ func (s *Service) UpdateApp(
 	status int,
 	publicData string,
 	privateData string,
+	encryptionType int,
+	encryptionKey string,
 ) (*dbmodel.App, error) {
 	// 查询应用是否存在且属于该用户
 	var app dbmodel.App
@@ -213,6 +215,21 @@ func (s *Service) UpdateApp(
 		updates["private_data"] = privateData
 	}
 
+	// 更新加密设置，目前仅支持不加密和AES加密
+	if encryptionType >= 0 {
+		if encryptionType > 1 {
+			return nil, errors.New("暂不支持该加密类型")
+		}
+		updates["encryption_type"] = encryptionType
+	}
+
+	if encryptionKey != "" {
+		updates["encryption_key"] = encryptionKey
+	} else if encryptionType == 1 && app.EncryptionKey == "" {
+		// 开启加密但未设置密钥时自动生成
+		updates["encryption_key"] = random.Hex(32)
+	}
+
 	if len(updates) > 0 {
 		result = database.DB.Model(&app).Updates(updates)
 		if result.Error != nil {
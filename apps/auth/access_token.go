@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/skyle1995/DevE-Server/apps/auth/model"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/middleware"
+	"github.com/skyle1995/DevE-Server/utils/crypto"
+	"github.com/skyle1995/DevE-Server/utils/random"
+)
+
+// 个人访问令牌参数
+const (
+	accessTokenLength       = 40 // 令牌前缀之后的随机字符长度
+	accessTokenPrefixLength = 12 // 保存用于识别令牌的开头字符长度
+	maxAccessTokens         = 20 // 每个用户最多拥有的未撤销令牌数量
+)
+
+// ErrAccessTokenNotFound 访问令牌不存在或已撤销
+var ErrAccessTokenNotFound = errors.New("访问令牌不存在或已撤销")
+
+// GetAccessTokens 获取用户未撤销的个人访问令牌，包括已过期的令牌
+func (s *Service) GetAccessTokens(userID uint) ([]model.AccessTokenResponse, error) {
+	var tokens []dbmodel.AccessToken
+	err := database.DB.Where("user_id = ? AND revoked_at IS NULL", userID).Order("id DESC").Find(&tokens).Error
+	if err != nil {
+		return nil, errors.New("获取访问令牌失败: " + err.Error())
+	}
+
+	now := time.Now()
+	list := make([]model.AccessTokenResponse, 0, len(tokens))
+	for _, token := range tokens {
+		list = append(list, model.NewAccessTokenResponse(token, now))
+	}
+	return list, nil
+}
+
+// normalizeScopes 检查授权范围是否为用户角色拥有的权限，并去除重复的权限
+func normalizeScopes(roleID uint, scopes []string) ([]string, error) {
+	result := make([]string, 0, len(scopes))
+	seen := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !dbmodel.IsValidPermission(scope) {
+			return nil, errors.New("无效的权限: " + scope)
+		}
+		if !middleware.RoleHasPermission(roleID, scope) {
+			return nil, errors.New("当前角色没有权限: " + scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result, nil
+}
+
+// CreateAccessToken 创建个人访问令牌，返回的令牌只显示一次，数据库中只保存摘要
+func (s *Service) CreateAccessToken(userID uint, req model.CreateAccessTokenRequest) (*model.CreatedAccessToken, error) {
+	var user dbmodel.User
+	if err := database.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, errors.New("用户不存在")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("令牌名称不能为空")
+	}
+	scopes, err := normalizeScopes(user.RoleID, req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	if req.AppID != 0 {
+		var count int64
+		database.DB.Model(&dbmodel.App{}).Where("id = ? AND user_id = ?", req.AppID, userID).Count(&count)
+		if count == 0 {
+			return nil, errors.New("应用不存在或无权访问")
+		}
+	}
+
+	var count int64
+	database.DB.Model(&dbmodel.AccessToken{}).Where("user_id = ? AND revoked_at IS NULL", userID).Count(&count)
+	if count >= maxAccessTokens {
+		return nil, errors.New("访问令牌数量已达上限，请先撤销不再使用的令牌")
+	}
+
+	now := time.Now()
+	token := dbmodel.AccessTokenPrefix + random.RandomLettersDigits(accessTokenLength)
+	record := dbmodel.AccessToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    token[:accessTokenPrefixLength],
+		TokenHash: crypto.SHA256(token),
+		Scopes:    scopes,
+		AppID:     req.AppID,
+	}
+	if req.ExpiresDays > 0 {
+		expiresAt := now.AddDate(0, 0, req.ExpiresDays)
+		record.ExpiresAt = &expiresAt
+	}
+	if err := database.DB.Create(&record).Error; err != nil {
+		return nil, errors.New("创建访问令牌失败: " + err.Error())
+	}
+
+	return &model.CreatedAccessToken{
+		AccessTokenResponse: model.NewAccessTokenResponse(record, now),
+		Token:               token,
+	}, nil
+}
+
+// RevokeAccessToken 撤销用户的个人访问令牌，撤销后立即失效
+func (s *Service) RevokeAccessToken(userID, id uint) (dbmodel.AccessToken, error) {
+	var token dbmodel.AccessToken
+	err := database.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).First(&token).Error
+	if err != nil {
+		return token, ErrAccessTokenNotFound
+	}
+	if err := database.DB.Model(&token).Update("revoked_at", time.Now()).Error; err != nil {
+		return token, errors.New("撤销访问令牌失败: " + err.Error())
+	}
+	return token, nil
+}
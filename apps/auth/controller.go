@@ -1,12 +1,14 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/skyle1995/DevE-Server/database"
+	"github.com/skyle1995/DevE-Server/apps/auth/model"
 	dbmodel "github.com/skyle1995/DevE-Server/database/model"
 	"github.com/skyle1995/DevE-Server/middleware"
 	"github.com/skyle1995/DevE-Server/utils/captcha"
@@ -65,6 +67,19 @@ func (c *Controller) Login(ctx *gin.Context) {
 		return
 	}
 
+	// 登录失败次数较多时要求输入验证码
+	ip := ctx.ClientIP()
+	if req.Captcha == "" && c.service.CaptchaRequired(req.Username, ip) {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请输入验证码",
+			"data": gin.H{
+				"captcha_required": true,
+			},
+		})
+		return
+	}
+
 	// 验证验证码
 	if req.Captcha != "" {
 		captchaID, _ := ctx.Cookie("captcha_id")
@@ -84,15 +99,100 @@ func (c *Controller) Login(ctx *gin.Context) {
 	}
 
 	// 调用服务层处理登录
-	user, token, err := c.service.Login(req.Username, req.Password, remember)
+	user, tokens, challenge, err := c.service.Login(req.Username, req.Password, ip, ctx.Request.UserAgent(), remember)
+
+	// 计算请求处理时间
+	latency := time.Since(startTime).Milliseconds()
+
+	if c.passwordChangeRequired(ctx, user.ID, err, latency) {
+		return
+	}
+	if err != nil {
+		c.loginFailed(ctx, user.ID, req.Username, err, latency)
+		return
+	}
+
+	// 需要两步验证时返回挑战码，不签发令牌
+	if challenge != nil {
+		middleware.LoginLog(user.ID, "密码验证通过，等待两步验证", ip, ctx.Request.UserAgent(), http.StatusOK, latency)
+
+		ctx.JSON(http.StatusOK, gin.H{
+			"code":    200,
+			"message": "请输入两步验证码",
+			"data":    challenge,
+		})
+		return
+	}
+
+	c.loginSucceeded(ctx, user.ID, "用户登录成功", tokens, latency, nil)
+}
+
+// LoginTwoFactor 处理登录的两步验证，验证通过后签发令牌
+func (c *Controller) LoginTwoFactor(ctx *gin.Context) {
+	// 记录请求开始时间
+	startTime := time.Now()
+
+	var req model.TwoFactorLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	result, err := c.service.LoginTwoFactor(req.Challenge, req.Code, ctx.ClientIP(), ctx.Request.UserAgent())
 
 	// 计算请求处理时间
 	latency := time.Since(startTime).Milliseconds()
 
+	if c.passwordChangeRequired(ctx, result.User.ID, err, latency) {
+		return
+	}
 	if err != nil {
-		// 记录登录失败日志
-		middleware.LoginLog(0, "登录失败: "+err.Error(), ctx.ClientIP(), ctx.Request.UserAgent(), http.StatusUnauthorized, latency)
+		c.loginFailed(ctx, result.User.ID, result.User.Username, err, latency)
+		return
+	}
+
+	var extra gin.H
+	if len(result.RecoveryCodes) > 0 {
+		extra = gin.H{"recovery_codes": result.RecoveryCodes}
+	}
+	c.loginSucceeded(ctx, result.User.ID, "用户登录成功", result.Tokens, latency, extra)
+}
+
+// Refresh 使用刷新令牌换取新的访问令牌，刷新令牌从请求体或cookie中读取
+func (c *Controller) Refresh(ctx *gin.Context) {
+	startTime := time.Now()
+
+	var req model.RefreshTokenRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{
+				"code":    400,
+				"message": "请求参数错误: " + err.Error(),
+			})
+			return
+		}
+	}
+	if req.RefreshToken == "" {
+		req.RefreshToken, _ = ctx.Cookie(refreshCookieName)
+	}
+	if req.RefreshToken == "" {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "未提供刷新令牌",
+		})
+		return
+	}
 
+	user, tokens, err := c.service.RefreshToken(req.RefreshToken, ctx.ClientIP())
+	latency := time.Since(startTime).Milliseconds()
+	if err != nil {
+		if user.ID != 0 {
+			middleware.LoginLog(user.ID, "刷新令牌失败: "+err.Error(), ctx.ClientIP(), ctx.Request.UserAgent(), http.StatusUnauthorized, latency)
+		}
+		clearTokenCookies(ctx)
 		ctx.JSON(http.StatusUnauthorized, gin.H{
 			"code":    401,
 			"message": err.Error(),
@@ -100,39 +200,189 @@ func (c *Controller) Login(ctx *gin.Context) {
 		return
 	}
 
-	// 记录登录成功日志
-	middleware.LoginLog(user.ID, "用户登录成功", ctx.ClientIP(), ctx.Request.UserAgent(), http.StatusOK, latency)
-
-	// 设置token到cookie
-	maxAge := 24 * 3600 // 默认1天
-	if remember {
-		// 从数据库获取记住密码的有效天数
-		var rememberMeDays int = 30 // 默认30天
-
-		// 查询数据库中的设置
-		var setting dbmodel.SystemSetting
-		result := database.DB.Where("key = ?", "user_remember_me_days").First(&setting)
-		if result.Error == nil && setting.Value != "" {
-			// 尝试将设置值转换为整数
-			fmt.Sscanf(setting.Value, "%d", &rememberMeDays)
+	c.loginSucceeded(ctx, user.ID, "刷新令牌成功", tokens, latency, nil)
+}
+
+// LoginChangePassword 使用登录返回的挑战码设置新密码，成功后签发令牌
+func (c *Controller) LoginChangePassword(ctx *gin.Context) {
+	// 记录请求开始时间
+	startTime := time.Now()
+
+	var req model.PasswordLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	user, tokens, err := c.service.LoginChangePassword(req.Challenge, req.Password, ctx.ClientIP(), ctx.Request.UserAgent())
+
+	// 计算请求处理时间
+	latency := time.Since(startTime).Milliseconds()
+
+	if err != nil {
+		if user.ID != 0 {
+			middleware.LoginLog(user.ID, "登录时修改密码失败: "+err.Error(), ctx.ClientIP(), ctx.Request.UserAgent(), http.StatusBadRequest, latency)
 		}
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
 
-		maxAge = rememberMeDays * 24 * 3600 // 设置为数据库配置的天数
+	c.loginSucceeded(ctx, user.ID, "修改密码并登录成功", tokens, latency, nil)
+}
+
+// passwordChangeRequired 登录需要先修改密码时记录日志并返回修改密码的挑战码，返回是否已处理
+func (c *Controller) passwordChangeRequired(ctx *gin.Context, userID uint, err error, latency int64) bool {
+	var changeErr *model.PasswordChangeRequiredError
+	if !errors.As(err, &changeErr) {
+		return false
 	}
 
-	// 设置HttpOnly cookie，增加安全性
-	ctx.SetCookie("token", token, maxAge, "/", "", false, true)
+	middleware.LoginLog(userID, "密码验证通过，等待修改密码", ctx.ClientIP(), ctx.Request.UserAgent(), http.StatusOK, latency)
 
-	// 返回登录成功响应
 	ctx.JSON(http.StatusOK, gin.H{
 		"code":    200,
-		"message": "登录成功",
+		"message": changeErr.Error(),
+		"data":    changeErr.Challenge,
+	})
+	return true
+}
+
+// GetPasswordPolicy 获取密码策略，供注册和修改密码页面提示密码要求
+func (c *Controller) GetPasswordPolicy(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    c.service.GetPasswordPolicy(),
+	})
+}
+
+// SetupTwoFactorByChallenge 角色要求两步验证但尚未开启的用户在登录时绑定验证器
+func (c *Controller) SetupTwoFactorByChallenge(ctx *gin.Context) {
+	var req model.TwoFactorChallengeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	setup, err := c.service.SetupTwoFactorByChallenge(req.Challenge)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    setup,
+	})
+}
+
+// loginFailed 记录登录失败日志并返回错误响应，登录被锁定时返回429
+func (c *Controller) loginFailed(ctx *gin.Context, userID uint, username string, err error, latency int64) {
+	ip := ctx.ClientIP()
+
+	var lockedErr *model.LoginLockedError
+	if errors.As(err, &lockedErr) {
+		// 本次失败触发锁定时记录锁定日志，锁定期间的登录请求记录为登录失败
+		if lockedErr.Triggered {
+			for _, lock := range lockedErr.Locks {
+				content := fmt.Sprintf("登录锁定: %s %s 登录失败次数过多，锁定至 %s", lockTargetName(lock.Target), lock.Value, lock.Until.Format("2006-01-02 15:04:05"))
+				middleware.LoginLog(userID, content, ip, ctx.Request.UserAgent(), http.StatusTooManyRequests, latency)
+			}
+		} else {
+			middleware.LoginLog(userID, "登录失败: "+err.Error(), ip, ctx.Request.UserAgent(), http.StatusTooManyRequests, latency)
+		}
+
+		ctx.JSON(http.StatusTooManyRequests, gin.H{
+			"code":    429,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 系统关闭登录时拒绝非管理员登录
+	if errors.Is(err, ErrLoginDisabled) {
+		middleware.LoginLog(userID, "登录失败: "+err.Error(), ip, ctx.Request.UserAgent(), http.StatusForbidden, latency)
+
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 邮箱未验证时提示重新发送验证邮件
+	if errors.Is(err, ErrEmailNotVerified) {
+		middleware.LoginLog(userID, "登录失败: "+err.Error(), ip, ctx.Request.UserAgent(), http.StatusForbidden, latency)
+
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"code":    403,
+			"message": err.Error(),
+			"data": gin.H{
+				"email_verification_required": true,
+			},
+		})
+		return
+	}
+
+	// 记录登录失败日志
+	middleware.LoginLog(userID, "登录失败: "+err.Error(), ip, ctx.Request.UserAgent(), http.StatusUnauthorized, latency)
+
+	ctx.JSON(http.StatusUnauthorized, gin.H{
+		"code":    401,
+		"message": err.Error(),
 		"data": gin.H{
-			"token": token,
+			"captcha_required": c.service.CaptchaRequired(username, ip),
 		},
 	})
 }
 
+// loginSucceeded 记录登录成功日志，将令牌写入cookie并返回登录成功响应
+// extra 为需要一并返回的其他数据
+func (c *Controller) loginSucceeded(ctx *gin.Context, userID uint, content string, tokens model.TokenPair, latency int64, extra gin.H) {
+	// 记录登录成功日志
+	middleware.LoginLog(userID, content, ctx.ClientIP(), ctx.Request.UserAgent(), http.StatusOK, latency)
+
+	// 设置HttpOnly cookie，增加安全性；刷新令牌只发送给认证接口
+	ctx.SetCookie("token", tokens.Token, tokens.ExpiresIn, "/", "", false, true)
+	ctx.SetCookie(refreshCookieName, tokens.RefreshToken, tokens.RefreshExpiresIn, refreshCookiePath, "", false, true)
+
+	data := gin.H{
+		"token":              tokens.Token,
+		"refresh_token":      tokens.RefreshToken,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_expires_in": tokens.RefreshExpiresIn,
+	}
+	for key, value := range extra {
+		data[key] = value
+	}
+
+	// 返回登录成功响应
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "登录成功",
+		"data":    data,
+	})
+}
+
+// clearTokenCookies 清除访问令牌和刷新令牌cookie
+func clearTokenCookies(ctx *gin.Context) {
+	ctx.SetCookie("token", "", -1, "/", "", false, true)
+	ctx.SetCookie(refreshCookieName, "", -1, refreshCookiePath, "", false, true)
+}
+
 // Register 处理用户注册请求
 func (c *Controller) Register(ctx *gin.Context) {
 	// 记录请求开始时间
@@ -140,9 +390,10 @@ func (c *Controller) Register(ctx *gin.Context) {
 
 	// 绑定请求参数
 	var req struct {
-		Username string `json:"username" binding:"required"`
-		Password string `json:"password" binding:"required"`
-		Email    string `json:"email" binding:"required,email"`
+		Username   string `json:"username" binding:"required"`
+		Password   string `json:"password" binding:"required"`
+		Email      string `json:"email" binding:"required,email"`
+		InviteCode string `json:"invite_code"`
 	}
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -154,7 +405,7 @@ func (c *Controller) Register(ctx *gin.Context) {
 	}
 
 	// 调用服务层处理注册
-	err := c.service.Register(req.Username, req.Password, req.Email)
+	result, err := c.service.Register(req.Username, req.Password, req.Email, req.InviteCode, ctx.ClientIP())
 
 	// 计算请求处理时间
 	latency := time.Since(startTime).Milliseconds()
@@ -174,9 +425,141 @@ func (c *Controller) Register(ctx *gin.Context) {
 	middleware.LoginLog(0, "用户注册成功: "+req.Username, ctx.ClientIP(), ctx.Request.UserAgent(), http.StatusOK, latency)
 
 	// 返回注册成功响应
+	message := "注册成功"
+	if result.EmailVerificationRequired {
+		message = "注册成功，请查收验证邮件完成邮箱验证"
+	} else if result.ApprovalRequired {
+		message = "注册成功，请等待管理员审核"
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": message,
+		"data":    result,
+	})
+}
+
+// VerifyEmail 使用验证邮件中的令牌完成邮箱验证
+func (c *Controller) VerifyEmail(ctx *gin.Context) {
+	startTime := time.Now()
+
+	token := ctx.Query("token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: 缺少token",
+		})
+		return
+	}
+
+	user, err := c.service.VerifyEmail(token)
+	latency := time.Since(startTime).Milliseconds()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	middleware.LoginLog(user.ID, "邮箱验证成功: "+user.Email, ctx.ClientIP(), ctx.Request.UserAgent(), http.StatusOK, latency)
+
+	message := "邮箱验证成功"
+	if user.Status == dbmodel.UserStatusDisabled {
+		message = "邮箱验证成功，请等待管理员审核"
+	}
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": message,
+	})
+}
+
+// ResendVerification 重新发送验证邮件
+func (c *Controller) ResendVerification(ctx *gin.Context) {
+	var req model.ResendVerificationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := c.service.ResendVerification(req.Email, ctx.ClientIP()); err != nil {
+		c.mailFailed(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "如果该邮箱已注册且未验证，验证邮件将发送到该邮箱",
+	})
+}
+
+// ForgotPassword 发送重置密码邮件
+func (c *Controller) ForgotPassword(ctx *gin.Context) {
+	var req model.ForgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := c.service.ForgotPassword(req.Email, ctx.ClientIP()); err != nil {
+		c.mailFailed(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "如果该邮箱已绑定账号，重置密码邮件将发送到该邮箱",
+	})
+}
+
+// ResetPassword 使用重置密码邮件中的令牌设置新密码
+func (c *Controller) ResetPassword(ctx *gin.Context) {
+	startTime := time.Now()
+
+	var req model.ResetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	user, err := c.service.ResetPassword(req.Token, req.Password)
+	latency := time.Since(startTime).Milliseconds()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	middleware.LoginLog(user.ID, "通过邮件重置密码成功", ctx.ClientIP(), ctx.Request.UserAgent(), http.StatusOK, latency)
+
 	ctx.JSON(http.StatusOK, gin.H{
 		"code":    200,
-		"message": "注册成功",
+		"message": "密码重置成功，请使用新密码登录",
+	})
+}
+
+// mailFailed 返回邮件发送失败的响应，发送过于频繁时返回429，邮件服务未启用或未设置网站地址时返回503
+func (c *Controller) mailFailed(ctx *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrMailTooFrequent):
+		status = http.StatusTooManyRequests
+	case errors.Is(err, ErrMailDisabled), errors.Is(err, ErrSiteURLRequired):
+		status = http.StatusServiceUnavailable
+	}
+	ctx.JSON(status, gin.H{
+		"code":    status,
+		"message": err.Error(),
 	})
 }
 
@@ -210,15 +593,11 @@ func (c *Controller) GetUserInfo(ctx *gin.Context) {
 	})
 }
 
-// UpdatePassword 更新用户密码
+// UpdatePassword 更新用户密码，更新后撤销所有会话
 func (c *Controller) UpdatePassword(ctx *gin.Context) {
 	// 从上下文中获取用户ID
-	userID, exists := ctx.Get("userID")
-	if !exists {
-		ctx.JSON(http.StatusUnauthorized, gin.H{
-			"code":    401,
-			"message": "未授权",
-		})
+	userID, ok := currentUserID(ctx)
+	if !ok {
 		return
 	}
 
@@ -237,7 +616,7 @@ func (c *Controller) UpdatePassword(ctx *gin.Context) {
 	}
 
 	// 调用服务层更新密码
-	err := c.service.UpdatePassword(userID.(uint), req.OldPassword, req.NewPassword)
+	err := c.service.UpdatePassword(userID, req.OldPassword, req.NewPassword)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
@@ -246,10 +625,13 @@ func (c *Controller) UpdatePassword(ctx *gin.Context) {
 		return
 	}
 
+	middleware.LoginLog(userID, "修改密码，已注销所有会话", ctx.ClientIP(), ctx.Request.UserAgent(), http.StatusOK)
+	clearTokenCookies(ctx)
+
 	// 返回更新成功响应
 	ctx.JSON(http.StatusOK, gin.H{
 		"code":    200,
-		"message": "密码更新成功",
+		"message": "密码更新成功，请重新登录",
 	})
 }
 
@@ -258,23 +640,39 @@ func (c *Controller) Logout(ctx *gin.Context) {
 	// 记录请求开始时间
 	startTime := time.Now()
 
-	// 从上下文中获取用户ID
-	userID, exists := ctx.Get("userID")
-	if !exists {
-		userID = uint(0)
-	} else if _, ok := userID.(uint); !ok {
-		// 如果类型不是uint，则设置为0
-		userID = uint(0)
+	// 访问令牌从请求头或cookie中读取，刷新令牌从请求体或cookie中读取
+	accessToken, err := newJWT().GetTokenFromHeader(ctx.GetHeader("Authorization"))
+	if err != nil {
+		accessToken, _ = ctx.Cookie("token")
 	}
-
+	var req model.RefreshTokenRequest
+	if ctx.Request.ContentLength > 0 {
+		_ = ctx.ShouldBindJSON(&req)
+	}
+	if req.RefreshToken == "" {
+		req.RefreshToken, _ = ctx.Cookie(refreshCookieName)
+	}
+
+	// 撤销用户的所有会话
+	userID, err := c.service.Logout(accessToken, req.RefreshToken)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": err.Error(),
+		})
+		return
+	}
+
 	// 清除token cookie
-	ctx.SetCookie("token", "", -1, "/", "", false, true)
+	clearTokenCookies(ctx)
 
 	// 计算请求处理时间
 	latency := time.Since(startTime).Milliseconds()
 
 	// 记录注销日志
-	middleware.LoginLog(userID.(uint), "用户注销成功", ctx.ClientIP(), ctx.Request.UserAgent(), http.StatusOK, latency)
+	if userID != 0 {
+		middleware.LoginLog(userID, "用户注销成功", ctx.ClientIP(), ctx.Request.UserAgent(), http.StatusOK, latency)
+	}
 
 	// 返回注销成功响应
 	ctx.JSON(http.StatusOK, gin.H{
@@ -282,3 +680,360 @@ func (c *Controller) Logout(ctx *gin.Context) {
 		"message": "注销成功",
 	})
 }
+
+// lockTargetName 返回锁定对象类型的中文名称
+func lockTargetName(target string) string {
+	if target == model.LoginLockTargetIP {
+		return "IP"
+	}
+	return "用户名"
+}
+
+// GetLoginLocks 获取当前生效的登录锁定
+func (c *Controller) GetLoginLocks(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    c.service.GetLoginLocks(),
+	})
+}
+
+// UnlockLogin 解除用户名或IP的登录锁定
+func (c *Controller) UnlockLogin(ctx *gin.Context) {
+	var req model.UnlockLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	if req.Username == "" && req.IP == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "用户名和IP至少填写一项",
+		})
+		return
+	}
+
+	locks := c.service.UnlockLogin(req.Username, req.IP)
+
+	// 记录解锁日志
+	adminID, _ := ctx.Get("user_id")
+	for _, lock := range locks {
+		content := fmt.Sprintf("解除登录锁定: %s %s", lockTargetName(lock.Target), lock.Value)
+		middleware.LoginLog(adminID.(uint), content, ctx.ClientIP(), ctx.Request.UserAgent(), http.StatusOK)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "解锁成功",
+		"data": gin.H{
+			"count": len(locks),
+		},
+	})
+}
+
+// currentUserID 获取JWT中间件设置的当前用户ID
+func currentUserID(ctx *gin.Context) (uint, bool) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{
+			"code":    401,
+			"message": "未授权",
+		})
+		return 0, false
+	}
+	return userID.(uint), true
+}
+
+// GetTwoFactorStatus 获取当前用户的两步验证状态
+func (c *Controller) GetTwoFactorStatus(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	status, err := c.service.GetTwoFactorStatus(userID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    status,
+	})
+}
+
+// SetupTwoFactor 生成验证器密钥和配置URI，提交验证码后才开启两步验证
+func (c *Controller) SetupTwoFactor(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	setup, err := c.service.SetupTwoFactor(userID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    setup,
+	})
+}
+
+// EnableTwoFactor 校验验证码并开启两步验证，返回恢复码
+func (c *Controller) EnableTwoFactor(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req model.TwoFactorCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	codes, err := c.service.EnableTwoFactor(userID, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	middleware.LoginLog(userID, "开启两步验证", ctx.ClientIP(), ctx.Request.UserAgent(), http.StatusOK)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "两步验证已开启",
+		"data":    model.RecoveryCodesResponse{RecoveryCodes: codes},
+	})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+func (c *Controller) RegenerateRecoveryCodes(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req model.TwoFactorCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	codes, err := c.service.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	middleware.LoginLog(userID, "重新生成两步验证恢复码", ctx.ClientIP(), ctx.Request.UserAgent(), http.StatusOK)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "恢复码已重新生成",
+		"data":    model.RecoveryCodesResponse{RecoveryCodes: codes},
+	})
+}
+
+// DisableTwoFactor 校验密码和验证码后关闭两步验证
+func (c *Controller) DisableTwoFactor(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req model.TwoFactorDisableRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := c.service.DisableTwoFactor(userID, req.Password, req.Code); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	middleware.LoginLog(userID, "关闭两步验证", ctx.ClientIP(), ctx.Request.UserAgent(), http.StatusOK)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "两步验证已关闭",
+	})
+}
+
+// GetSessions 获取当前用户的登录会话
+func (c *Controller) GetSessions(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	sessions, err := c.service.GetSessions(userID, ctx.GetString("token_id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    sessions,
+	})
+}
+
+// RevokeSession 撤销当前用户的一个登录会话，用于退出其他设备
+func (c *Controller) RevokeSession(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	sessionID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的会话ID",
+		})
+		return
+	}
+
+	if err := c.service.RevokeSession(userID, uint(sessionID)); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	middleware.LoginLog(userID, fmt.Sprintf("撤销登录会话 %d", sessionID), ctx.ClientIP(), ctx.Request.UserAgent(), http.StatusOK)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "会话已撤销",
+	})
+}
+
+// GetAccessTokens 获取当前用户的个人访问令牌
+func (c *Controller) GetAccessTokens(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	tokens, err := c.service.GetAccessTokens(userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{
+			"code":    500,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    tokens,
+	})
+}
+
+// CreateAccessToken 创建个人访问令牌，响应中包含令牌，由控制器记录日志
+func (c *Controller) CreateAccessToken(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	var req model.CreateAccessTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	token, err := c.service.CreateAccessToken(userID, req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	middleware.LoginLog(userID, fmt.Sprintf("创建访问令牌 %d: %s", token.ID, token.Name), ctx.ClientIP(), ctx.Request.UserAgent(), http.StatusOK)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "访问令牌已创建，请立即保存，令牌只显示一次",
+		"data":    token,
+	})
+}
+
+// RevokeAccessToken 撤销当前用户的个人访问令牌
+func (c *Controller) RevokeAccessToken(ctx *gin.Context) {
+	userID, ok := currentUserID(ctx)
+	if !ok {
+		return
+	}
+
+	tokenID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "无效的令牌ID",
+		})
+		return
+	}
+
+	token, err := c.service.RevokeAccessToken(userID, uint(tokenID))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	middleware.LoginLog(userID, fmt.Sprintf("撤销访问令牌 %d: %s", token.ID, token.Name), ctx.ClientIP(), ctx.Request.UserAgent(), http.StatusOK)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "访问令牌已撤销",
+	})
+}
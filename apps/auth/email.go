@@ -0,0 +1,278 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skyle1995/DevE-Server/apps/setting"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/utils/cache"
+	"github.com/skyle1995/DevE-Server/utils/crypto"
+	"github.com/skyle1995/DevE-Server/utils/mailer"
+	"github.com/skyle1995/DevE-Server/utils/random"
+	"gorm.io/gorm"
+)
+
+// 邮件令牌参数
+const (
+	emailVerifyTTL     = 24 * time.Hour   // 邮箱验证链接有效期
+	passwordResetTTL   = 30 * time.Minute // 重置密码链接有效期
+	mailResendInterval = time.Minute      // 同一邮箱重复发送同类邮件的最短间隔
+	mailIPLimit        = 10               // 每个IP每小时最多触发的邮件数量
+	userTokenLength    = 48               // 令牌长度
+	verifyEmailPath    = "/#/verify-email?token="
+	resetPasswordPath  = "/#/reset-password?token="
+	mailThrottlePrefix = "mail:"
+	mailIPPrefix       = "mail_ip:"
+)
+
+// 邮件相关错误
+var (
+	ErrEmailNotVerified = errors.New("邮箱未验证，请先完成邮箱验证")
+	ErrUserTokenInvalid = errors.New("链接无效或已过期")
+	ErrMailDisabled     = errors.New("邮件服务未启用，请联系管理员")
+	ErrMailTooFrequent  = errors.New("邮件发送过于频繁，请稍后再试")
+	ErrSiteURLRequired  = errors.New("网站访问地址未设置，无法发送邮件，请联系管理员")
+)
+
+// mailThrottle 邮件发送频率限制，服务重启后清空
+var mailThrottle = cache.New(0, 5*time.Minute)
+
+// emailVerificationRequired 判断注册是否需要验证邮箱，邮件服务未启用时不要求
+func emailVerificationRequired() bool {
+	if !setting.NewService().GetBool("user_require_email_verification", false) {
+		return false
+	}
+	if !setting.NewService().GetMailConfig().Enable {
+		log.Warn("系统设置要求注册验证邮箱，但邮件服务未启用，新用户将直接启用")
+		return false
+	}
+	return true
+}
+
+// siteURL 返回生成邮件链接使用的网站地址
+// 链接只使用系统设置 site_url，不使用请求的Host请求头，避免链接被伪造的请求指向其他网站；未设置或不是有效的http(s)地址时返回 ErrSiteURLRequired
+func siteURL() (string, error) {
+	value := strings.TrimRight(strings.TrimSpace(setting.NewService().GetString("site_url", "")), "/")
+	parsed, err := url.Parse(value)
+	if value == "" || err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		log.Error("系统设置 site_url 未设置或无效，无法发送包含链接的邮件")
+		return "", ErrSiteURLRequired
+	}
+	return value, nil
+}
+
+// mailLinkBase 检查邮件服务是否可以发送包含链接的邮件，返回生成链接使用的网站地址
+func mailLinkBase() (string, error) {
+	if !setting.NewService().GetMailConfig().Enable {
+		return "", ErrMailDisabled
+	}
+	return siteURL()
+}
+
+// siteName 返回邮件中显示的网站名称
+func siteName() string {
+	if name := setting.NewService().GetString("site_name", ""); name != "" {
+		return name
+	}
+	return defaultTwoFactorIssuer
+}
+
+// issueUserToken 为用户生成一次性令牌，同类型未使用的旧令牌同时作废
+func issueUserToken(tx *gorm.DB, user dbmodel.User, tokenType string, ttl time.Duration, ip string) (string, error) {
+	token := random.RandomLettersDigits(userTokenLength)
+
+	if err := tx.Where("user_id = ? AND type = ? AND used_at IS NULL", user.ID, tokenType).Delete(&dbmodel.UserToken{}).Error; err != nil {
+		return "", err
+	}
+	record := dbmodel.UserToken{
+		UserID:    user.ID,
+		Type:      tokenType,
+		TokenHash: crypto.SHA256(token),
+		Email:     user.Email,
+		IP:        ip,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeUserToken 校验并使用令牌，令牌只能使用一次，且用户邮箱需与发送时一致
+func consumeUserToken(tx *gorm.DB, token, tokenType string) (dbmodel.User, error) {
+	var user dbmodel.User
+	var record dbmodel.UserToken
+	err := tx.Where("token_hash = ? AND type = ?", crypto.SHA256(token), tokenType).First(&record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, ErrUserTokenInvalid
+		}
+		return user, err
+	}
+	if record.UsedAt != nil || time.Now().After(record.ExpiresAt) {
+		return user, ErrUserTokenInvalid
+	}
+
+	// 按未使用条件更新，并发使用同一令牌时只有一个请求成功
+	result := tx.Model(&dbmodel.UserToken{}).Where("id = ? AND used_at IS NULL", record.ID).Update("used_at", time.Now())
+	if result.Error != nil {
+		return user, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return user, ErrUserTokenInvalid
+	}
+
+	if err := tx.First(&user, record.UserID).Error; err != nil {
+		return user, ErrUserTokenInvalid
+	}
+	if !strings.EqualFold(user.Email, record.Email) {
+		return user, ErrUserTokenInvalid
+	}
+	return user, nil
+}
+
+// sendUserTokenMail 使用模板发送包含令牌链接的邮件
+func sendUserTokenMail(user dbmodel.User, templateName, link string, ttl time.Duration) error {
+	msg, err := mailer.Render(templateName, map[string]string{
+		"SiteName":  siteName(),
+		"Username":  user.Username,
+		"Link":      link,
+		"ExpiresIn": formatDuration(ttl),
+	})
+	if err != nil {
+		return err
+	}
+	msg.To = []string{user.Email}
+	return mailer.New(setting.NewService().GetMailConfig()).Send(msg)
+}
+
+// formatDuration 将有效期格式化为中文描述
+func formatDuration(d time.Duration) string {
+	if d >= time.Hour && d%time.Hour == 0 {
+		return fmt.Sprintf("%d小时", d/time.Hour)
+	}
+	return fmt.Sprintf("%d分钟", d/time.Minute)
+}
+
+// throttleMail 检查邮件发送频率，同一邮箱的同类邮件间隔不少于 mailResendInterval，每个IP每小时不超过 mailIPLimit 封
+func throttleMail(tokenType, email, ip string) error {
+	key := mailThrottlePrefix + tokenType + ":" + strings.ToLower(email)
+	if _, found := mailThrottle.GetOrSet(key, true, mailResendInterval); found {
+		return ErrMailTooFrequent
+	}
+	if ip != "" && mailThrottle.IncrementOrSet(mailIPPrefix+ip, 1, time.Hour) > mailIPLimit {
+		return ErrMailTooFrequent
+	}
+	return nil
+}
+
+// VerifyEmail 使用验证邮件中的令牌完成邮箱验证，待验证的用户验证后启用
+func (s *Service) VerifyEmail(token string) (dbmodel.User, error) {
+	var user dbmodel.User
+	err := database.Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, err = consumeUserToken(tx, token, dbmodel.UserTokenTypeEmailVerify)
+		if err != nil {
+			return err
+		}
+		if user.Status == dbmodel.UserStatusPending {
+			user.Status = defaultUserStatus()
+			return tx.Model(&user).Update("status", user.Status).Error
+		}
+		return nil
+	})
+	return user, err
+}
+
+// ResendVerification 重新发送验证邮件
+// 为避免泄露邮箱是否已注册，邮箱不存在或已验证时同样返回成功
+func (s *Service) ResendVerification(email, ip string) error {
+	baseURL, err := mailLinkBase()
+	if err != nil {
+		return err
+	}
+	if err := throttleMail(dbmodel.UserTokenTypeEmailVerify, email, ip); err != nil {
+		return err
+	}
+
+	var user dbmodel.User
+	if err := database.DB.Where("email = ? AND status = ?", email, dbmodel.UserStatusPending).First(&user).Error; err != nil {
+		return nil
+	}
+
+	token, err := issueUserToken(database.DB, user, dbmodel.UserTokenTypeEmailVerify, emailVerifyTTL, ip)
+	if err != nil {
+		return err
+	}
+	if err := sendUserTokenMail(user, mailer.TemplateVerifyEmail, baseURL+verifyEmailPath+token, emailVerifyTTL); err != nil {
+		log.Errorf("发送验证邮件失败: %v", err)
+		return errors.New("验证邮件发送失败，请稍后再试")
+	}
+	return nil
+}
+
+// ForgotPassword 发送重置密码邮件
+// 为避免泄露邮箱是否已注册，邮箱不存在或账号不可用时同样返回成功
+func (s *Service) ForgotPassword(email, ip string) error {
+	baseURL, err := mailLinkBase()
+	if err != nil {
+		return err
+	}
+	if err := throttleMail(dbmodel.UserTokenTypePasswordReset, email, ip); err != nil {
+		return err
+	}
+
+	var user dbmodel.User
+	if err := database.DB.Where("email = ? AND status = ?", email, dbmodel.UserStatusActive).First(&user).Error; err != nil {
+		return nil
+	}
+
+	token, err := issueUserToken(database.DB, user, dbmodel.UserTokenTypePasswordReset, passwordResetTTL, ip)
+	if err != nil {
+		return err
+	}
+	if err := sendUserTokenMail(user, mailer.TemplateResetPassword, baseURL+resetPasswordPath+token, passwordResetTTL); err != nil {
+		log.Errorf("发送重置密码邮件失败: %v", err)
+		return errors.New("重置密码邮件发送失败，请稍后再试")
+	}
+	return nil
+}
+
+// ResetPassword 使用重置密码邮件中的令牌设置新密码，新密码需符合密码策略，成功后清除该用户名的登录失败次数
+func (s *Service) ResetPassword(token, password string) (dbmodel.User, error) {
+	var user dbmodel.User
+	err := database.Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, err = consumeUserToken(tx, token, dbmodel.UserTokenTypePasswordReset)
+		if err != nil {
+			return err
+		}
+		if user.Status != dbmodel.UserStatusActive {
+			return errors.New("账号已被禁用")
+		}
+
+		// 按密码策略检查并更新密码，不符合时回滚，令牌仍可使用
+		if err := setPassword(tx, user, password, false); err != nil {
+			return err
+		}
+		// 作废其他未使用的重置密码令牌
+		if err := tx.Where("user_id = ? AND type = ? AND used_at IS NULL", user.ID, dbmodel.UserTokenTypePasswordReset).
+			Delete(&dbmodel.UserToken{}).Error; err != nil {
+			return err
+		}
+		// 撤销所有会话，需要使用新密码重新登录
+		return revokeSessions(tx, "user_id", user.ID)
+	})
+	if err != nil {
+		return user, err
+	}
+
+	clearLoginFailures(user.Username)
+	return user, nil
+}
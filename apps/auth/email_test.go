@@ -0,0 +1,432 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skyle1995/DevE-Server/apps/invite"
+	invitemodel "github.com/skyle1995/DevE-Server/apps/invite/model"
+	"github.com/skyle1995/DevE-Server/apps/setting"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/utils/mailer/mailertest"
+	"github.com/spf13/viper"
+)
+
+// testSiteURL 测试中邮件链接使用的网站地址
+const testSiteURL = "https://deve.test"
+
+// tokenPattern 从邮件正文中提取令牌
+var tokenPattern = regexp.MustCompile(`token=([A-Za-z0-9]+)`)
+
+// TestMain 使用临时SQLite数据库
+func TestMain(m *testing.M) {
+	viper.Set("server.mode", "test")
+	gin.SetMode(gin.TestMode)
+	viper.Set("security.bcrypt_cost", 4)
+
+	dir, err := os.MkdirTemp("", "deve-auth-test")
+	if err != nil {
+		panic(err)
+	}
+	viper.Set("database.type", "SQLite")
+	viper.Set("database.sqlite.path", dir+"/test.db")
+	database.Init()
+
+	if err := database.NewMigration().AutoMigrate(); err != nil {
+		panic(err)
+	}
+	if err := database.NewMigration().InitDefaultData(); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	database.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// setSetting 修改系统设置，测试结束后恢复原值
+func setSetting(t *testing.T, key, value string) {
+	t.Helper()
+	service := setting.NewService()
+	current, err := service.GetSettingByKey(key)
+	if err != nil {
+		t.Fatalf("系统设置 %s 不存在: %v", key, err)
+	}
+	if err := service.UpdateSetting(key, value); err != nil {
+		t.Fatalf("修改系统设置 %s 失败: %v", key, err)
+	}
+	t.Cleanup(func() {
+		service.UpdateSetting(key, current.Value)
+	})
+}
+
+// useMailServer 启动本地SMTP服务器并将邮件设置指向该服务器
+func useMailServer(t *testing.T) *mailertest.Server {
+	t.Helper()
+	server := mailertest.NewServer(t)
+	config := server.Config()
+	setSetting(t, "mail_enable", "1")
+	setSetting(t, "mail_smtp_host", config.Host)
+	setSetting(t, "mail_smtp_port", strconv.Itoa(config.Port))
+	setSetting(t, "mail_smtp_secure", "0")
+	setSetting(t, "mail_username", config.Username)
+	setSetting(t, "mail_password", config.Password)
+	setSetting(t, "site_url", testSiteURL)
+	return server
+}
+
+// receivedToken 返回发送给指定邮箱的最后一封邮件中的令牌
+func receivedToken(t *testing.T, server *mailertest.Server, email, path string) string {
+	t.Helper()
+	messages := server.Messages()
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if len(msg.To) != 1 || msg.To[0] != email {
+			continue
+		}
+		text := msg.Text()
+		if !strings.Contains(text, testSiteURL+path) {
+			t.Fatalf("邮件链接应使用 site_url 和 %s: %q", path, text)
+		}
+		if !strings.Contains(msg.HTML(), testSiteURL+path) {
+			t.Fatalf("HTML正文缺少链接: %q", msg.HTML())
+		}
+		match := tokenPattern.FindStringSubmatch(text)
+		if match == nil {
+			t.Fatalf("邮件中没有令牌: %q", text)
+		}
+		return match[1]
+	}
+	t.Fatalf("没有发送给 %s 的邮件", email)
+	return ""
+}
+
+// roleID 返回默认角色的ID
+func roleID(t *testing.T, code string) uint {
+	t.Helper()
+	var role dbmodel.Role
+	if err := database.DB.Where("code = ?", code).First(&role).Error; err != nil {
+		t.Fatalf("查询角色 %s 失败: %v", code, err)
+	}
+	return role.ID
+}
+
+// newUser 创建已启用的普通会员
+func newUser(t *testing.T, password string) dbmodel.User {
+	t.Helper()
+	name := strings.ToLower(strings.ReplaceAll(t.Name(), "/", "_"))
+	user := dbmodel.User{
+		Username: name,
+		Password: password,
+		Email:    name + "@example.com",
+		RoleID:   roleID(t, dbmodel.RoleCodeMember),
+		Status:   dbmodel.UserStatusActive,
+	}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	return user
+}
+
+// newInviteCode 为当前测试创建邀请码
+func newInviteCode(t *testing.T, creator dbmodel.User, req invitemodel.CreateInviteCodeRequest) dbmodel.InviteCode {
+	t.Helper()
+	codes, err := invite.NewService().CreateInviteCodes(req, creator.ID)
+	if err != nil {
+		t.Fatalf("创建邀请码失败: %v", err)
+	}
+	return codes[0]
+}
+
+func TestRegisterEmailVerification(t *testing.T) {
+	server := useMailServer(t)
+	setSetting(t, "user_require_email_verification", "1")
+	s := NewService()
+
+	email := "verify@example.com"
+	result, err := s.Register("verify_user", "secret123", email, "", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("注册失败: %v", err)
+	}
+	if !result.EmailVerificationRequired {
+		t.Fatal("要求验证邮箱时注册应返回待验证")
+	}
+
+	var user dbmodel.User
+	database.DB.Where("username = ?", "verify_user").First(&user)
+	if user.Status != dbmodel.UserStatusPending {
+		t.Fatalf("用户状态 = %d，期望待验证", user.Status)
+	}
+	if got := server.Messages()[0].Subject(); !strings.Contains(got, "验证") {
+		t.Errorf("验证邮件主题 = %q", got)
+	}
+	token := receivedToken(t, server, email, verifyEmailPath)
+
+	// 验证前不能登录，密码错误时不提示未验证
+	if _, _, _, err := s.Login("verify_user", "wrong", "", "", false); errors.Is(err, ErrEmailNotVerified) {
+		t.Error("密码错误时不应提示邮箱未验证")
+	}
+	if _, _, _, err := s.Login("verify_user", "secret123", "", "", false); !errors.Is(err, ErrEmailNotVerified) {
+		t.Fatalf("未验证邮箱登录应返回 ErrEmailNotVerified，实际为 %v", err)
+	}
+
+	if _, err := s.VerifyEmail("invalid"); !errors.Is(err, ErrUserTokenInvalid) {
+		t.Errorf("无效令牌应返回 ErrUserTokenInvalid，实际为 %v", err)
+	}
+	verified, err := s.VerifyEmail(token)
+	if err != nil {
+		t.Fatalf("验证邮箱失败: %v", err)
+	}
+	if verified.ID != user.ID || verified.Status != dbmodel.UserStatusActive {
+		t.Errorf("验证后的用户 = %+v", verified)
+	}
+	if _, err := s.VerifyEmail(token); !errors.Is(err, ErrUserTokenInvalid) {
+		t.Errorf("令牌只能使用一次，实际为 %v", err)
+	}
+
+	if _, tokens, _, err := s.Login("verify_user", "secret123", "", "", false); err != nil || tokens.Token == "" {
+		t.Fatalf("验证邮箱后登录失败: %v", err)
+	}
+}
+
+func TestRegisterMailFailure(t *testing.T) {
+	useMailServer(t)
+	setSetting(t, "user_require_email_verification", "1")
+
+	// 指向已关闭的端口
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	setSetting(t, "mail_smtp_port", strconv.Itoa(port))
+
+	creator := newUser(t, "secret123")
+	code := newInviteCode(t, creator, invitemodel.CreateInviteCodeRequest{RoleID: roleID(t, dbmodel.RoleCodeMember), MaxUses: 1})
+	if _, err := NewService().Register("mail_failure", "secret123", "mail_failure@example.com", code.Code, ""); err == nil {
+		t.Fatal("验证邮件发送失败时注册应失败")
+	}
+	var count int64
+	database.DB.Unscoped().Model(&dbmodel.User{}).Where("username = ?", "mail_failure").Count(&count)
+	if count != 0 {
+		t.Error("验证邮件发送失败时不应保留用户")
+	}
+
+	// 邀请码的使用次数恢复，可以使用同一用户名和邀请码重新注册
+	var current dbmodel.InviteCode
+	database.DB.First(&current, code.ID)
+	database.DB.Model(&dbmodel.InviteCodeUsage{}).Where("invite_code_id = ?", code.ID).Count(&count)
+	if current.UsedCount != 0 || count != 0 {
+		t.Errorf("邀请码使用次数 = %d，使用记录 = %d，期望均为0", current.UsedCount, count)
+	}
+	setSetting(t, "user_require_email_verification", "0")
+	if _, err := NewService().Register("mail_failure", "secret123", "mail_failure@example.com", code.Code, ""); err != nil {
+		t.Errorf("重新注册失败: %v", err)
+	}
+}
+
+func TestRegisterWithoutVerification(t *testing.T) {
+	for _, tc := range []struct {
+		name, require, mailEnable string
+	}{
+		{"not_required", "0", "1"},
+		{"mail_disabled", "1", "0"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := useMailServer(t)
+			setSetting(t, "user_require_email_verification", tc.require)
+			setSetting(t, "mail_enable", tc.mailEnable)
+			s := NewService()
+
+			username := "no_verify_" + tc.name
+			result, err := s.Register(username, "secret123", username+"@example.com", "", "")
+			if err != nil || result.EmailVerificationRequired {
+				t.Fatalf("注册结果 %+v err=%v，期望直接启用", result, err)
+			}
+			if n := len(server.Messages()); n != 0 {
+				t.Errorf("不需要验证时发送了 %d 封邮件", n)
+			}
+			if _, tokens, _, err := s.Login(username, "secret123", "", "", false); err != nil || tokens.Token == "" {
+				t.Errorf("注册后登录失败: %v", err)
+			}
+		})
+	}
+}
+
+func TestResendVerification(t *testing.T) {
+	server := useMailServer(t)
+	setSetting(t, "user_require_email_verification", "1")
+	s := NewService()
+
+	email := "resend@example.com"
+	if _, err := s.Register("resend_user", "secret123", email, "", ""); err != nil {
+		t.Fatalf("注册失败: %v", err)
+	}
+	oldToken := receivedToken(t, server, email, verifyEmailPath)
+
+	if err := s.ResendVerification(email, ""); err != nil {
+		t.Fatalf("重新发送验证邮件失败: %v", err)
+	}
+	if n := len(server.Messages()); n != 2 {
+		t.Fatalf("收到 %d 封邮件，期望 2 封", n)
+	}
+	newToken := receivedToken(t, server, email, verifyEmailPath)
+	if err := s.ResendVerification(email, ""); !errors.Is(err, ErrMailTooFrequent) {
+		t.Errorf("频繁发送应返回 ErrMailTooFrequent，实际为 %v", err)
+	}
+
+	// 重新发送后旧令牌作废
+	if _, err := s.VerifyEmail(oldToken); !errors.Is(err, ErrUserTokenInvalid) {
+		t.Errorf("旧令牌应作废，实际为 %v", err)
+	}
+	if _, err := s.VerifyEmail(newToken); err != nil {
+		t.Fatalf("验证邮箱失败: %v", err)
+	}
+
+	// 未注册或已验证的邮箱同样返回成功，但不发送邮件
+	server.Reset()
+	for _, email := range []string{"unknown@example.com", "resend_verified@example.com"} {
+		if err := s.ResendVerification(email, ""); err != nil {
+			t.Errorf("邮箱 %s 应返回成功，实际为 %v", email, err)
+		}
+	}
+	if n := len(server.Messages()); n != 0 {
+		t.Errorf("不应发送邮件，实际发送了 %d 封", n)
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	server := useMailServer(t)
+	s := NewService()
+	user := newUser(t, "oldpass123")
+
+	if err := s.ForgotPassword(user.Email, ""); err != nil {
+		t.Fatalf("发送重置密码邮件失败: %v", err)
+	}
+	token := receivedToken(t, server, user.Email, resetPasswordPath)
+	if err := s.ForgotPassword(user.Email, ""); !errors.Is(err, ErrMailTooFrequent) {
+		t.Errorf("频繁发送应返回 ErrMailTooFrequent，实际为 %v", err)
+	}
+
+	// 登录失败次数在重置密码后清除
+	for i := 0; i < 2; i++ {
+		s.Login(user.Username, "wrong", "", "", false)
+	}
+	if _, err := s.ResetPassword("invalid", "newpass123"); !errors.Is(err, ErrUserTokenInvalid) {
+		t.Errorf("无效令牌应返回 ErrUserTokenInvalid，实际为 %v", err)
+	}
+	if _, err := s.ResetPassword(token, "newpass123"); err != nil {
+		t.Fatalf("重置密码失败: %v", err)
+	}
+	if s.CaptchaRequired(user.Username, "") {
+		t.Error("重置密码后应清除登录失败次数")
+	}
+	if _, err := s.ResetPassword(token, "another123"); !errors.Is(err, ErrUserTokenInvalid) {
+		t.Errorf("令牌只能使用一次，实际为 %v", err)
+	}
+
+	if _, _, _, err := s.Login(user.Username, "oldpass123", "", "", false); err == nil {
+		t.Error("重置后旧密码不应能登录")
+	}
+	if _, tokens, _, err := s.Login(user.Username, "newpass123", "", "", false); err != nil || tokens.Token == "" {
+		t.Fatalf("新密码登录失败: %v", err)
+	}
+}
+
+func TestMailRequiresSiteURL(t *testing.T) {
+	server := useMailServer(t)
+	setSetting(t, "user_require_email_verification", "1")
+	s := NewService()
+	user := newUser(t, "secret123")
+
+	// 未设置或无效的 site_url 不能发送包含链接的邮件，不使用请求的Host生成链接
+	for _, value := range []string{"", "evil.example", "javascript:alert(1)"} {
+		setSetting(t, "site_url", value)
+		if err := s.ForgotPassword(user.Email, ""); !errors.Is(err, ErrSiteURLRequired) {
+			t.Errorf("site_url = %q 时找回密码应返回 ErrSiteURLRequired，实际为 %v", value, err)
+		}
+		username := fmt.Sprintf("site_url_%d", len(value))
+		if _, err := s.Register(username, "secret123", username+"@example.com", "", ""); !errors.Is(err, ErrSiteURLRequired) {
+			t.Errorf("site_url = %q 时需要验证邮箱的注册应返回 ErrSiteURLRequired，实际为 %v", value, err)
+		}
+	}
+
+	if n := len(server.Messages()); n != 0 {
+		t.Errorf("未设置网站地址时发送了 %d 封邮件", n)
+	}
+	var count int64
+	database.DB.Model(&dbmodel.UserToken{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != 0 {
+		t.Error("未设置网站地址时不应生成令牌")
+	}
+	database.DB.Model(&dbmodel.User{}).Where("username LIKE ?", "site_url_%").Count(&count)
+	if count != 0 {
+		t.Error("未设置网站地址时不应创建需要验证邮箱的用户")
+	}
+}
+
+func TestPasswordResetTokenRules(t *testing.T) {
+	useMailServer(t)
+	s := NewService()
+
+	issue := func(user dbmodel.User) string {
+		token, err := issueUserToken(database.DB, user, dbmodel.UserTokenTypePasswordReset, passwordResetTTL, "")
+		if err != nil {
+			t.Fatalf("生成令牌失败: %v", err)
+		}
+		return token
+	}
+
+	t.Run("expired", func(t *testing.T) {
+		user := newUser(t, "secret123")
+		token := issue(user)
+		database.DB.Model(&dbmodel.UserToken{}).Where("user_id = ?", user.ID).Update("expires_at", time.Now().Add(-time.Second))
+		if _, err := s.ResetPassword(token, "newpass123"); !errors.Is(err, ErrUserTokenInvalid) {
+			t.Errorf("过期令牌应返回 ErrUserTokenInvalid，实际为 %v", err)
+		}
+	})
+
+	t.Run("email_changed", func(t *testing.T) {
+		user := newUser(t, "secret123")
+		token := issue(user)
+		database.DB.Model(&user).Update("email", fmt.Sprintf("changed_%d@example.com", user.ID))
+		if _, err := s.ResetPassword(token, "newpass123"); !errors.Is(err, ErrUserTokenInvalid) {
+			t.Errorf("邮箱变更后令牌应失效，实际为 %v", err)
+		}
+	})
+
+	t.Run("wrong_type", func(t *testing.T) {
+		user := newUser(t, "secret123")
+		token := issue(user)
+		if _, err := s.VerifyEmail(token); !errors.Is(err, ErrUserTokenInvalid) {
+			t.Errorf("重置密码令牌不能用于验证邮箱，实际为 %v", err)
+		}
+		if _, err := s.ResetPassword(token, "newpass123"); err != nil {
+			t.Errorf("类型不符的使用不应消耗令牌: %v", err)
+		}
+	})
+
+	t.Run("unknown_email", func(t *testing.T) {
+		if err := s.ForgotPassword("nobody@example.com", ""); err != nil {
+			t.Errorf("未注册的邮箱应返回成功，实际为 %v", err)
+		}
+	})
+
+	t.Run("mail_disabled", func(t *testing.T) {
+		setSetting(t, "mail_enable", "0")
+		if err := s.ForgotPassword("nobody2@example.com", ""); !errors.Is(err, ErrMailDisabled) {
+			t.Errorf("邮件服务未启用应返回 ErrMailDisabled，实际为 %v", err)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/skyle1995/DevE-Server/apps/auth/model"
+	"github.com/skyle1995/DevE-Server/apps/setting"
+	"github.com/skyle1995/DevE-Server/utils/cache"
+	"github.com/spf13/viper"
+)
+
+// 登录失败计数和锁定状态的缓存键前缀
+const (
+	loginFailPrefix = "login_fail:"
+	loginLockPrefix = "login_lock:"
+)
+
+// loginAttempts 登录失败计数和锁定状态，按用户名和IP分别记录，服务重启后清空
+var loginAttempts = cache.New(0, 5*time.Minute)
+
+// loginPolicy 登录安全策略
+type loginPolicy struct {
+	MaxAttempts     int           // 锁定前允许的失败次数，0表示不锁定
+	LockTime        time.Duration // 锁定时间，同时也是失败次数的统计周期
+	CaptchaAttempts int           // 失败达到该次数后要求输入验证码，0表示不要求
+}
+
+// currentLoginPolicy 读取登录安全策略，依次使用系统设置、配置文件和默认值
+func currentLoginPolicy() loginPolicy {
+	return loginPolicy{
+		MaxAttempts:     securitySetting("security_max_login_attempts", "security.max_login_attempts", 5),
+		LockTime:        time.Duration(securitySetting("security_login_lock_time", "security.login_lock_time", 30)) * time.Minute,
+		CaptchaAttempts: securitySetting("security_captcha_attempts", "security.captcha_attempts", 3),
+	}
+}
+
+// securitySetting 读取整数类型的安全设置，设置值无效时使用配置文件或默认值
+func securitySetting(key, configKey string, defaultValue int) int {
+	if value := setting.NewService().GetInt(key, -1); value >= 0 {
+		return value
+	}
+	if viper.IsSet(configKey) {
+		if value := viper.GetInt(configKey); value >= 0 {
+			return value
+		}
+	}
+	return defaultValue
+}
+
+// loginKeys 返回用户名和IP对应的缓存键后缀，用户名不区分大小写
+func loginKeys(username, ip string) []string {
+	keys := make([]string, 0, 2)
+	if username != "" {
+		keys = append(keys, model.LoginLockTargetUsername+":"+strings.ToLower(username))
+	}
+	if ip != "" {
+		keys = append(keys, model.LoginLockTargetIP+":"+ip)
+	}
+	return keys
+}
+
+// parseLoginKey 将缓存键后缀解析为锁定对象类型和值
+func parseLoginKey(key string) (string, string) {
+	target, value, _ := strings.Cut(key, ":")
+	return target, value
+}
+
+// activeLoginLocks 返回用户名或IP当前生效的锁定
+func activeLoginLocks(username, ip string) []model.LoginLock {
+	var locks []model.LoginLock
+	for _, key := range loginKeys(username, ip) {
+		if value, found := loginAttempts.Get(loginLockPrefix + key); found {
+			target, name := parseLoginKey(key)
+			locks = append(locks, model.LoginLock{Target: target, Value: name, Until: value.(time.Time)})
+		}
+	}
+	return locks
+}
+
+// checkLoginLock 检查用户名或IP是否已被锁定
+func checkLoginLock(username, ip string) error {
+	if locks := activeLoginLocks(username, ip); len(locks) > 0 {
+		return &model.LoginLockedError{Locks: locks}
+	}
+	return nil
+}
+
+// recordLoginFailure 记录一次登录失败，用户名或IP的失败次数达到上限时锁定
+// 用户名不存在时同样计数，避免通过是否锁定判断用户名是否存在
+func recordLoginFailure(username, ip string) error {
+	policy := currentLoginPolicy()
+	if policy.MaxAttempts <= 0 && policy.CaptchaAttempts <= 0 {
+		return nil
+	}
+	// 只要求验证码时，失败次数按默认锁定时间统计
+	window := policy.LockTime
+	if window <= 0 {
+		window = 30 * time.Minute
+	}
+
+	var locks []model.LoginLock
+	for _, key := range loginKeys(username, ip) {
+		count := loginAttempts.IncrementOrSet(loginFailPrefix+key, 1, window)
+		if policy.MaxAttempts <= 0 || policy.LockTime <= 0 || count < int64(policy.MaxAttempts) {
+			continue
+		}
+
+		// 锁定后重新计数，解锁后再次失败达到上限时重新锁定
+		until := time.Now().Add(policy.LockTime)
+		loginAttempts.Set(loginLockPrefix+key, until, policy.LockTime)
+		loginAttempts.Delete(loginFailPrefix + key)
+		target, name := parseLoginKey(key)
+		locks = append(locks, model.LoginLock{Target: target, Value: name, Until: until})
+	}
+
+	if len(locks) > 0 {
+		return &model.LoginLockedError{Locks: locks, Triggered: true}
+	}
+	return nil
+}
+
+// clearLoginFailures 登录成功后清除用户名的失败次数
+// IP的失败次数不清除，避免使用一个有效账号重置对其他账号的猜测次数
+func clearLoginFailures(username string) {
+	for _, key := range loginKeys(username, "") {
+		loginAttempts.Delete(loginFailPrefix + key)
+	}
+}
+
+// captchaRequired 判断用户名或IP的失败次数是否已达到要求输入验证码的次数
+func captchaRequired(username, ip string) bool {
+	policy := currentLoginPolicy()
+	if policy.CaptchaAttempts <= 0 {
+		return false
+	}
+	for _, key := range loginKeys(username, ip) {
+		if value, found := loginAttempts.Get(loginFailPrefix + key); found && value.(int64) >= int64(policy.CaptchaAttempts) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLoginLocks 获取当前生效的登录锁定，按解锁时间倒序
+func (s *Service) GetLoginLocks() []model.LoginLock {
+	locks := make([]model.LoginLock, 0)
+	for key, item := range loginAttempts.Items() {
+		if !strings.HasPrefix(key, loginLockPrefix) {
+			continue
+		}
+		target, value := parseLoginKey(strings.TrimPrefix(key, loginLockPrefix))
+		locks = append(locks, model.LoginLock{Target: target, Value: value, Until: item.Value.(time.Time)})
+	}
+	sort.Slice(locks, func(i, j int) bool {
+		return locks[i].Until.After(locks[j].Until)
+	})
+	return locks
+}
+
+// UnlockLogin 解除用户名或IP的登录锁定并清除其失败次数，返回解除前生效的锁定
+func (s *Service) UnlockLogin(username, ip string) []model.LoginLock {
+	locks := activeLoginLocks(username, ip)
+	for _, key := range loginKeys(username, ip) {
+		loginAttempts.Delete(loginLockPrefix + key)
+		loginAttempts.Delete(loginFailPrefix + key)
+	}
+	return locks
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+)
+
+// CreateAccessTokenRequest 创建个人访问令牌请求
+type CreateAccessTokenRequest struct {
+	Name        string   `json:"name" binding:"required,max=50"`                 // 令牌名称
+	Scopes      []string `json:"scopes" binding:"required,min=1"`                // 授权范围，为权限标识列表
+	AppID       uint     `json:"app_id"`                                         // 限定访问的应用ID，0表示不限
+	ExpiresDays int      `json:"expires_days" binding:"omitempty,min=1,max=365"` // 有效天数，0表示永不过期
+}
+
+// AccessTokenResponse 个人访问令牌信息
+type AccessTokenResponse struct {
+	ID         uint       `json:"id"`           // 令牌ID
+	Name       string     `json:"name"`         // 令牌名称
+	Prefix     string     `json:"prefix"`       // 令牌开头的字符
+	Scopes     []string   `json:"scopes"`       // 授权范围
+	AppID      uint       `json:"app_id"`       // 限定访问的应用ID，0表示不限
+	ExpiresAt  *time.Time `json:"expires_at"`   // 过期时间，为空表示永不过期
+	Expired    bool       `json:"expired"`      // 是否已过期
+	LastUsedAt *time.Time `json:"last_used_at"` // 最后使用时间
+	LastUsedIP string     `json:"last_used_ip"` // 最后使用IP
+	CreatedAt  time.Time  `json:"created_at"`   // 创建时间
+}
+
+// NewAccessTokenResponse 将访问令牌转换为响应
+func NewAccessTokenResponse(token dbmodel.AccessToken, now time.Time) AccessTokenResponse {
+	scopes := token.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return AccessTokenResponse{
+		ID:         token.ID,
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     scopes,
+		AppID:      token.AppID,
+		ExpiresAt:  token.ExpiresAt,
+		Expired:    token.Expired(now),
+		LastUsedAt: token.LastUsedAt,
+		LastUsedIP: token.LastUsedIP,
+		CreatedAt:  token.CreatedAt,
+	}
+}
+
+// CreatedAccessToken 创建的个人访问令牌，令牌只在创建时返回一次
+type CreatedAccessToken struct {
+	AccessTokenResponse
+	Token string `json:"token"` // 令牌
+}
@@ -0,0 +1,17 @@
+package model
+
+// ResendVerificationRequest 重新发送验证邮件请求
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"` // 注册邮箱
+}
+
+// ForgotPasswordRequest 找回密码请求
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"` // 账号绑定的邮箱
+}
+
+// ResetPasswordRequest 重置密码请求
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`    // 重置密码邮件中的令牌
+	Password string `json:"password" binding:"required"` // 新密码，需符合密码策略
+}
@@ -29,8 +29,8 @@ func (u *User) GenerateToken() (string, error) {
 	// 创建JWT实例
 	jwtInstance := jwt.New(jwtConfig)
 
-	// 将角色转换为字符串
-	roleStr := fmt.Sprintf("%d", u.Role)
+	// 将角色ID转换为字符串
+	roleStr := fmt.Sprintf("%d", u.RoleID)
 	// 创建令牌
 	tokenString, err := jwtInstance.CreateToken(u.ID, u.Username, roleStr)
 	return tokenString, err
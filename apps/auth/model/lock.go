@@ -0,0 +1,43 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// 登录锁定对象类型
+const (
+	LoginLockTargetUsername = "username" // 按用户名锁定
+	LoginLockTargetIP       = "ip"       // 按IP锁定
+)
+
+// LoginLock 登录锁定记录
+type LoginLock struct {
+	Target string    `json:"target"` // 锁定对象类型：username-用户名，ip-IP地址
+	Value  string    `json:"value"`  // 被锁定的用户名或IP地址
+	Until  time.Time `json:"until"`  // 锁定截止时间
+}
+
+// UnlockLoginRequest 解除登录锁定请求
+type UnlockLoginRequest struct {
+	Username string `json:"username"` // 用户名，可选
+	IP       string `json:"ip"`       // IP地址，可选，与用户名至少填写一项
+}
+
+// LoginLockedError 登录被锁定错误
+type LoginLockedError struct {
+	Locks     []LoginLock // 当前生效的锁定
+	Triggered bool        // 是否由本次登录失败触发锁定
+}
+
+// Error 实现error接口，提示距离最晚解锁的剩余分钟数
+func (e *LoginLockedError) Error() string {
+	var until time.Time
+	for _, lock := range e.Locks {
+		if lock.Until.After(until) {
+			until = lock.Until
+		}
+	}
+	minutes := int(time.Until(until).Minutes()) + 1
+	return fmt.Sprintf("登录失败次数过多，请%d分钟后再试", minutes)
+}
@@ -14,12 +14,12 @@ func VerifyPassword(hashedPassword, plainPassword string) bool {
 }
 
 // GenerateToken 为用户生成JWT令牌
-func GenerateToken(userID uint, username string, role int) (string, error) {
+func GenerateToken(userID uint, username string, roleID uint) (string, error) {
 	user := &User{
 		User: dbmodel.User{
 			ID:       userID,
 			Username: username,
-			Role:     role,
+			RoleID:   roleID,
 		},
 	}
 	return user.GenerateToken()
@@ -31,3 +31,46 @@ func GenerateToken(userID uint, username string, role int) (string, error) {
 func HashPassword(plainPassword string) (string, error) {
 	return crypto.HashPassword(plainPassword)
 }
+
+// 登录时需要修改密码的原因
+const (
+	PasswordChangeReasonExpired  = "expired"  // 密码已超过有效天数
+	PasswordChangeReasonRequired = "required" // 管理员重置密码后要求修改
+)
+
+// PasswordPolicy 密码策略，由系统设置配置
+type PasswordPolicy struct {
+	MinLength  int  `json:"min_length"`   // 最小长度
+	CharTypes  int  `json:"char_types"`   // 至少包含的字符种类数，种类为大写字母、小写字母、数字和特殊字符
+	History    int  `json:"history"`      // 禁止重复使用最近几次的密码，0表示不限制
+	MaxAgeDays int  `json:"max_age_days"` // 密码有效天数，0表示永不过期
+	Denylist   bool `json:"denylist"`     // 是否禁止使用常见弱密码
+}
+
+// PasswordChangeChallenge 密码验证通过但需要修改密码时返回的挑战，使用挑战码设置新密码后才签发令牌
+type PasswordChangeChallenge struct {
+	PasswordChangeRequired bool     `json:"password_change_required"` // 是否需要修改密码，固定为true
+	Challenge              string   `json:"challenge"`                // 挑战码
+	Reason                 string   `json:"reason"`                   // 需要修改密码的原因：expired-密码已过期，required-管理员要求修改
+	ExpiresIn              int      `json:"expires_in"`               // 挑战码有效期（秒）
+	RecoveryCodes          []string `json:"recovery_codes,omitempty"` // 登录时开启两步验证生成的恢复码
+}
+
+// PasswordChangeRequiredError 登录需要先修改密码
+type PasswordChangeRequiredError struct {
+	Challenge PasswordChangeChallenge // 修改密码使用的挑战
+}
+
+// Error 实现error接口，按原因提示修改密码
+func (e *PasswordChangeRequiredError) Error() string {
+	if e.Challenge.Reason == PasswordChangeReasonExpired {
+		return "密码已过期，请修改密码后登录"
+	}
+	return "请修改密码后登录"
+}
+
+// PasswordLoginRequest 使用挑战码修改密码并登录的请求
+type PasswordLoginRequest struct {
+	Challenge string `json:"challenge" binding:"required"` // 挑战码
+	Password  string `json:"password" binding:"required"`  // 新密码
+}
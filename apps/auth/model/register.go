@@ -0,0 +1,7 @@
+package model
+
+// RegisterResult 注册结果
+type RegisterResult struct {
+	EmailVerificationRequired bool `json:"email_verification_required"` // 是否需要验证邮箱后才能登录
+	ApprovalRequired          bool `json:"approval_required"`           // 是否需要等待管理员审核后才能登录
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/utils/qqwry"
+)
+
+// SessionResponse 登录会话信息
+type SessionResponse struct {
+	ID         uint      `json:"id"`           // 会话ID
+	UserAgent  string    `json:"user_agent"`   // 登录时的浏览器或客户端标识
+	IP         string    `json:"ip"`           // 最后活动IP
+	Location   string    `json:"location"`     // IP归属地
+	Remember   bool      `json:"remember"`     // 是否记住登录
+	Current    bool      `json:"current"`      // 是否为发起请求的会话
+	CreatedAt  time.Time `json:"created_at"`   // 登录时间
+	LastSeenAt time.Time `json:"last_seen_at"` // 最后活动时间
+	ExpiresAt  time.Time `json:"expires_at"`   // 过期时间
+}
+
+// NewSessionResponse 将会话转换为响应，currentTokenID 为发起请求的访问令牌ID
+func NewSessionResponse(session dbmodel.UserSession, currentTokenID string) SessionResponse {
+	location := ""
+	if session.IP != "" {
+		location = strings.TrimSpace(qqwry.GetIPLocation(session.IP))
+	}
+	return SessionResponse{
+		ID:         session.ID,
+		UserAgent:  session.UserAgent,
+		IP:         session.IP,
+		Location:   location,
+		Remember:   session.Remember,
+		Current:    currentTokenID != "" && session.AccessTokenID == currentTokenID,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+		ExpiresAt:  session.ExpiresAt,
+	}
+}
@@ -0,0 +1,14 @@
+package model
+
+// TokenPair 登录和刷新时签发的令牌
+type TokenPair struct {
+	Token            string `json:"token"`              // 访问令牌（JWT）
+	RefreshToken     string `json:"refresh_token"`      // 刷新令牌，只能使用一次
+	ExpiresIn        int    `json:"expires_in"`         // 访问令牌的有效期（秒）
+	RefreshExpiresIn int    `json:"refresh_expires_in"` // 刷新令牌的有效期（秒）
+}
+
+// RefreshTokenRequest 刷新令牌请求，为空时从cookie中读取
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"` // 刷新令牌
+}
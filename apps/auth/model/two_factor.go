@@ -0,0 +1,65 @@
+package model
+
+import (
+	"time"
+
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+)
+
+// TwoFactorChallenge 密码验证通过后返回的两步验证挑战，使用挑战码提交验证码后才签发令牌
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"two_factor_required"` // 是否需要两步验证，固定为true
+	Challenge         string `json:"challenge"`           // 挑战码
+	SetupRequired     bool   `json:"setup_required"`      // 角色要求两步验证但尚未开启，需先绑定验证器
+	ExpiresIn         int    `json:"expires_in"`          // 挑战码有效期（秒）
+}
+
+// TwoFactorLoginResult 两步验证登录结果，验证失败时只包含已知的用户信息
+type TwoFactorLoginResult struct {
+	User          dbmodel.User // 登录用户
+	Tokens        TokenPair    // 签发的令牌
+	Remember      bool         // 是否记住登录
+	RecoveryCodes []string     // 登录时开启两步验证生成的恢复码
+}
+
+// TwoFactorLoginRequest 两步验证登录请求
+type TwoFactorLoginRequest struct {
+	Challenge string `json:"challenge" binding:"required"` // 挑战码
+	Code      string `json:"code" binding:"required"`      // 验证器中的6位验证码或恢复码
+}
+
+// TwoFactorChallengeRequest 使用挑战码绑定验证器的请求
+type TwoFactorChallengeRequest struct {
+	Challenge string `json:"challenge" binding:"required"` // 挑战码
+}
+
+// TwoFactorCodeRequest 提交验证码的请求
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"` // 验证器中的6位验证码或恢复码
+}
+
+// TwoFactorDisableRequest 关闭两步验证请求
+type TwoFactorDisableRequest struct {
+	Password string `json:"password" binding:"required"` // 登录密码
+	Code     string `json:"code" binding:"required"`     // 验证器中的6位验证码或恢复码
+}
+
+// TwoFactorSetupResponse 绑定验证器响应
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`      // Base32编码的密钥，用于手动输入
+	OtpauthURI string `json:"otpauth_uri"` // 密钥配置URI，前端生成二维码供验证器应用扫码
+	ExpiresIn  int    `json:"expires_in"`  // 需在该时间内提交验证码完成开启（秒）
+}
+
+// TwoFactorStatusResponse 两步验证状态响应
+type TwoFactorStatusResponse struct {
+	Enabled                bool       `json:"enabled"`                  // 是否已开启
+	Required               bool       `json:"required"`                 // 当前角色是否要求开启
+	EnabledAt              *time.Time `json:"enabled_at"`               // 开启时间
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"` // 剩余可用的恢复码数量
+}
+
+// RecoveryCodesResponse 恢复码响应，恢复码只在生成时返回一次
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"` // 恢复码，每个只能使用一次
+}
@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/skyle1995/DevE-Server/apps/auth/model"
+	"github.com/skyle1995/DevE-Server/apps/setting"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/utils/crypto"
+	"github.com/skyle1995/DevE-Server/utils/random"
+	"github.com/skyle1995/DevE-Server/utils/validator"
+	"gorm.io/gorm"
+)
+
+// 密码策略参数
+const (
+	maxPasswordBytes           = 72 // bcrypt只使用密码的前72个字节
+	maxPasswordHistory         = 24 // 禁止重复使用的密码次数上限
+	passwordChallengeKeyPrefix = "password_challenge:"
+)
+
+// ErrCommonPassword 密码为常见弱密码
+var ErrCommonPassword = errors.New("密码过于常见，请使用更复杂的密码")
+
+// passwordChallenge 密码验证通过后等待修改密码的登录
+type passwordChallenge struct {
+	UserID   uint
+	Remember bool
+}
+
+// currentPasswordPolicy 读取密码策略，设置值超出范围时取最接近的有效值
+func currentPasswordPolicy() model.PasswordPolicy {
+	settings := setting.NewService()
+	policy := model.PasswordPolicy{
+		MinLength:  settings.GetInt("security_password_min_length", 8),
+		CharTypes:  settings.GetInt("security_password_char_types", 2),
+		History:    settings.GetInt("security_password_history", 3),
+		MaxAgeDays: settings.GetInt("security_password_max_age", 0),
+		Denylist:   settings.GetBool("security_password_denylist", true),
+	}
+	policy.MinLength = clamp(policy.MinLength, 1, maxPasswordBytes)
+	policy.CharTypes = clamp(policy.CharTypes, 0, 4)
+	policy.History = clamp(policy.History, 0, maxPasswordHistory)
+	if policy.MaxAgeDays < 0 {
+		policy.MaxAgeDays = 0
+	}
+	return policy
+}
+
+// clamp 将数值限制在指定范围内
+func clamp(value, lower, upper int) int {
+	if value < lower {
+		return lower
+	}
+	if value > upper {
+		return upper
+	}
+	return value
+}
+
+// GetPasswordPolicy 获取当前的密码策略，供前端提示密码要求
+func (s *Service) GetPasswordPolicy() model.PasswordPolicy {
+	return currentPasswordPolicy()
+}
+
+// CheckPasswordPolicy 检查密码的长度、字符种类和是否为常见弱密码，不检查密码历史，用于创建用户
+func (s *Service) CheckPasswordPolicy(password string) error {
+	return checkPasswordPolicy(currentPasswordPolicy(), password)
+}
+
+// checkPasswordPolicy 检查密码是否符合密码策略，不检查密码历史
+func checkPasswordPolicy(policy model.PasswordPolicy, password string) error {
+	if utf8.RuneCountInString(password) < policy.MinLength {
+		return fmt.Errorf("密码长度不能少于%d位", policy.MinLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("密码长度不能超过%d个字节", maxPasswordBytes)
+	}
+	if validator.PasswordCharTypes(password) < policy.CharTypes {
+		return fmt.Errorf("密码需要包含大写字母、小写字母、数字和特殊字符中的至少%d种", policy.CharTypes)
+	}
+	if policy.Denylist && validator.IsCommonPassword(password) {
+		return ErrCommonPassword
+	}
+	return nil
+}
+
+// passwordReused 判断密码是否为用户当前的密码或最近使用过的密码，history为禁止重复使用的次数
+func passwordReused(tx *gorm.DB, user dbmodel.User, password string, history int) bool {
+	if history <= 0 {
+		return false
+	}
+	if model.VerifyPassword(user.Password, password) {
+		return true
+	}
+	if history == 1 {
+		return false
+	}
+
+	var histories []dbmodel.PasswordHistory
+	tx.Where("user_id = ?", user.ID).Order("id DESC").Limit(history - 1).Find(&histories)
+	for _, item := range histories {
+		if model.VerifyPassword(item.Password, password) {
+			return true
+		}
+	}
+	return false
+}
+
+// savePasswordHistory 保存用户的旧密码哈希，只保留禁止重复使用所需的记录，当前密码不计入历史
+func savePasswordHistory(tx *gorm.DB, userID uint, oldPassword string, history int) error {
+	keep := history - 1
+	if keep > 0 && oldPassword != "" {
+		if err := tx.Create(&dbmodel.PasswordHistory{UserID: userID, Password: oldPassword}).Error; err != nil {
+			return err
+		}
+	}
+
+	var ids []uint
+	if err := tx.Model(&dbmodel.PasswordHistory{}).Where("user_id = ?", userID).Order("id DESC").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if keep < 0 {
+		keep = 0
+	}
+	if len(ids) <= keep {
+		return nil
+	}
+	return tx.Delete(&dbmodel.PasswordHistory{}, ids[keep:]).Error
+}
+
+// setPassword 按密码策略检查并保存用户的新密码，旧密码写入密码历史
+// changeRequired 为true时用户下次登录需要修改密码，用于管理员重置密码
+func setPassword(tx *gorm.DB, user dbmodel.User, password string, changeRequired bool) error {
+	policy := currentPasswordPolicy()
+	if err := checkPasswordPolicy(policy, password); err != nil {
+		return err
+	}
+	if passwordReused(tx, user, password, policy.History) {
+		return fmt.Errorf("不能使用最近%d次使用过的密码", policy.History)
+	}
+
+	hashedPassword, err := crypto.HashPassword(password)
+	if err != nil {
+		return errors.New("密码处理失败")
+	}
+
+	// 密码已哈希，跳过模型钩子直接更新
+	oldPassword := user.Password
+	now := time.Now()
+	if err := tx.Model(&user).UpdateColumns(map[string]interface{}{
+		"password":                 hashedPassword,
+		"password_changed_at":      now,
+		"password_change_required": changeRequired,
+		"updated_at":               now,
+	}).Error; err != nil {
+		return errors.New("更新密码失败: " + err.Error())
+	}
+	return savePasswordHistory(tx, user.ID, oldPassword, policy.History)
+}
+
+// SetUserPassword 设置用户密码并撤销其所有会话，用于管理员重置密码
+// changeRequired 为true时用户下次登录需要修改密码
+func (s *Service) SetUserPassword(userID uint, password string, changeRequired bool) error {
+	var user dbmodel.User
+	if err := database.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		return errors.New("用户不存在")
+	}
+	return database.Transaction(func(tx *gorm.DB) error {
+		if err := setPassword(tx, user, password, changeRequired); err != nil {
+			return err
+		}
+		return revokeSessions(tx, "user_id", user.ID)
+	})
+}
+
+// passwordChangeReason 返回用户登录时需要修改密码的原因，不需要时返回空字符串
+// 没有修改密码时间的用户按创建时间计算密码有效期
+func passwordChangeReason(user dbmodel.User, policy model.PasswordPolicy) string {
+	if user.PasswordChangeRequired {
+		return model.PasswordChangeReasonRequired
+	}
+	if policy.MaxAgeDays > 0 {
+		changedAt := user.CreatedAt
+		if user.PasswordChangedAt != nil {
+			changedAt = *user.PasswordChangedAt
+		}
+		if !time.Now().Before(changedAt.AddDate(0, 0, policy.MaxAgeDays)) {
+			return model.PasswordChangeReasonExpired
+		}
+	}
+	return ""
+}
+
+// checkPasswordChange 检查用户登录前是否需要修改密码，需要时创建修改密码的挑战码并返回 *model.PasswordChangeRequiredError
+// recoveryCodes 为登录时开启两步验证生成的恢复码，随挑战一并返回
+func checkPasswordChange(user dbmodel.User, remember bool, recoveryCodes []string) error {
+	reason := passwordChangeReason(user, currentPasswordPolicy())
+	if reason == "" {
+		return nil
+	}
+
+	token := random.RandomLettersDigits(48)
+	twoFactorCache.Set(passwordChallengeKeyPrefix+token, &passwordChallenge{
+		UserID:   user.ID,
+		Remember: remember,
+	}, challengeTTL)
+	return &model.PasswordChangeRequiredError{
+		Challenge: model.PasswordChangeChallenge{
+			PasswordChangeRequired: true,
+			Challenge:              token,
+			Reason:                 reason,
+			ExpiresIn:              int(challengeTTL.Seconds()),
+			RecoveryCodes:          recoveryCodes,
+		},
+	}
+}
+
+// LoginChangePassword 使用登录返回的挑战码设置新密码，成功后撤销用户的其他会话并签发令牌
+// 新密码不符合密码策略时挑战码仍然有效，可以重新提交
+func (s *Service) LoginChangePassword(token, password, ip, userAgent string) (dbmodel.User, model.TokenPair, error) {
+	var user dbmodel.User
+	var tokens model.TokenPair
+	value, found := twoFactorCache.Get(passwordChallengeKeyPrefix + token)
+	if !found {
+		return user, tokens, ErrChallengeExpired
+	}
+	challenge := value.(*passwordChallenge)
+
+	if err := database.DB.First(&user, challenge.UserID).Error; err != nil {
+		return user, tokens, errors.New("用户不存在")
+	}
+	if user.Status != dbmodel.UserStatusActive {
+		return user, tokens, ErrUserDisabled
+	}
+	if !loginAllowed(user) {
+		return user, tokens, ErrLoginDisabled
+	}
+
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := setPassword(tx, user, password, false); err != nil {
+			return err
+		}
+		return revokeSessions(tx, "user_id", user.ID)
+	})
+	if err != nil {
+		return user, tokens, err
+	}
+
+	twoFactorCache.Delete(passwordChallengeKeyPrefix + token)
+	tokens, err = s.issueTokens(user, challenge.Remember, ip, userAgent)
+	return user, tokens, err
+}
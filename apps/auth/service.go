@@ -2,14 +2,25 @@ package auth
 
 import (
 	"errors"
-	"fmt"
+	"strings"
 	"time"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/skyle1995/DevE-Server/apps/auth/model"
+	"github.com/skyle1995/DevE-Server/apps/invite"
+	"github.com/skyle1995/DevE-Server/apps/setting"
 	"github.com/skyle1995/DevE-Server/database"
 	dbmodel "github.com/skyle1995/DevE-Server/database/model"
-	"github.com/skyle1995/DevE-Server/utils/jwt"
-	"github.com/spf13/viper"
+	"github.com/skyle1995/DevE-Server/middleware"
+	"github.com/skyle1995/DevE-Server/utils/mailer"
+	"gorm.io/gorm"
+)
+
+// 注册和登录开关相关错误
+var (
+	ErrRegisterDisabled = errors.New("系统当前不允许注册新用户")
+	ErrLoginDisabled    = errors.New("系统当前未开放登录")
+	ErrUserDisabled     = errors.New("账号已被禁用或正在等待管理员审核")
 )
 
 // Service 提供认证相关的服务
@@ -20,126 +31,241 @@ func NewService() *Service {
 	return &Service{}
 }
 
+// CaptchaRequired 判断用户名或IP的登录失败次数是否已达到要求输入验证码的次数
+func (s *Service) CaptchaRequired(username, ip string) bool {
+	return captchaRequired(username, ip)
+}
+
 // Login 用户登录
-func (s *Service) Login(username, password string, remember bool) (dbmodel.User, string, error) {
+// 用户名或IP被锁定时直接拒绝，密码错误时累计失败次数，达到上限时返回 *model.LoginLockedError
+// 开启两步验证或角色要求两步验证的用户只返回两步验证挑战，通过 LoginTwoFactor 校验验证码后才签发令牌
+// 密码过期或管理员要求修改密码时返回 *model.PasswordChangeRequiredError，通过 LoginChangePassword 设置新密码后才签发令牌
+func (s *Service) Login(username, password, ip, userAgent string, remember bool) (dbmodel.User, model.TokenPair, *model.TwoFactorChallenge, error) {
+	var tokens model.TokenPair
+
+	// 检查登录锁定
+	if err := checkLoginLock(username, ip); err != nil {
+		return dbmodel.User{}, tokens, nil, err
+	}
+
 	// 查询用户
 	var dbUser dbmodel.User
 	result := database.DB.Where("username = ?", username).First(&dbUser)
 	if result.Error != nil {
-		return dbmodel.User{}, "", errors.New("用户名或密码错误")
-	}
-
-	// 检查用户状态
-	if dbUser.Status != 1 {
-		return dbmodel.User{}, "", errors.New("账号已被禁用")
+		return dbmodel.User{}, tokens, nil, loginFailed(username, ip)
 	}
 
 	// 验证密码
 	if !model.VerifyPassword(dbUser.Password, password) {
-		return dbmodel.User{}, "", errors.New("用户名或密码错误")
+		return dbUser, tokens, nil, loginFailed(username, ip)
 	}
 
-	// 创建JWT实例
-	jwtConfig := jwt.DefaultConfig()
-	// 从配置中获取JWT密钥
-	if viper.IsSet("security.jwt_secret") {
-		jwtConfig.SigningKey = viper.GetString("security.jwt_secret")
+	// 密码正确后再检查用户状态，避免不知道密码的人通过用户名探测账号是否被禁用或未验证
+	if dbUser.Status != dbmodel.UserStatusActive && dbUser.Status != dbmodel.UserStatusPending {
+		return dbmodel.User{}, tokens, nil, ErrUserDisabled
+	}
+	if dbUser.Status == dbmodel.UserStatusPending {
+		return dbUser, tokens, nil, ErrEmailNotVerified
+	}
+	if !loginAllowed(dbUser) {
+		return dbUser, tokens, nil, ErrLoginDisabled
 	}
 
-	// 设置过期时间
-	if remember {
-		// 如果记住密码，从数据库获取有效天数设置
-		var rememberMeDays int = 30 // 默认30天
+	// 两步验证，失败次数在验证码通过后再清除
+	if dbUser.TwoFactorEnabled || s.TwoFactorRequired(dbUser.RoleID) {
+		return dbUser, tokens, newLoginChallenge(dbUser, remember, !dbUser.TwoFactorEnabled), nil
+	}
+	clearLoginFailures(username)
 
-		// 查询数据库中的设置
-		var setting dbmodel.SystemSetting
-		result := database.DB.Where("key = ?", "user_remember_me_days").First(&setting)
-		if result.Error == nil && setting.Value != "" {
-			// 尝试将设置值转换为整数
-			fmt.Sscanf(setting.Value, "%d", &rememberMeDays)
-		}
+	// 密码过期或管理员要求修改密码时返回修改密码的挑战码
+	if err := checkPasswordChange(dbUser, remember, nil); err != nil {
+		return dbUser, tokens, nil, err
+	}
 
-		jwtConfig.ExpiresTime = time.Duration(rememberMeDays) * 24 * time.Hour
-	} else {
-		// 否则使用配置中的会话超时时间
-		if viper.IsSet("server.session_timeout") {
-			jwtConfig.ExpiresTime = time.Duration(viper.GetInt("server.session_timeout")) * time.Hour
-		} else {
-			// 默认24小时
-			jwtConfig.ExpiresTime = 24 * time.Hour
-		}
+	tokens, err := s.issueTokens(dbUser, remember, ip, userAgent)
+	if err != nil {
+		return dbmodel.User{}, tokens, nil, err
 	}
+	return dbUser, tokens, nil, nil
+}
 
-	jwtInstance := jwt.New(jwtConfig)
+// loginAllowed 判断用户是否可以登录，系统设置关闭登录时只有拥有对应权限的用户可以登录
+func loginAllowed(user dbmodel.User) bool {
+	return setting.NewService().GetBool("user_enable_login", true) ||
+		middleware.RoleHasPermission(user.RoleID, dbmodel.PermissionLoginDisabled)
+}
 
-	// 生成JWT令牌
-	// 将角色转换为字符串
-	roleStr := fmt.Sprintf("%d", dbUser.Role)
-	token, err := jwtInstance.CreateToken(dbUser.ID, dbUser.Username, roleStr)
-	if err != nil {
-		return dbmodel.User{}, "", errors.New("生成令牌失败")
+// defaultRoleID 返回注册用户的默认角色ID
+func defaultRoleID() (uint, error) {
+	var role dbmodel.Role
+	if err := database.DB.Where("is_default = ?", true).First(&role).Error; err != nil {
+		return 0, errors.New("未设置注册用户的默认角色")
 	}
+	return role.ID, nil
+}
 
-	// 更新用户最后登录时间
-	database.DB.Model(&dbUser).Updates(map[string]interface{}{
-		"last_login": time.Now(),
-	})
+// defaultUserStatus 返回新用户的默认状态，0表示需要等待管理员审核
+func defaultUserStatus() int {
+	status := setting.NewService().GetInt("user_default_status", dbmodel.UserStatusActive)
+	if status != dbmodel.UserStatusDisabled && status != dbmodel.UserStatusActive {
+		return dbmodel.UserStatusActive
+	}
+	return status
+}
 
-	return dbUser, token, nil
+// rememberMeDays 返回记住登录的有效天数
+func rememberMeDays() int {
+	days := setting.NewService().GetInt("user_remember_me_days", 30)
+	if days <= 0 {
+		return 30
+	}
+	return days
+}
+
+// loginFailed 记录登录失败，触发锁定时返回锁定错误
+func loginFailed(username, ip string) error {
+	if err := recordLoginFailure(username, ip); err != nil {
+		return err
+	}
+	return errors.New("用户名或密码错误")
 }
 
 // Register 处理用户注册
-func (s *Service) Register(username, password, email string) error {
+// 系统设置 user_enable_register 关闭时拒绝注册，密码需符合密码策略，新用户的状态为 user_default_status，为0时需要等待管理员审核
+// 系统设置要求邀请码时必须填写有效的邀请码，使用邀请码注册的用户分配邀请码指定的角色
+// 系统设置要求验证邮箱且邮件服务已启用时，新用户为待验证状态并发送验证邮件，验证后改为默认状态，未设置 site_url 或邮件发送失败时不创建用户
+func (s *Service) Register(username, password, email, inviteCode, ip string) (model.RegisterResult, error) {
+	var result model.RegisterResult
+
+	// 检查是否允许注册
+	if !setting.NewService().GetBool("user_enable_register", true) {
+		return result, ErrRegisterDisabled
+	}
+
+	// 检查密码策略
+	if err := checkPasswordPolicy(currentPasswordPolicy(), password); err != nil {
+		return result, err
+	}
+
 	// 检查用户名是否已存在
 	var count int64
 	database.DB.Model(&dbmodel.User{}).Where("username = ?", username).Count(&count)
 	if count > 0 {
-		return errors.New("用户名已存在")
+		return result, errors.New("用户名已存在")
 	}
 
 	// 检查邮箱是否已存在
 	database.DB.Model(&dbmodel.User{}).Where("email = ?", email).Count(&count)
 	if count > 0 {
-		return errors.New("邮箱已被使用")
-	}
-
-	// 检查是否允许注册
-	allowRegister := viper.GetBool("system.allow_register")
-	if !allowRegister {
-		return errors.New("系统当前不允许注册新用户")
+		return result, errors.New("邮箱已被使用")
 	}
 
-	// 哈希密码
-	hashedPassword, err := model.HashPassword(password)
-	if err != nil {
-		return errors.New("密码处理失败")
+	// 检查邀请码，使用次数在创建用户的事务中扣减
+	var roleID uint
+	inviteCode = strings.TrimSpace(inviteCode)
+	if inviteCode == "" {
+		if setting.NewService().GetBool("user_require_invite_code", false) {
+			return result, errors.New("请填写邀请码")
+		}
+		id, err := defaultRoleID()
+		if err != nil {
+			return result, err
+		}
+		roleID = id
+	} else {
+		code, err := invite.NewService().CheckInviteCode(inviteCode)
+		if err != nil {
+			return result, err
+		}
+		roleID = code.RoleID
 	}
 
-	// 创建新用户
+	// 创建新用户，密码由模型钩子哈希
+	verify := emailVerificationRequired()
+	status := defaultUserStatus()
 	newUser := dbmodel.User{
 		Username:  username,
-		Password:  hashedPassword,
+		Password:  password,
 		Email:     email,
-		Role:      1, // 默认角色
-		Status:    1, // 默认状态：启用
+		RoleID:    roleID,
+		Status:    status,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
+	var baseURL string
+	if verify {
+		newUser.Status = dbmodel.UserStatusPending
+		url, err := siteURL()
+		if err != nil {
+			return result, err
+		}
+		baseURL = url
+	}
 
-	// 保存到数据库
-	result := database.DB.Create(&newUser)
-	if result.Error != nil {
-		return errors.New("创建用户失败: " + result.Error.Error())
+	// 保存到数据库，需要验证邮箱时在同一事务中生成令牌，提交后再发送验证邮件，避免发送邮件时长时间占用数据库写锁
+	var token string
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newUser).Error; err != nil {
+			return errors.New("创建用户失败: " + err.Error())
+		}
+		// 状态字段有默认值，创建时零值会被替换为默认值，等待审核的状态需要单独写入
+		if !verify && status == dbmodel.UserStatusDisabled {
+			newUser.Status = status
+			if err := tx.Model(&newUser).Update("status", status).Error; err != nil {
+				return errors.New("创建用户失败: " + err.Error())
+			}
+		}
+		if inviteCode != "" {
+			if _, err := invite.NewService().RedeemInviteCode(tx, inviteCode, newUser.ID, ip); err != nil {
+				return err
+			}
+		}
+		if !verify {
+			return nil
+		}
+
+		var err error
+		token, err = issueUserToken(tx, newUser, dbmodel.UserTokenTypeEmailVerify, emailVerifyTTL, ip)
+		return err
+	})
+	if err != nil {
+		return result, err
 	}
 
-	return nil
+	// 验证邮件发送失败时删除刚创建的用户，用户名和邮箱可以重新注册
+	if verify {
+		if err := sendUserTokenMail(newUser, mailer.TemplateVerifyEmail, baseURL+verifyEmailPath+token, emailVerifyTTL); err != nil {
+			log.Errorf("发送验证邮件失败: %v", err)
+			if err := deleteRegisteredUser(newUser.ID); err != nil {
+				log.Errorf("删除验证邮件发送失败的用户 %s 失败: %v", newUser.Username, err)
+			}
+			return result, errors.New("验证邮件发送失败，请稍后再试")
+		}
+	}
+
+	result.EmailVerificationRequired = verify
+	result.ApprovalRequired = status == dbmodel.UserStatusDisabled
+	return result, nil
+}
+
+// deleteRegisteredUser 删除注册时创建的用户及其令牌，并恢复使用的邀请码
+func deleteRegisteredUser(userID uint) error {
+	return database.Transaction(func(tx *gorm.DB) error {
+		if err := invite.NewService().ReleaseInviteCode(tx, userID); err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&dbmodel.UserToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&dbmodel.User{}, userID).Error
+	})
 }
 
 // GetUserInfo 获取用户信息
 func (s *Service) GetUserInfo(userID uint) (map[string]interface{}, error) {
 	// 查询用户
 	var user dbmodel.User
-	result := database.DB.Select("id, username, email, role, status, created_at, last_login").Where("id = ?", userID).First(&user)
+	result := database.DB.Select("id, username, email, role_id, status, created_at, last_login").Where("id = ?", userID).First(&user)
 	if result.Error != nil {
 		return nil, errors.New("用户不存在")
 	}
@@ -149,7 +275,7 @@ func (s *Service) GetUserInfo(userID uint) (map[string]interface{}, error) {
 		"id":         user.ID,
 		"username":   user.Username,
 		"email":      user.Email,
-		"role":       user.Role,
+		"role_id":    user.RoleID,
 		"status":     user.Status,
 		"created_at": user.CreatedAt,
 		"last_login": user.LastLogin,
@@ -158,7 +284,7 @@ func (s *Service) GetUserInfo(userID uint) (map[string]interface{}, error) {
 	return userInfo, nil
 }
 
-// UpdatePassword 更新用户密码
+// UpdatePassword 更新用户密码，新密码需符合密码策略且不能是最近使用过的密码
 func (s *Service) UpdatePassword(userID uint, oldPassword, newPassword string) error {
 	// 查询用户
 	var user dbmodel.User
@@ -172,19 +298,11 @@ func (s *Service) UpdatePassword(userID uint, oldPassword, newPassword string) e
 		return errors.New("原密码错误")
 	}
 
-	// 哈希新密码
-	hashedPassword, err := model.HashPassword(newPassword)
-	if err != nil {
-		return errors.New("密码处理失败")
-	}
-
-	// 更新密码
-	user.Password = hashedPassword
-	user.UpdatedAt = time.Now()
-	result = database.DB.Save(&user)
-	if result.Error != nil {
-		return errors.New("更新密码失败: " + result.Error.Error())
+	// 按密码策略检查并更新密码
+	if err := setPassword(database.DB, user, newPassword, false); err != nil {
+		return err
 	}
 
-	return nil
+	// 修改密码后撤销所有会话，需要重新登录
+	return s.RevokeUserSessions(user.ID)
 }
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/skyle1995/DevE-Server/apps/auth/model"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"gorm.io/gorm"
+)
+
+// ErrSessionNotFound 会话不存在、已过期或已撤销
+var ErrSessionNotFound = errors.New("会话不存在或已失效")
+
+// GetSessions 获取用户当前有效的登录会话，按最后活动时间倒序排列
+// currentTokenID 为发起请求的访问令牌ID，用于标记当前会话
+func (s *Service) GetSessions(userID uint, currentTokenID string) ([]model.SessionResponse, error) {
+	var sessions []dbmodel.UserSession
+	err := database.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").Find(&sessions).Error
+	if err != nil {
+		return nil, errors.New("获取会话列表失败: " + err.Error())
+	}
+
+	list := make([]model.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		list = append(list, model.NewSessionResponse(session, currentTokenID))
+	}
+	return list, nil
+}
+
+// RevokeSession 撤销用户的一个登录会话，该会话的访问令牌和刷新令牌立即失效
+func (s *Service) RevokeSession(userID, id uint) error {
+	return database.Transaction(func(tx *gorm.DB) error {
+		var session dbmodel.UserSession
+		err := tx.Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", id, userID, time.Now()).
+			First(&session).Error
+		if err != nil {
+			return ErrSessionNotFound
+		}
+		return revokeSessions(tx, "session_id", session.SessionID)
+	})
+}
@@ -0,0 +1,288 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skyle1995/DevE-Server/apps/auth/model"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/utils/crypto"
+	"github.com/skyle1995/DevE-Server/utils/jwt"
+	"github.com/skyle1995/DevE-Server/utils/random"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 令牌相关参数
+const (
+	defaultAccessTokenTTL = 15 * time.Minute // 访问令牌默认有效期
+	defaultSessionTTL     = 24 * time.Hour   // 未记住登录时的默认会话有效期
+	refreshTokenLength    = 48               // 刷新令牌长度
+	sessionIDLength       = 32               // 会话ID长度
+	accessTokenIDLength   = 32               // 访问令牌ID长度
+	maxUserAgentLength    = 255              // 会话记录的客户端标识最大长度
+	refreshCookieName     = "refresh_token"
+	refreshCookiePath     = "/api/v1/auth" // 刷新令牌cookie只发送给认证接口
+)
+
+// ErrRefreshTokenInvalid 刷新令牌无效、已过期或已撤销
+var ErrRefreshTokenInvalid = errors.New("登录已失效，请重新登录")
+
+// newJWT 返回签发访问令牌使用的JWT实例
+func newJWT() *jwt.JWT {
+	jwtConfig := jwt.DefaultConfig()
+	if viper.IsSet("security.jwt_secret") {
+		jwtConfig.SigningKey = viper.GetString("security.jwt_secret")
+	}
+	jwtConfig.ExpiresTime = accessTokenTTL()
+	return jwt.New(jwtConfig)
+}
+
+// accessTokenTTL 返回访问令牌有效期，配置项 security.access_token_expire 的单位为分钟
+func accessTokenTTL() time.Duration {
+	if minutes := viper.GetInt("security.access_token_expire"); minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultAccessTokenTTL
+}
+
+// sessionTTL 返回刷新令牌有效期，记住登录时使用系统设置的天数，否则使用会话超时时间
+func sessionTTL(remember bool) time.Duration {
+	if remember {
+		return time.Duration(rememberMeDays()) * 24 * time.Hour
+	}
+	if hours := viper.GetInt("server.session_timeout"); hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return defaultSessionTTL
+}
+
+// issueTokens 为通过验证的用户创建会话，签发访问令牌和刷新令牌，并更新最后登录时间
+func (s *Service) issueTokens(user dbmodel.User, remember bool, ip, userAgent string) (model.TokenPair, error) {
+	now := time.Now()
+	refresh := dbmodel.RefreshToken{
+		UserID:    user.ID,
+		SessionID: random.Hex(sessionIDLength),
+		Remember:  remember,
+		IP:        ip,
+		ExpiresAt: now.Add(sessionTTL(remember)),
+	}
+	var tokens model.TokenPair
+	err := database.Transaction(func(tx *gorm.DB) error {
+		var err error
+		tokens, err = signTokens(tx, user, &refresh, now)
+		if err != nil {
+			return err
+		}
+
+		// 创建登录会话，用户可以查看和撤销
+		if len([]rune(userAgent)) > maxUserAgentLength {
+			userAgent = string([]rune(userAgent)[:maxUserAgentLength])
+		}
+		session := dbmodel.UserSession{
+			UserID:        user.ID,
+			SessionID:     refresh.SessionID,
+			AccessTokenID: refresh.AccessTokenID,
+			UserAgent:     userAgent,
+			IP:            ip,
+			Remember:      remember,
+			LastSeenAt:    now,
+			ExpiresAt:     refresh.ExpiresAt,
+		}
+		if err := tx.Create(&session).Error; err != nil {
+			return errors.New("创建会话失败: " + err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return tokens, err
+	}
+
+	// 更新用户最后登录时间
+	database.DB.Model(&user).Updates(map[string]interface{}{
+		"last_login": now,
+	})
+	return tokens, nil
+}
+
+// signTokens 签发访问令牌和刷新令牌，并保存刷新令牌的摘要
+func signTokens(tx *gorm.DB, user dbmodel.User, refresh *dbmodel.RefreshToken, now time.Time) (model.TokenPair, error) {
+	jwtInstance := newJWT()
+	tokenID := random.Hex(accessTokenIDLength)
+	accessToken, err := jwtInstance.CreateTokenWithID(user.ID, user.Username, strconv.FormatUint(uint64(user.RoleID), 10), tokenID)
+	if err != nil {
+		return model.TokenPair{}, errors.New("生成令牌失败")
+	}
+
+	refreshToken := random.RandomLettersDigits(refreshTokenLength)
+	refresh.TokenHash = crypto.SHA256(refreshToken)
+	refresh.AccessTokenID = tokenID
+	refresh.AccessExpiresAt = now.Add(jwtInstance.Config.ExpiresTime)
+	if err := tx.Create(refresh).Error; err != nil {
+		return model.TokenPair{}, errors.New("保存刷新令牌失败: " + err.Error())
+	}
+
+	return model.TokenPair{
+		Token:            accessToken,
+		RefreshToken:     refreshToken,
+		ExpiresIn:        int(jwtInstance.Config.ExpiresTime.Seconds()),
+		RefreshExpiresIn: int(refresh.ExpiresAt.Sub(now).Seconds()),
+	}, nil
+}
+
+// RefreshToken 使用刷新令牌签发新的访问令牌和刷新令牌，旧的刷新令牌作废
+// 新令牌属于同一会话，会话有效期不会延长；已轮换的刷新令牌再次使用时视为泄露，撤销整个会话
+func (s *Service) RefreshToken(token, ip string) (dbmodel.User, model.TokenPair, error) {
+	var user dbmodel.User
+	var tokens model.TokenPair
+	reusedSession := ""
+
+	err := database.Transaction(func(tx *gorm.DB) error {
+		var current dbmodel.RefreshToken
+		if err := tx.Where("token_hash = ?", crypto.SHA256(token)).First(&current).Error; err != nil {
+			return ErrRefreshTokenInvalid
+		}
+		now := time.Now()
+		if current.RevokedAt != nil || !now.Before(current.ExpiresAt) {
+			return ErrRefreshTokenInvalid
+		}
+
+		// 按条件标记为已使用，并发刷新时只有一个请求成功
+		result := tx.Model(&current).Where("used_at IS NULL AND revoked_at IS NULL").Update("used_at", now)
+		if result.Error != nil {
+			return errors.New("刷新令牌失败: " + result.Error.Error())
+		}
+		if result.RowsAffected == 0 {
+			reusedSession = current.SessionID
+			return ErrRefreshTokenInvalid
+		}
+
+		if err := tx.First(&user, current.UserID).Error; err != nil {
+			return ErrRefreshTokenInvalid
+		}
+		if user.Status != dbmodel.UserStatusActive {
+			return ErrUserDisabled
+		}
+		if !loginAllowed(user) {
+			return ErrLoginDisabled
+		}
+
+		next := dbmodel.RefreshToken{
+			UserID:    user.ID,
+			SessionID: current.SessionID,
+			Remember:  current.Remember,
+			IP:        ip,
+			ExpiresAt: current.ExpiresAt,
+		}
+		var err error
+		tokens, err = signTokens(tx, user, &next, now)
+		if err != nil {
+			return err
+		}
+
+		// 会话记录新的访问令牌ID
+		return tx.Model(&dbmodel.UserSession{}).Where("session_id = ?", current.SessionID).Updates(map[string]interface{}{
+			"access_token_id": next.AccessTokenID,
+			"ip":              ip,
+			"last_seen_at":    now,
+		}).Error
+	})
+
+	if reusedSession != "" {
+		log.Warnf("刷新令牌被重复使用，撤销会话 %s", reusedSession)
+		if revokeErr := database.Transaction(func(tx *gorm.DB) error {
+			return revokeSessions(tx, "session_id", reusedSession)
+		}); revokeErr != nil {
+			log.Errorf("撤销会话失败: %v", revokeErr)
+		}
+	}
+	return user, tokens, err
+}
+
+// RevokeUserSessions 撤销用户的所有会话，已签发的访问令牌加入撤销列表，刷新令牌作废
+func (s *Service) RevokeUserSessions(userID uint) error {
+	return database.Transaction(func(tx *gorm.DB) error {
+		return revokeSessions(tx, "user_id", userID)
+	})
+}
+
+// Logout 注销登录，撤销用户的所有会话
+// 访问令牌无效或已过期时使用刷新令牌识别用户，两者都无效时返回的用户ID为0
+func (s *Service) Logout(accessToken, refreshToken string) (uint, error) {
+	var userID uint
+	var tokenID string
+	var expiresAt time.Time
+	if claims, err := newJWT().ParseToken(accessToken); err == nil {
+		userID = claims.UserID
+		tokenID = claims.ID
+		expiresAt = claims.ExpiresAt.Time
+	} else if refreshToken != "" {
+		var current dbmodel.RefreshToken
+		err := database.DB.Where("token_hash = ? AND revoked_at IS NULL AND expires_at > ?", crypto.SHA256(refreshToken), time.Now()).
+			First(&current).Error
+		if err == nil {
+			userID = current.UserID
+		}
+	}
+	if userID == 0 {
+		return 0, nil
+	}
+
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if tokenID != "" {
+			if err := revokeAccessToken(tx, tokenID, userID, expiresAt); err != nil {
+				return err
+			}
+		}
+		return revokeSessions(tx, "user_id", userID)
+	})
+	return userID, err
+}
+
+// revokeSessions 撤销指定用户或会话的登录会话和刷新令牌，并将仍在有效期内的访问令牌加入撤销列表
+// column 只能为 user_id 或 session_id
+func revokeSessions(tx *gorm.DB, column string, value interface{}) error {
+	now := time.Now()
+
+	var tokens []dbmodel.RefreshToken
+	if err := tx.Where(column+" = ? AND revoked_at IS NULL AND access_expires_at > ?", value, now).Find(&tokens).Error; err != nil {
+		return errors.New("查询会话失败: " + err.Error())
+	}
+	for _, token := range tokens {
+		if err := revokeAccessToken(tx, token.AccessTokenID, token.UserID, token.AccessExpiresAt); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(&dbmodel.RefreshToken{}).Where(column+" = ? AND revoked_at IS NULL", value).
+		Update("revoked_at", now).Error; err != nil {
+		return errors.New("撤销会话失败: " + err.Error())
+	}
+	if err := tx.Model(&dbmodel.UserSession{}).Where(column+" = ? AND revoked_at IS NULL", value).
+		Update("revoked_at", now).Error; err != nil {
+		return errors.New("撤销会话失败: " + err.Error())
+	}
+
+	// 清理已过期的令牌和会话
+	tx.Where("expires_at < ?", now).Delete(&dbmodel.RevokedToken{})
+	tx.Where("expires_at < ?", now).Delete(&dbmodel.RefreshToken{})
+	tx.Where("expires_at < ?", now).Delete(&dbmodel.UserSession{})
+	return nil
+}
+
+// revokeAccessToken 将访问令牌加入撤销列表
+func revokeAccessToken(tx *gorm.DB, tokenID string, userID uint, expiresAt time.Time) error {
+	revoked := dbmodel.RevokedToken{
+		TokenID:   tokenID,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&revoked).Error; err != nil {
+		return errors.New("撤销令牌失败: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/skyle1995/DevE-Server/apps/auth/model"
+	"github.com/skyle1995/DevE-Server/apps/setting"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/middleware"
+	"github.com/skyle1995/DevE-Server/utils/cache"
+	"github.com/skyle1995/DevE-Server/utils/crypto"
+	"github.com/skyle1995/DevE-Server/utils/random"
+	"github.com/skyle1995/DevE-Server/utils/totp"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 两步验证参数
+const (
+	challengeTTL           = 5 * time.Minute  // 登录挑战码有效期
+	setupTTL               = 10 * time.Minute // 绑定验证器后提交验证码的有效期
+	maxChallengeAttempts   = 5                // 每个挑战码允许的验证失败次数
+	recoveryCodeCount      = 10               // 恢复码数量
+	recoveryCodeLength     = 10               // 恢复码长度，不含分隔符
+	challengeKeyPrefix     = "2fa_challenge:"
+	setupSecretKeyPrefix   = "2fa_setup:"
+	recoveryCodeCharset    = "abcdefghjkmnpqrstuvwxyz23456789" // 去除易混淆的字符
+	defaultTwoFactorIssuer = "DevE"
+)
+
+// 两步验证错误
+var (
+	ErrChallengeExpired    = errors.New("登录验证已过期，请重新登录")
+	ErrTwoFactorCode       = errors.New("验证码错误")
+	ErrTwoFactorEnabled    = errors.New("已开启两步验证")
+	ErrTwoFactorNotEnabled = errors.New("未开启两步验证")
+	ErrTwoFactorSetup      = errors.New("请先获取两步验证密钥")
+	ErrTwoFactorRequired   = errors.New("当前角色要求开启两步验证，无法关闭")
+)
+
+// twoFactorCache 登录挑战码和待确认的验证器密钥，服务重启后清空
+var twoFactorCache = cache.New(0, time.Minute)
+
+// loginChallenge 密码验证通过后等待两步验证的登录
+type loginChallenge struct {
+	UserID        uint
+	Username      string
+	Remember      bool
+	SetupRequired bool
+	attempts      int32
+}
+
+// twoFactorRoles 读取要求开启两步验证的角色，设置值为逗号分隔的角色编码
+func twoFactorRoles() map[string]bool {
+	roles := make(map[string]bool)
+	for _, item := range strings.Split(setting.NewService().GetString("security_two_factor_roles", ""), ",") {
+		if code := strings.TrimSpace(item); code != "" {
+			roles[code] = true
+		}
+	}
+	return roles
+}
+
+// TwoFactorRequired 判断角色是否要求开启两步验证
+func (s *Service) TwoFactorRequired(roleID uint) bool {
+	role, ok := middleware.GetRole(roleID)
+	return ok && twoFactorRoles()[role.Code]
+}
+
+// newLoginChallenge 为密码验证通过的用户创建登录挑战码
+func newLoginChallenge(user dbmodel.User, remember, setupRequired bool) *model.TwoFactorChallenge {
+	token := random.RandomLettersDigits(48)
+	twoFactorCache.Set(challengeKeyPrefix+token, &loginChallenge{
+		UserID:        user.ID,
+		Username:      user.Username,
+		Remember:      remember,
+		SetupRequired: setupRequired,
+	}, challengeTTL)
+	return &model.TwoFactorChallenge{
+		TwoFactorRequired: true,
+		Challenge:         token,
+		SetupRequired:     setupRequired,
+		ExpiresIn:         int(challengeTTL.Seconds()),
+	}
+}
+
+// getLoginChallenge 获取未过期的登录挑战
+func getLoginChallenge(token string) (*loginChallenge, error) {
+	value, found := twoFactorCache.Get(challengeKeyPrefix + token)
+	if !found {
+		return nil, ErrChallengeExpired
+	}
+	return value.(*loginChallenge), nil
+}
+
+// hashRecoveryCode 计算恢复码摘要，忽略大小写、空格和分隔符
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return crypto.SHA256(code)
+}
+
+// newRecoveryCodes 生成恢复码，返回明文和摘要
+func newRecoveryCodes() ([]string, []string) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code := random.String(recoveryCodeLength, recoveryCodeCharset)
+		codes[i] = code[:recoveryCodeLength/2] + "-" + code[recoveryCodeLength/2:]
+		hashes[i] = hashRecoveryCode(code)
+	}
+	return codes, hashes
+}
+
+// verifySecondFactor 在事务中校验验证器验证码或恢复码
+// 验证码所在的时间步必须大于上次使用的时间步，恢复码使用后立即作废
+func verifySecondFactor(tx *gorm.DB, userID uint, code string) error {
+	var user dbmodel.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+		return errors.New("用户不存在")
+	}
+	if !user.TwoFactorEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if step, ok := totp.Validate(user.TwoFactorSecret, code, time.Now()); ok {
+		if step <= user.TwoFactorLastStep {
+			return ErrTwoFactorCode
+		}
+		return tx.Model(&user).Update("two_factor_last_step", step).Error
+	}
+
+	hash := hashRecoveryCode(code)
+	for i, stored := range user.TwoFactorRecoveryCodes {
+		if stored != hash {
+			continue
+		}
+		remaining := append(append([]string{}, user.TwoFactorRecoveryCodes[:i]...), user.TwoFactorRecoveryCodes[i+1:]...)
+		return saveRecoveryCodes(tx, user.ID, remaining)
+	}
+	return ErrTwoFactorCode
+}
+
+// saveRecoveryCodes 保存恢复码摘要，使用结构体更新以便按JSON序列化
+func saveRecoveryCodes(tx *gorm.DB, userID uint, hashes []string) error {
+	return tx.Model(&dbmodel.User{ID: userID}).Select("two_factor_recovery_codes").
+		Updates(&dbmodel.User{TwoFactorRecoveryCodes: hashes}).Error
+}
+
+// GetTwoFactorStatus 获取用户的两步验证状态
+func (s *Service) GetTwoFactorStatus(userID uint) (*model.TwoFactorStatusResponse, error) {
+	var user dbmodel.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	return &model.TwoFactorStatusResponse{
+		Enabled:                user.TwoFactorEnabled,
+		Required:               s.TwoFactorRequired(user.RoleID),
+		EnabledAt:              user.TwoFactorEnabledAt,
+		RecoveryCodesRemaining: len(user.TwoFactorRecoveryCodes),
+	}, nil
+}
+
+// SetupTwoFactor 生成新的验证器密钥，用户提交验证码确认后才会开启两步验证
+func (s *Service) SetupTwoFactor(userID uint) (*model.TwoFactorSetupResponse, error) {
+	var user dbmodel.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return nil, errors.New("用户不存在")
+	}
+	if user.TwoFactorEnabled {
+		return nil, errors.New("已开启两步验证，请先关闭后再重新绑定")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, errors.New("生成两步验证密钥失败")
+	}
+	twoFactorCache.Set(setupSecretKeyPrefix+strconv.FormatUint(uint64(user.ID), 10), secret, setupTTL)
+
+	return &model.TwoFactorSetupResponse{
+		Secret:     secret,
+		OtpauthURI: totp.ProvisioningURI(siteName(), user.Username, secret),
+		ExpiresIn:  int(setupTTL.Seconds()),
+	}, nil
+}
+
+// EnableTwoFactor 校验新绑定的验证器的验证码并开启两步验证，返回恢复码
+func (s *Service) EnableTwoFactor(userID uint, code string) ([]string, error) {
+	key := setupSecretKeyPrefix + strconv.FormatUint(uint64(userID), 10)
+	value, found := twoFactorCache.Get(key)
+	if !found {
+		return nil, ErrTwoFactorSetup
+	}
+	secret := value.(string)
+
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return nil, ErrTwoFactorCode
+	}
+
+	codes, hashes := newRecoveryCodes()
+	now := time.Now()
+	result := database.DB.Model(&dbmodel.User{}).
+		Where("id = ? AND two_factor_enabled = ?", userID, false).
+		Select("two_factor_enabled", "two_factor_secret", "two_factor_last_step", "two_factor_recovery_codes", "two_factor_enabled_at").
+		Updates(&dbmodel.User{
+			TwoFactorEnabled:       true,
+			TwoFactorSecret:        secret,
+			TwoFactorLastStep:      step,
+			TwoFactorRecoveryCodes: hashes,
+			TwoFactorEnabledAt:     &now,
+		})
+	if result.Error != nil {
+		return nil, errors.New("开启两步验证失败: " + result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	twoFactorCache.Delete(key)
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes 校验验证码后重新生成恢复码，原有的恢复码全部作废
+func (s *Service) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	codes, hashes := newRecoveryCodes()
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := verifySecondFactor(tx, userID, code); err != nil {
+			return err
+		}
+		return saveRecoveryCodes(tx, userID, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor 校验密码和验证码后关闭两步验证，角色要求两步验证时不能关闭
+func (s *Service) DisableTwoFactor(userID uint, password, code string) error {
+	var user dbmodel.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return errors.New("用户不存在")
+	}
+	if s.TwoFactorRequired(user.RoleID) {
+		return ErrTwoFactorRequired
+	}
+	if !model.VerifyPassword(user.Password, password) {
+		return errors.New("密码错误")
+	}
+
+	return database.Transaction(func(tx *gorm.DB) error {
+		if err := verifySecondFactor(tx, userID, code); err != nil {
+			return err
+		}
+		return clearTwoFactor(tx, userID)
+	})
+}
+
+// clearTwoFactor 清除用户的两步验证设置
+func clearTwoFactor(tx *gorm.DB, userID uint) error {
+	return tx.Model(&dbmodel.User{ID: userID}).
+		Select("two_factor_enabled", "two_factor_secret", "two_factor_last_step", "two_factor_recovery_codes", "two_factor_enabled_at").
+		Updates(&dbmodel.User{}).Error
+}
+
+// SetupTwoFactorByChallenge 角色要求两步验证但尚未开启的用户在登录时绑定验证器
+func (s *Service) SetupTwoFactorByChallenge(token string) (*model.TwoFactorSetupResponse, error) {
+	challenge, err := getLoginChallenge(token)
+	if err != nil {
+		return nil, err
+	}
+	if !challenge.SetupRequired {
+		return nil, ErrTwoFactorEnabled
+	}
+	return s.SetupTwoFactor(challenge.UserID)
+}
+
+// LoginTwoFactor 校验登录挑战码和验证码，通过后签发令牌
+// 需要在登录时绑定验证器的用户，验证码通过后同时开启两步验证并返回恢复码
+// 验证失败计入用户名和IP的登录失败次数，挑战码失败次数达到上限后作废
+// 验证通过但需要修改密码时返回 *model.PasswordChangeRequiredError
+func (s *Service) LoginTwoFactor(token, code, ip, userAgent string) (model.TwoFactorLoginResult, error) {
+	var result model.TwoFactorLoginResult
+	challenge, err := getLoginChallenge(token)
+	if err != nil {
+		return result, err
+	}
+	result.Remember = challenge.Remember
+	if err := checkLoginLock(challenge.Username, ip); err != nil {
+		return result, err
+	}
+
+	if err := database.DB.First(&result.User, challenge.UserID).Error; err != nil {
+		return result, errors.New("用户不存在")
+	}
+	if result.User.Status != dbmodel.UserStatusActive {
+		return result, ErrUserDisabled
+	}
+	if !loginAllowed(result.User) {
+		return result, ErrLoginDisabled
+	}
+
+	if challenge.SetupRequired && !result.User.TwoFactorEnabled {
+		result.RecoveryCodes, err = s.EnableTwoFactor(result.User.ID, code)
+		if errors.Is(err, ErrTwoFactorSetup) {
+			return result, err
+		}
+	} else {
+		err = database.Transaction(func(tx *gorm.DB) error {
+			return verifySecondFactor(tx, result.User.ID, code)
+		})
+	}
+	if err != nil {
+		if atomic.AddInt32(&challenge.attempts, 1) >= maxChallengeAttempts {
+			twoFactorCache.Delete(challengeKeyPrefix + token)
+		}
+		if lockErr := recordLoginFailure(challenge.Username, ip); lockErr != nil {
+			twoFactorCache.Delete(challengeKeyPrefix + token)
+			return result, lockErr
+		}
+		return result, err
+	}
+
+	twoFactorCache.Delete(challengeKeyPrefix + token)
+	clearLoginFailures(challenge.Username)
+
+	// 密码过期或管理员要求修改密码时返回修改密码的挑战码，登录时生成的恢复码随挑战返回
+	if err := checkPasswordChange(result.User, challenge.Remember, result.RecoveryCodes); err != nil {
+		return result, err
+	}
+	result.Tokens, err = s.issueTokens(result.User, challenge.Remember, ip, userAgent)
+	return result, err
+}
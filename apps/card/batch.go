@@ -0,0 +1,203 @@
+package card
+
+import (
+	"errors"
+	"time"
+
+	"github.com/skyle1995/DevE-Server/apps/card/model"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"gorm.io/gorm"
+)
+
+// batchStatsRow 按批次分组的卡密统计
+type batchStatsRow struct {
+	BatchID uint
+	model.CardBatchStats
+}
+
+// getCardBatchStats 统计批次内各状态卡密数量
+// 已激活但过期时间已过的卡密在客户端下次验证前仍为已使用状态，统计时按已过期计算
+func getCardBatchStats(batchIDs []uint) (map[uint]model.CardBatchStats, error) {
+	stats := make(map[uint]model.CardBatchStats, len(batchIDs))
+	if len(batchIDs) == 0 {
+		return stats, nil
+	}
+
+	now := time.Now()
+	var rows []batchStatsRow
+	err := database.DB.Model(&dbmodel.Card{}).
+		Select(`batch_id,
+			SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS unused,
+			SUM(CASE WHEN status = ? AND (expire_at IS NULL OR expire_at > ?) THEN 1 ELSE 0 END) AS active,
+			SUM(CASE WHEN status = ? OR (status = ? AND expire_at <= ?) THEN 1 ELSE 0 END) AS expired,
+			SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS disabled,
+			SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS frozen`,
+			dbmodel.CardStatusUnused,
+			dbmodel.CardStatusActive, now,
+			dbmodel.CardStatusExpired, dbmodel.CardStatusActive, now,
+			dbmodel.CardStatusDisabled,
+			dbmodel.CardStatusFrozen).
+		Where("batch_id IN ?", batchIDs).
+		Group("batch_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		stats[row.BatchID] = row.CardBatchStats
+	}
+	return stats, nil
+}
+
+// getCardBatch 获取当前用户的卡密批次
+func getCardBatch(id int, userID int) (*dbmodel.CardBatch, error) {
+	var batch dbmodel.CardBatch
+	result := database.DB.Preload("CardType").Where("id = ? AND user_id = ?", id, userID).First(&batch)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("卡密批次不存在或无权限访问")
+		}
+		return nil, errors.New("查询卡密批次失败: " + result.Error.Error())
+	}
+	return &batch, nil
+}
+
+// GetCardBatchList 获取卡密批次列表
+// @param req 获取卡密批次列表请求
+// @param userID 当前用户ID
+// @return 批次列表、各批次统计、总数和错误信息
+func (s *Service) GetCardBatchList(req model.GetCardBatchListRequest, userID int) ([]dbmodel.CardBatch, map[uint]model.CardBatchStats, int64, error) {
+	query := database.DB.Model(&dbmodel.CardBatch{}).Where("user_id = ?", userID)
+	if req.AppID > 0 {
+		query = query.Where("app_id = ?", req.AppID)
+	}
+	if req.TypeID > 0 {
+		query = query.Where("type_id = ?", req.TypeID)
+	}
+	if req.Channel != "" {
+		query = query.Where("channel = ?", req.Channel)
+	}
+	if req.Name != "" {
+		query = query.Where("name LIKE ?", "%"+req.Name+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, nil, 0, errors.New("查询卡密批次失败: " + err.Error())
+	}
+
+	var batches []dbmodel.CardBatch
+	offset := (req.Page - 1) * req.PageSize
+	if err := query.Preload("CardType").Order("id DESC").Offset(offset).Limit(req.PageSize).Find(&batches).Error; err != nil {
+		return nil, nil, 0, errors.New("查询卡密批次失败: " + err.Error())
+	}
+
+	batchIDs := make([]uint, len(batches))
+	for i, batch := range batches {
+		batchIDs[i] = batch.ID
+	}
+	stats, err := getCardBatchStats(batchIDs)
+	if err != nil {
+		return nil, nil, 0, errors.New("统计卡密批次失败: " + err.Error())
+	}
+
+	return batches, stats, total, nil
+}
+
+// GetCardBatch 获取卡密批次详情
+// @param id 批次ID
+// @param userID 当前用户ID
+// @return 批次、批次统计和错误信息
+func (s *Service) GetCardBatch(id int, userID int) (*dbmodel.CardBatch, model.CardBatchStats, error) {
+	batch, err := getCardBatch(id, userID)
+	if err != nil {
+		return nil, model.CardBatchStats{}, err
+	}
+
+	stats, err := getCardBatchStats([]uint{batch.ID})
+	if err != nil {
+		return nil, model.CardBatchStats{}, errors.New("统计卡密批次失败: " + err.Error())
+	}
+
+	return batch, stats[batch.ID], nil
+}
+
+// UpdateCardBatch 更新卡密批次的名称、备注和发放渠道
+// @param id 批次ID
+// @param req 更新卡密批次请求
+// @param userID 当前用户ID
+// @return 错误信息
+func (s *Service) UpdateCardBatch(id int, req model.UpdateCardBatchRequest, userID int) error {
+	batch, err := getCardBatch(id, userID)
+	if err != nil {
+		return err
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Note != nil {
+		updates["note"] = *req.Note
+	}
+	if req.Channel != nil {
+		updates["channel"] = *req.Channel
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+
+	if err := database.DB.Model(batch).Updates(updates).Error; err != nil {
+		return errors.New("更新卡密批次失败: " + err.Error())
+	}
+	return nil
+}
+
+// RevokeCardBatch 撤销卡密批次，禁用批次内所有未禁用的卡密
+// 用于批次泄露等情况，撤销后批次内的卡密无法再激活或登录
+// @param id 批次ID
+// @param userID 当前用户ID
+// @param ip 操作IP
+// @return 本次禁用的卡密数量和错误信息
+func (s *Service) RevokeCardBatch(id int, userID int, ip string) (int64, error) {
+	batch, err := getCardBatch(id, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	var disabled int64
+	err = database.Transaction(func(tx *gorm.DB) error {
+		scope := func() *gorm.DB {
+			return tx.Model(&dbmodel.Card{}).
+				Where("batch_id = ? AND user_id = ? AND status IN ?", batch.ID, userID, dbmodel.CardStatusesTo(dbmodel.CardStatusDisabled))
+		}
+		err := recordCardEvents(tx, scope(), dbmodel.CardEventDisable, userID, ip, "撤销批次", func(card dbmodel.Card) (interface{}, interface{}) {
+			return map[string]interface{}{"status": card.Status}, map[string]interface{}{"status": dbmodel.CardStatusDisabled}
+		})
+		if err != nil {
+			return err
+		}
+
+		result := scope().Updates(map[string]interface{}{"status": dbmodel.CardStatusDisabled, "is_online": 0})
+		if result.Error != nil {
+			return result.Error
+		}
+		disabled = result.RowsAffected
+
+		if batch.Status == dbmodel.CardBatchStatusRevoked {
+			return nil
+		}
+		now := time.Now()
+		return tx.Model(batch).Updates(map[string]interface{}{
+			"status":     dbmodel.CardBatchStatusRevoked,
+			"revoked_at": &now,
+		}).Error
+	})
+	if err != nil {
+		return 0, errors.New("撤销卡密批次失败: " + err.Error())
+	}
+
+	return disabled, nil
+}
@@ -0,0 +1,336 @@
+package card
+
+import (
+	"errors"
+	"time"
+
+	"github.com/skyle1995/DevE-Server/apps/card/model"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/utils/timeutil"
+	"gorm.io/gorm"
+)
+
+// 批量操作类型
+const (
+	BulkActionDisable      = "disable"       // 禁用
+	BulkActionEnable       = "enable"        // 启用
+	BulkActionDelete       = "delete"        // 删除
+	BulkActionExtend       = "extend"        // 延长有效期
+	BulkActionResetBinding = "reset_binding" // 重置绑定
+	BulkActionChangeType   = "change_type"   // 修改卡密类型
+	BulkActionSetLimit     = "set_limit"     // 设置最大换绑/解绑次数
+)
+
+// bulkMaxIDs 按ID批量操作时的最大数量
+const bulkMaxIDs = 10000
+
+// bulkAction 批量操作
+type bulkAction struct {
+	// applicable 筛选匹配卡密中适用该操作的卡密，为nil时全部适用
+	applicable func(query *gorm.DB) *gorm.DB
+	// apply 对适用的卡密执行操作，scope每次返回一个新的适用卡密查询，返回受影响的数量
+	apply func(tx *gorm.DB, scope func() *gorm.DB) (int64, error)
+	// event 执行前为每张适用的卡密记录的事件类型，为空时由apply自行记录
+	event string
+	// change 计算事件中修改前后的值
+	change cardChange
+}
+
+// newBulkAction 校验批量操作参数并创建操作
+func newBulkAction(req model.BulkCardRequest, userID int, ip string) (*bulkAction, error) {
+	switch req.Action {
+	case BulkActionDisable:
+		return &bulkAction{
+			applicable: func(query *gorm.DB) *gorm.DB {
+				return query.Where("status IN ?", dbmodel.CardStatusesTo(dbmodel.CardStatusDisabled))
+			},
+			apply: func(tx *gorm.DB, scope func() *gorm.DB) (int64, error) {
+				result := scope().Updates(map[string]interface{}{"status": dbmodel.CardStatusDisabled, "is_online": 0})
+				return result.RowsAffected, result.Error
+			},
+			event: dbmodel.CardEventDisable,
+			change: func(card dbmodel.Card) (interface{}, interface{}) {
+				return map[string]interface{}{"status": card.Status}, map[string]interface{}{"status": dbmodel.CardStatusDisabled}
+			},
+		}, nil
+
+	case BulkActionEnable:
+		// 启用后恢复为禁用前的状态，与dbmodel.Card.EnabledStatus一致：
+		// 冻结期间被禁用的卡密恢复为已冻结，未激活的恢复为未使用，已激活的按过期时间恢复为已过期或已使用
+		return &bulkAction{
+			applicable: func(query *gorm.DB) *gorm.DB {
+				return query.Where("status = ?", dbmodel.CardStatusDisabled)
+			},
+			apply: func(tx *gorm.DB, scope func() *gorm.DB) (int64, error) {
+				now := time.Now()
+				restores := []struct {
+					where  string
+					args   []interface{}
+					status int
+				}{
+					{"frozen_at IS NOT NULL", nil, dbmodel.CardStatusFrozen},
+					{"activate_at IS NULL", nil, dbmodel.CardStatusUnused},
+					{"expire_at <= ?", []interface{}{now}, dbmodel.CardStatusExpired},
+					{"", nil, dbmodel.CardStatusActive}, // 其余已激活且未过期的卡密
+				}
+				var affected int64
+				for _, restore := range restores {
+					query := scope()
+					if restore.where != "" {
+						query = query.Where(restore.where, restore.args...)
+					}
+					result := query.Update("status", restore.status)
+					if result.Error != nil {
+						return 0, result.Error
+					}
+					affected += result.RowsAffected
+				}
+				return affected, nil
+			},
+			event: dbmodel.CardEventEnable,
+			change: func(card dbmodel.Card) (interface{}, interface{}) {
+				return map[string]interface{}{"status": card.Status}, map[string]interface{}{"status": card.EnabledStatus()}
+			},
+		}, nil
+
+	case BulkActionDelete:
+		return &bulkAction{
+			apply: func(tx *gorm.DB, scope func() *gorm.DB) (int64, error) {
+				result := scope().Delete(&dbmodel.Card{})
+				return result.RowsAffected, result.Error
+			},
+			event: dbmodel.CardEventDelete,
+		}, nil
+
+	case BulkActionExtend:
+		if req.Duration <= 0 {
+			return nil, errors.New("延长时长必须大于0")
+		}
+		unit := req.TimeUnit
+		if unit == "" {
+			unit = "day"
+		}
+		// 未激活的卡密没有过期时间，激活时按卡密类型计算，因此跳过；已冻结的卡密解冻时重新计算过期时间，同样跳过
+		return &bulkAction{
+			applicable: func(query *gorm.DB) *gorm.DB {
+				return query.Where("expire_at IS NOT NULL AND status <> ?", dbmodel.CardStatusFrozen)
+			},
+			apply: func(tx *gorm.DB, scope func() *gorm.DB) (int64, error) {
+				var (
+					cards    []dbmodel.Card
+					affected int64
+				)
+				now := time.Now()
+				result := scope().FindInBatches(&cards, 500, func(_ *gorm.DB, _ int) error {
+					events := make([]dbmodel.CardEvent, 0, len(cards))
+					for _, card := range cards {
+						updates := map[string]interface{}{
+							"expire_at": timeutil.AddUnits(*card.ExpireAt, req.Duration, unit),
+						}
+						// 已过期的卡密延长后未过期则恢复为已使用
+						if card.Status == dbmodel.CardStatusExpired && updates["expire_at"].(time.Time).After(now) {
+							updates["status"] = dbmodel.CardStatusActive
+						}
+						if err := tx.Model(&dbmodel.Card{}).Where("id = ?", card.ID).Updates(updates).Error; err != nil {
+							return err
+						}
+						event := dbmodel.NewCardEvent(card, dbmodel.CardEventRecharge, dbmodel.CardActorUser, uint(userID), ip).
+							WithChange(map[string]interface{}{"expire_at": card.ExpireAt, "status": card.Status}, updates)
+						event.Message = "批量延长有效期"
+						events = append(events, event)
+						affected++
+					}
+					return tx.Create(&events).Error
+				})
+				return affected, result.Error
+			},
+		}, nil
+
+	case BulkActionResetBinding:
+		return &bulkAction{
+			applicable: func(query *gorm.DB) *gorm.DB {
+				return query.Where("(device_id IS NOT NULL OR bind_count > 0 OR rebind_count > 0 OR unbind_count > 0)")
+			},
+			apply: func(tx *gorm.DB, scope func() *gorm.DB) (int64, error) {
+				result := scope().Updates(map[string]interface{}{
+					"device_id":    nil,
+					"binding_info": "",
+					"bind_count":   0,
+					"rebind_count": 0,
+					"unbind_count": 0,
+					"is_online":    0,
+					"version":      gorm.Expr("version + 1"), // 使读取了旧绑定的客户端换绑、解绑失效
+				})
+				return result.RowsAffected, result.Error
+			},
+			event: dbmodel.CardEventEdit,
+			change: func(card dbmodel.Card) (interface{}, interface{}) {
+				return map[string]interface{}{
+					"device_id":    card.DeviceID,
+					"bind_count":   card.BindCount,
+					"rebind_count": card.RebindCount,
+					"unbind_count": card.UnbindCount,
+				}, map[string]interface{}{
+					"device_id":    nil,
+					"bind_count":   0,
+					"rebind_count": 0,
+					"unbind_count": 0,
+				}
+			},
+		}, nil
+
+	case BulkActionChangeType:
+		if req.TypeID <= 0 {
+			return nil, errors.New("请指定目标卡密类型")
+		}
+		var cardType dbmodel.CardType
+		result := database.DB.Where("id = ? AND user_id = ?", req.TypeID, userID).First(&cardType)
+		if result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return nil, errors.New("卡密类型不存在或无权限使用")
+			}
+			return nil, errors.New("查询卡密类型失败: " + result.Error.Error())
+		}
+		// 卡密只能修改为同一应用下的类型，其他应用的卡密跳过
+		return &bulkAction{
+			applicable: func(query *gorm.DB) *gorm.DB {
+				return query.Where("app_id = ? AND type_id <> ?", cardType.AppID, cardType.ID)
+			},
+			apply: func(tx *gorm.DB, scope func() *gorm.DB) (int64, error) {
+				result := scope().Update("type_id", cardType.ID)
+				return result.RowsAffected, result.Error
+			},
+			event: dbmodel.CardEventEdit,
+			change: func(card dbmodel.Card) (interface{}, interface{}) {
+				return map[string]interface{}{"type_id": card.TypeID}, map[string]interface{}{"type_id": cardType.ID}
+			},
+		}, nil
+
+	case BulkActionSetLimit:
+		updates := make(map[string]interface{})
+		if req.MaxRebindCount != nil {
+			if *req.MaxRebindCount < 0 {
+				return nil, errors.New("最大换绑次数不能小于0")
+			}
+			updates["max_rebind_count"] = *req.MaxRebindCount
+		}
+		if req.MaxUnbindCount != nil {
+			if *req.MaxUnbindCount < 0 {
+				return nil, errors.New("最大解绑次数不能小于0")
+			}
+			updates["max_unbind_count"] = *req.MaxUnbindCount
+		}
+		if len(updates) == 0 {
+			return nil, errors.New("请指定最大换绑次数或最大解绑次数")
+		}
+		return &bulkAction{
+			apply: func(tx *gorm.DB, scope func() *gorm.DB) (int64, error) {
+				result := scope().Updates(updates)
+				return result.RowsAffected, result.Error
+			},
+			event: dbmodel.CardEventEdit,
+			change: func(card dbmodel.Card) (interface{}, interface{}) {
+				return map[string]interface{}{
+					"max_rebind_count": card.MaxRebindCount,
+					"max_unbind_count": card.MaxUnbindCount,
+				}, updates
+			},
+		}, nil
+	}
+
+	return nil, errors.New("不支持的批量操作")
+}
+
+// bulkScope 创建批量操作匹配卡密的查询条件
+func bulkScope(req model.BulkCardRequest, userID int) (func(db *gorm.DB) *gorm.DB, error) {
+	if len(req.IDs) > 0 && req.Filter != nil {
+		return nil, errors.New("ids和filter只能指定一个")
+	}
+
+	if len(req.IDs) > 0 {
+		if len(req.IDs) > bulkMaxIDs {
+			return nil, errors.New("按ID批量操作最多10000张卡密")
+		}
+		return func(db *gorm.DB) *gorm.DB {
+			return db.Model(&dbmodel.Card{}).Where("user_id = ? AND id IN ?", userID, req.IDs)
+		}, nil
+	}
+
+	filter := req.Filter
+	if filter == nil {
+		return nil, errors.New("请指定卡密ID列表或筛选条件")
+	}
+	// 空筛选条件会匹配全部卡密，为避免误操作不允许
+	if filter.AppID <= 0 && filter.TypeID <= 0 && filter.BatchID <= 0 && filter.Status == nil && filter.CardNo == "" {
+		return nil, errors.New("筛选条件不能为空")
+	}
+	status := -1
+	if filter.Status != nil {
+		status = *filter.Status
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		query := db.Model(&dbmodel.Card{}).Where("user_id = ?", userID)
+		return filterCards(query, cardFilter{
+			AppID:   filter.AppID,
+			TypeID:  filter.TypeID,
+			BatchID: filter.BatchID,
+			Status:  status,
+			CardNo:  filter.CardNo,
+		})
+	}, nil
+}
+
+// BulkCards 批量操作卡密
+// 所有操作在同一事务中执行，任一步骤失败则全部回滚；预览时只统计数量不执行操作
+// 执行时为每张受影响的卡密记录一条事件
+// @param req 批量操作卡密请求
+// @param userID 当前用户ID
+// @param preview 是否仅预览
+// @param ip 操作IP
+// @return 操作结果和错误信息
+func (s *Service) BulkCards(req model.BulkCardRequest, userID int, preview bool, ip string) (*model.BulkCardResponse, error) {
+	scope, err := bulkScope(req, userID)
+	if err != nil {
+		return nil, err
+	}
+	action, err := newBulkAction(req, userID, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	applicable := func(db *gorm.DB) *gorm.DB {
+		query := scope(db)
+		if action.applicable != nil {
+			query = action.applicable(query)
+		}
+		return query
+	}
+
+	res := &model.BulkCardResponse{Action: req.Action, Preview: preview}
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := scope(tx).Count(&res.Matched).Error; err != nil {
+			return err
+		}
+
+		if preview {
+			return applicable(tx).Count(&res.Affected).Error
+		}
+
+		if action.event != "" {
+			if err := recordCardEvents(tx, applicable(tx), action.event, userID, ip, "批量操作", action.change); err != nil {
+				return err
+			}
+		}
+
+		affected, err := action.apply(tx, func() *gorm.DB { return applicable(tx) })
+		res.Affected = affected
+		return err
+	})
+	if err != nil {
+		return nil, errors.New("批量操作卡密失败: " + err.Error())
+	}
+
+	res.Skipped = res.Matched - res.Affected
+	return res, nil
+}
@@ -2,9 +2,13 @@ package card
 
 import (
 	"errors"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
 	"github.com/skyle1995/DevE-Server/apps/card/model"
 	"github.com/skyle1995/DevE-Server/database"
 	dbmodel "github.com/skyle1995/DevE-Server/database/model"
@@ -97,6 +101,20 @@ func (c *Controller) CreateCardType(ctx *gin.Context) {
 		return
 	}
 
+	// 验证功能权益
+	if err := req.Entitlements.Validate(); err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	// 验证卡密模板是否属于当前用户
+	for _, templateID := range []*uint{req.CardNoTemplateID, req.CardKeyTemplateID} {
+		if err := c.service.checkCardTemplate(templateID, int(userID.(uint))); err != nil {
+			response.FailWithMessage(err.Error(), ctx)
+			return
+		}
+	}
+
 	// 创建卡密类型
 	cardType := dbmodel.CardType{
 		Name:                  req.Name,
@@ -106,7 +124,12 @@ func (c *Controller) CreateCardType(ctx *gin.Context) {
 		Status:                req.Status,
 		DefaultMaxRebindCount: req.DefaultMaxRebindCount,
 		DefaultMaxUnbindCount: req.DefaultMaxUnbindCount,
-		UserID:                userID.(int),
+		AllowClientFreeze:     req.AllowClientFreeze,
+		MaxFreezeCount:        req.MaxFreezeCount,
+		Entitlements:          req.Entitlements,
+		CardNoTemplateID:      nonZero(req.CardNoTemplateID),
+		CardKeyTemplateID:     nonZero(req.CardKeyTemplateID),
+		UserID:                int(userID.(uint)),
 	}
 
 	result := database.DB.Create(&cardType)
@@ -178,6 +201,34 @@ func (c *Controller) UpdateCardType(ctx *gin.Context) {
 	if req.Status >= 0 {
 		cardType.Status = req.Status
 	}
+	if req.AllowClientFreeze != nil {
+		cardType.AllowClientFreeze = *req.AllowClientFreeze
+	}
+	if req.MaxFreezeCount != nil {
+		cardType.MaxFreezeCount = *req.MaxFreezeCount
+	}
+	if req.Entitlements != nil {
+		// 权益在客户端请求时实时读取，修改后对该类型的所有卡密立即生效
+		if err := req.Entitlements.Validate(); err != nil {
+			response.FailWithMessage(err.Error(), ctx)
+			return
+		}
+		cardType.Entitlements = req.Entitlements
+	}
+	if req.CardNoTemplateID != nil {
+		if err := c.service.checkCardTemplate(req.CardNoTemplateID, int(userID.(uint))); err != nil {
+			response.FailWithMessage(err.Error(), ctx)
+			return
+		}
+		cardType.CardNoTemplateID = nonZero(req.CardNoTemplateID)
+	}
+	if req.CardKeyTemplateID != nil {
+		if err := c.service.checkCardTemplate(req.CardKeyTemplateID, int(userID.(uint))); err != nil {
+			response.FailWithMessage(err.Error(), ctx)
+			return
+		}
+		cardType.CardKeyTemplateID = nonZero(req.CardKeyTemplateID)
+	}
 
 	result = database.DB.Save(&cardType)
 	if result.Error != nil {
@@ -236,6 +287,171 @@ func (c *Controller) DeleteCardType(ctx *gin.Context) {
 	response.Ok(ctx)
 }
 
+// GetCardTemplates 获取卡密模板列表
+// @Summary 获取卡密模板列表
+// @Description 获取当前用户的卡号/卡密生成模板
+// @Tags 用户API
+// @Produce json
+// @Success 200 {object} response.Response{data=model.CardTemplateListResponse} "获取成功"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/card/templates [get]
+func (c *Controller) GetCardTemplates(ctx *gin.Context) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.FailWithMessage("未找到用户信息", ctx)
+		return
+	}
+
+	templates, err := c.service.GetCardTemplateList(int(userID.(uint)))
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	items := make([]model.CardTemplateResponse, len(templates))
+	for i, template := range templates {
+		items[i] = model.FromCardTemplate(template)
+		items[i].Example = templateFormat(template).Generate()
+	}
+	response.OkWithData(model.CardTemplateListResponse{
+		Total: len(items),
+		Items: items,
+	}, ctx)
+}
+
+// CreateCardTemplate 创建卡密模板
+// @Summary 创建卡密模板
+// @Description 创建卡号/卡密生成模板，布局中的X为随机字符
+// @Tags 用户API
+// @Accept json
+// @Produce json
+// @Param request body model.CardTemplateRequest true "卡密模板请求"
+// @Success 200 {object} response.Response{data=model.CardTemplateResponse} "创建成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Router /api/v1/card/templates [post]
+func (c *Controller) CreateCardTemplate(ctx *gin.Context) {
+	c.saveCardTemplate(ctx, 0)
+}
+
+// UpdateCardTemplate 更新卡密模板
+// @Summary 更新卡密模板
+// @Description 更新卡号/卡密生成模板，只影响之后生成的卡密
+// @Tags 用户API
+// @Accept json
+// @Produce json
+// @Param id path int true "模板ID"
+// @Param request body model.CardTemplateRequest true "卡密模板请求"
+// @Success 200 {object} response.Response{data=model.CardTemplateResponse} "更新成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Router /api/v1/card/templates/{id} [put]
+func (c *Controller) UpdateCardTemplate(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil || id <= 0 {
+		response.FailWithMessage("卡密模板ID格式错误", ctx)
+		return
+	}
+	c.saveCardTemplate(ctx, id)
+}
+
+// saveCardTemplate 创建或更新卡密模板
+func (c *Controller) saveCardTemplate(ctx *gin.Context, id int) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.FailWithMessage("未找到用户信息", ctx)
+		return
+	}
+
+	var req model.CardTemplateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("请求参数错误: "+err.Error(), ctx)
+		return
+	}
+
+	template, err := c.service.SaveCardTemplate(id, req, int(userID.(uint)))
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	res := model.FromCardTemplate(*template)
+	res.Example = templateFormat(*template).Generate()
+	response.OkWithData(res, ctx)
+}
+
+// DeleteCardTemplate 删除卡密模板
+// @Summary 删除卡密模板
+// @Description 删除卡密模板，引用该模板的卡密类型改为使用默认模板
+// @Tags 用户API
+// @Produce json
+// @Param id path int true "模板ID"
+// @Success 200 {object} response.Response "删除成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Router /api/v1/card/templates/{id} [delete]
+func (c *Controller) DeleteCardTemplate(ctx *gin.Context) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.FailWithMessage("未找到用户信息", ctx)
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		response.FailWithMessage("卡密模板ID格式错误", ctx)
+		return
+	}
+
+	if err := c.service.DeleteCardTemplate(id, int(userID.(uint))); err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	response.Ok(ctx)
+}
+
+// PreviewCardTemplate 预览卡密模板
+// @Summary 预览卡密模板
+// @Description 按模板配置生成示例，不保存模板
+// @Tags 用户API
+// @Accept json
+// @Produce json
+// @Param request body model.PreviewCardTemplateRequest true "预览卡密模板请求"
+// @Success 200 {object} response.Response{data=model.PreviewCardTemplateResponse} "获取成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Router /api/v1/card/templates/preview [post]
+func (c *Controller) PreviewCardTemplate(ctx *gin.Context) {
+	var req model.PreviewCardTemplateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("请求参数错误: "+err.Error(), ctx)
+		return
+	}
+
+	format, err := newTemplateFormat(req.Layout, req.Charset, req.Case, req.Checksum)
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	if req.Count == 0 {
+		req.Count = 5
+	}
+	examples := make([]string, req.Count)
+	for i := range examples {
+		examples[i] = format.Generate()
+	}
+	response.OkWithData(model.PreviewCardTemplateResponse{Examples: examples}, ctx)
+}
+
+// nonZero 模板ID为0时返回nil
+func nonZero(id *uint) *uint {
+	if id == nil || *id == 0 {
+		return nil
+	}
+	return id
+}
+
 // GetCards 获取卡密列表
 // @Summary 获取卡密列表
 // @Description 获取当前用户创建的卡密列表
@@ -281,6 +497,16 @@ func (c *Controller) GetCards(ctx *gin.Context) {
 		}
 		req.TypeID = id
 	}
+	// 批次ID
+	batchIDStr := ctx.Query("batch_id")
+	if batchIDStr != "" {
+		id, err := strconv.Atoi(batchIDStr)
+		if err != nil {
+			response.FailWithMessage("批次ID格式错误", ctx)
+			return
+		}
+		req.BatchID = id
+	}
 	// 卡密状态
 	statusStr := ctx.Query("status")
 	if statusStr != "" {
@@ -289,7 +515,7 @@ func (c *Controller) GetCards(ctx *gin.Context) {
 			response.FailWithMessage("卡密状态格式错误", ctx)
 			return
 		}
-		req.Status = status
+		req.Status = &status
 	}
 	// 分页参数
 	pageStr := ctx.DefaultQuery("page", "1")
@@ -307,15 +533,18 @@ func (c *Controller) GetCards(ctx *gin.Context) {
 
 	// 查询卡密列表
 	var cards []dbmodel.Card
-	db := database.DB.Preload("CardType").Where("user_id = ?", userID)
+	db := database.DB.Model(&dbmodel.Card{}).Preload("CardType").Where("user_id = ?", userID)
 	if req.AppID > 0 {
 		db = db.Where("app_id = ?", req.AppID)
 	}
 	if req.TypeID > 0 {
 		db = db.Where("type_id = ?", req.TypeID)
 	}
-	if req.Status > 0 {
-		db = db.Where("status = ?", req.Status)
+	if req.BatchID > 0 {
+		db = db.Where("batch_id = ?", req.BatchID)
+	}
+	if req.Status != nil {
+		db = db.Where("status = ?", *req.Status)
 	}
 
 	// 计算总数
@@ -337,6 +566,213 @@ func (c *Controller) GetCards(ctx *gin.Context) {
 	}, ctx)
 }
 
+// ExportCards 导出卡密
+// @Summary 导出卡密
+// @Description 按筛选条件流式导出当前用户的卡密，支持CSV、TXT和XLSX格式，每次导出都会被记录
+// @Tags 用户API
+// @Produce octet-stream
+// @Param app_id query int false "应用ID"
+// @Param type_id query int false "卡密类型ID"
+// @Param status query int false "卡密状态，不传则导出全部状态"
+// @Param card_no query string false "卡号，模糊查询"
+// @Param format query string false "导出格式：csv、txt、xlsx，默认csv"
+// @Param template query string false "文本格式的行模板，默认 {card_no}----{card_key}"
+// @Success 200 {file} file "导出文件"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Router /api/v1/card/cards/export [get]
+func (c *Controller) ExportCards(ctx *gin.Context) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.FailWithMessage("未找到用户信息", ctx)
+		return
+	}
+
+	// 状态默认-1，表示导出全部状态
+	req := model.ExportCardRequest{Status: -1}
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), ctx)
+		return
+	}
+	c.exportCards(ctx, req, "cards", userID.(uint))
+}
+
+// exportCards 输出卡密导出文件
+func (c *Controller) exportCards(ctx *gin.Context, req model.ExportCardRequest, name string, userID uint) {
+	if req.Format == "" {
+		req.Format = ExportFormatCSV
+	}
+
+	filename := name + "_" + time.Now().Format("20060102150405") + "." + req.Format
+	ctx.Header("Content-Type", exportContentType(req.Format))
+	ctx.Header("Content-Disposition", "attachment; filename="+filename)
+	ctx.Status(200)
+
+	// 响应头已发出，导出中途出错只能记录日志并中断输出
+	count, err := c.service.ExportCards(req, userID, ctx.ClientIP(), ctx.Request.UserAgent(), ctx.Writer)
+	if err != nil {
+		log.Errorf("用户 %v 导出卡密失败（已导出 %d 张）: %v", userID, count, err)
+	}
+}
+
+// BulkCards 批量操作卡密
+// @Summary 批量操作卡密
+// @Description 按卡密ID列表或筛选条件批量禁用、启用、删除、延长有效期、重置绑定、修改类型或设置换绑/解绑次数，在同一事务中执行
+// @Tags 用户API
+// @Accept json
+// @Produce json
+// @Param request body model.BulkCardRequest true "批量操作卡密请求"
+// @Success 200 {object} response.Response{data=model.BulkCardResponse} "操作成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Router /api/v1/card/cards/bulk [post]
+func (c *Controller) BulkCards(ctx *gin.Context) {
+	c.bulkCards(ctx, false)
+}
+
+// PreviewBulkCards 预览批量操作卡密
+// @Summary 预览批量操作卡密
+// @Description 统计批量操作将匹配和影响的卡密数量，不执行操作
+// @Tags 用户API
+// @Accept json
+// @Produce json
+// @Param request body model.BulkCardRequest true "批量操作卡密请求"
+// @Success 200 {object} response.Response{data=model.BulkCardResponse} "获取成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Router /api/v1/card/cards/bulk/preview [post]
+func (c *Controller) PreviewBulkCards(ctx *gin.Context) {
+	c.bulkCards(ctx, true)
+}
+
+// bulkCards 批量操作卡密
+func (c *Controller) bulkCards(ctx *gin.Context, preview bool) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.FailWithMessage("未找到用户信息", ctx)
+		return
+	}
+
+	var req model.BulkCardRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("请求参数错误: "+err.Error(), ctx)
+		return
+	}
+
+	res, err := c.service.BulkCards(req, int(userID.(uint)), preview, ctx.ClientIP())
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	response.OkWithData(res, ctx)
+}
+
+// ImportCards 导入卡密
+// @Summary 导入卡密
+// @Description 从CSV或JSON文件导入已有卡密，包括激活状态、过期时间和绑定设备，逐行返回错误
+// @Tags 用户API
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "导入文件"
+// @Param type_id formData int true "卡密类型ID"
+// @Param app_id formData int false "应用ID"
+// @Param format formData string false "文件格式：csv、json，默认按文件扩展名判断"
+// @Param dry_run formData bool false "仅校验不导入"
+// @Success 200 {object} response.Response{data=model.ImportCardResponse} "导入完成"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Router /api/v1/card/cards/import [post]
+func (c *Controller) ImportCards(ctx *gin.Context) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.FailWithMessage("未找到用户信息", ctx)
+		return
+	}
+
+	var req model.ImportCardRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), ctx)
+		return
+	}
+
+	header, err := ctx.FormFile("file")
+	if err != nil {
+		response.FailWithMessage("请上传导入文件", ctx)
+		return
+	}
+	if header.Size > importMaxFileSize {
+		response.FailWithMessage("导入文件不能超过10MB", ctx)
+		return
+	}
+
+	// 未指定格式时按文件扩展名判断
+	if req.Format == "" {
+		req.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+		if req.Format != ImportFormatCSV && req.Format != ImportFormatJSON {
+			response.FailWithMessage("无法识别文件格式，请指定format为csv或json", ctx)
+			return
+		}
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		response.FailWithMessage("读取导入文件失败: "+err.Error(), ctx)
+		return
+	}
+	defer file.Close()
+
+	res, err := c.service.ImportCards(req, file, int(userID.(uint)))
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	response.OkWithDetailed(res, "导入完成", ctx)
+}
+
+// GetCardExports 获取卡密导出记录
+// @Summary 获取卡密导出记录
+// @Description 获取当前用户的卡密导出记录
+// @Tags 用户API
+// @Accept json
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} response.Response{data=model.CardExportListResponse} "获取成功"
+// @Failure 500 {object} response.Response "服务器内部错误"
+// @Router /api/v1/card/exports [get]
+func (c *Controller) GetCardExports(ctx *gin.Context) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.FailWithMessage("未找到用户信息", ctx)
+		return
+	}
+
+	var req model.GetCardExportListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), ctx)
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PageSize < 1 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+
+	exports, total, err := c.service.GetCardExportList(req, userID.(uint))
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	response.OkWithData(model.CardExportListResponse{
+		Total: int(total),
+		Items: model.FromCardExports(exports),
+	}, ctx)
+}
+
 // GenerateCards 生成卡密
 // @Summary 生成卡密
 // @Description 批量生成卡密
@@ -382,7 +818,7 @@ func (c *Controller) GenerateCards(ctx *gin.Context) {
 	}
 
 	// 生成卡密
-	cards, err := c.service.GenerateCards(req, userID.(int))
+	cards, err := c.service.GenerateCards(req, int(userID.(uint)))
 	if err != nil {
 		response.FailWithMessage("生成卡密失败: "+err.Error(), ctx)
 		return
@@ -391,6 +827,7 @@ func (c *Controller) GenerateCards(ctx *gin.Context) {
 	// 构建响应
 	response.OkWithData(model.GenerateCardResponse{
 		Success: true,
+		BatchID: *cards[0].BatchID,
 		Count:   len(cards),
 		Cards:   model.FromCards(cards, true),
 	}, ctx)
@@ -429,42 +866,10 @@ func (c *Controller) UpdateCard(ctx *gin.Context) {
 		response.FailWithMessage("请求参数错误: "+err.Error(), ctx)
 		return
 	}
+	req.ID = id
 
-	// 查询卡密
-	var card dbmodel.Card
-	result := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&card)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			response.FailWithMessage("卡密不存在或无权限修改", ctx)
-			return
-		}
-		response.FailWithMessage("查询卡密失败: "+result.Error.Error(), ctx)
-		return
-	}
-
-	// 更新卡密
-	if req.TypeID > 0 {
-		// 验证卡密类型是否存在且属于当前用户
-		var cardType dbmodel.CardType
-		result = database.DB.Where("id = ? AND user_id = ?", req.TypeID, userID).First(&cardType)
-		if result.Error != nil {
-			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-				response.FailWithMessage("卡密类型不存在或无权限使用", ctx)
-				return
-			}
-			response.FailWithMessage("查询卡密类型失败: "+result.Error.Error(), ctx)
-			return
-		}
-		card.TypeID = uint(req.TypeID)
-	}
-
-	if req.Status >= 0 {
-		card.Status = req.Status
-	}
-
-	result = database.DB.Save(&card)
-	if result.Error != nil {
-		response.FailWithMessage("更新卡密失败: "+result.Error.Error(), ctx)
+	if _, err := c.service.UpdateCard(req, int(userID.(uint)), ctx.ClientIP()); err != nil {
+		response.FailWithMessage(err.Error(), ctx)
 		return
 	}
 
@@ -497,24 +902,314 @@ func (c *Controller) DeleteCard(ctx *gin.Context) {
 		return
 	}
 
-	// 查询卡密
-	var card dbmodel.Card
-	result := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&card)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			response.FailWithMessage("卡密不存在或无权限删除", ctx)
-			return
-		}
-		response.FailWithMessage("查询卡密失败: "+result.Error.Error(), ctx)
+	if err := c.service.DeleteCard(model.DeleteCardRequest{ID: id}, int(userID.(uint)), ctx.ClientIP()); err != nil {
+		response.FailWithMessage(err.Error(), ctx)
 		return
 	}
 
-	// 删除卡密
-	result = database.DB.Delete(&card)
-	if result.Error != nil {
-		response.FailWithMessage("删除卡密失败: "+result.Error.Error(), ctx)
+	response.Ok(ctx)
+}
+
+// FreezeCard 冻结卡密
+// @Summary 冻结卡密
+// @Description 冻结使用中的卡密，冻结期间客户端无法验证和心跳，剩余有效时长在解冻时恢复
+// @Tags 用户API
+// @Accept json
+// @Produce json
+// @Param id path int true "卡密ID"
+// @Success 200 {object} response.Response{data=model.CardResponse} "冻结成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Router /api/v1/card/cards/{id}/freeze [post]
+func (c *Controller) FreezeCard(ctx *gin.Context) {
+	c.changeFrozen(ctx, c.service.FreezeCard, "冻结成功")
+}
+
+// UnfreezeCard 解冻卡密
+// @Summary 解冻卡密
+// @Description 解冻卡密，过期时间按冻结时的剩余时长从当前时间重新计算
+// @Tags 用户API
+// @Accept json
+// @Produce json
+// @Param id path int true "卡密ID"
+// @Success 200 {object} response.Response{data=model.CardResponse} "解冻成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Router /api/v1/card/cards/{id}/unfreeze [post]
+func (c *Controller) UnfreezeCard(ctx *gin.Context) {
+	c.changeFrozen(ctx, c.service.UnfreezeCard, "解冻成功")
+}
+
+// changeFrozen 冻结或解冻卡密
+func (c *Controller) changeFrozen(ctx *gin.Context, change func(id int, userID int, ip string) (*dbmodel.Card, error), message string) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.FailWithMessage("未找到用户信息", ctx)
+		return
+	}
+
+	// 解析卡密ID
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		response.FailWithMessage("卡密ID格式错误", ctx)
+		return
+	}
+
+	card, err := change(id, int(userID.(uint)), ctx.ClientIP())
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	response.OkWithDetailed(model.FromCard(*card, false), message, ctx)
+}
+
+// GetCardBatches 获取卡密批次列表
+// @Summary 获取卡密批次列表
+// @Description 获取当前用户的卡密批次列表，包含批次内各状态卡密数量
+// @Tags 用户API
+// @Accept json
+// @Produce json
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页数量，默认20，最大100"
+// @Param app_id query int false "应用ID"
+// @Param type_id query int false "卡密类型ID"
+// @Param channel query string false "发放渠道"
+// @Param name query string false "批次名称，模糊查询"
+// @Success 200 {object} response.Response{data=model.CardBatchListResponse} "成功"
+// @Router /api/v1/card/batches [get]
+func (c *Controller) GetCardBatches(ctx *gin.Context) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.FailWithMessage("未找到用户信息", ctx)
+		return
+	}
+
+	var req model.GetCardBatchListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), ctx)
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PageSize < 1 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+
+	batches, stats, total, err := c.service.GetCardBatchList(req, int(userID.(uint)))
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	items := make([]model.CardBatchResponse, len(batches))
+	for i, batch := range batches {
+		items[i] = model.FromCardBatch(batch, stats[batch.ID])
+	}
+	response.OkWithData(model.CardBatchListResponse{
+		Total: int(total),
+		Items: items,
+	}, ctx)
+}
+
+// GetCardBatch 获取卡密批次详情
+// @Summary 获取卡密批次详情
+// @Description 获取卡密批次详情，包含批次内各状态卡密数量
+// @Tags 用户API
+// @Accept json
+// @Produce json
+// @Param id path int true "批次ID"
+// @Success 200 {object} response.Response{data=model.CardBatchResponse} "成功"
+// @Router /api/v1/card/batches/{id} [get]
+func (c *Controller) GetCardBatch(ctx *gin.Context) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.FailWithMessage("未找到用户信息", ctx)
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		response.FailWithMessage("批次ID格式错误", ctx)
+		return
+	}
+
+	batch, stats, err := c.service.GetCardBatch(id, int(userID.(uint)))
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	response.OkWithData(model.FromCardBatch(*batch, stats), ctx)
+}
+
+// UpdateCardBatch 更新卡密批次
+// @Summary 更新卡密批次
+// @Description 更新卡密批次的名称、备注和发放渠道
+// @Tags 用户API
+// @Accept json
+// @Produce json
+// @Param id path int true "批次ID"
+// @Param data body model.UpdateCardBatchRequest true "批次信息"
+// @Success 200 {object} response.Response "成功"
+// @Router /api/v1/card/batches/{id} [put]
+func (c *Controller) UpdateCardBatch(ctx *gin.Context) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.FailWithMessage("未找到用户信息", ctx)
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		response.FailWithMessage("批次ID格式错误", ctx)
+		return
+	}
+
+	var req model.UpdateCardBatchRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), ctx)
+		return
+	}
+
+	if err := c.service.UpdateCardBatch(id, req, int(userID.(uint))); err != nil {
+		response.FailWithMessage(err.Error(), ctx)
 		return
 	}
 
 	response.Ok(ctx)
 }
+
+// RevokeCardBatch 撤销卡密批次
+// @Summary 撤销卡密批次
+// @Description 禁用批次内所有卡密并将批次标记为已撤销，用于批次泄露等情况
+// @Tags 用户API
+// @Accept json
+// @Produce json
+// @Param id path int true "批次ID"
+// @Success 200 {object} response.Response{data=model.RevokeCardBatchResponse} "成功"
+// @Router /api/v1/card/batches/{id}/revoke [post]
+func (c *Controller) RevokeCardBatch(ctx *gin.Context) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.FailWithMessage("未找到用户信息", ctx)
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		response.FailWithMessage("批次ID格式错误", ctx)
+		return
+	}
+
+	disabled, err := c.service.RevokeCardBatch(id, int(userID.(uint)), ctx.ClientIP())
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	response.OkWithDetailed(model.RevokeCardBatchResponse{Disabled: disabled}, "批次已撤销", ctx)
+}
+
+// ExportCardBatch 导出卡密批次
+// @Summary 导出卡密批次
+// @Description 导出批次内的卡密，格式参数与导出卡密一致
+// @Tags 用户API
+// @Produce octet-stream
+// @Param id path int true "批次ID"
+// @Param status query int false "状态，不传则导出全部状态"
+// @Param format query string false "导出格式：csv、txt、xlsx，默认csv"
+// @Param template query string false "文本格式的行模板，默认 {card_no}----{card_key}"
+// @Success 200 {file} file "导出文件"
+// @Router /api/v1/card/batches/{id}/export [get]
+func (c *Controller) ExportCardBatch(ctx *gin.Context) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.FailWithMessage("未找到用户信息", ctx)
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		response.FailWithMessage("批次ID格式错误", ctx)
+		return
+	}
+
+	// 状态默认-1，表示导出全部状态
+	req := model.ExportCardRequest{Status: -1}
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), ctx)
+		return
+	}
+
+	// 响应头发出前检查批次权限
+	batch, _, err := c.service.GetCardBatch(id, int(userID.(uint)))
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+	req.BatchID = int(batch.ID)
+	req.AppID = 0
+	req.TypeID = 0
+
+	c.exportCards(ctx, req, "batch_"+strconv.Itoa(id), userID.(uint))
+}
+
+// GetCardEvents 获取卡密事件时间线
+// @Summary 获取卡密事件时间线
+// @Description 按时间倒序获取卡密的激活、验证失败、换绑、解绑、续期、修改、冻结、过期等事件，已删除的卡密同样可以查询
+// @Tags 用户API
+// @Accept json
+// @Produce json
+// @Param id path int true "卡密ID"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页数量，默认20，最大100"
+// @Param type query string false "事件类型"
+// @Success 200 {object} response.Response{data=model.CardEventListResponse} "成功"
+// @Router /api/v1/card/cards/{id}/events [get]
+func (c *Controller) GetCardEvents(ctx *gin.Context) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.FailWithMessage("未找到用户信息", ctx)
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		response.FailWithMessage("卡密ID格式错误", ctx)
+		return
+	}
+
+	var req model.GetCardEventListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), ctx)
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PageSize < 1 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+
+	events, total, err := c.service.GetCardEvents(id, req, int(userID.(uint)))
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	items := make([]model.CardEventResponse, len(events))
+	for i, event := range events {
+		items[i] = model.FromCardEvent(event)
+	}
+	response.OkWithData(model.CardEventListResponse{
+		Total: int(total),
+		Items: items,
+	}, ctx)
+}
@@ -0,0 +1,65 @@
+package card
+
+import (
+	"errors"
+
+	"github.com/skyle1995/DevE-Server/apps/card/model"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"gorm.io/gorm"
+)
+
+// cardChange 计算卡密事件修改前后的值，返回nil表示不记录
+type cardChange func(card dbmodel.Card) (oldValue, newValue interface{})
+
+// recordCardEvents 为查询匹配的每张卡密记录一条所有者操作事件
+// 需在修改卡密之前调用，以便记录修改前的值
+func recordCardEvents(tx *gorm.DB, query *gorm.DB, eventType string, userID int, ip, message string, change cardChange) error {
+	var cards []dbmodel.Card
+	result := query.FindInBatches(&cards, 500, func(batch *gorm.DB, _ int) error {
+		events := make([]dbmodel.CardEvent, len(cards))
+		for i, card := range cards {
+			events[i] = dbmodel.NewCardEvent(card, eventType, dbmodel.CardActorUser, uint(userID), ip)
+			events[i].Message = message
+			if change != nil {
+				events[i] = events[i].WithChange(change(card))
+			}
+		}
+		return tx.Create(&events).Error
+	})
+	return result.Error
+}
+
+// GetCardEvents 获取卡密的事件时间线，已删除的卡密同样可以查询
+// @param id 卡密ID
+// @param req 获取卡密事件请求
+// @param userID 当前用户ID
+// @return 事件列表、总数和错误信息
+func (s *Service) GetCardEvents(id int, req model.GetCardEventListRequest, userID int) ([]dbmodel.CardEvent, int64, error) {
+	var card dbmodel.Card
+	result := database.DB.Unscoped().Select("id").Where("id = ? AND user_id = ?", id, userID).First(&card)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, 0, errors.New("卡密不存在或无权限查看")
+		}
+		return nil, 0, errors.New("查询卡密失败: " + result.Error.Error())
+	}
+
+	query := database.DB.Model(&dbmodel.CardEvent{}).Where("card_id = ?", card.ID)
+	if req.Type != "" {
+		query = query.Where("type = ?", req.Type)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("查询卡密事件失败: " + err.Error())
+	}
+
+	var events []dbmodel.CardEvent
+	offset := (req.Page - 1) * req.PageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(req.PageSize).Find(&events).Error; err != nil {
+		return nil, 0, errors.New("查询卡密事件失败: " + err.Error())
+	}
+
+	return events, total, nil
+}
@@ -0,0 +1,204 @@
+package card
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"strings"
+	"time"
+
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/xuri/excelize/v2"
+)
+
+// 导出格式
+const (
+	ExportFormatCSV  = "csv"  // CSV表格
+	ExportFormatTXT  = "txt"  // 纯文本，每行一张卡密
+	ExportFormatXLSX = "xlsx" // Excel表格
+)
+
+// defaultExportTemplate 文本导出的默认行模板
+const defaultExportTemplate = "{card_no}----{card_key}"
+
+// exportHeader 表格导出的表头
+var exportHeader = []string{"卡号", "卡密", "卡密类型", "状态", "激活时间", "过期时间", "创建时间"}
+
+// cardStatusText 卡密状态文本
+var cardStatusText = dbmodel.CardStatusText
+
+// cardExporter 卡密导出器，按行写出卡密
+type cardExporter interface {
+	// Write 写出一张卡密
+	Write(card dbmodel.Card) error
+	// Flush 将已写出的内容刷新到输出
+	Flush() error
+	// Close 完成导出
+	Close() error
+}
+
+// exportContentType 获取导出格式的Content-Type
+func exportContentType(format string) string {
+	switch format {
+	case ExportFormatTXT:
+		return "text/plain; charset=utf-8"
+	case ExportFormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// newCardExporter 创建指定格式的导出器
+func newCardExporter(format, template string, w io.Writer) (cardExporter, error) {
+	switch format {
+	case ExportFormatTXT:
+		if template == "" {
+			template = defaultExportTemplate
+		}
+		return &textExporter{w: bufio.NewWriter(w), template: template}, nil
+	case ExportFormatXLSX:
+		return newXLSXExporter(w)
+	default:
+		return newCSVExporter(w)
+	}
+}
+
+// exportRow 将卡密转换为表格行
+func exportRow(card dbmodel.Card) []string {
+	return []string{
+		card.CardNo,
+		card.CardKey,
+		card.CardType.Name,
+		cardStatusText[card.Status],
+		formatExportTime(card.ActivateAt),
+		formatExportTime(card.ExpireAt),
+		card.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// formatExportTime 格式化可为空的时间
+func formatExportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// csvExporter CSV导出器
+type csvExporter struct {
+	w *csv.Writer
+}
+
+// newCSVExporter 创建CSV导出器，写入UTF-8 BOM以便Excel正确识别中文
+func newCSVExporter(w io.Writer) (*csvExporter, error) {
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return nil, err
+	}
+	e := &csvExporter{w: csv.NewWriter(w)}
+	if err := e.w.Write(exportHeader); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *csvExporter) Write(card dbmodel.Card) error {
+	return e.w.Write(exportRow(card))
+}
+
+func (e *csvExporter) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExporter) Close() error {
+	return e.Flush()
+}
+
+// textExporter 纯文本导出器
+// 行模板支持的占位符：{card_no}、{card_key}、{type}、{status}、{expire_at}
+type textExporter struct {
+	w        *bufio.Writer
+	template string
+}
+
+func (e *textExporter) Write(card dbmodel.Card) error {
+	line := strings.NewReplacer(
+		"{card_no}", card.CardNo,
+		"{card_key}", card.CardKey,
+		"{type}", card.CardType.Name,
+		"{status}", cardStatusText[card.Status],
+		"{expire_at}", formatExportTime(card.ExpireAt),
+	).Replace(e.template)
+	_, err := e.w.WriteString(line + "\r\n")
+	return err
+}
+
+func (e *textExporter) Flush() error {
+	return e.w.Flush()
+}
+
+func (e *textExporter) Close() error {
+	return e.Flush()
+}
+
+// xlsxExporter Excel导出器
+// 使用流式写入生成工作表，数据先写入临时文件，完成后一次性输出
+type xlsxExporter struct {
+	out    io.Writer
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	row    int
+}
+
+// newXLSXExporter 创建Excel导出器
+func newXLSXExporter(w io.Writer) (*xlsxExporter, error) {
+	file := excelize.NewFile()
+	stream, err := file.NewStreamWriter("Sheet1")
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	e := &xlsxExporter{out: w, file: file, stream: stream, row: 1}
+	header := make([]interface{}, len(exportHeader))
+	for i, v := range exportHeader {
+		header[i] = v
+	}
+	if err := e.writeRow(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// writeRow 写出一行
+func (e *xlsxExporter) writeRow(values []interface{}) error {
+	cell, err := excelize.CoordinatesToCellName(1, e.row)
+	if err != nil {
+		return err
+	}
+	e.row++
+	return e.stream.SetRow(cell, values)
+}
+
+func (e *xlsxExporter) Write(card dbmodel.Card) error {
+	row := exportRow(card)
+	values := make([]interface{}, len(row))
+	for i, v := range row {
+		values[i] = v
+	}
+	return e.writeRow(values)
+}
+
+func (e *xlsxExporter) Flush() error {
+	return nil
+}
+
+func (e *xlsxExporter) Close() error {
+	defer e.file.Close()
+	if err := e.stream.Flush(); err != nil {
+		return err
+	}
+	return e.file.Write(e.out)
+}
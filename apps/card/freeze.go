@@ -0,0 +1,99 @@
+package card
+
+import (
+	"errors"
+
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"gorm.io/gorm"
+)
+
+// freezeColumns 冻结和解冻时更新的字段
+var freezeColumns = []string{"status", "expire_at", "is_online", "frozen_at", "frozen_remain", "freeze_source", "freeze_count"}
+
+// getUserCard 获取当前用户的卡密
+func getUserCard(id int, userID int) (*dbmodel.Card, error) {
+	var card dbmodel.Card
+	result := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&card)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("卡密不存在或无权限操作")
+		}
+		return nil, errors.New("查询卡密失败: " + result.Error.Error())
+	}
+	return &card, nil
+}
+
+// saveFrozenState 保存卡密的冻结状态并记录事件，卡密状态已被其他请求修改时返回错误
+func saveFrozenState(card *dbmodel.Card, fromStatus int, event dbmodel.CardEvent) error {
+	return database.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(card).Where("status = ?", fromStatus).Select(freezeColumns).Updates(card)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("卡密状态已变化，请刷新后重试")
+		}
+		return tx.Create(&event).Error
+	})
+}
+
+// FreezeCard 冻结卡密
+// 冻结后客户端无法验证和心跳，剩余有效时长保留到解冻时重新计算过期时间；所有者冻结不受卡密类型的冻结次数限制
+// @param id 卡密ID
+// @param userID 当前用户ID
+// @param ip 操作IP
+// @return 冻结后的卡密和错误信息
+func (s *Service) FreezeCard(id int, userID int, ip string) (*dbmodel.Card, error) {
+	card, err := getUserCard(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch card.Status {
+	case dbmodel.CardStatusActive:
+	case dbmodel.CardStatusFrozen:
+		return nil, errors.New("卡密已冻结")
+	default:
+		return nil, errors.New("只能冻结使用中的卡密")
+	}
+	if card.IsExpired() {
+		return nil, errors.New("卡密已过期，无法冻结")
+	}
+
+	if err := card.Freeze(dbmodel.CardFreezeByOwner); err != nil {
+		return nil, err
+	}
+	event := dbmodel.NewCardEvent(*card, dbmodel.CardEventFreeze, dbmodel.CardActorUser, uint(userID), ip).
+		WithChange(nil, map[string]interface{}{"frozen_remain": card.FrozenRemain})
+	if err := saveFrozenState(card, dbmodel.CardStatusActive, event); err != nil {
+		return nil, errors.New("冻结卡密失败: " + err.Error())
+	}
+	return card, nil
+}
+
+// UnfreezeCard 解冻卡密，过期时间按冻结时的剩余时长从当前时间重新计算
+// 所有者可以解冻任意来源的冻结
+// @param id 卡密ID
+// @param userID 当前用户ID
+// @param ip 操作IP
+// @return 解冻后的卡密和错误信息
+func (s *Service) UnfreezeCard(id int, userID int, ip string) (*dbmodel.Card, error) {
+	card, err := getUserCard(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if card.Status != dbmodel.CardStatusFrozen {
+		return nil, errors.New("卡密未冻结")
+	}
+
+	if err := card.Unfreeze(); err != nil {
+		return nil, err
+	}
+	event := dbmodel.NewCardEvent(*card, dbmodel.CardEventUnfreeze, dbmodel.CardActorUser, uint(userID), ip).
+		WithChange(nil, map[string]interface{}{"expire_at": card.ExpireAt})
+	if err := saveFrozenState(card, dbmodel.CardStatusFrozen, event); err != nil {
+		return nil, errors.New("解冻卡密失败: " + err.Error())
+	}
+	return card, nil
+}
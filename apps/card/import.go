@@ -0,0 +1,271 @@
+package card
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+)
+
+// 导入格式
+const (
+	ImportFormatCSV  = "csv"  // CSV表格，首行为表头
+	ImportFormatJSON = "json" // JSON对象数组
+)
+
+const (
+	importMaxFileSize = 10 << 20 // 导入文件的最大大小
+	importMaxRows     = 50000    // 单次导入的最大卡密数量
+	importChunkSize   = 500      // 每个事务导入的卡密数量
+)
+
+// importColumns 导入字段及其别名，CSV表头兼容导出文件的中文表头
+var importColumns = map[string]string{
+	"card_no":          "card_no",
+	"卡号":               "card_no",
+	"card_key":         "card_key",
+	"卡密":               "card_key",
+	"status":           "status",
+	"状态":               "status",
+	"activate_at":      "activate_at",
+	"激活时间":             "activate_at",
+	"expire_at":        "expire_at",
+	"过期时间":             "expire_at",
+	"device_id":        "device_id",
+	"设备ID":             "device_id",
+	"max_rebind_count": "max_rebind_count",
+	"rebind_count":     "rebind_count",
+	"max_unbind_count": "max_unbind_count",
+	"unbind_count":     "unbind_count",
+}
+
+// importTimeLayouts 导入支持的时间格式
+var importTimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+	"2006-01-02",
+}
+
+// importRecord 导入文件中的一行数据
+type importRecord struct {
+	row    int               // 行号
+	fields map[string]string // 字段值
+}
+
+// parseImportFile 解析导入文件
+func parseImportFile(format string, r io.Reader) ([]importRecord, error) {
+	switch format {
+	case ImportFormatCSV:
+		return parseImportCSV(r)
+	case ImportFormatJSON:
+		return parseImportJSON(r)
+	default:
+		return nil, errors.New("不支持的导入格式")
+	}
+}
+
+// parseImportCSV 解析CSV导入文件，首行为表头，未识别的列会被忽略
+func parseImportCSV(r io.Reader) ([]importRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("导入文件为空")
+		}
+		return nil, errors.New("读取表头失败: " + err.Error())
+	}
+
+	columns := make([]string, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\xEF\xBB\xBF"))
+		columns[i] = importColumns[strings.ToLower(name)]
+	}
+
+	var records []importRecord
+	for line := 2; ; line++ {
+		values, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("第%d行解析失败: %v", line, err)
+		}
+		if len(records) >= importMaxRows {
+			return nil, fmt.Errorf("单次最多导入%d张卡密", importMaxRows)
+		}
+
+		fields := make(map[string]string)
+		for i, value := range values {
+			if i < len(columns) && columns[i] != "" {
+				fields[columns[i]] = strings.TrimSpace(value)
+			}
+		}
+		records = append(records, importRecord{row: line, fields: fields})
+	}
+
+	return records, nil
+}
+
+// parseImportJSON 解析JSON导入文件，内容为对象数组
+func parseImportJSON(r io.Reader) ([]importRecord, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var items []map[string]interface{}
+	if err := decoder.Decode(&items); err != nil {
+		return nil, errors.New("JSON格式错误，应为对象数组: " + err.Error())
+	}
+	if len(items) > importMaxRows {
+		return nil, fmt.Errorf("单次最多导入%d张卡密", importMaxRows)
+	}
+
+	records := make([]importRecord, 0, len(items))
+	for i, item := range items {
+		fields := make(map[string]string)
+		for key, value := range item {
+			column := importColumns[strings.ToLower(key)]
+			if column == "" || value == nil {
+				continue
+			}
+			fields[column] = strings.TrimSpace(fmt.Sprint(value))
+		}
+		records = append(records, importRecord{row: i + 1, fields: fields})
+	}
+
+	return records, nil
+}
+
+// buildImportCard 校验导入数据并转换为卡密，卡密的类型、应用和次数默认值取自卡密类型
+func buildImportCard(record importRecord, cardType dbmodel.CardType, userID int) (dbmodel.Card, error) {
+	fields := record.fields
+	card := dbmodel.Card{
+		CardNo:         fields["card_no"],
+		CardKey:        fields["card_key"],
+		TypeID:         cardType.ID,
+		AppID:          cardType.AppID,
+		UserID:         userID,
+		MaxRebindCount: cardType.DefaultMaxRebindCount,
+		MaxUnbindCount: cardType.DefaultMaxUnbindCount,
+	}
+
+	if card.CardNo == "" || card.CardKey == "" {
+		return card, errors.New("卡号和卡密不能为空")
+	}
+	if len(card.CardNo) > 50 {
+		return card, errors.New("卡号长度不能超过50")
+	}
+	if len(card.CardKey) > 100 {
+		return card, errors.New("卡密长度不能超过100")
+	}
+
+	status, err := parseImportStatus(fields["status"])
+	if err != nil {
+		return card, err
+	}
+	card.Status = status
+
+	if card.ActivateAt, err = parseImportTime(fields["activate_at"]); err != nil {
+		return card, errors.New("激活时间" + err.Error())
+	}
+	if card.ExpireAt, err = parseImportTime(fields["expire_at"]); err != nil {
+		return card, errors.New("过期时间" + err.Error())
+	}
+	if card.ActivateAt != nil && card.ExpireAt != nil && card.ExpireAt.Before(*card.ActivateAt) {
+		return card, errors.New("过期时间不能早于激活时间")
+	}
+
+	if deviceID := fields["device_id"]; deviceID != "" {
+		if len(deviceID) > 100 {
+			return card, errors.New("设备ID长度不能超过100")
+		}
+		card.DeviceID = &deviceID
+	}
+
+	// 状态必须与激活时间、过期时间一致，已禁用的卡密启用后按时间恢复状态
+	if card.Status == dbmodel.CardStatusUnused && (card.ActivateAt != nil || card.DeviceID != nil) {
+		return card, errors.New("未使用的卡密不能包含激活时间或绑定设备")
+	}
+	if (card.Status == dbmodel.CardStatusActive || card.Status == dbmodel.CardStatusExpired) && card.ActivateAt == nil {
+		return card, errors.New("已使用或已过期的卡密必须提供激活时间")
+	}
+	if card.Status == dbmodel.CardStatusExpired && !card.IsExpired() {
+		return card, errors.New("已过期的卡密必须提供早于当前时间的过期时间")
+	}
+	if card.Status == dbmodel.CardStatusActive && card.IsExpired() {
+		return card, errors.New("过期时间早于当前时间，卡密状态应为已过期")
+	}
+	if card.Status == dbmodel.CardStatusDisabled && card.DeviceID != nil && card.ActivateAt == nil {
+		return card, errors.New("绑定设备的卡密必须提供激活时间")
+	}
+
+	counts := []struct {
+		field string
+		name  string
+		value *int
+	}{
+		{"max_rebind_count", "最大换绑次数", &card.MaxRebindCount},
+		{"rebind_count", "已换绑次数", &card.RebindCount},
+		{"max_unbind_count", "最大解绑次数", &card.MaxUnbindCount},
+		{"unbind_count", "已解绑次数", &card.UnbindCount},
+	}
+	for _, count := range counts {
+		value := fields[count.field]
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return card, errors.New(count.name + "必须为非负整数")
+		}
+		*count.value = n
+	}
+
+	return card, nil
+}
+
+// parseImportStatus 解析卡密状态，支持状态码和导出文件中的状态文本
+func parseImportStatus(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	status := -1
+	for code, text := range cardStatusText {
+		if value == text {
+			status = code
+		}
+	}
+	if status < 0 {
+		code, err := strconv.Atoi(value)
+		if err != nil || cardStatusText[code] == "" {
+			return 0, errors.New("无效的卡密状态: " + value)
+		}
+		status = code
+	}
+	// 冻结需要记录剩余时长，导入的卡密不能直接为已冻结
+	if status == dbmodel.CardStatusFrozen {
+		return 0, errors.New("不能导入已冻结的卡密，请先解冻后再导出")
+	}
+	return status, nil
+}
+
+// parseImportTime 解析可为空的时间，不带时区的时间按服务器本地时区处理
+func parseImportTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range importTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, errors.New("格式错误: " + value)
+}
@@ -1,18 +1,25 @@
 package model
 
+import dbmodel "github.com/skyle1995/DevE-Server/database/model"
+
 // 这里保留给卡密管理相关请求
 
 // 用户卡密类型管理相关请求
 
 // CreateCardTypeRequest 创建卡密类型请求
 type CreateCardTypeRequest struct {
-	Name                  string `json:"name" binding:"required"`                     // 卡密类型名称
-	Duration              int    `json:"duration" binding:"required"`                 // 时长
-	TimeUnit              string `json:"time_unit" binding:"required"`                // 时间单位（day, month, year）
-	AppID                 int    `json:"app_id" binding:"required"`                   // 所属应用ID
-	Status                int    `json:"status" binding:"required"`                   // 状态：0-禁用，1-启用
-	DefaultMaxRebindCount int    `json:"default_max_rebind_count" binding:"required"` // 默认最大换绑次数
-	DefaultMaxUnbindCount int    `json:"default_max_unbind_count" binding:"required"` // 默认最大解绑次数
+	Name                  string               `json:"name" binding:"required"`                     // 卡密类型名称
+	Duration              int                  `json:"duration" binding:"required"`                 // 时长
+	TimeUnit              string               `json:"time_unit" binding:"required"`                // 时间单位（day, month, year）
+	AppID                 int                  `json:"app_id" binding:"required"`                   // 所属应用ID
+	Status                int                  `json:"status" binding:"required"`                   // 状态：0-禁用，1-启用
+	DefaultMaxRebindCount int                  `json:"default_max_rebind_count" binding:"required"` // 默认最大换绑次数
+	DefaultMaxUnbindCount int                  `json:"default_max_unbind_count" binding:"required"` // 默认最大解绑次数
+	CardNoTemplateID      *uint                `json:"card_no_template_id"`                         // 卡号模板ID，可选
+	CardKeyTemplateID     *uint                `json:"card_key_template_id"`                        // 卡密模板ID，可选
+	AllowClientFreeze     bool                 `json:"allow_client_freeze"`                         // 是否允许终端用户冻结，可选
+	MaxFreezeCount        int                  `json:"max_freeze_count" binding:"min=0"`            // 终端用户最大冻结次数，可选，0为不限
+	Entitlements          dbmodel.Entitlements `json:"entitlements"`                                // 功能权益，可选，如 {"export":true,"max_threads":8}
 }
 
 // GetCardTypeListRequest 获取卡密类型列表请求
@@ -26,14 +33,19 @@ type GetCardTypeListRequest struct {
 
 // UpdateCardTypeRequest 更新卡密类型请求
 type UpdateCardTypeRequest struct {
-	ID                    int    `json:"id" binding:"required"`    // 卡密类型ID
-	Name                  string `json:"name"`                     // 卡密类型名称
-	Duration              int    `json:"duration"`                 // 时长
-	TimeUnit              string `json:"time_unit"`                // 时间单位（day, month, year）
-	AppID                 int    `json:"app_id"`                   // 所属应用ID
-	Status                int    `json:"status"`                   // 状态：0-禁用，1-启用
-	DefaultMaxRebindCount int    `json:"default_max_rebind_count"` // 默认最大换绑次数
-	DefaultMaxUnbindCount int    `json:"default_max_unbind_count"` // 默认最大解绑次数
+	ID                    int                  `json:"id" binding:"required"`                      // 卡密类型ID
+	Name                  string               `json:"name"`                                       // 卡密类型名称
+	Duration              int                  `json:"duration"`                                   // 时长
+	TimeUnit              string               `json:"time_unit"`                                  // 时间单位（day, month, year）
+	AppID                 int                  `json:"app_id"`                                     // 所属应用ID
+	Status                int                  `json:"status"`                                     // 状态：0-禁用，1-启用
+	DefaultMaxRebindCount int                  `json:"default_max_rebind_count"`                   // 默认最大换绑次数
+	DefaultMaxUnbindCount int                  `json:"default_max_unbind_count"`                   // 默认最大解绑次数
+	CardNoTemplateID      *uint                `json:"card_no_template_id"`                        // 卡号模板ID，可选，为0时取消
+	CardKeyTemplateID     *uint                `json:"card_key_template_id"`                       // 卡密模板ID，可选，为0时取消
+	AllowClientFreeze     *bool                `json:"allow_client_freeze"`                        // 是否允许终端用户冻结，可选
+	MaxFreezeCount        *int                 `json:"max_freeze_count" binding:"omitempty,min=0"` // 终端用户最大冻结次数，可选，0为不限
+	Entitlements          dbmodel.Entitlements `json:"entitlements"`                               // 功能权益，可选，不传则不修改，传 {} 清空
 }
 
 // DeleteCardTypeRequest 删除卡密类型请求
@@ -45,12 +57,17 @@ type DeleteCardTypeRequest struct {
 
 // GenerateCardRequest 生成卡密请求
 type GenerateCardRequest struct {
-	TypeID         int    `json:"type_id" binding:"required"` // 卡密类型ID
-	Count          int    `json:"count" binding:"required"`   // 生成数量
-	Prefix         string `json:"prefix"`                     // 卡号前缀，可选
-	MaxRebindCount *int   `json:"max_rebind_count"`           // 最大换绑次数，可选，不传则使用卡密类型默认值
-	MaxUnbindCount *int   `json:"max_unbind_count"`           // 最大解绑次数，可选，不传则使用卡密类型默认值
-	KeyLength      *int   `json:"key_length"`                 // 卡密长度，可选，不传则使用默认值16
+	TypeID            int    `json:"type_id" binding:"required"`     // 卡密类型ID
+	Count             int    `json:"count" binding:"required,min=1"` // 生成数量
+	Prefix            string `json:"prefix"`                         // 卡号前缀，可选
+	MaxRebindCount    *int   `json:"max_rebind_count"`               // 最大换绑次数，可选，不传则使用卡密类型默认值
+	MaxUnbindCount    *int   `json:"max_unbind_count"`               // 最大解绑次数，可选，不传则使用卡密类型默认值
+	KeyLength         *int   `json:"key_length"`                     // 卡密长度，可选，不传则使用默认值16，使用卡密模板时无效
+	CardNoTemplateID  *uint  `json:"card_no_template_id"`            // 卡号模板ID，可选，不传则使用卡密类型或用户默认模板
+	CardKeyTemplateID *uint  `json:"card_key_template_id"`           // 卡密模板ID，可选，不传则使用卡密类型或用户默认模板
+	BatchName         string `json:"batch_name" binding:"max=100"`   // 批次名称，可选，默认为卡密类型名称加生成时间
+	BatchNote         string `json:"batch_note" binding:"max=255"`   // 批次备注，可选
+	Channel           string `json:"channel" binding:"max=100"`      // 发放渠道，可选
 }
 
 // GetCardListRequest 获取卡密列表请求
@@ -59,7 +76,8 @@ type GetCardListRequest struct {
 	PageSize int    `form:"page_size" json:"page_size" binding:"required"` // 每页数量
 	AppID    int    `form:"app_id" json:"app_id"`                          // 应用ID，可选
 	TypeID   int    `form:"type_id" json:"type_id"`                        // 卡密类型ID，可选
-	Status   int    `form:"status" json:"status"`                          // 状态，可选
+	BatchID  int    `form:"batch_id" json:"batch_id"`                      // 批次ID，可选
+	Status   *int   `form:"status" json:"status"`                          // 状态，可选
 	CardNo   string `form:"card_no" json:"card_no"`                        // 卡号，可选，模糊查询
 }
 
@@ -67,7 +85,7 @@ type GetCardListRequest struct {
 type UpdateCardRequest struct {
 	ID             int  `json:"id" binding:"required"` // 卡密ID
 	TypeID         int  `json:"type_id"`               // 卡密类型ID，可选
-	Status         int  `json:"status"`                // 状态，可选
+	Status         *int `json:"status"`                // 状态，可选，只能禁用（3）或启用已禁用的卡密
 	MaxRebindCount *int `json:"max_rebind_count"`      // 最大换绑次数，可选
 	MaxUnbindCount *int `json:"max_unbind_count"`      // 最大解绑次数，可选
 }
@@ -77,8 +95,91 @@ type DeleteCardRequest struct {
 	ID int `json:"id" binding:"required"` // 卡密ID
 }
 
-// ExportCardRequest 导出卡密请求
+// ExportCardRequest 导出卡密请求，筛选条件与GetCardListRequest一致
 type ExportCardRequest struct {
-	TypeID int  `json:"type_id"` // 卡密类型ID，可选
-	Used   bool `json:"used"`    // 是否已使用，可选
+	AppID    int    `form:"app_id" json:"app_id"`                                        // 应用ID，可选
+	TypeID   int    `form:"type_id" json:"type_id"`                                      // 卡密类型ID，可选
+	BatchID  int    `form:"batch_id" json:"batch_id"`                                    // 批次ID，可选
+	Status   int    `form:"status" json:"status"`                                        // 状态，可选，不传则导出全部状态
+	CardNo   string `form:"card_no" json:"card_no"`                                      // 卡号，可选，模糊查询
+	Format   string `form:"format" json:"format" binding:"omitempty,oneof=csv txt xlsx"` // 导出格式：csv、txt、xlsx，默认csv
+	Template string `form:"template" json:"template"`                                    // 文本格式的行模板，默认 {card_no}----{card_key}
+}
+
+// GetCardExportListRequest 获取卡密导出记录请求
+type GetCardExportListRequest struct {
+	Page     int `form:"page" json:"page"`           // 页码
+	PageSize int `form:"page_size" json:"page_size"` // 每页数量
+}
+
+// ImportCardRequest 导入卡密请求，导入文件通过multipart表单的file字段上传
+type ImportCardRequest struct {
+	TypeID int    `form:"type_id" json:"type_id" binding:"required"`               // 卡密类型ID
+	AppID  int    `form:"app_id" json:"app_id"`                                    // 应用ID，可选，需与卡密类型所属应用一致
+	Format string `form:"format" json:"format" binding:"omitempty,oneof=csv json"` // 文件格式：csv、json，默认按文件扩展名判断
+	DryRun bool   `form:"dry_run" json:"dry_run"`                                  // 仅校验不导入
+}
+
+// BulkCardFilter 批量操作的筛选条件，与GetCardListRequest一致
+type BulkCardFilter struct {
+	AppID   int    `json:"app_id"`   // 应用ID，可选
+	TypeID  int    `json:"type_id"`  // 卡密类型ID，可选
+	BatchID int    `json:"batch_id"` // 批次ID，可选
+	Status  *int   `json:"status"`   // 状态，可选
+	CardNo  string `json:"card_no"`  // 卡号，可选，模糊查询
+}
+
+// BulkCardRequest 批量操作卡密请求，ids与filter二选一
+type BulkCardRequest struct {
+	IDs            []uint          `json:"ids"`                                                                                              // 卡密ID列表
+	Filter         *BulkCardFilter `json:"filter"`                                                                                           // 筛选条件
+	Action         string          `json:"action" binding:"required,oneof=disable enable delete extend reset_binding change_type set_limit"` // 操作类型
+	Duration       int             `json:"duration"`                                                                                         // extend：延长时长
+	TimeUnit       string          `json:"time_unit" binding:"omitempty,oneof=day month year"`                                               // extend：时间单位，默认day
+	TypeID         int             `json:"type_id"`                                                                                          // change_type：目标卡密类型ID
+	MaxRebindCount *int            `json:"max_rebind_count"`                                                                                 // set_limit：最大换绑次数
+	MaxUnbindCount *int            `json:"max_unbind_count"`                                                                                 // set_limit：最大解绑次数
+}
+
+// CardTemplateRequest 创建/更新卡密模板请求
+type CardTemplateRequest struct {
+	Name       string `json:"name" binding:"required,max=50"`                                         // 模板名称
+	Layout     string `json:"layout" binding:"required,max=100"`                                      // 布局，X为随机字符，例如 XXXX-XXXX-XXXX-XXXX
+	Charset    string `json:"charset" binding:"omitempty,oneof=alnum digits letters hex unambiguous"` // 字符集，默认alnum
+	Case       string `json:"case" binding:"omitempty,oneof=upper lower mixed"`                       // 大小写，默认upper
+	Checksum   bool   `json:"checksum"`                                                               // 最后一个随机字符是否为校验位
+	DefaultFor string `json:"default_for" binding:"omitempty,oneof=card_no card_key"`                 // 设为用户默认模板的用途，可选
+}
+
+// PreviewCardTemplateRequest 预览卡密模板请求
+type PreviewCardTemplateRequest struct {
+	Layout   string `json:"layout" binding:"required,max=100"`                                      // 布局
+	Charset  string `json:"charset" binding:"omitempty,oneof=alnum digits letters hex unambiguous"` // 字符集，默认alnum
+	Case     string `json:"case" binding:"omitempty,oneof=upper lower mixed"`                       // 大小写，默认upper
+	Checksum bool   `json:"checksum"`                                                               // 是否开启校验位
+	Count    int    `json:"count" binding:"omitempty,min=1,max=20"`                                 // 示例数量，默认5
+}
+
+// GetCardBatchListRequest 获取卡密批次列表请求
+type GetCardBatchListRequest struct {
+	Page     int    `form:"page" json:"page"`           // 页码
+	PageSize int    `form:"page_size" json:"page_size"` // 每页数量
+	AppID    int    `form:"app_id" json:"app_id"`       // 应用ID，可选
+	TypeID   int    `form:"type_id" json:"type_id"`     // 卡密类型ID，可选
+	Channel  string `form:"channel" json:"channel"`     // 发放渠道，可选
+	Name     string `form:"name" json:"name"`           // 批次名称，可选，模糊查询
+}
+
+// UpdateCardBatchRequest 更新卡密批次请求
+type UpdateCardBatchRequest struct {
+	Name    *string `json:"name" binding:"omitempty,min=1,max=100"` // 批次名称
+	Note    *string `json:"note" binding:"omitempty,max=255"`       // 备注
+	Channel *string `json:"channel" binding:"omitempty,max=100"`    // 发放渠道
+}
+
+// GetCardEventListRequest 获取卡密事件请求
+type GetCardEventListRequest struct {
+	Page     int    `form:"page" json:"page"`           // 页码
+	PageSize int    `form:"page_size" json:"page_size"` // 每页数量
+	Type     string `form:"type" json:"type"`           // 事件类型，可选
 }
@@ -8,19 +8,24 @@ import (
 
 // CardTypeResponse 卡密类型响应
 type CardTypeResponse struct {
-	ID                    uint      `json:"id"`
-	Name                  string    `json:"name"`
-	Description           string    `json:"description"`
-	Duration              int       `json:"duration"`  // 时长
-	TimeUnit              string    `json:"time_unit"` // 时间单位（day, month, year）
-	Price                 float64   `json:"price"`
-	Status                int       `json:"status"` // 1-启用, 0-禁用
-	AppID                 uint      `json:"app_id"`
-	AppName               string    `json:"app_name,omitempty"`
-	DefaultMaxRebindCount int       `json:"default_max_rebind_count"` // 默认最大换绑次数
-	DefaultMaxUnbindCount int       `json:"default_max_unbind_count"` // 默认最大解绑次数
-	CreatedAt             time.Time `json:"created_at"`
-	UpdatedAt             time.Time `json:"updated_at"`
+	ID                    uint                 `json:"id"`
+	Name                  string               `json:"name"`
+	Description           string               `json:"description"`
+	Duration              int                  `json:"duration"`  // 时长
+	TimeUnit              string               `json:"time_unit"` // 时间单位（day, month, year）
+	Price                 float64              `json:"price"`
+	Status                int                  `json:"status"` // 1-启用, 0-禁用
+	AppID                 uint                 `json:"app_id"`
+	AppName               string               `json:"app_name,omitempty"`
+	DefaultMaxRebindCount int                  `json:"default_max_rebind_count"` // 默认最大换绑次数
+	DefaultMaxUnbindCount int                  `json:"default_max_unbind_count"` // 默认最大解绑次数
+	CardNoTemplateID      *uint                `json:"card_no_template_id"`      // 卡号模板ID
+	CardKeyTemplateID     *uint                `json:"card_key_template_id"`     // 卡密模板ID
+	AllowClientFreeze     bool                 `json:"allow_client_freeze"`      // 是否允许终端用户冻结
+	MaxFreezeCount        int                  `json:"max_freeze_count"`         // 终端用户最大冻结次数，0为不限
+	Entitlements          dbmodel.Entitlements `json:"entitlements"`             // 功能权益
+	CreatedAt             time.Time            `json:"created_at"`
+	UpdatedAt             time.Time            `json:"updated_at"`
 }
 
 // CardResponse 卡密响应
@@ -32,7 +37,8 @@ type CardResponse struct {
 	CardType       string     `json:"card_type"`
 	AppID          uint       `json:"app_id"`
 	AppName        string     `json:"app_name,omitempty"`
-	Status         int        `json:"status"` // 0-未使用, 1-已使用, 2-已过期, 3-已禁用
+	BatchID        *uint      `json:"batch_id"` // 所属批次ID
+	Status         int        `json:"status"`   // 0-未使用, 1-已使用, 2-已过期, 3-已禁用, 4-已冻结
 	DeviceID       *string    `json:"device_id,omitempty"`
 	MaxRebindCount int        `json:"max_rebind_count"` // 最大换绑次数
 	RebindCount    int        `json:"rebind_count"`     // 已换绑次数
@@ -40,6 +46,9 @@ type CardResponse struct {
 	UnbindCount    int        `json:"unbind_count"`     // 已解绑次数
 	ActivateAt     *time.Time `json:"activate_at,omitempty"`
 	ExpireAt       *time.Time `json:"expire_at,omitempty"`
+	FrozenAt       *time.Time `json:"frozen_at,omitempty"`     // 冻结时间
+	FrozenRemain   *int64     `json:"frozen_remain,omitempty"` // 冻结时的剩余有效秒数
+	FreezeCount    int        `json:"freeze_count"`            // 终端用户已冻结次数
 	CreatedAt      time.Time  `json:"created_at"`
 }
 
@@ -48,6 +57,7 @@ type CardResponse struct {
 // GenerateCardResponse 生成卡密响应
 type GenerateCardResponse struct {
 	Success bool           `json:"success"`
+	BatchID uint           `json:"batch_id"` // 本次生成的批次ID
 	Count   int            `json:"count"`
 	Cards   []CardResponse `json:"cards"`
 }
@@ -77,6 +87,11 @@ func FromCardType(cardType dbmodel.CardType) CardTypeResponse {
 		AppID:                 cardType.AppID,
 		DefaultMaxRebindCount: cardType.DefaultMaxRebindCount,
 		DefaultMaxUnbindCount: cardType.DefaultMaxUnbindCount,
+		CardNoTemplateID:      cardType.CardNoTemplateID,
+		CardKeyTemplateID:     cardType.CardKeyTemplateID,
+		AllowClientFreeze:     cardType.AllowClientFreeze,
+		MaxFreezeCount:        cardType.MaxFreezeCount,
+		Entitlements:          cardType.Entitlements.OrEmpty(),
 		CreatedAt:             cardType.CreatedAt,
 		UpdatedAt:             cardType.UpdatedAt,
 	}
@@ -97,6 +112,7 @@ func FromCard(card dbmodel.Card, includeKey bool) CardResponse {
 		TypeID:         card.TypeID,
 		CardType:       card.CardType.Name,
 		AppID:          card.AppID,
+		BatchID:        card.BatchID,
 		Status:         card.Status,
 		MaxRebindCount: card.MaxRebindCount,
 		RebindCount:    card.RebindCount,
@@ -104,6 +120,9 @@ func FromCard(card dbmodel.Card, includeKey bool) CardResponse {
 		UnbindCount:    card.UnbindCount,
 		ActivateAt:     card.ActivateAt,
 		ExpireAt:       card.ExpireAt,
+		FrozenAt:       card.FrozenAt,
+		FrozenRemain:   card.FrozenRemain,
+		FreezeCount:    card.FreezeCount,
 		CreatedAt:      card.CreatedAt,
 	}
 
@@ -112,11 +131,8 @@ func FromCard(card dbmodel.Card, includeKey bool) CardResponse {
 		response.CardKey = card.CardKey
 	}
 
-	// 如果有设备信息，添加设备ID
-	if card.DeviceID != nil && card.Device != nil {
-		deviceID := card.Device.DeviceID
-		response.DeviceID = &deviceID
-	}
+	// 如果已绑定设备，添加设备ID
+	response.DeviceID = card.DeviceID
 
 	// 如果有应用信息，添加应用名称
 	if card.App.ID > 0 {
@@ -143,3 +159,198 @@ func FromCardTypes(cardTypes []dbmodel.CardType) []CardTypeResponse {
 	}
 	return responses
 }
+
+// CardExportResponse 卡密导出记录响应
+type CardExportResponse struct {
+	ID        uint      `json:"id"`
+	Format    string    `json:"format"` // 导出格式
+	Filter    string    `json:"filter"` // 导出时的筛选条件（JSON）
+	Count     int       `json:"count"`  // 导出的卡密数量
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CardExportListResponse 卡密导出记录列表响应
+type CardExportListResponse struct {
+	Total int                  `json:"total"`
+	Items []CardExportResponse `json:"items"`
+}
+
+// FromCardExports 将数据库卡密导出记录列表转换为响应模型列表
+func FromCardExports(exports []dbmodel.CardExport) []CardExportResponse {
+	responses := make([]CardExportResponse, len(exports))
+	for i, export := range exports {
+		responses[i] = CardExportResponse{
+			ID:        export.ID,
+			Format:    export.Format,
+			Filter:    export.Filter,
+			Count:     export.Count,
+			IP:        export.IP,
+			UserAgent: export.UserAgent,
+			CreatedAt: export.CreatedAt,
+		}
+	}
+	return responses
+}
+
+// ImportCardError 卡密导入的行错误
+type ImportCardError struct {
+	Row     int    `json:"row"`     // 行号，CSV为文件行号，JSON为数组序号（从1开始）
+	CardNo  string `json:"card_no"` // 卡号
+	Message string `json:"message"` // 错误信息
+}
+
+// ImportCardResponse 卡密导入响应
+type ImportCardResponse struct {
+	DryRun   bool              `json:"dry_run"`  // 是否仅校验
+	Total    int               `json:"total"`    // 文件中的卡密总数
+	Imported int               `json:"imported"` // 导入成功数量，仅校验时为校验通过的数量
+	Failed   int               `json:"failed"`   // 失败数量
+	Errors   []ImportCardError `json:"errors"`   // 失败的行及原因
+}
+
+// BulkCardResponse 批量操作卡密响应
+type BulkCardResponse struct {
+	Action   string `json:"action"`   // 操作类型
+	Preview  bool   `json:"preview"`  // 是否为预览，预览时不执行操作
+	Matched  int64  `json:"matched"`  // 匹配的卡密数量
+	Affected int64  `json:"affected"` // 执行操作（预览时为将要执行操作）的卡密数量
+	Skipped  int64  `json:"skipped"`  // 不适用该操作而跳过的卡密数量
+}
+
+// CardTemplateResponse 卡密模板响应
+type CardTemplateResponse struct {
+	ID         uint      `json:"id"`
+	Name       string    `json:"name"`
+	Layout     string    `json:"layout"`
+	Charset    string    `json:"charset"`
+	Case       string    `json:"case"`
+	Checksum   bool      `json:"checksum"`
+	DefaultFor string    `json:"default_for"`       // 作为用户默认模板的用途
+	Example    string    `json:"example,omitempty"` // 按模板生成的示例
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// CardTemplateListResponse 卡密模板列表响应
+type CardTemplateListResponse struct {
+	Total int                    `json:"total"`
+	Items []CardTemplateResponse `json:"items"`
+}
+
+// PreviewCardTemplateResponse 预览卡密模板响应
+type PreviewCardTemplateResponse struct {
+	Examples []string `json:"examples"` // 按模板生成的示例
+}
+
+// FromCardTemplate 将数据库卡密模板模型转换为响应模型
+func FromCardTemplate(template dbmodel.CardTemplate) CardTemplateResponse {
+	return CardTemplateResponse{
+		ID:         template.ID,
+		Name:       template.Name,
+		Layout:     template.Layout,
+		Charset:    template.Charset,
+		Case:       template.Case,
+		Checksum:   template.Checksum,
+		DefaultFor: template.DefaultFor,
+		CreatedAt:  template.CreatedAt,
+		UpdatedAt:  template.UpdatedAt,
+	}
+}
+
+// CardBatchStats 卡密批次统计
+type CardBatchStats struct {
+	Unused   int64 `json:"unused"`   // 未使用
+	Active   int64 `json:"active"`   // 使用中（已激活且未过期）
+	Expired  int64 `json:"expired"`  // 已过期
+	Disabled int64 `json:"disabled"` // 已禁用
+	Frozen   int64 `json:"frozen"`   // 已冻结
+}
+
+// CardBatchResponse 卡密批次响应
+type CardBatchResponse struct {
+	ID        uint           `json:"id"`
+	AppID     uint           `json:"app_id"`
+	TypeID    uint           `json:"type_id"`
+	CardType  string         `json:"card_type"`
+	Name      string         `json:"name"`
+	Note      string         `json:"note"`
+	Channel   string         `json:"channel"`
+	Count     int            `json:"count"`  // 生成数量
+	Status    int            `json:"status"` // 0-已撤销, 1-正常
+	Stats     CardBatchStats `json:"stats"`  // 批次内卡密统计
+	RevokedAt *time.Time     `json:"revoked_at,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// CardBatchListResponse 卡密批次列表响应
+type CardBatchListResponse struct {
+	Total int                 `json:"total"`
+	Items []CardBatchResponse `json:"items"`
+}
+
+// RevokeCardBatchResponse 撤销卡密批次响应
+type RevokeCardBatchResponse struct {
+	Disabled int64 `json:"disabled"` // 本次禁用的卡密数量
+}
+
+// FromCardBatch 将数据库卡密批次模型转换为响应模型
+func FromCardBatch(batch dbmodel.CardBatch, stats CardBatchStats) CardBatchResponse {
+	return CardBatchResponse{
+		ID:        batch.ID,
+		AppID:     batch.AppID,
+		TypeID:    batch.TypeID,
+		CardType:  batch.CardType.Name,
+		Name:      batch.Name,
+		Note:      batch.Note,
+		Channel:   batch.Channel,
+		Count:     batch.Count,
+		Status:    batch.Status,
+		Stats:     stats,
+		RevokedAt: batch.RevokedAt,
+		CreatedAt: batch.CreatedAt,
+	}
+}
+
+// CardEventResponse 卡密事件响应
+type CardEventResponse struct {
+	ID        uint      `json:"id"`
+	CardID    uint      `json:"card_id"`
+	CardNo    string    `json:"card_no"`
+	Type      string    `json:"type"`       // 事件类型
+	ActorType string    `json:"actor_type"` // 操作者类型：client、user、system
+	ActorID   uint      `json:"actor_id"`   // 操作者ID
+	DeviceID  string    `json:"device_id"`  // 设备ID
+	OldValue  string    `json:"old_value"`  // 变更前的值（JSON格式）
+	NewValue  string    `json:"new_value"`  // 变更后的值（JSON格式）
+	Message   string    `json:"message"`    // 事件说明
+	IP        string    `json:"ip"`         // 请求IP
+	Location  string    `json:"location"`   // IP归属地
+	CreatedAt time.Time `json:"created_at"` // 发生时间
+}
+
+// CardEventListResponse 卡密事件列表响应
+type CardEventListResponse struct {
+	Total int                 `json:"total"`
+	Items []CardEventResponse `json:"items"`
+}
+
+// FromCardEvent 将数据库卡密事件模型转换为响应模型
+func FromCardEvent(event dbmodel.CardEvent) CardEventResponse {
+	return CardEventResponse{
+		ID:        event.ID,
+		CardID:    event.CardID,
+		CardNo:    event.CardNo,
+		Type:      event.Type,
+		ActorType: event.ActorType,
+		ActorID:   event.ActorID,
+		DeviceID:  event.DeviceID,
+		OldValue:  event.OldValue,
+		NewValue:  event.NewValue,
+		Message:   event.Message,
+		IP:        event.IP,
+		Location:  event.Location,
+		CreatedAt: event.CreatedAt,
+	}
+}
@@ -1,7 +1,14 @@
 package card
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
 	"github.com/skyle1995/DevE-Server/middleware"
 )
 
@@ -13,16 +20,88 @@ func SetupCardRoutes(r *gin.Engine) {
 	cardGroup := r.Group("/api/v1/card")
 	cardGroup.Use(middleware.JWTAuthMiddleware())
 	{
+		view := middleware.PermissionMiddleware(dbmodel.PermissionCardView)
+		generate := middleware.PermissionMiddleware(dbmodel.PermissionCardGenerate)
+		manage := middleware.PermissionMiddleware(dbmodel.PermissionCardManage)
+		export := middleware.PermissionMiddleware(dbmodel.PermissionCardExport)
+
+		// 限定应用的访问令牌只能调用以下检查了应用范围的接口
+		queryApp := middleware.AppScopeMiddleware(middleware.AppIDFromQuery("app_id"))
+		bodyTypeApp := middleware.AppScopeMiddleware(cardTypeAppFromBody)
+		formTypeApp := middleware.AppScopeMiddleware(cardTypeAppFromForm)
+
 		// 卡密类型管理
-		cardGroup.GET("/types", cardController.GetCardTypes)          // 获取卡密类型列表
-		cardGroup.POST("/types", cardController.CreateCardType)       // 创建卡密类型
-		cardGroup.PUT("/types/:id", cardController.UpdateCardType)    // 更新卡密类型
-		cardGroup.DELETE("/types/:id", cardController.DeleteCardType) // 删除卡密类型
+		cardGroup.GET("/types", queryApp, view, cardController.GetCardTypes)  // 获取卡密类型列表
+		cardGroup.POST("/types", manage, cardController.CreateCardType)       // 创建卡密类型
+		cardGroup.PUT("/types/:id", manage, cardController.UpdateCardType)    // 更新卡密类型
+		cardGroup.DELETE("/types/:id", manage, cardController.DeleteCardType) // 删除卡密类型
+
+		// 卡号/卡密生成模板
+		cardGroup.GET("/templates", view, cardController.GetCardTemplates)             // 获取模板列表
+		cardGroup.POST("/templates", manage, cardController.CreateCardTemplate)        // 创建模板
+		cardGroup.POST("/templates/preview", view, cardController.PreviewCardTemplate) // 预览模板
+		cardGroup.PUT("/templates/:id", manage, cardController.UpdateCardTemplate)     // 更新模板
+		cardGroup.DELETE("/templates/:id", manage, cardController.DeleteCardTemplate)  // 删除模板
 
 		// 卡密管理
-		cardGroup.GET("/cards", cardController.GetCards)          // 获取卡密列表
-		cardGroup.POST("/generate", cardController.GenerateCards) // 生成卡密
-		cardGroup.PUT("/cards/:id", cardController.UpdateCard)    // 更新卡密
-		cardGroup.DELETE("/cards/:id", cardController.DeleteCard) // 删除卡密
+		cardGroup.GET("/cards", queryApp, view, cardController.GetCards)                 // 获取卡密列表
+		cardGroup.POST("/generate", bodyTypeApp, generate, cardController.GenerateCards) // 生成卡密
+		cardGroup.PUT("/cards/:id", manage, cardController.UpdateCard)                   // 更新卡密
+		cardGroup.DELETE("/cards/:id", manage, cardController.DeleteCard)                // 删除卡密
+		cardGroup.GET("/cards/:id/events", view, cardController.GetCardEvents)           // 获取卡密事件时间线
+
+		// 卡密冻结
+		cardGroup.POST("/cards/:id/freeze", manage, cardController.FreezeCard)     // 冻结卡密
+		cardGroup.POST("/cards/:id/unfreeze", manage, cardController.UnfreezeCard) // 解冻卡密
+
+		// 卡密批量操作
+		cardGroup.POST("/cards/bulk/preview", manage, cardController.PreviewBulkCards)                       // 预览批量操作
+		cardGroup.POST("/cards/bulk", manage, middleware.OperationLogMiddleware(), cardController.BulkCards) // 批量操作卡密
+
+		// 卡密导出
+		cardGroup.GET("/cards/export", queryApp, export, cardController.ExportCards) // 导出卡密
+		cardGroup.GET("/exports", export, cardController.GetCardExports)             // 获取导出记录
+
+		// 卡密批次
+		cardGroup.GET("/batches", view, cardController.GetCardBatches)                                                     // 获取批次列表
+		cardGroup.GET("/batches/:id", view, cardController.GetCardBatch)                                                   // 获取批次详情
+		cardGroup.PUT("/batches/:id", manage, cardController.UpdateCardBatch)                                              // 更新批次
+		cardGroup.POST("/batches/:id/revoke", manage, middleware.OperationLogMiddleware(), cardController.RevokeCardBatch) // 撤销批次
+		cardGroup.GET("/batches/:id/export", export, cardController.ExportCardBatch)                                       // 导出批次
+
+		// 卡密导入
+		cardGroup.POST("/cards/import", formTypeApp, generate, cardController.ImportCards) // 导入卡密
+	}
+}
+
+// cardTypeApp 返回当前用户的卡密类型所属的应用ID，卡密类型不存在时返回0
+func cardTypeApp(ctx *gin.Context, typeID uint64) uint {
+	var cardType dbmodel.CardType
+	if typeID == 0 || database.DB.Select("app_id").Where("id = ? AND user_id = ?", typeID, ctx.GetUint("user_id")).First(&cardType).Error != nil {
+		return 0
+	}
+	return cardType.AppID
+}
+
+// cardTypeAppFromBody 根据JSON请求体中的卡密类型ID返回应用ID，读取后重置请求体
+func cardTypeAppFromBody(ctx *gin.Context) uint {
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return 0
 	}
+	ctx.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+
+	var req struct {
+		TypeID uint64 `json:"type_id"`
+	}
+	if json.Unmarshal(body, &req) != nil {
+		return 0
+	}
+	return cardTypeApp(ctx, req.TypeID)
+}
+
+// cardTypeAppFromForm 根据表单中的卡密类型ID返回应用ID
+func cardTypeAppFromForm(ctx *gin.Context) uint {
+	typeID, _ := strconv.ParseUint(ctx.PostForm("type_id"), 10, 32)
+	return cardTypeApp(ctx, typeID)
 }
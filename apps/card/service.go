@@ -1,8 +1,15 @@
 package card
 
 import (
+	"encoding/json"
 	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/skyle1995/DevE-Server/apps/card/model"
 	"github.com/skyle1995/DevE-Server/database"
 	dbmodel "github.com/skyle1995/DevE-Server/database/model"
@@ -19,6 +26,7 @@ func NewService() *Service {
 }
 
 // GenerateCards 生成卡密
+// 每次生成创建一个批次；卡号和卡密按模板生成，与已有卡密或本批次重复时重新生成
 // @param req 生成卡密请求
 // @param userID 当前用户ID
 // @return 生成的卡密列表和错误信息
@@ -33,35 +41,66 @@ func (s *Service) GenerateCards(req model.GenerateCardRequest, userID int) ([]db
 		return nil, errors.New("查询卡密类型失败: " + result.Error.Error())
 	}
 
-	// 生成卡密
-	cards := make([]dbmodel.Card, 0, req.Count)
-	for i := 0; i < req.Count; i++ {
-		// 生成卡号
+	// 确定卡号和卡密的生成方式，请求指定的模板优先于卡密类型的模板
+	noTemplateID := cardType.CardNoTemplateID
+	if req.CardNoTemplateID != nil {
+		noTemplateID = req.CardNoTemplateID
+	}
+	noFormat, err := s.resolveCardFormat(noTemplateID, userID, dbmodel.CardTemplateForCardNo)
+	if err != nil {
+		return nil, err
+	}
+	keyTemplateID := cardType.CardKeyTemplateID
+	if req.CardKeyTemplateID != nil {
+		keyTemplateID = req.CardKeyTemplateID
+	}
+	keyFormat, err := s.resolveCardFormat(keyTemplateID, userID, dbmodel.CardTemplateForCardKey)
+	if err != nil {
+		return nil, err
+	}
+
+	generateNo := func() string {
 		cardNo := ""
 		if req.Prefix != "" {
 			cardNo = req.Prefix + "-"
 		}
-		cardNo += random.GenerateRandomString(10)
-
-		// 确定卡密长度，默认为16，最低限制为8
-		keyLength := 16
-		if req.KeyLength != nil && *req.KeyLength >= 8 {
-			keyLength = *req.KeyLength
-		} else if req.KeyLength != nil && *req.KeyLength < 8 {
-			// 如果指定的长度小于8，则使用最低限制8
-			keyLength = 8
+		if noFormat != nil {
+			return cardNo + noFormat.Generate()
 		}
+		return cardNo + random.GenerateRandomString(10)
+	}
+	if len(generateNo()) > 50 {
+		return nil, errors.New("卡号长度超过50，请缩短前缀或模板布局")
+	}
 
-		// 生成卡密
-		cardKey := random.GenerateRandomString(keyLength)
+	// 确定卡密长度，默认为16，最低限制为8；使用模板时由模板布局决定
+	keyLength := 16
+	if req.KeyLength != nil && *req.KeyLength >= 8 {
+		keyLength = *req.KeyLength
+	} else if req.KeyLength != nil && *req.KeyLength < 8 {
+		// 如果指定的长度小于8，则使用最低限制8
+		keyLength = 8
+	}
+	generateKey := func() string {
+		if keyFormat != nil {
+			return keyFormat.Generate()
+		}
+		return random.GenerateRandomString(keyLength)
+	}
+	if len(generateKey()) > 100 {
+		return nil, errors.New("卡密长度不能超过100")
+	}
 
+	// 生成卡密
+	cards := make([]dbmodel.Card, 0, req.Count)
+	for i := 0; i < req.Count; i++ {
 		// 创建卡密
 		card := dbmodel.Card{
-			CardNo:  cardNo,
-			CardKey: cardKey,
+			CardNo:  generateNo(),
+			CardKey: generateKey(),
 			TypeID:  uint(req.TypeID),
 			AppID:   cardType.AppID,
-			Status:  0, // 0-未使用
+			Status:  dbmodel.CardStatusUnused,
 			UserID:  userID,
 		}
 
@@ -82,15 +121,113 @@ func (s *Service) GenerateCards(req model.GenerateCardRequest, userID int) ([]db
 		cards = append(cards, card)
 	}
 
-	// 批量保存卡密
-	result = database.DB.Create(&cards)
-	if result.Error != nil {
-		return nil, errors.New("保存卡密失败: " + result.Error.Error())
+	// 创建批次并批量保存卡密
+	batch := dbmodel.CardBatch{
+		UserID:  userID,
+		AppID:   cardType.AppID,
+		TypeID:  cardType.ID,
+		Name:    req.BatchName,
+		Note:    req.BatchNote,
+		Channel: req.Channel,
+		Count:   len(cards),
+		Status:  dbmodel.CardBatchStatusNormal,
+	}
+	if batch.Name == "" {
+		batch.Name = cardType.Name + " " + time.Now().Format("2006-01-02 15:04:05")
+	}
+
+	// 重复的卡号或卡密重新生成，模板可用的组合过少时多次重试仍可能重复
+	// 检查后其他请求可能已写入相同的卡号或卡密，保存时违反唯一索引同样重新检查并生成
+	for retry := 0; ; retry++ {
+		conflicts, err := findCardConflicts(cards)
+		if err != nil {
+			return nil, errors.New("检查卡密重复失败: " + err.Error())
+		}
+		if len(conflicts) > 0 {
+			if retry >= generateMaxRetries {
+				return nil, errors.New("生成的卡号或卡密重复过多，请调整模板或减少生成数量")
+			}
+			for _, i := range conflicts {
+				cards[i].CardNo = generateNo()
+				cards[i].CardKey = generateKey()
+			}
+			continue
+		}
+
+		// 事务回滚后清除上次保存时写入的主键
+		batch.ID = 0
+		for i := range cards {
+			cards[i].ID = 0
+		}
+		err = database.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&batch).Error; err != nil {
+				return err
+			}
+			for i := range cards {
+				cards[i].BatchID = &batch.ID
+			}
+			return tx.CreateInBatches(&cards, 500).Error
+		})
+		if err == nil {
+			break
+		}
+		if !database.IsDuplicateKeyError(err) || retry >= generateMaxRetries {
+			return nil, errors.New("保存卡密失败: " + err.Error())
+		}
 	}
 
 	return cards, nil
 }
 
+// findCardConflicts 查找卡号或卡密与本批次其他卡密或已有卡密重复的卡密，返回其下标
+func findCardConflicts(cards []dbmodel.Card) ([]int, error) {
+	seenNos := make(map[string]bool, len(cards))
+	seenKeys := make(map[string]bool, len(cards))
+	duplicated := make(map[int]bool)
+	for i, card := range cards {
+		if seenNos[card.CardNo] || seenKeys[card.CardKey] {
+			duplicated[i] = true
+			continue
+		}
+		seenNos[card.CardNo] = true
+		seenKeys[card.CardKey] = true
+	}
+
+	// 唯一索引包含已删除的卡密，因此不排除软删除记录
+	existingNos := make(map[string]bool)
+	existingKeys := make(map[string]bool)
+	for start := 0; start < len(cards); start += 500 {
+		end := start + 500
+		if end > len(cards) {
+			end = len(cards)
+		}
+		cardNos := make([]string, 0, end-start)
+		cardKeys := make([]string, 0, end-start)
+		for _, card := range cards[start:end] {
+			cardNos = append(cardNos, card.CardNo)
+			cardKeys = append(cardKeys, card.CardKey)
+		}
+
+		var existing []dbmodel.Card
+		if err := database.DB.Unscoped().Select("card_no", "card_key").
+			Where("card_no IN ? OR card_key IN ?", cardNos, cardKeys).Find(&existing).Error; err != nil {
+			return nil, err
+		}
+		for _, card := range existing {
+			existingNos[card.CardNo] = true
+			existingKeys[card.CardKey] = true
+		}
+	}
+
+	var conflicts []int
+	for i, card := range cards {
+		if duplicated[i] || existingNos[card.CardNo] || existingKeys[card.CardKey] {
+			conflicts = append(conflicts, i)
+		}
+	}
+	return conflicts, nil
+}
+
 // GetCardList 获取卡密列表
 // @param req 获取卡密列表请求
 // @param userID 当前用户ID
@@ -100,21 +237,17 @@ func (s *Service) GetCardList(req model.GetCardListRequest, userID int) ([]dbmod
 	query := database.DB.Model(&dbmodel.Card{}).Where("user_id = ?", userID)
 
 	// 应用筛选条件
-	if req.AppID > 0 {
-		query = query.Where("app_id = ?", req.AppID)
-	}
-
-	if req.TypeID > 0 {
-		query = query.Where("type_id = ?", req.TypeID)
-	}
-
-	if req.Status >= 0 {
-		query = query.Where("status = ?", req.Status)
-	}
-
-	if req.CardNo != "" {
-		query = query.Where("card_no LIKE ?", "%"+req.CardNo+"%")
-	}
+	status := -1
+	if req.Status != nil {
+		status = *req.Status
+	}
+	query = filterCards(query, cardFilter{
+		AppID:   req.AppID,
+		TypeID:  req.TypeID,
+		BatchID: req.BatchID,
+		Status:  status,
+		CardNo:  req.CardNo,
+	})
 
 	// 获取总数
 	var total int64
@@ -140,11 +273,333 @@ func (s *Service) GetCardList(req model.GetCardListRequest, userID int) ([]dbmod
 	return cards, total, nil
 }
 
+// cardFilter 卡密列表的筛选条件
+type cardFilter struct {
+	AppID   int    // 应用ID，大于0时筛选
+	TypeID  int    // 卡密类型ID，大于0时筛选
+	BatchID int    // 批次ID，大于0时筛选
+	Status  int    // 状态，小于0时不筛选
+	CardNo  string // 卡号，模糊查询
+}
+
+// filterCards 应用卡密列表的筛选条件
+func filterCards(query *gorm.DB, filter cardFilter) *gorm.DB {
+	if filter.AppID > 0 {
+		query = query.Where("app_id = ?", filter.AppID)
+	}
+
+	if filter.TypeID > 0 {
+		query = query.Where("type_id = ?", filter.TypeID)
+	}
+
+	if filter.BatchID > 0 {
+		query = query.Where("batch_id = ?", filter.BatchID)
+	}
+
+	if filter.Status >= 0 {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	if filter.CardNo != "" {
+		query = query.Where("card_no LIKE ?", "%"+filter.CardNo+"%")
+	}
+
+	return query
+}
+
+// ExportCards 按筛选条件流式导出卡密，并记录导出操作
+// @param req 导出卡密请求
+// @param userID 当前用户ID
+// @param ip 操作IP
+// @param userAgent 用户代理
+// @param w 导出内容的输出
+// @return 导出数量和错误信息
+func (s *Service) ExportCards(req model.ExportCardRequest, userID uint, ip, userAgent string, w io.Writer) (int, error) {
+	exporter, err := newCardExporter(req.Format, req.Template, w)
+	if err != nil {
+		return 0, errors.New("创建导出文件失败: " + err.Error())
+	}
+
+	query := database.DB.Model(&dbmodel.Card{}).Preload("CardType").Where("user_id = ?", userID)
+	query = filterCards(query, cardFilter{
+		AppID:   req.AppID,
+		TypeID:  req.TypeID,
+		BatchID: req.BatchID,
+		Status:  req.Status,
+		CardNo:  req.CardNo,
+	})
+
+	// 分批查询并写出，避免一次性加载全部卡密
+	var (
+		cards   []dbmodel.Card
+		cardIDs []uint
+	)
+	result := query.Order("id ASC").FindInBatches(&cards, 500, func(tx *gorm.DB, batch int) error {
+		for _, card := range cards {
+			if err := exporter.Write(card); err != nil {
+				return err
+			}
+			cardIDs = append(cardIDs, card.ID)
+		}
+		if err := exporter.Flush(); err != nil {
+			return err
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if result.Error == nil {
+		result.Error = exporter.Close()
+	}
+
+	// 无论导出是否完整都记录已写出的卡密，导出内容包含卡密密钥
+	if err := s.recordExport(req, userID, ip, userAgent, cardIDs); err != nil {
+		log.Errorf("记录卡密导出失败: %v", err)
+	}
+
+	if result.Error != nil {
+		return len(cardIDs), errors.New("导出卡密失败: " + result.Error.Error())
+	}
+	return len(cardIDs), nil
+}
+
+// recordExport 记录卡密导出操作和导出的卡密
+func (s *Service) recordExport(req model.ExportCardRequest, userID uint, ip, userAgent string, cardIDs []uint) error {
+	filter, _ := json.Marshal(req)
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	return database.Transaction(func(tx *gorm.DB) error {
+		export := dbmodel.CardExport{
+			UserID:    userID,
+			Format:    req.Format,
+			Filter:    string(filter),
+			Count:     len(cardIDs),
+			IP:        ip,
+			UserAgent: userAgent,
+		}
+		if err := tx.Create(&export).Error; err != nil {
+			return err
+		}
+
+		if len(cardIDs) == 0 {
+			return nil
+		}
+
+		items := make([]dbmodel.CardExportItem, 0, len(cardIDs))
+		for _, id := range cardIDs {
+			items = append(items, dbmodel.CardExportItem{ExportID: export.ID, CardID: id})
+		}
+		return tx.CreateInBatches(items, 500).Error
+	})
+}
+
+// ImportCards 从文件导入卡密
+// 逐行校验后按批次在事务中导入，单个批次失败不影响其他批次；仅校验时不写入数据库
+// @param req 导入卡密请求
+// @param file 导入文件内容
+// @param userID 当前用户ID
+// @return 导入结果和错误信息
+func (s *Service) ImportCards(req model.ImportCardRequest, file io.Reader, userID int) (*model.ImportCardResponse, error) {
+	// 查询卡密类型
+	var cardType dbmodel.CardType
+	result := database.DB.Where("id = ? AND user_id = ?", req.TypeID, userID).First(&cardType)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("卡密类型不存在或无权限使用")
+		}
+		return nil, errors.New("查询卡密类型失败: " + result.Error.Error())
+	}
+	if req.AppID > 0 && uint(req.AppID) != cardType.AppID {
+		return nil, errors.New("卡密类型不属于该应用")
+	}
+
+	records, err := parseImportFile(req.Format, file)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("导入文件没有卡密数据")
+	}
+
+	res := &model.ImportCardResponse{
+		DryRun: req.DryRun,
+		Total:  len(records),
+		Errors: []model.ImportCardError{},
+	}
+	fail := func(row int, cardNo, message string) {
+		res.Errors = append(res.Errors, model.ImportCardError{Row: row, CardNo: cardNo, Message: message})
+	}
+
+	// 逐行校验，并检查文件内重复的卡号和卡密
+	var (
+		cards    []dbmodel.Card
+		rows     []int
+		cardNos  = make(map[string]int)
+		cardKeys = make(map[string]int)
+	)
+	for _, record := range records {
+		card, err := buildImportCard(record, cardType, userID)
+		if err != nil {
+			fail(record.row, card.CardNo, err.Error())
+			continue
+		}
+		if row, ok := cardNos[card.CardNo]; ok {
+			fail(record.row, card.CardNo, "卡号与第"+strconv.Itoa(row)+"行重复")
+			continue
+		}
+		if row, ok := cardKeys[card.CardKey]; ok {
+			fail(record.row, card.CardNo, "卡密与第"+strconv.Itoa(row)+"行重复")
+			continue
+		}
+		cardNos[card.CardNo] = record.row
+		cardKeys[card.CardKey] = record.row
+		cards = append(cards, card)
+		rows = append(rows, record.row)
+	}
+
+	// 分批检查数据库冲突并导入，避免大文件长时间锁表
+	for start := 0; start < len(cards); start += importChunkSize {
+		end := start + importChunkSize
+		if end > len(cards) {
+			end = len(cards)
+		}
+		res.Imported += s.importChunk(cards[start:end], rows[start:end], cardType.AppID, req.DryRun, fail)
+	}
+
+	res.Failed = len(res.Errors)
+	sort.Slice(res.Errors, func(i, j int) bool {
+		return res.Errors[i].Row < res.Errors[j].Row
+	})
+	return res, nil
+}
+
+// importChunk 导入一批卡密，返回成功数量
+// 与已有卡密冲突的行记为失败，其余卡密及其绑定设备在同一事务中写入
+func (s *Service) importChunk(cards []dbmodel.Card, rows []int, appID uint, dryRun bool, fail func(row int, cardNo, message string)) int {
+	cardNos := make([]string, 0, len(cards))
+	cardKeys := make([]string, 0, len(cards))
+	deviceIDs := make([]string, 0)
+	for _, card := range cards {
+		cardNos = append(cardNos, card.CardNo)
+		cardKeys = append(cardKeys, card.CardKey)
+		if card.DeviceID != nil {
+			deviceIDs = append(deviceIDs, *card.DeviceID)
+		}
+	}
+
+	// 唯一索引包含已删除的卡密，因此不排除软删除记录
+	var existing []dbmodel.Card
+	if err := database.DB.Unscoped().Select("card_no", "card_key").
+		Where("card_no IN ? OR card_key IN ?", cardNos, cardKeys).Find(&existing).Error; err != nil {
+		for i, card := range cards {
+			fail(rows[i], card.CardNo, "检查卡密冲突失败: "+err.Error())
+		}
+		return 0
+	}
+	existingNos := make(map[string]bool, len(existing))
+	existingKeys := make(map[string]bool, len(existing))
+	for _, card := range existing {
+		existingNos[card.CardNo] = true
+		existingKeys[card.CardKey] = true
+	}
+
+	// 设备按（应用ID, 设备ID）唯一，只需登记本应用中尚不存在的设备
+	devices := make(map[string]dbmodel.Device)
+	if len(deviceIDs) > 0 {
+		var list []dbmodel.Device
+		if err := database.DB.Unscoped().Where("app_id = ? AND device_id IN ?", appID, deviceIDs).Find(&list).Error; err != nil {
+			for i, card := range cards {
+				fail(rows[i], card.CardNo, "检查设备失败: "+err.Error())
+			}
+			return 0
+		}
+		for _, device := range list {
+			devices[device.DeviceID] = device
+		}
+	}
+
+	valid := make([]dbmodel.Card, 0, len(cards))
+	validRows := make([]int, 0, len(cards))
+	for i, card := range cards {
+		switch {
+		case existingNos[card.CardNo]:
+			fail(rows[i], card.CardNo, "卡号已存在")
+		case existingKeys[card.CardKey]:
+			fail(rows[i], card.CardNo, "卡密已存在")
+		default:
+			valid = append(valid, card)
+			validRows = append(validRows, rows[i])
+		}
+	}
+
+	if dryRun || len(valid) == 0 {
+		return len(valid)
+	}
+
+	err := database.Transaction(func(tx *gorm.DB) error {
+		for _, card := range valid {
+			if card.DeviceID == nil {
+				continue
+			}
+			if _, ok := devices[*card.DeviceID]; ok {
+				continue
+			}
+			device := dbmodel.Device{
+				DeviceID:   *card.DeviceID,
+				LastActive: time.Now(),
+				Status:     1,
+				AppID:      appID,
+			}
+			if card.ActivateAt != nil {
+				device.LastActive = *card.ActivateAt
+			}
+			if err := tx.Create(&device).Error; err != nil {
+				return errors.New("创建设备记录失败: " + err.Error())
+			}
+			devices[device.DeviceID] = device
+		}
+		return tx.Create(&valid).Error
+	})
+	if err != nil {
+		for i, card := range valid {
+			fail(validRows[i], card.CardNo, "导入失败: "+err.Error())
+		}
+		return 0
+	}
+
+	return len(valid)
+}
+
+// GetCardExportList 获取卡密导出记录
+// @param req 获取卡密导出记录请求
+// @param userID 当前用户ID
+// @return 导出记录列表、总数和错误信息
+func (s *Service) GetCardExportList(req model.GetCardExportListRequest, userID uint) ([]dbmodel.CardExport, int64, error) {
+	query := database.DB.Model(&dbmodel.CardExport{}).Where("user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("获取导出记录总数失败: " + err.Error())
+	}
+
+	var exports []dbmodel.CardExport
+	offset := (req.Page - 1) * req.PageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(req.PageSize).Find(&exports).Error; err != nil {
+		return nil, 0, errors.New("获取导出记录失败: " + err.Error())
+	}
+
+	return exports, total, nil
+}
+
 // UpdateCard 更新卡密
 // @param req 更新卡密请求
 // @param userID 当前用户ID
+// @param ip 操作IP
 // @return 更新后的卡密和错误信息
-func (s *Service) UpdateCard(req model.UpdateCardRequest, userID int) (*dbmodel.Card, error) {
+func (s *Service) UpdateCard(req model.UpdateCardRequest, userID int, ip string) (*dbmodel.Card, error) {
 	// 查询卡密
 	var card dbmodel.Card
 	result := database.DB.Where("id = ? AND user_id = ?", req.ID, userID).First(&card)
@@ -156,6 +611,7 @@ func (s *Service) UpdateCard(req model.UpdateCardRequest, userID int) (*dbmodel.
 	}
 
 	// 更新卡密信息
+	old := card
 	if req.TypeID > 0 {
 		// 查询卡密类型
 		var cardType dbmodel.CardType
@@ -171,8 +627,10 @@ func (s *Service) UpdateCard(req model.UpdateCardRequest, userID int) (*dbmodel.
 		card.AppID = cardType.AppID
 	}
 
-	if req.Status >= 0 {
-		card.Status = req.Status
+	if req.Status != nil && *req.Status != card.Status {
+		if err := changeCardStatus(&card, *req.Status); err != nil {
+			return nil, err
+		}
 	}
 
 	if req.MaxRebindCount != nil {
@@ -183,20 +641,67 @@ func (s *Service) UpdateCard(req model.UpdateCardRequest, userID int) (*dbmodel.
 		card.MaxUnbindCount = *req.MaxUnbindCount
 	}
 
-	// 保存卡密
-	result = database.DB.Save(&card)
-	if result.Error != nil {
-		return nil, errors.New("更新卡密失败: " + result.Error.Error())
+	// 按版本保存卡密并记录修改前后的值，避免覆盖客户端同时进行的激活、换绑或解绑
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := card.SaveWithVersion(tx, old.Status); err != nil {
+			return err
+		}
+		oldValue, newValue := cardChanges(old, card)
+		if len(newValue) == 0 {
+			return nil
+		}
+		event := dbmodel.NewCardEvent(card, dbmodel.CardEventEdit, dbmodel.CardActorUser, uint(userID), ip).
+			WithChange(oldValue, newValue)
+		return tx.Create(&event).Error
+	})
+	if err != nil {
+		return nil, errors.New("更新卡密失败: " + err.Error())
 	}
 
 	return &card, nil
 }
 
+// changeCardStatus 修改卡密状态，只能禁用卡密或启用已禁用的卡密
+// 启用时恢复为禁用前的状态，激活、冻结和续期需通过对应的操作完成
+func changeCardStatus(card *dbmodel.Card, status int) error {
+	if status != dbmodel.CardStatusDisabled {
+		if card.Status != dbmodel.CardStatusDisabled {
+			return errors.New("只能将卡密修改为已禁用，激活、冻结和续期请使用对应的操作")
+		}
+		status = card.EnabledStatus()
+	}
+	return card.Transit(status)
+}
+
+// cardChanges 比较卡密可修改的字段，返回修改前后的值
+func cardChanges(old, card dbmodel.Card) (map[string]interface{}, map[string]interface{}) {
+	oldValue := make(map[string]interface{})
+	newValue := make(map[string]interface{})
+	fields := []struct {
+		name     string
+		old, new int
+	}{
+		{"type_id", int(old.TypeID), int(card.TypeID)},
+		{"app_id", int(old.AppID), int(card.AppID)},
+		{"status", old.Status, card.Status},
+		{"max_rebind_count", old.MaxRebindCount, card.MaxRebindCount},
+		{"max_unbind_count", old.MaxUnbindCount, card.MaxUnbindCount},
+	}
+	for _, field := range fields {
+		if field.old != field.new {
+			oldValue[field.name] = field.old
+			newValue[field.name] = field.new
+		}
+	}
+	return oldValue, newValue
+}
+
 // DeleteCard 删除卡密
 // @param req 删除卡密请求
 // @param userID 当前用户ID
+// @param ip 操作IP
 // @return 错误信息
-func (s *Service) DeleteCard(req model.DeleteCardRequest, userID int) error {
+func (s *Service) DeleteCard(req model.DeleteCardRequest, userID int, ip string) error {
 	// 查询卡密
 	var card dbmodel.Card
 	result := database.DB.Where("id = ? AND user_id = ?", req.ID, userID).First(&card)
@@ -207,10 +712,16 @@ func (s *Service) DeleteCard(req model.DeleteCardRequest, userID int) error {
 		return errors.New("查询卡密失败: " + result.Error.Error())
 	}
 
-	// 删除卡密
-	result = database.DB.Delete(&card)
-	if result.Error != nil {
-		return errors.New("删除卡密失败: " + result.Error.Error())
+	// 删除卡密，卡密为软删除，删除后仍可查询事件记录
+	err := database.Transaction(func(tx *gorm.DB) error {
+		event := dbmodel.NewCardEvent(card, dbmodel.CardEventDelete, dbmodel.CardActorUser, uint(userID), ip)
+		if err := tx.Create(&event).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&card).Error
+	})
+	if err != nil {
+		return errors.New("删除卡密失败: " + err.Error())
 	}
 
 	return nil
@@ -231,6 +742,11 @@ func (s *Service) CreateCardType(req model.CreateCardTypeRequest, userID int) (*
 		return nil, errors.New("查询应用失败: " + result.Error.Error())
 	}
 
+	// 验证功能权益
+	if err := req.Entitlements.Validate(); err != nil {
+		return nil, err
+	}
+
 	// 创建卡密类型
 	cardType := dbmodel.CardType{
 		Name:                  req.Name,
@@ -240,6 +756,7 @@ func (s *Service) CreateCardType(req model.CreateCardTypeRequest, userID int) (*
 		Status:                req.Status,
 		DefaultMaxRebindCount: req.DefaultMaxRebindCount,
 		DefaultMaxUnbindCount: req.DefaultMaxUnbindCount,
+		Entitlements:          req.Entitlements,
 		UserID:                userID,
 	}
 
@@ -351,6 +868,13 @@ func (s *Service) UpdateCardType(req model.UpdateCardTypeRequest, userID int) (*
 		cardType.DefaultMaxUnbindCount = req.DefaultMaxUnbindCount
 	}
 
+	if req.Entitlements != nil {
+		if err := req.Entitlements.Validate(); err != nil {
+			return nil, err
+		}
+		cardType.Entitlements = req.Entitlements
+	}
+
 	// 保存卡密类型
 	result = database.DB.Save(&cardType)
 	if result.Error != nil {
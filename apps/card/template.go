@@ -0,0 +1,173 @@
+package card
+
+import (
+	"errors"
+
+	"github.com/skyle1995/DevE-Server/apps/card/model"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/utils/cardcode"
+	"gorm.io/gorm"
+)
+
+// generateMaxRetries 生成卡密时卡号或卡密重复的最大重试次数
+const generateMaxRetries = 5
+
+// templateFormat 将卡密模板转换为生成格式
+func templateFormat(template dbmodel.CardTemplate) cardcode.Format {
+	return cardcode.Format{
+		Layout:   template.Layout,
+		Charset:  template.Charset,
+		Case:     template.Case,
+		Checksum: template.Checksum,
+	}
+}
+
+// resolveCardFormat 获取卡号或卡密的生成格式
+// 指定模板时使用该模板，否则使用用户对应用途的默认模板，都没有时返回nil
+func (s *Service) resolveCardFormat(templateID *uint, userID int, target string) (*cardcode.Format, error) {
+	var template dbmodel.CardTemplate
+	query := database.DB.Where("user_id = ?", userID)
+	if templateID != nil && *templateID > 0 {
+		query = query.Where("id = ?", *templateID)
+	} else {
+		query = query.Where("default_for = ?", target)
+	}
+
+	result := query.First(&template)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			if templateID != nil && *templateID > 0 {
+				return nil, errors.New("卡密模板不存在或无权限使用")
+			}
+			return nil, nil
+		}
+		return nil, errors.New("查询卡密模板失败: " + result.Error.Error())
+	}
+
+	format := templateFormat(template)
+	return &format, nil
+}
+
+// checkCardTemplate 检查模板是否存在且属于当前用户，模板ID为空或0时不检查
+func (s *Service) checkCardTemplate(templateID *uint, userID int) error {
+	if templateID == nil || *templateID == 0 {
+		return nil
+	}
+
+	var count int64
+	if err := database.DB.Model(&dbmodel.CardTemplate{}).Where("id = ? AND user_id = ?", *templateID, userID).Count(&count).Error; err != nil {
+		return errors.New("查询卡密模板失败: " + err.Error())
+	}
+	if count == 0 {
+		return errors.New("卡密模板不存在或无权限使用")
+	}
+	return nil
+}
+
+// newTemplateFormat 根据请求创建生成格式并检查配置
+func newTemplateFormat(layout, charset, letterCase string, checksum bool) (cardcode.Format, error) {
+	format := cardcode.Format{
+		Layout:   layout,
+		Charset:  charset,
+		Case:     letterCase,
+		Checksum: checksum,
+	}
+	if format.Charset == "" {
+		format.Charset = cardcode.CharsetAlnum
+	}
+	if format.Case == "" {
+		format.Case = cardcode.CaseUpper
+	}
+	return format, format.Check()
+}
+
+// GetCardTemplateList 获取卡密模板列表
+// @param userID 当前用户ID
+// @return 模板列表和错误信息
+func (s *Service) GetCardTemplateList(userID int) ([]dbmodel.CardTemplate, error) {
+	var templates []dbmodel.CardTemplate
+	if err := database.DB.Where("user_id = ?", userID).Order("id ASC").Find(&templates).Error; err != nil {
+		return nil, errors.New("查询卡密模板失败: " + err.Error())
+	}
+	return templates, nil
+}
+
+// SaveCardTemplate 创建或更新卡密模板
+// 设为默认模板时取消该用户同一用途的其他默认模板
+// @param id 模板ID，为0时创建
+// @param req 卡密模板请求
+// @param userID 当前用户ID
+// @return 模板和错误信息
+func (s *Service) SaveCardTemplate(id int, req model.CardTemplateRequest, userID int) (*dbmodel.CardTemplate, error) {
+	format, err := newTemplateFormat(req.Layout, req.Charset, req.Case, req.Checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	var template dbmodel.CardTemplate
+	if id > 0 {
+		result := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&template)
+		if result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return nil, errors.New("卡密模板不存在或无权限修改")
+			}
+			return nil, errors.New("查询卡密模板失败: " + result.Error.Error())
+		}
+	}
+
+	template.UserID = userID
+	template.Name = req.Name
+	template.Layout = format.Layout
+	template.Charset = format.Charset
+	template.Case = format.Case
+	template.Checksum = format.Checksum
+	template.DefaultFor = req.DefaultFor
+
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if template.DefaultFor != "" {
+			if err := tx.Model(&dbmodel.CardTemplate{}).
+				Where("user_id = ? AND default_for = ? AND id <> ?", userID, template.DefaultFor, template.ID).
+				Update("default_for", "").Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(&template).Error
+	})
+	if err != nil {
+		return nil, errors.New("保存卡密模板失败: " + err.Error())
+	}
+
+	return &template, nil
+}
+
+// DeleteCardTemplate 删除卡密模板，引用该模板的卡密类型改为使用默认模板
+// @param id 模板ID
+// @param userID 当前用户ID
+// @return 错误信息
+func (s *Service) DeleteCardTemplate(id int, userID int) error {
+	var template dbmodel.CardTemplate
+	result := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&template)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return errors.New("卡密模板不存在或无权限删除")
+		}
+		return errors.New("查询卡密模板失败: " + result.Error.Error())
+	}
+
+	err := database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&dbmodel.CardType{}).Where("card_no_template_id = ?", template.ID).
+			Update("card_no_template_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&dbmodel.CardType{}).Where("card_key_template_id = ?", template.ID).
+			Update("card_key_template_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&template).Error
+	})
+	if err != nil {
+		return errors.New("删除卡密模板失败: " + err.Error())
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 // Controller 客户端控制器
 type Controller struct {
 	service *Service
+	legacy  bool // 是否为v1兼容模式，兼容模式下失败响应统一返回400状态码
 }
 
 // NewController 创建客户端控制器
@@ -18,25 +19,53 @@ func NewController() *Controller {
 	}
 }
 
+// NewLegacyController 创建v1兼容模式的客户端控制器
+func NewLegacyController() *Controller {
+	return &Controller{
+		service: NewService(),
+		legacy:  true,
+	}
+}
+
+// fail 返回失败响应
+// v2接口返回错误对应的业务状态码，v1接口保持原有的400状态码
+func (c *Controller) fail(err error, ctx *gin.Context) {
+	if c.legacy {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+	response.FailWithError(err, ctx)
+}
+
+// failWithParam 返回参数错误响应
+func (c *Controller) failWithParam(err error, ctx *gin.Context) {
+	c.fail(response.NewCodeError(response.CodeParamError, "参数错误: "+err.Error()), ctx)
+}
+
+// failWithApp 返回应用信息获取失败响应
+func (c *Controller) failWithApp(ctx *gin.Context) {
+	c.fail(response.NewCodeError(response.CodeUnauthorized, "应用信息获取失败"), ctx)
+}
+
 // ActivateCard 激活卡密
 func (c *Controller) ActivateCard(ctx *gin.Context) {
 	var req model.ActivateCardRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		response.FailWithMessage("参数错误: "+err.Error(), ctx)
+		c.failWithParam(err, ctx)
 		return
 	}
 
 	// 从上下文中获取应用信息
 	app, exists := ctx.Get("app")
 	if !exists {
-		response.FailWithMessage("应用信息获取失败", ctx)
+		c.failWithApp(ctx)
 		return
 	}
 
 	// 调用服务层处理激活卡密
-	res, err := c.service.ActivateCard(req, app)
+	res, err := c.service.ActivateCard(req, app, ctx.ClientIP())
 	if err != nil {
-		response.FailWithMessage(err.Error(), ctx)
+		c.fail(err, ctx)
 		return
 	}
 
@@ -47,21 +76,21 @@ func (c *Controller) ActivateCard(ctx *gin.Context) {
 func (c *Controller) VerifyDevice(ctx *gin.Context) {
 	var req model.ActivateCardRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		response.FailWithMessage("参数错误: "+err.Error(), ctx)
+		c.failWithParam(err, ctx)
 		return
 	}
 
 	// 从上下文中获取应用信息
 	app, exists := ctx.Get("app")
 	if !exists {
-		response.FailWithMessage("应用信息获取失败", ctx)
+		c.failWithApp(ctx)
 		return
 	}
 
 	// 调用服务层处理验证设备
-	res, err := c.service.VerifyDevice(req, app)
+	res, err := c.service.VerifyDevice(req, app, ctx.ClientIP())
 	if err != nil {
-		response.FailWithMessage(err.Error(), ctx)
+		c.fail(err, ctx)
 		return
 	}
 
@@ -72,21 +101,21 @@ func (c *Controller) VerifyDevice(ctx *gin.Context) {
 func (c *Controller) RebindCard(ctx *gin.Context) {
 	var req model.RebindCardRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		response.FailWithMessage("参数错误: "+err.Error(), ctx)
+		c.failWithParam(err, ctx)
 		return
 	}
 
 	// 从上下文中获取应用信息
 	app, exists := ctx.Get("app")
 	if !exists {
-		response.FailWithMessage("应用信息获取失败", ctx)
+		c.failWithApp(ctx)
 		return
 	}
 
 	// 调用服务层处理换绑卡密
-	res, err := c.service.RebindCard(req, app)
+	res, err := c.service.RebindCard(req, app, ctx.ClientIP())
 	if err != nil {
-		response.FailWithMessage(err.Error(), ctx)
+		c.fail(err, ctx)
 		return
 	}
 
@@ -97,21 +126,55 @@ func (c *Controller) RebindCard(ctx *gin.Context) {
 func (c *Controller) UnbindCard(ctx *gin.Context) {
 	var req model.UnbindCardRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		response.FailWithMessage("参数错误: "+err.Error(), ctx)
+		c.failWithParam(err, ctx)
 		return
 	}
 
 	// 从上下文中获取应用信息
 	app, exists := ctx.Get("app")
 	if !exists {
-		response.FailWithMessage("应用信息获取失败", ctx)
+		c.failWithApp(ctx)
 		return
 	}
 
 	// 调用服务层处理解绑卡密
-	res, err := c.service.UnbindCard(req, app)
+	res, err := c.service.UnbindCard(req, app, ctx.ClientIP())
 	if err != nil {
-		response.FailWithMessage(err.Error(), ctx)
+		c.fail(err, ctx)
+		return
+	}
+
+	response.OkWithData(res, ctx)
+}
+
+// FreezeCard 冻结卡密
+func (c *Controller) FreezeCard(ctx *gin.Context) {
+	c.changeFrozen(ctx, c.service.FreezeCard)
+}
+
+// UnfreezeCard 解冻卡密
+func (c *Controller) UnfreezeCard(ctx *gin.Context) {
+	c.changeFrozen(ctx, c.service.UnfreezeCard)
+}
+
+// changeFrozen 冻结或解冻卡密
+func (c *Controller) changeFrozen(ctx *gin.Context, change func(model.FreezeCardRequest, interface{}, string) (*model.FreezeCardResponse, error)) {
+	var req model.FreezeCardRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.failWithParam(err, ctx)
+		return
+	}
+
+	// 从上下文中获取应用信息
+	app, exists := ctx.Get("app")
+	if !exists {
+		c.failWithApp(ctx)
+		return
+	}
+
+	res, err := change(req, app, ctx.ClientIP())
+	if err != nil {
+		c.fail(err, ctx)
 		return
 	}
 
@@ -122,21 +185,41 @@ func (c *Controller) UnbindCard(ctx *gin.Context) {
 func (c *Controller) VerifyApp(ctx *gin.Context) {
 	var req model.VerifyAppRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		response.FailWithMessage("参数错误: "+err.Error(), ctx)
+		c.failWithParam(err, ctx)
+		return
+	}
+
+	// 从上下文中获取应用信息
+	app, exists := ctx.Get("app")
+	if !exists {
+		c.failWithApp(ctx)
+		return
+	}
+
+	// 调用服务层获取应用信息
+	res, err := c.service.VerifyApp(app)
+	if err != nil {
+		c.fail(err, ctx)
 		return
 	}
 
+	response.OkWithData(res, ctx)
+}
+
+// GetAppStatus 获取应用状态
+func (c *Controller) GetAppStatus(ctx *gin.Context) {
 	// 从上下文中获取应用信息
-	_, exists := ctx.Get("app")
+	app, exists := ctx.Get("app")
 	if !exists {
-		response.FailWithMessage("应用信息获取失败", ctx)
+		c.failWithApp(ctx)
 		return
 	}
 
-	// 验证应用成功，直接返回成功响应
-	res := model.VerifyAppResponse{
-		Success: true,
-		Message: "应用验证成功",
+	// 调用服务层获取应用状态
+	res, err := c.service.GetAppStatus(app)
+	if err != nil {
+		c.fail(err, ctx)
+		return
 	}
 
 	response.OkWithData(res, ctx)
@@ -146,21 +229,21 @@ func (c *Controller) VerifyApp(ctx *gin.Context) {
 func (c *Controller) Heartbeat(ctx *gin.Context) {
 	var req model.HeartbeatRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		response.FailWithMessage("参数错误: "+err.Error(), ctx)
+		c.failWithParam(err, ctx)
 		return
 	}
 
 	// 从上下文中获取应用信息
 	app, exists := ctx.Get("app")
 	if !exists {
-		response.FailWithMessage("应用信息获取失败", ctx)
+		c.failWithApp(ctx)
 		return
 	}
 
 	// 调用服务层处理心跳
-	res, err := c.service.Heartbeat(req, app)
+	res, err := c.service.Heartbeat(req, app, ctx.ClientIP())
 	if err != nil {
-		response.FailWithMessage(err.Error(), ctx)
+		c.fail(err, ctx)
 		return
 	}
 
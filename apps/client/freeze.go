@@ -0,0 +1,140 @@
+package client
+
+import (
+	"errors"
+
+	"github.com/skyle1995/DevE-Server/apps/client/model"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/utils/response"
+	"gorm.io/gorm"
+)
+
+// 冻结相关错误
+var (
+	ErrFreezeNotAllowed   = response.NewCodeError(response.CodeForbidden, "该卡密不允许冻结")
+	ErrFreezeLimit        = response.NewCodeError(response.CodeForbidden, "已达到最大冻结次数限制")
+	ErrCardNotFrozen      = response.NewCodeError(response.CodeParamError, "卡密未冻结")
+	ErrUnfreezeNotAllowed = response.NewCodeError(response.CodeForbidden, "卡密已被卖家冻结，请联系卖家解冻")
+)
+
+// findBoundCard 查询卡密并检查是否绑定了请求中的设备
+func (s *Service) findBoundCard(cardNo, deviceID string, appID uint) (*dbmodel.Card, error) {
+	var card dbmodel.Card
+	result := s.db.Preload("CardType").Where("card_no = ? AND app_id = ?", cardNo, appID).First(&card)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrCardNotFound
+		}
+		return nil, response.NewCodeError(response.CodeServerError, "查询卡密信息失败")
+	}
+
+	if card.DeviceID == nil {
+		return nil, ErrCardUnbound
+	}
+	if *card.DeviceID != deviceID {
+		return nil, ErrDeviceMismatch
+	}
+	return &card, nil
+}
+
+// saveFrozenState 保存卡密的冻结状态，卡密状态已被其他请求修改时返回错误
+func (s *Service) saveFrozenState(card *dbmodel.Card, fromStatus int) error {
+	result := s.db.Model(card).Where("status = ?", fromStatus).
+		Select("status", "expire_at", "is_online", "frozen_at", "frozen_remain", "freeze_source", "freeze_count").
+		Updates(card)
+	if result.Error != nil {
+		return response.NewCodeError(response.CodeServerError, "更新卡密信息失败")
+	}
+	if result.RowsAffected == 0 {
+		return response.NewCodeError(response.CodeServerError, "卡密状态已变化，请重试")
+	}
+	return nil
+}
+
+// FreezeCard 终端用户冻结卡密
+// 需卡密类型允许客户端冻结，冻结次数受卡密类型的最大冻结次数限制
+func (s *Service) FreezeCard(req model.FreezeCardRequest, app interface{}, ip string) (*model.FreezeCardResponse, error) {
+	// 类型断言获取应用信息
+	appInfo, ok := app.(dbmodel.App)
+	if !ok {
+		return nil, ErrAppInfoInvalid
+	}
+
+	card, err := s.findBoundCard(req.CardNo, req.DeviceID, appInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 检查卡密状态
+	if err := cardStatusError(*card); err != nil {
+		return nil, err
+	}
+
+	// 检查卡密类型的冻结限制
+	if !card.CardType.AllowClientFreeze {
+		return nil, ErrFreezeNotAllowed
+	}
+	if card.CardType.MaxFreezeCount > 0 && card.FreezeCount >= card.CardType.MaxFreezeCount {
+		return nil, ErrFreezeLimit
+	}
+
+	if err := card.Freeze(dbmodel.CardFreezeByClient); err != nil {
+		return nil, response.NewCodeError(response.CodeServerError, err.Error())
+	}
+	if err := s.saveFrozenState(card, dbmodel.CardStatusActive); err != nil {
+		return nil, err
+	}
+	s.recordEvent(dbmodel.NewCardEvent(*card, dbmodel.CardEventFreeze, dbmodel.CardActorClient, 0, ip).
+		WithChange(nil, map[string]interface{}{"frozen_remain": card.FrozenRemain}))
+
+	return &model.FreezeCardResponse{
+		Success:        true,
+		CardNo:         card.CardNo,
+		Frozen:         true,
+		RemainSeconds:  card.FrozenRemain,
+		FreezeCount:    card.FreezeCount,
+		MaxFreezeCount: card.CardType.MaxFreezeCount,
+		Message:        "卡密冻结成功",
+	}, nil
+}
+
+// UnfreezeCard 终端用户解冻卡密
+// 只能解冻终端用户自己冻结的卡密，卖家冻结的卡密需由卖家解冻
+func (s *Service) UnfreezeCard(req model.FreezeCardRequest, app interface{}, ip string) (*model.FreezeCardResponse, error) {
+	// 类型断言获取应用信息
+	appInfo, ok := app.(dbmodel.App)
+	if !ok {
+		return nil, ErrAppInfoInvalid
+	}
+
+	card, err := s.findBoundCard(req.CardNo, req.DeviceID, appInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if card.Status != dbmodel.CardStatusFrozen {
+		return nil, ErrCardNotFrozen
+	}
+	if card.FreezeSource != dbmodel.CardFreezeByClient {
+		return nil, ErrUnfreezeNotAllowed
+	}
+
+	if err := card.Unfreeze(); err != nil {
+		return nil, response.NewCodeError(response.CodeServerError, err.Error())
+	}
+	if err := s.saveFrozenState(card, dbmodel.CardStatusFrozen); err != nil {
+		return nil, err
+	}
+	s.recordEvent(dbmodel.NewCardEvent(*card, dbmodel.CardEventUnfreeze, dbmodel.CardActorClient, 0, ip).
+		WithChange(nil, map[string]interface{}{"expire_at": card.ExpireAt}))
+
+	return &model.FreezeCardResponse{
+		Success:        true,
+		CardNo:         card.CardNo,
+		Frozen:         false,
+		ExpireAt:       card.ExpireAt,
+		FreezeCount:    card.FreezeCount,
+		MaxFreezeCount: card.CardType.MaxFreezeCount,
+		Message:        "卡密解冻成功",
+	}, nil
+}
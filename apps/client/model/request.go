@@ -39,3 +39,11 @@ type HeartbeatRequest struct {
 	AppKey    string `json:"app_key" binding:"required"`   // 应用密钥
 	Timestamp int64  `json:"timestamp" binding:"required"` // 时间戳
 }
+
+// FreezeCardRequest 冻结/解冻卡密请求
+type FreezeCardRequest struct {
+	CardNo    string `json:"card_no" binding:"required"`   // 卡号
+	DeviceID  string `json:"device_id" binding:"required"` // 设备ID，需与卡密绑定的设备一致
+	AppKey    string `json:"app_key" binding:"required"`   // 应用密钥
+	Timestamp int64  `json:"timestamp" binding:"required"` // 时间戳
+}
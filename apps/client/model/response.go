@@ -4,17 +4,18 @@ import "time"
 
 // ActivateCardResponse 激活卡密响应
 type ActivateCardResponse struct {
-	CardNo        string     `json:"card_no"`        // 卡号
-	Status        int        `json:"status"`         // 状态
-	Activated     bool       `json:"activated"`      // 是否已激活
-	ExpireAt      *time.Time `json:"expire_time"`    // 过期时间
-	DeviceBinding bool       `json:"device_binding"` // 是否绑定设备
-	BindingInfo   string     `json:"binding_info"`   // 绑定信息
-	BindCount     int        `json:"bind_count"`     // 已绑定次数
-	MaxBindCount  int        `json:"max_bind_count"` // 最大绑定次数
-	CanRebind     bool       `json:"can_rebind"`     // 是否可以换绑
-	CanUnbind     bool       `json:"can_unbind"`     // 是否可以解绑
-	Message       string     `json:"message"`        // 消息
+	CardNo        string       `json:"card_no"`        // 卡号
+	Status        int          `json:"status"`         // 状态
+	Activated     bool         `json:"activated"`      // 是否已激活
+	ExpireAt      *time.Time   `json:"expire_time"`    // 过期时间
+	DeviceBinding bool         `json:"device_binding"` // 是否绑定设备
+	BindingInfo   string       `json:"binding_info"`   // 绑定信息
+	BindCount     int          `json:"bind_count"`     // 已绑定次数
+	MaxBindCount  int          `json:"max_bind_count"` // 最大绑定次数
+	CanRebind     bool         `json:"can_rebind"`     // 是否可以换绑
+	CanUnbind     bool         `json:"can_unbind"`     // 是否可以解绑
+	Entitlements  Entitlements `json:"entitlements"`   // 卡密类型的功能权益
+	Message       string       `json:"message"`        // 消息
 }
 
 // RebindCardResponse 换绑卡密响应
@@ -34,25 +35,84 @@ type UnbindCardResponse struct {
 
 // VerifyAppResponse 验证应用响应
 type VerifyAppResponse struct {
-	Success bool   `json:"success"` // 是否成功
-	Message string `json:"message"` // 消息
+	Success     bool      `json:"success"`      // 是否成功
+	Message     string    `json:"message"`      // 消息
+	AppID       uint      `json:"app_id"`       // 应用ID
+	AppName     string    `json:"app_name"`     // 应用名称
+	Version     string    `json:"version"`      // 应用当前版本
+	DownloadUrl string    `json:"download_url"` // 应用下载地址
+	Notice      string    `json:"notice"`       // 应用公告内容
+	PublicData  string    `json:"public_data"`  // 公有数据
+	TrialInfo   TrialInfo `json:"trial_info"`   // 试用信息
+}
+
+// TrialInfo 应用试用信息
+type TrialInfo struct {
+	HasTrial      bool       `json:"has_trial"`                 // 是否允许试用
+	TrialEndTime  *time.Time `json:"trial_end_time,omitempty"`  // 试用结束时间
+	TrialTimeLeft int64      `json:"trial_time_left,omitempty"` // 剩余小时数
+}
+
+// AppStatusResponse 应用状态响应
+type AppStatusResponse struct {
+	AppID       uint   `json:"app_id"`       // 应用ID
+	AppName     string `json:"app_name"`     // 应用名称
+	Status      int    `json:"status"`       // 应用状态
+	Version     string `json:"version"`      // 应用当前版本
+	DownloadUrl string `json:"download_url"` // 应用下载地址
 }
 
 // VerifyDeviceResponse 验证设备响应
 type VerifyDeviceResponse struct {
-	Success    bool       `json:"success"`     // 是否成功
-	CardNo     string     `json:"card_no"`     // 卡号
-	ExpireAt   *time.Time `json:"expire_time"` // 过期时间
-	RemainDays int        `json:"remain_days"` // 剩余天数
-	Message    string     `json:"message"`     // 消息
+	Success      bool         `json:"success"`      // 是否成功
+	CardNo       string       `json:"card_no"`      // 卡号
+	ExpireAt     *time.Time   `json:"expire_time"`  // 过期时间
+	RemainDays   int          `json:"remain_days"`  // 剩余天数
+	Entitlements Entitlements `json:"entitlements"` // 卡密类型的功能权益
+	Message      string       `json:"message"`      // 消息
 }
 
 // HeartbeatResponse 心跳响应
 type HeartbeatResponse struct {
-	Success    bool       `json:"success"`     // 是否成功
-	CardNo     string     `json:"card_no"`     // 卡号
-	IsOnline   bool       `json:"is_online"`   // 是否在线
-	ExpireAt   *time.Time `json:"expire_time"` // 过期时间
-	RemainDays int        `json:"remain_days"` // 剩余天数
-	Message    string     `json:"message"`     // 消息
+	Success      bool         `json:"success"`      // 是否成功
+	CardNo       string       `json:"card_no"`      // 卡号
+	IsOnline     bool         `json:"is_online"`    // 是否在线
+	ExpireAt     *time.Time   `json:"expire_time"`  // 过期时间
+	RemainDays   int          `json:"remain_days"`  // 剩余天数
+	Entitlements Entitlements `json:"entitlements"` // 卡密类型的功能权益
+	Message      string       `json:"message"`      // 消息
+}
+
+// FreezeCardResponse 冻结/解冻卡密响应
+type FreezeCardResponse struct {
+	Success        bool       `json:"success"`          // 是否成功
+	CardNo         string     `json:"card_no"`          // 卡号
+	Frozen         bool       `json:"frozen"`           // 是否已冻结
+	RemainSeconds  *int64     `json:"remain_seconds"`   // 冻结时保留的剩余有效秒数，永久卡密为空
+	ExpireAt       *time.Time `json:"expire_time"`      // 解冻后的过期时间
+	FreezeCount    int        `json:"freeze_count"`     // 已冻结次数
+	MaxFreezeCount int        `json:"max_freeze_count"` // 最大冻结次数，0为不限
+	Message        string     `json:"message"`          // 消息
+}
+
+// Entitlements 卡密类型配置的功能权益，值为布尔开关、数值限制或字符串配置
+// 权益在每次激活、验证和心跳时实时返回，卡密类型修改后下一次请求即可获取最新值
+type Entitlements map[string]interface{}
+
+// Bool 获取布尔类型的权益，不存在或类型不符时返回false
+func (e Entitlements) Bool(key string) bool {
+	v, _ := e[key].(bool)
+	return v
+}
+
+// Number 获取数值类型的权益，第二个返回值表示权益是否存在且为数值
+func (e Entitlements) Number(key string) (float64, bool) {
+	v, ok := e[key].(float64)
+	return v, ok
+}
+
+// String 获取字符串类型的权益，不存在或类型不符时返回空字符串
+func (e Entitlements) String(key string) string {
+	v, _ := e[key].(string)
+	return v
 }
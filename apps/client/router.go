@@ -2,34 +2,72 @@ package client
 
 import (
 	"github.com/gin-gonic/gin"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
 	"github.com/skyle1995/DevE-Server/middleware"
 )
 
 // SetupClientRoutes 设置客户端路由
 func SetupClientRoutes(r *gin.Engine) {
-	// 创建控制器实例
-	controller := NewController()
-
-	// 客户端API路由组
+	// v1客户端API路由组（已废弃，保留至客户端迁移完成）
+	legacyController := NewLegacyController()
 	clientAPI := r.Group("/api/v1/client")
-	clientAPI.Use(middleware.ClientAuthMiddleware())
+	clientAPI.Use(
+		middleware.DeprecationMiddleware("/api/v2/client"),
+		middleware.ClientAuthMiddleware(),
+	)
 	{
 		// 验证应用
-		clientAPI.POST("/verify-app", controller.VerifyApp)
+		clientAPI.POST("/verify-app", legacyController.VerifyApp)
 
 		// 激活卡密
-		clientAPI.POST("/activate", controller.ActivateCard)
+		clientAPI.POST("/activate", legacyController.ActivateCard)
 
 		// 验证设备
-		clientAPI.POST("/verify", controller.VerifyDevice)
+		clientAPI.POST("/verify", legacyController.VerifyDevice)
 
 		// 换绑卡密
-		clientAPI.POST("/rebind", controller.RebindCard)
+		clientAPI.POST("/rebind", legacyController.RebindCard)
 
 		// 解绑卡密
-		clientAPI.POST("/unbind", controller.UnbindCard)
+		clientAPI.POST("/unbind", legacyController.UnbindCard)
 
 		// 心跳接口
-		clientAPI.POST("/heartbeat", controller.Heartbeat)
+		clientAPI.POST("/heartbeat", legacyController.Heartbeat)
+	}
+
+	// v2客户端API路由组
+	// 统一使用签名认证，支持数据加密，返回细分的业务状态码，并记录应用日志
+	controller := NewController()
+	clientV2 := r.Group("/api/v2/client")
+	clientV2.Use(
+		middleware.LogMiddleware(dbmodel.LogTypeApp),
+		middleware.ClientAuthV2Middleware(),
+		middleware.ClientCryptoMiddleware(),
+	)
+	{
+		// 验证应用
+		clientV2.POST("/verify-app", controller.VerifyApp)
+
+		// 获取应用状态（需在试用期内）
+		clientV2.GET("/status", middleware.AppTrialMiddleware(), controller.GetAppStatus)
+
+		// 激活卡密
+		clientV2.POST("/activate", controller.ActivateCard)
+
+		// 验证设备
+		clientV2.POST("/verify", controller.VerifyDevice)
+
+		// 换绑卡密
+		clientV2.POST("/rebind", controller.RebindCard)
+
+		// 解绑卡密
+		clientV2.POST("/unbind", controller.UnbindCard)
+
+		// 心跳接口
+		clientV2.POST("/heartbeat", controller.Heartbeat)
+
+		// 冻结/解冻卡密（需卡密类型允许）
+		clientV2.POST("/freeze", controller.FreezeCard)
+		clientV2.POST("/unfreeze", controller.UnfreezeCard)
 	}
 }
@@ -2,15 +2,47 @@ package client
 
 import (
 	"errors"
+	"strings"
 	"time"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/skyle1995/DevE-Server/apps/client/model"
 	"github.com/skyle1995/DevE-Server/database"
 	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/utils/cardcode"
+	"github.com/skyle1995/DevE-Server/utils/response"
 	"github.com/skyle1995/DevE-Server/utils/timeutil"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// 客户端业务错误，携带接口状态码
+var (
+	ErrAppInfoInvalid    = response.NewCodeError(response.CodeServerError, "应用信息类型错误")
+	ErrCardNotFound      = response.NewCodeError(response.CodeCardNotFound, "卡密不存在")
+	ErrCardNoMistyped    = response.NewCodeError(response.CodeCardNotFound, "卡号校验失败，请检查是否输入有误")
+	ErrCardNotActivated  = response.NewCodeError(response.CodeCardNotActivated, "卡密未激活")
+	ErrCardNotActive     = response.NewCodeError(response.CodeCardNotActivated, "卡密不存在或未激活")
+	ErrCardExpired       = response.NewCodeError(response.CodeCardExpired, "卡密已过期")
+	ErrCardDisabled      = response.NewCodeError(response.CodeCardDisabled, "卡密已被禁用")
+	ErrCardFrozen        = response.NewCodeError(response.CodeCardFrozen, "卡密已冻结")
+	ErrCardBoundOther    = response.NewCodeError(response.CodeCardBoundOther, "卡密已绑定其他设备")
+	ErrCardUnbound       = response.NewCodeError(response.CodeBindRequired, "卡密未绑定设备")
+	ErrCardTypeNotFound  = response.NewCodeError(response.CodeCardNotFound, "卡密类型不存在")
+	ErrRebindLimit       = response.NewCodeError(response.CodeCardBindLimit, "已达到最大换绑次数限制")
+	ErrUnbindLimit       = response.NewCodeError(response.CodeCardBindLimit, "已达到最大解绑次数限制")
+	ErrRebindNotAllowed  = response.NewCodeError(response.CodeBindNotAllowed, "应用不允许换绑")
+	ErrUnbindNotAllowed  = response.NewCodeError(response.CodeBindNotAllowed, "应用不允许解绑")
+	ErrDeviceNotFound    = response.NewCodeError(response.CodeBindVerifyFailed, "设备未注册")
+	ErrDeviceDisabled    = response.NewCodeError(response.CodeBindVerifyFailed, "设备已被禁用")
+	ErrDeviceMismatch    = response.NewCodeError(response.CodeCardDeviceMismatch, "设备ID不匹配")
+	ErrBoundCardNotFound = response.NewCodeError(response.CodeCardNotFound, "未找到关联的卡密")
+	ErrCardConflict      = response.NewCodeError(response.CodeServerError, "卡密状态已变化，请重试")
+)
+
+// activateRetries 激活时卡密被其他请求修改后的最大尝试次数
+const activateRetries = 3
+
 // Service 客户端服务
 type Service struct {
 	db *gorm.DB
@@ -23,52 +55,251 @@ func NewService() *Service {
 	}
 }
 
+// recordEvent 记录卡密事件，记录失败只写日志，不影响客户端请求
+func (s *Service) recordEvent(event dbmodel.CardEvent) {
+	if err := s.db.Create(&event).Error; err != nil {
+		log.Errorf("记录卡密事件失败: card=%d type=%s: %v", event.CardID, event.Type, err)
+	}
+}
+
+// entitlements 实时读取卡密类型的功能权益，修改卡密类型后对该类型的所有卡密立即生效
+// 读取失败只写日志并返回空权益，不影响客户端验证
+func (s *Service) entitlements(typeID uint) model.Entitlements {
+	var cardType dbmodel.CardType
+	if err := s.db.Select("id", "entitlements").First(&cardType, typeID).Error; err != nil {
+		log.Errorf("查询卡密类型权益失败: type=%d: %v", typeID, err)
+		return model.Entitlements{}
+	}
+	return model.Entitlements(cardType.Entitlements.OrEmpty())
+}
+
+// verifyFailed 记录卡密验证失败事件并返回原错误
+// 设备ID为请求中的设备，便于排查卡密在哪台设备上被拒绝
+func (s *Service) verifyFailed(card dbmodel.Card, deviceID, ip string, err error) error {
+	event := dbmodel.NewCardEvent(card, dbmodel.CardEventVerifyFailed, dbmodel.CardActorClient, 0, ip)
+	event.DeviceID = deviceID
+	event.Message = err.Error()
+	s.recordEvent(event)
+	return err
+}
+
+// findOrCreateDevice 查询设备并更新设备信息，设备不存在时创建
+// 设备按（应用ID, 设备ID）唯一，同一设备在每个应用中分别登记
+func findOrCreateDevice(tx *gorm.DB, deviceID string, deviceInfo map[string]interface{}, appID uint) (*dbmodel.Device, error) {
+	var device dbmodel.Device
+	result := tx.Where("device_id = ? AND app_id = ?", deviceID, appID).First(&device)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		// 创建新设备
+		device = dbmodel.Device{
+			DeviceID:   deviceID,
+			AppID:      appID,
+			DeviceInfo: deviceInfo,
+			Status:     1, // 正常状态
+			LastActive: time.Now(),
+		}
+		result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&device)
+		if result.Error != nil {
+			return nil, response.NewCodeError(response.CodeServerError, "创建设备记录失败")
+		}
+		if result.RowsAffected > 0 {
+			return &device, nil
+		}
+
+		// 同一设备的并发请求已创建设备，加锁读取以获取其他事务刚提交的记录
+		device = dbmodel.Device{}
+		result = tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("device_id = ? AND app_id = ?", deviceID, appID).First(&device)
+	}
+	if result.Error != nil {
+		return nil, response.NewCodeError(response.CodeServerError, "查询设备信息失败")
+	}
+
+	// 更新设备信息
+	device.DeviceInfo = deviceInfo
+	device.LastActive = time.Now()
+	tx.Model(&device).Select("device_info", "last_active").Updates(&device)
+	return &device, nil
+}
+
+// saveCardError 转换按版本保存卡密时的错误
+func saveCardError(err error) error {
+	if errors.Is(err, dbmodel.ErrCardVersionConflict) {
+		return ErrCardConflict
+	}
+	var codeErr *response.CodeError
+	if errors.As(err, &codeErr) {
+		return err
+	}
+	return response.NewCodeError(response.CodeServerError, "更新卡密信息失败")
+}
+
+// cardStatusError 返回卡密当前状态下不能使用的原因，已使用且未过期时返回nil
+func cardStatusError(card dbmodel.Card) error {
+	switch card.Status {
+	case dbmodel.CardStatusActive:
+		if card.IsExpired() {
+			return ErrCardExpired
+		}
+		return nil
+	case dbmodel.CardStatusExpired:
+		return ErrCardExpired
+	case dbmodel.CardStatusDisabled:
+		return ErrCardDisabled
+	case dbmodel.CardStatusFrozen:
+		return ErrCardFrozen
+	default:
+		return ErrCardNotActivated
+	}
+}
+
+// VerifyApp 验证应用并返回应用信息
+func (s *Service) VerifyApp(app interface{}) (*model.VerifyAppResponse, error) {
+	// 类型断言获取应用信息
+	appInfo, ok := app.(dbmodel.App)
+	if !ok {
+		return nil, ErrAppInfoInvalid
+	}
+
+	res := &model.VerifyAppResponse{
+		Success:     true,
+		Message:     "应用验证成功",
+		AppID:       appInfo.ID,
+		AppName:     appInfo.Name,
+		Version:     appInfo.Version,
+		DownloadUrl: appInfo.DownloadUrl,
+		Notice:      appInfo.Notice,
+		PublicData:  appInfo.PublicData,
+	}
+
+	// 计算试用期信息
+	if trialEndTime, hasTrial := appInfo.TrialEndTime(); hasTrial {
+		res.TrialInfo.HasTrial = true
+		res.TrialInfo.TrialEndTime = &trialEndTime
+		res.TrialInfo.TrialTimeLeft = int64(time.Until(trialEndTime).Hours())
+		if res.TrialInfo.TrialTimeLeft < 0 {
+			res.TrialInfo.TrialTimeLeft = 0
+		}
+	}
+
+	return res, nil
+}
+
+// GetAppStatus 获取应用状态
+func (s *Service) GetAppStatus(app interface{}) (*model.AppStatusResponse, error) {
+	// 类型断言获取应用信息
+	appInfo, ok := app.(dbmodel.App)
+	if !ok {
+		return nil, ErrAppInfoInvalid
+	}
+
+	return &model.AppStatusResponse{
+		AppID:       appInfo.ID,
+		AppName:     appInfo.Name,
+		Status:      appInfo.Status,
+		Version:     appInfo.Version,
+		DownloadUrl: appInfo.DownloadUrl,
+	}, nil
+}
+
+// checkCardNo 按应用使用的卡号模板检查校验位，用于区分输错的卡号和不存在的卡号
+// 只在卡号不存在时调用，模板创建前生成的卡密可能恰好符合模板布局而不能通过校验，不能据此拒绝已有的卡密
+// 卡号去掉前缀后符合某个开启校验位的模板布局、且不能通过任何匹配模板的校验时返回false
+func (s *Service) checkCardNo(cardNo string, appInfo dbmodel.App) bool {
+	var templates []dbmodel.CardTemplate
+	typeTemplates := s.db.Model(&dbmodel.CardType{}).Select("card_no_template_id").
+		Where("app_id = ? AND card_no_template_id IS NOT NULL", appInfo.ID)
+	result := s.db.Where("user_id = ? AND (default_for = ? OR id IN (?))", appInfo.UserID, dbmodel.CardTemplateForCardNo, typeTemplates).
+		Find(&templates)
+	if result.Error != nil {
+		return true
+	}
+
+	checked := false
+	for _, template := range templates {
+		format := cardcode.Format{
+			Layout:   template.Layout,
+			Charset:  template.Charset,
+			Case:     template.Case,
+			Checksum: template.Checksum,
+		}
+
+		// 生成时卡号为 前缀-模板内容，只校验模板部分
+		runes := []rune(cardNo)
+		size := len([]rune(format.Layout))
+		if len(runes) < size {
+			continue
+		}
+		prefix, code := string(runes[:len(runes)-size]), string(runes[len(runes)-size:])
+		if prefix != "" && !strings.HasSuffix(prefix, "-") {
+			continue
+		}
+		if !format.Match(code) {
+			continue
+		}
+		if format.Verify(code) {
+			return true
+		}
+		if format.Checksum {
+			checked = true
+		}
+	}
+	return !checked
+}
+
 // ActivateCard 激活卡密
-func (s *Service) ActivateCard(req model.ActivateCardRequest, app interface{}) (*model.ActivateCardResponse, error) {
+// 激活在事务中按版本号保存卡密，卡密在此期间被其他请求修改时重新读取后重试，
+// 因此同一张卡密被多台设备同时激活时只有一台设备能够绑定，其余请求返回卡密已绑定其他设备
+func (s *Service) ActivateCard(req model.ActivateCardRequest, app interface{}, ip string) (*model.ActivateCardResponse, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := s.activateCard(req, app, ip)
+		if errors.Is(err, ErrCardConflict) && attempt < activateRetries {
+			continue
+		}
+		return res, err
+	}
+}
+
+// activateCard 读取卡密并尝试激活一次
+func (s *Service) activateCard(req model.ActivateCardRequest, app interface{}, ip string) (*model.ActivateCardResponse, error) {
 	// 类型断言获取应用信息
 	appInfo, ok := app.(dbmodel.App)
 	if !ok {
-		return nil, errors.New("应用信息类型错误")
+		return nil, ErrAppInfoInvalid
 	}
 
-	// 查询卡密
+	// 查询卡密，卡号不存在时按卡号模板的校验位提示输入错误
 	var card dbmodel.Card
 	result := s.db.Where("card_no = ? AND app_id = ?", req.CardNo, appInfo.ID).First(&card)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("卡密不存在")
+			if !s.checkCardNo(req.CardNo, appInfo) {
+				return nil, ErrCardNoMistyped
+			}
+			return nil, ErrCardNotFound
 		}
 		return nil, result.Error
 	}
 
-	// 检查卡密状态
-	if card.Status == 2 { // 已禁用
-		return nil, errors.New("卡密已被禁用")
-	}
-
-	// 检查卡密是否已过期
-	if card.ExpireAt != nil && card.ExpireAt.Before(time.Now()) {
-		return nil, errors.New("卡密已过期")
+	// 检查卡密状态，未使用的卡密可以激活，其他状态只有已使用且未过期时可以继续
+	if card.Status != dbmodel.CardStatusUnused {
+		if err := cardStatusError(card); err != nil {
+			return nil, s.verifyFailed(card, req.DeviceID, ip, err)
+		}
 	}
 
 	// 查询卡密类型
 	var cardType dbmodel.CardType
 	result = s.db.First(&cardType, card.TypeID)
 	if result.Error != nil {
-		return nil, errors.New("卡密类型不存在")
+		return nil, ErrCardTypeNotFound
 	}
 
-	// 查询应用设置
-	var appSetting dbmodel.App
-	result = s.db.Where("app_id = ?", appInfo.ID).First(&appSetting)
-	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		return nil, errors.New("获取应用设置失败")
-	}
+	// 应用设置即为当前认证的应用
+	appSetting := appInfo
 
-	// 如果卡密已激活，检查设备ID是否匹配
-	if card.Status == 1 { // 已激活
-		if card.DeviceID != nil && *card.DeviceID != req.DeviceID {
-			return nil, errors.New("卡密已绑定其他设备")
+	// 如果卡密已激活且绑定了设备，检查设备ID是否匹配
+	if card.Status == dbmodel.CardStatusActive && card.DeviceID != nil {
+		if *card.DeviceID != req.DeviceID {
+			return nil, s.verifyFailed(card, req.DeviceID, ip, ErrCardBoundOther)
 		}
 
 		// 返回卡密信息
@@ -81,62 +312,48 @@ func (s *Service) ActivateCard(req model.ActivateCardRequest, app interface{}) (
 			BindingInfo:   "已绑定当前设备",
 			BindCount:     card.RebindCount,
 			MaxBindCount:  cardType.MaxBindCount,
-			CanRebind:     (appSetting.BindPermission == 1 || appSetting.BindPermission == 3) && (cardType.MaxBindCount == 0 || card.RebindCount < cardType.MaxBindCount),
+			CanRebind:     (appSetting.BindPermission == 1 || appSetting.BindPermission == 3) && !rebindExhausted(card, cardType),
 			CanUnbind:     appSetting.BindPermission == 2 || appSetting.BindPermission == 3,
+			Entitlements:  model.Entitlements(cardType.Entitlements.OrEmpty()),
 			Message:       "卡密已激活",
 		}, nil
 	}
 
-	// 查询或创建设备
-	var device dbmodel.Device
-	result = s.db.Where("device_id = ? AND app_id = ?", req.DeviceID, appInfo.ID).First(&device)
-	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		// 创建新设备
-		device = dbmodel.Device{
-			DeviceID:   req.DeviceID,
-			AppID:      appInfo.ID,
-			DeviceInfo: req.DeviceInfo,
-			Status:     1, // 正常状态
-			LastActive: time.Now(),
-		}
-		result = s.db.Create(&device)
-		if result.Error != nil {
-			return nil, errors.New("创建设备记录失败")
+	// 更新卡密信息
+	fromStatus := card.Status
+	message := "卡密激活成功"
+	event := dbmodel.NewCardEvent(card, dbmodel.CardEventActivate, dbmodel.CardActorClient, 0, ip)
+	if card.Status == dbmodel.CardStatusUnused {
+		if err := card.Activate(req.DeviceID, cardType.ValidDays); err != nil {
+			return nil, response.NewCodeError(response.CodeServerError, err.Error())
 		}
-	} else if result.Error != nil {
-		return nil, errors.New("查询设备信息失败")
 	} else {
-		// 更新设备信息
-		device.DeviceInfo = req.DeviceInfo
-		device.LastActive = time.Now()
-		s.db.Save(&device)
-	}
-
-	// 检查设备状态
-	if device.Status != 1 {
-		return nil, errors.New("设备已被禁用")
-	}
-
-	// 计算过期时间
-	var expireAt *time.Time
-	if cardType.ValidDays > 0 {
-		now := time.Now()
-		expire := now.AddDate(0, 0, cardType.ValidDays)
-		expireAt = &expire
+		// 解绑后重新绑定设备，保留激活时间和过期时间
+		card.DeviceID = &req.DeviceID
+		message = "设备绑定成功"
+		event.Message = "解绑后重新绑定设备"
+	}
+	event.DeviceID = req.DeviceID
+
+	// 在同一事务中登记设备并保存卡密，卡密已被其他请求激活或修改时整体回滚
+	err := database.Transaction(func(tx *gorm.DB) error {
+		device, err := findOrCreateDevice(tx, req.DeviceID, req.DeviceInfo, appInfo.ID)
+		if err != nil {
+			return err
+		}
+		// 检查设备状态
+		if device.Status != 1 {
+			return ErrDeviceDisabled
+		}
+		return card.SaveWithVersion(tx, fromStatus)
+	})
+	if errors.Is(err, ErrDeviceDisabled) {
+		return nil, s.verifyFailed(card, req.DeviceID, ip, err)
 	}
-
-	// 更新卡密信息
-	card.Status = 1 // 已激活
-	card.DeviceID = &req.DeviceID
-	now := time.Now()
-	card.ActivateAt = &now
-	card.ExpireAt = expireAt
-	card.RebindCount = 0 // 初始化换绑次数
-
-	result = s.db.Save(&card)
-	if result.Error != nil {
-		return nil, errors.New("更新卡密信息失败")
+	if err != nil {
+		return nil, saveCardError(err)
 	}
+	s.recordEvent(event.WithChange(nil, map[string]interface{}{"expire_at": card.ExpireAt}))
 
 	// 返回激活成功响应
 	return &model.ActivateCardResponse{
@@ -148,18 +365,19 @@ func (s *Service) ActivateCard(req model.ActivateCardRequest, app interface{}) (
 		BindingInfo:   "已绑定当前设备",
 		BindCount:     card.RebindCount,
 		MaxBindCount:  cardType.MaxBindCount,
-		CanRebind:     (appSetting.BindPermission == 1 || appSetting.BindPermission == 3) && (cardType.MaxBindCount == 0 || card.RebindCount < cardType.MaxBindCount),
+		CanRebind:     (appSetting.BindPermission == 1 || appSetting.BindPermission == 3) && !rebindExhausted(card, cardType),
 		CanUnbind:     appSetting.BindPermission == 2 || appSetting.BindPermission == 3,
-		Message:       "卡密激活成功",
+		Entitlements:  model.Entitlements(cardType.Entitlements.OrEmpty()),
+		Message:       message,
 	}, nil
 }
 
 // VerifyDevice 验证设备
-func (s *Service) VerifyDevice(req model.ActivateCardRequest, app interface{}) (*model.VerifyDeviceResponse, error) {
+func (s *Service) VerifyDevice(req model.ActivateCardRequest, app interface{}, ip string) (*model.VerifyDeviceResponse, error) {
 	// 类型断言获取应用信息
 	appInfo, ok := app.(dbmodel.App)
 	if !ok {
-		return nil, errors.New("应用信息类型错误")
+		return nil, ErrAppInfoInvalid
 	}
 
 	// 查询设备
@@ -167,40 +385,40 @@ func (s *Service) VerifyDevice(req model.ActivateCardRequest, app interface{}) (
 	result := s.db.Where("device_id = ? AND app_id = ?", req.DeviceID, appInfo.ID).First(&device)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("设备未注册")
+			return nil, ErrDeviceNotFound
 		}
-		return nil, errors.New("查询设备信息失败")
+		return nil, response.NewCodeError(response.CodeServerError, "查询设备信息失败")
 	}
 
 	// 检查设备状态
 	if device.Status != 1 {
-		return nil, errors.New("设备已被禁用")
+		return nil, ErrDeviceDisabled
 	}
 
-	// 更新设备活跃时间
+	// 更新设备活跃时间，只更新该字段，避免覆盖所有者同时修改的名称或状态
 	device.LastActive = time.Now()
-	s.db.Save(&device)
+	s.db.Model(&device).Update("last_active", device.LastActive)
 
 	// 查询关联的卡密
 	var card dbmodel.Card
-	result = s.db.Where("device_id = ? AND app_id = ? AND status = 1", req.DeviceID, appInfo.ID).First(&card)
+	result = s.db.Where("device_id = ? AND app_id = ? AND status IN ?", req.DeviceID, appInfo.ID, []int{dbmodel.CardStatusActive, dbmodel.CardStatusFrozen}).First(&card)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("未找到关联的卡密")
+			return nil, ErrBoundCardNotFound
 		}
-		return nil, errors.New("查询卡密信息失败")
+		return nil, response.NewCodeError(response.CodeServerError, "查询卡密信息失败")
 	}
 
-	// 检查卡密是否过期
-	if card.ExpireAt != nil && card.ExpireAt.Before(time.Now()) {
-		return nil, errors.New("卡密已过期")
+	// 检查卡密是否已冻结或过期
+	if err := cardStatusError(card); err != nil {
+		return nil, s.verifyFailed(card, req.DeviceID, ip, err)
 	}
 
-	// 更新卡密在线状态和心跳时间
+	// 更新卡密在线状态和心跳时间，只更新这两个字段，避免覆盖同时进行的换绑或解绑
 	now := time.Now()
 	card.IsOnline = 1 // 设置为在线
 	card.LastHeartbeat = &now
-	s.db.Save(&card)
+	s.db.Model(&card).Select("is_online", "last_heartbeat").Updates(&card)
 
 	// 计算剩余天数
 	remainDays := 0
@@ -210,20 +428,21 @@ func (s *Service) VerifyDevice(req model.ActivateCardRequest, app interface{}) (
 
 	// 返回验证成功响应
 	return &model.VerifyDeviceResponse{
-		Success:    true,
-		CardNo:     card.CardNo,
-		ExpireAt:   card.ExpireAt,
-		RemainDays: remainDays,
-		Message:    "设备验证成功",
+		Success:      true,
+		CardNo:       card.CardNo,
+		ExpireAt:     card.ExpireAt,
+		RemainDays:   remainDays,
+		Entitlements: s.entitlements(card.TypeID),
+		Message:      "设备验证成功",
 	}, nil
 }
 
 // RebindCard 换绑卡密
-func (s *Service) RebindCard(req model.RebindCardRequest, app interface{}) (*model.RebindCardResponse, error) {
+func (s *Service) RebindCard(req model.RebindCardRequest, app interface{}, ip string) (*model.RebindCardResponse, error) {
 	// 类型断言获取应用信息
 	appInfo, ok := app.(dbmodel.App)
 	if !ok {
-		return nil, errors.New("应用信息类型错误")
+		return nil, ErrAppInfoInvalid
 	}
 
 	// 查询卡密
@@ -231,93 +450,63 @@ func (s *Service) RebindCard(req model.RebindCardRequest, app interface{}) (*mod
 	result := s.db.Where("card_no = ? AND app_id = ?", req.CardNo, appInfo.ID).First(&card)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("卡密不存在")
+			return nil, ErrCardNotFound
 		}
 		return nil, result.Error
 	}
 
 	// 检查卡密状态
-	if card.Status != 1 {
-		return nil, errors.New("卡密未激活")
-	}
-
-	// 检查卡密是否已过期
-	if card.ExpireAt != nil && card.ExpireAt.Before(time.Now()) {
-		return nil, errors.New("卡密已过期")
-	}
-
-	// 检查卡密是否已禁用
-	if card.Status == 2 {
-		return nil, errors.New("卡密已被禁用")
+	if err := cardStatusError(card); err != nil {
+		return nil, err
 	}
 
 	// 检查卡密是否已绑定设备
 	if card.DeviceID == nil {
-		return nil, errors.New("卡密未绑定设备")
+		return nil, ErrCardUnbound
 	}
 
-	// 查询应用设置
-	var appSetting dbmodel.App
-	result = s.db.Where("app_id = ?", appInfo.ID).First(&appSetting)
-	if result.Error != nil {
-		return nil, errors.New("获取应用设置失败")
-	}
+	// 应用设置即为当前认证的应用
+	appSetting := appInfo
 
 	// 检查应用是否允许换绑
 	if appSetting.BindPermission != 1 && appSetting.BindPermission != 3 {
-		return nil, errors.New("应用不允许换绑")
+		return nil, ErrRebindNotAllowed
 	}
 
 	// 查询卡密类型
 	var cardType dbmodel.CardType
 	result = s.db.First(&cardType, card.TypeID)
 	if result.Error != nil {
-		return nil, errors.New("卡密类型不存在")
+		return nil, ErrCardTypeNotFound
 	}
 
 	// 检查换绑次数限制
-	if cardType.MaxBindCount > 0 && card.RebindCount >= cardType.MaxBindCount {
-		return nil, errors.New("已达到最大换绑次数限制")
-	}
-
-	// 查询或创建新设备
-	var device dbmodel.Device
-	result = s.db.Where("device_id = ? AND app_id = ?", req.DeviceID, appInfo.ID).First(&device)
-	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		// 创建新设备
-		device = dbmodel.Device{
-			DeviceID:   req.DeviceID,
-			AppID:      appInfo.ID,
-			DeviceInfo: req.DeviceInfo,
-			Status:     1, // 正常状态
-			LastActive: time.Now(),
-		}
-		result = s.db.Create(&device)
-		if result.Error != nil {
-			return nil, errors.New("创建设备记录失败")
-		}
-	} else if result.Error != nil {
-		return nil, errors.New("查询设备信息失败")
-	} else {
-		// 更新设备信息
-		device.DeviceInfo = req.DeviceInfo
-		device.LastActive = time.Now()
-		s.db.Save(&device)
-	}
-
-	// 检查设备状态
-	if device.Status != 1 {
-		return nil, errors.New("设备已被禁用")
+	if rebindExhausted(card, cardType) {
+		return nil, ErrRebindLimit
 	}
 
 	// 更新卡密信息
+	oldDeviceID := *card.DeviceID
 	card.DeviceID = &req.DeviceID
 	card.RebindCount++
 
-	result = s.db.Save(&card)
-	if result.Error != nil {
-		return nil, errors.New("更新卡密信息失败")
+	// 在同一事务中登记新设备并保存卡密，卡密已被其他请求修改时整体回滚
+	err := database.Transaction(func(tx *gorm.DB) error {
+		device, err := findOrCreateDevice(tx, req.DeviceID, req.DeviceInfo, appInfo.ID)
+		if err != nil {
+			return err
+		}
+		// 检查设备状态
+		if device.Status != 1 {
+			return ErrDeviceDisabled
+		}
+		return card.SaveWithVersion(tx, card.Status)
+	})
+	if err != nil {
+		return nil, saveCardError(err)
 	}
+	s.recordEvent(dbmodel.NewCardEvent(card, dbmodel.CardEventRebind, dbmodel.CardActorClient, 0, ip).
+		WithChange(map[string]interface{}{"device_id": oldDeviceID}, map[string]interface{}{"device_id": req.DeviceID}))
 
 	// 返回换绑成功响应
 	return &model.RebindCardResponse{
@@ -329,49 +518,62 @@ func (s *Service) RebindCard(req model.RebindCardRequest, app interface{}) (*mod
 }
 
 // Heartbeat 处理客户端心跳
-func (s *Service) Heartbeat(req model.HeartbeatRequest, app interface{}) (*model.HeartbeatResponse, error) {
+func (s *Service) Heartbeat(req model.HeartbeatRequest, app interface{}, ip string) (*model.HeartbeatResponse, error) {
 	// 类型断言获取应用信息
 	appInfo, ok := app.(dbmodel.App)
 	if !ok {
-		return nil, errors.New("应用信息类型错误")
+		return nil, ErrAppInfoInvalid
 	}
 
 	// 查询卡密
 	var card dbmodel.Card
-	result := s.db.Where("card_no = ? AND app_id = ? AND status = 1", req.CardNo, appInfo.ID).First(&card)
+	result := s.db.Where("card_no = ? AND app_id = ? AND status IN ?", req.CardNo, appInfo.ID, []int{dbmodel.CardStatusActive, dbmodel.CardStatusFrozen}).First(&card)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("卡密不存在或未激活")
+			return nil, ErrCardNotActive
 		}
-		return nil, errors.New("查询卡密信息失败")
+		return nil, response.NewCodeError(response.CodeServerError, "查询卡密信息失败")
+	}
+
+	// 检查卡密是否已冻结
+	if card.Status == dbmodel.CardStatusFrozen {
+		return nil, s.verifyFailed(card, req.DeviceID, ip, ErrCardFrozen)
 	}
 
 	// 检查设备ID是否匹配
 	if card.DeviceID == nil || *card.DeviceID != req.DeviceID {
-		return nil, errors.New("设备ID不匹配")
+		return nil, s.verifyFailed(card, req.DeviceID, ip, ErrDeviceMismatch)
 	}
 
 	// 检查卡密是否过期
-	if card.ExpireAt != nil && card.ExpireAt.Before(time.Now()) {
-		// 更新卡密状态为已过期
-		card.Status = 2   // 已过期
-		card.IsOnline = 0 // 设置为离线
-		s.db.Save(&card)
-		return nil, errors.New("卡密已过期")
+	if card.IsExpired() {
+		// 更新卡密状态为已过期，同时设置为离线
+		if err := card.Transit(dbmodel.CardStatusExpired); err != nil {
+			return nil, response.NewCodeError(response.CodeServerError, err.Error())
+		}
+		s.db.Model(&card).Where("status = ?", dbmodel.CardStatusActive).Select("status", "is_online").Updates(&card)
+		s.recordEvent(dbmodel.NewCardEvent(card, dbmodel.CardEventExpire, dbmodel.CardActorSystem, 0, ip).
+			WithChange(map[string]interface{}{"status": dbmodel.CardStatusActive},
+				map[string]interface{}{"status": dbmodel.CardStatusExpired, "expire_at": card.ExpireAt}))
+		return nil, ErrCardExpired
+	}
+
+	// 检查设备状态，设备被所有者禁用后不再接受心跳
+	var device dbmodel.Device
+	result = s.db.Where("device_id = ? AND app_id = ?", req.DeviceID, appInfo.ID).First(&device)
+	if result.Error == nil && device.Status != 1 {
+		return nil, s.verifyFailed(card, req.DeviceID, ip, ErrDeviceDisabled)
 	}
 
-	// 更新卡密在线状态和心跳时间
+	// 更新卡密在线状态和心跳时间，只更新这两个字段，避免覆盖同时进行的换绑或解绑
 	now := time.Now()
 	card.IsOnline = 1 // 设置为在线
 	card.LastHeartbeat = &now
-	s.db.Save(&card)
+	s.db.Model(&card).Select("is_online", "last_heartbeat").Updates(&card)
 
 	// 更新设备活跃时间
-	var device dbmodel.Device
-	result = s.db.Where("device_id = ? AND app_id = ?", req.DeviceID, appInfo.ID).First(&device)
 	if result.Error == nil {
-		device.LastActive = now
-		s.db.Save(&device)
+		s.db.Model(&device).Update("last_active", now)
 	}
 
 	// 计算剩余天数
@@ -385,21 +587,30 @@ func (s *Service) Heartbeat(req model.HeartbeatRequest, app interface{}) (*model
 
 	// 返回心跳响应
 	return &model.HeartbeatResponse{
-		Success:    true,
-		CardNo:     card.CardNo,
-		IsOnline:   true,
-		ExpireAt:   card.ExpireAt,
-		RemainDays: remainDays,
-		Message:    "心跳成功",
+		Success:      true,
+		CardNo:       card.CardNo,
+		IsOnline:     true,
+		ExpireAt:     card.ExpireAt,
+		RemainDays:   remainDays,
+		Entitlements: s.entitlements(card.TypeID),
+		Message:      "心跳成功",
 	}, nil
 }
 
+// rebindExhausted 判断卡密是否已用完换绑次数，卡密类型和卡密自身的上限都生效，0 表示不限制
+func rebindExhausted(card dbmodel.Card, cardType dbmodel.CardType) bool {
+	if cardType.MaxBindCount > 0 && card.RebindCount >= cardType.MaxBindCount {
+		return true
+	}
+	return card.MaxRebindCount > 0 && card.RebindCount >= card.MaxRebindCount
+}
+
 // UnbindCard 解绑卡密
-func (s *Service) UnbindCard(req model.UnbindCardRequest, app interface{}) (*model.UnbindCardResponse, error) {
+func (s *Service) UnbindCard(req model.UnbindCardRequest, app interface{}, ip string) (*model.UnbindCardResponse, error) {
 	// 类型断言获取应用信息
 	appInfo, ok := app.(dbmodel.App)
 	if !ok {
-		return nil, errors.New("应用信息类型错误")
+		return nil, ErrAppInfoInvalid
 	}
 
 	// 查询卡密
@@ -407,51 +618,48 @@ func (s *Service) UnbindCard(req model.UnbindCardRequest, app interface{}) (*mod
 	result := s.db.Where("card_no = ? AND app_id = ?", req.CardNo, appInfo.ID).First(&card)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("卡密不存在")
+			return nil, ErrCardNotFound
 		}
 		return nil, result.Error
 	}
 
 	// 检查卡密状态
-	if card.Status != 1 {
-		return nil, errors.New("卡密未激活")
-	}
-
-	// 检查卡密是否已过期
-	if card.ExpireAt != nil && card.ExpireAt.Before(time.Now()) {
-		return nil, errors.New("卡密已过期")
-	}
-
-	// 检查卡密是否已禁用
-	if card.Status == 2 {
-		return nil, errors.New("卡密已被禁用")
+	if err := cardStatusError(card); err != nil {
+		return nil, err
 	}
 
 	// 检查卡密是否已绑定设备
 	if card.DeviceID == nil {
-		return nil, errors.New("卡密未绑定设备")
+		return nil, ErrCardUnbound
 	}
 
-	// 查询应用设置
-	var appSetting dbmodel.App
-	result = s.db.Where("app_id = ?", appInfo.ID).First(&appSetting)
-	if result.Error != nil {
-		return nil, errors.New("获取应用设置失败")
-	}
+	// 应用设置即为当前认证的应用
+	appSetting := appInfo
 
 	// 检查应用是否允许解绑
 	if appSetting.BindPermission != 2 && appSetting.BindPermission != 3 {
-		return nil, errors.New("应用不允许解绑")
+		return nil, ErrUnbindNotAllowed
 	}
 
-	// 更新卡密信息
+	// 检查解绑次数限制，0 表示不限制
+	if card.MaxUnbindCount > 0 && card.UnbindCount >= card.MaxUnbindCount {
+		return nil, ErrUnbindLimit
+	}
+
+	// 更新卡密信息，解绑后仍为已使用状态，重新激活时绑定新设备并保留过期时间
+	event := dbmodel.NewCardEvent(card, dbmodel.CardEventUnbind, dbmodel.CardActorClient, 0, ip).
+		WithChange(map[string]interface{}{"device_id": *card.DeviceID}, nil)
 	card.DeviceID = nil
-	card.Status = 0 // 重置为未使用状态
+	card.IsOnline = 0
+	card.UnbindCount++
 
-	result = s.db.Save(&card)
-	if result.Error != nil {
-		return nil, errors.New("更新卡密信息失败")
+	err := database.Transaction(func(tx *gorm.DB) error {
+		return card.SaveWithVersion(tx, card.Status)
+	})
+	if err != nil {
+		return nil, saveCardError(err)
 	}
+	s.recordEvent(event)
 
 	// 返回解绑成功响应
 	return &model.UnbindCardResponse{
@@ -0,0 +1,386 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/skyle1995/DevE-Server/apps/client/model"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/utils/cardcode"
+	"github.com/skyle1995/DevE-Server/utils/random"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// concurrentDevices 并发测试中同时请求的设备数量
+const concurrentDevices = 10
+
+var fixtureCount int32
+
+// TestMain 默认使用临时SQLite数据库；设置环境变量 DEVE_TEST_MYSQL=user:password@host:port/database 时使用MySQL
+func TestMain(m *testing.M) {
+	viper.Set("server.mode", "test")
+
+	dir := ""
+	if dsn := os.Getenv("DEVE_TEST_MYSQL"); dsn != "" {
+		u, err := url.Parse("mysql://" + dsn)
+		if err != nil {
+			panic("DEVE_TEST_MYSQL格式错误: " + err.Error())
+		}
+		password, _ := u.User.Password()
+		port := u.Port()
+		if port == "" {
+			port = "3306"
+		}
+		viper.Set("database.type", "MySQL")
+		viper.Set("database.mysql.host", u.Hostname())
+		viper.Set("database.mysql.port", port)
+		viper.Set("database.mysql.username", u.User.Username())
+		viper.Set("database.mysql.password", password)
+		viper.Set("database.mysql.database", strings.TrimPrefix(u.Path, "/"))
+		viper.Set("database.mysql.charset", "utf8mb4")
+		viper.Set("database.mysql.max_idle_conns", concurrentDevices)
+		viper.Set("database.mysql.max_open_conns", concurrentDevices*2)
+	} else {
+		var err error
+		dir, err = os.MkdirTemp("", "deve-client-test")
+		if err != nil {
+			panic(err)
+		}
+		viper.Set("database.type", "SQLite")
+		viper.Set("database.sqlite.path", dir+"/test.db")
+	}
+	database.Init()
+
+	if err := database.NewMigration().AutoMigrate(); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	database.Close()
+	if dir != "" {
+		os.RemoveAll(dir)
+	}
+	os.Exit(code)
+}
+
+// newCard 创建应用、卡密类型和一张未使用的卡密
+func newCard(t *testing.T) (dbmodel.App, dbmodel.Card) {
+	t.Helper()
+
+	name := fmt.Sprintf("%s_%d", t.Name(), atomic.AddInt32(&fixtureCount, 1))
+	app := dbmodel.App{
+		Name:           name,
+		AppKey:         "key_" + name,
+		AppSecret:      "secret_" + name,
+		Status:         1,
+		UserID:         1,
+		BindPermission: 3,
+	}
+	if err := database.DB.Create(&app).Error; err != nil {
+		t.Fatalf("创建应用失败: %v", err)
+	}
+
+	cardType := dbmodel.CardType{Name: name, Duration: 30, TimeUnit: "day", ValidDays: 30, AppID: app.ID, UserID: 1, Status: 1}
+	if err := database.DB.Create(&cardType).Error; err != nil {
+		t.Fatalf("创建卡密类型失败: %v", err)
+	}
+
+	card := dbmodel.Card{
+		CardNo:  name + "_NO",
+		CardKey: name + "_KEY",
+		TypeID:  cardType.ID,
+		AppID:   app.ID,
+		UserID:  1,
+	}
+	if err := database.DB.Create(&card).Error; err != nil {
+		t.Fatalf("创建卡密失败: %v", err)
+	}
+	return app, card
+}
+
+// readBarrier 让前n次查询卡密的请求等待，直到n个请求都查询完成后再继续
+// 保证并发请求都基于相同的卡密状态进行修改，否则先完成的请求可能在其他请求查询前就已提交
+func readBarrier(t *testing.T, n int) {
+	t.Helper()
+
+	var reads int32
+	all := make(chan struct{})
+	name := "test:card_read_barrier_" + t.Name()
+	err := database.DB.Callback().Query().After("gorm:query").Register(name, func(db *gorm.DB) {
+		if db.Statement.Table != "cards" {
+			return
+		}
+		switch count := atomic.AddInt32(&reads, 1); {
+		case count == int32(n):
+			close(all)
+		case count < int32(n):
+			select {
+			case <-all:
+			case <-time.After(10 * time.Second):
+			}
+		}
+	})
+	if err != nil {
+		t.Fatalf("注册查询回调失败: %v", err)
+	}
+	t.Cleanup(func() {
+		database.DB.Callback().Query().Remove(name)
+	})
+}
+
+// concurrently 让每台设备同时执行一次请求，返回每台设备的错误
+func concurrently(devices int, request func(device int) error) []error {
+	errs := make([]error, devices)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < devices; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = request(i)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+// countEvents 统计卡密指定类型的事件数量
+func countEvents(t *testing.T, cardID uint, eventType string) int64 {
+	t.Helper()
+
+	var count int64
+	if err := database.DB.Model(&dbmodel.CardEvent{}).Where("card_id = ? AND type = ?", cardID, eventType).Count(&count).Error; err != nil {
+		t.Fatalf("查询卡密事件失败: %v", err)
+	}
+	return count
+}
+
+func TestActivateCardConcurrent(t *testing.T) {
+	app, card := newCard(t)
+	service := NewService()
+
+	readBarrier(t, concurrentDevices)
+	errs := concurrently(concurrentDevices, func(device int) error {
+		_, err := service.ActivateCard(model.ActivateCardRequest{
+			CardNo:   card.CardNo,
+			CardKey:  card.CardKey,
+			DeviceID: fmt.Sprintf("%s_device_%d", app.Name, device),
+		}, app, "127.0.0.1")
+		return err
+	})
+
+	winner := -1
+	for device, err := range errs {
+		switch {
+		case err == nil:
+			if winner >= 0 {
+				t.Fatalf("设备%d和设备%d都激活成功", winner, device)
+			}
+			winner = device
+		case errors.Is(err, ErrCardBoundOther), errors.Is(err, ErrCardConflict):
+		default:
+			t.Errorf("设备%d激活返回意外错误: %v", device, err)
+		}
+	}
+	if winner < 0 {
+		t.Fatal("没有设备激活成功")
+	}
+
+	var saved dbmodel.Card
+	if err := database.DB.First(&saved, card.ID).Error; err != nil {
+		t.Fatalf("查询卡密失败: %v", err)
+	}
+	if saved.Status != dbmodel.CardStatusActive {
+		t.Errorf("卡密状态为%d，期望为已使用", saved.Status)
+	}
+	if want := fmt.Sprintf("%s_device_%d", app.Name, winner); saved.DeviceID == nil || *saved.DeviceID != want {
+		t.Errorf("卡密绑定的设备为%v，期望为%s", saved.DeviceID, want)
+	}
+	if saved.Version != 1 {
+		t.Errorf("卡密版本号为%d，期望只保存一次", saved.Version)
+	}
+	if n := countEvents(t, card.ID, dbmodel.CardEventActivate); n != 1 {
+		t.Errorf("记录了%d条激活事件，期望为1条", n)
+	}
+}
+
+func TestRebindCardConcurrent(t *testing.T) {
+	app, card := newCard(t)
+	service := NewService()
+
+	_, err := service.ActivateCard(model.ActivateCardRequest{
+		CardNo:   card.CardNo,
+		CardKey:  card.CardKey,
+		DeviceID: app.Name + "_origin",
+	}, app, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("激活卡密失败: %v", err)
+	}
+
+	readBarrier(t, concurrentDevices)
+	errs := concurrently(concurrentDevices, func(device int) error {
+		_, err := service.RebindCard(model.RebindCardRequest{
+			CardNo:   card.CardNo,
+			DeviceID: fmt.Sprintf("%s_device_%d", app.Name, device),
+		}, app, "127.0.0.1")
+		return err
+	})
+
+	succeeded := 0
+	for device, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, ErrCardConflict):
+		default:
+			t.Errorf("设备%d换绑返回意外错误: %v", device, err)
+		}
+	}
+
+	var saved dbmodel.Card
+	if err := database.DB.First(&saved, card.ID).Error; err != nil {
+		t.Fatalf("查询卡密失败: %v", err)
+	}
+	// 所有请求都基于同一次查询的结果换绑，只能有一个请求成功
+	if succeeded != 1 {
+		t.Errorf("%d个设备换绑成功，期望为1个", succeeded)
+	}
+	if saved.RebindCount != succeeded {
+		t.Errorf("换绑次数为%d，成功换绑%d次", saved.RebindCount, succeeded)
+	}
+	if n := countEvents(t, card.ID, dbmodel.CardEventRebind); n != int64(succeeded) {
+		t.Errorf("记录了%d条换绑事件，成功换绑%d次", n, succeeded)
+	}
+}
+
+func TestActivateSameDeviceAcrossApps(t *testing.T) {
+	const apps = 3
+	deviceID := t.Name() + "_device"
+	service := NewService()
+
+	appList := make([]dbmodel.App, apps)
+	cards := make([]dbmodel.Card, apps)
+	for i := range appList {
+		appList[i], cards[i] = newCard(t)
+	}
+
+	// 同一台设备同时激活多个应用的卡密，每个应用分别登记设备
+	errs := concurrently(apps, func(i int) error {
+		_, err := service.ActivateCard(model.ActivateCardRequest{
+			CardNo:   cards[i].CardNo,
+			CardKey:  cards[i].CardKey,
+			DeviceID: deviceID,
+		}, appList[i], "127.0.0.1")
+		return err
+	})
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("应用%d激活卡密失败: %v", i, err)
+		}
+	}
+
+	var devices []dbmodel.Device
+	if err := database.DB.Where("device_id = ?", deviceID).Find(&devices).Error; err != nil {
+		t.Fatalf("查询设备失败: %v", err)
+	}
+	if len(devices) != apps {
+		t.Fatalf("登记了%d条设备记录，期望每个应用一条共%d条", len(devices), apps)
+	}
+	registered := make(map[uint]dbmodel.Device, apps)
+	for _, device := range devices {
+		registered[device.AppID] = device
+	}
+	for i, app := range appList {
+		if _, ok := registered[app.ID]; !ok {
+			t.Errorf("应用%d没有登记设备", i)
+		}
+	}
+
+	// 在第一个应用中禁用设备，不影响其他应用
+	if err := database.DB.Model(&dbmodel.Device{}).Where("id = ?", registered[appList[0].ID].ID).Update("status", 0).Error; err != nil {
+		t.Fatalf("禁用设备失败: %v", err)
+	}
+	for i, app := range appList {
+		_, err := service.VerifyDevice(model.ActivateCardRequest{DeviceID: deviceID}, app, "127.0.0.1")
+		switch {
+		case i == 0 && !errors.Is(err, ErrDeviceDisabled):
+			t.Errorf("应用%d中已禁用的设备验证返回%v，期望为设备已被禁用", i, err)
+		case i > 0 && err != nil:
+			t.Errorf("应用%d验证设备失败: %v", i, err)
+		}
+	}
+
+	// 同一应用中再次激活时复用已登记的设备
+	last := dbmodel.Card{
+		CardNo:  cards[1].CardNo + "_2",
+		CardKey: cards[1].CardKey + "_2",
+		TypeID:  cards[1].TypeID,
+		AppID:   appList[1].ID,
+		UserID:  1,
+	}
+	if err := database.DB.Create(&last).Error; err != nil {
+		t.Fatalf("创建卡密失败: %v", err)
+	}
+	if _, err := service.ActivateCard(model.ActivateCardRequest{
+		CardNo:   last.CardNo,
+		CardKey:  last.CardKey,
+		DeviceID: deviceID,
+	}, appList[1], "127.0.0.1"); err != nil {
+		t.Fatalf("再次激活卡密失败: %v", err)
+	}
+	var count int64
+	if err := database.DB.Model(&dbmodel.Device{}).Where("device_id = ? AND app_id = ?", deviceID, appList[1].ID).Count(&count).Error; err != nil {
+		t.Fatalf("查询设备失败: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("应用内登记了%d条相同的设备记录，期望为1条", count)
+	}
+}
+
+func TestActivateLegacyCardWithChecksumTemplate(t *testing.T) {
+	app, card := newCard(t)
+	service := NewService()
+
+	// 应用所有者在已有卡密之后创建了开启校验位的默认卡号模板
+	template := dbmodel.CardTemplate{UserID: 1, Name: t.Name(), Layout: "XXXXXXXXXX", Charset: cardcode.CharsetAlnum,
+		Case: cardcode.CaseUpper, Checksum: true, DefaultFor: dbmodel.CardTemplateForCardNo}
+	if err := database.DB.Create(&template).Error; err != nil {
+		t.Fatalf("创建卡号模板失败: %v", err)
+	}
+	t.Cleanup(func() { database.DB.Unscoped().Delete(&template) })
+	format := cardcode.Format{Layout: template.Layout, Charset: template.Charset, Case: template.Case, Checksum: true}
+
+	// 旧卡号恰好符合模板布局但不能通过校验
+	legacyNo := "LEGACY0000"
+	for format.Verify(legacyNo) {
+		legacyNo = "LEGACY" + random.String(4, "0123456789")
+	}
+	if err := database.DB.Model(&card).Update("card_no", legacyNo).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.ActivateCard(model.ActivateCardRequest{CardNo: legacyNo, CardKey: card.CardKey, DeviceID: app.Name}, app, ""); err != nil {
+		t.Fatalf("模板创建前的卡密激活失败: %v", err)
+	}
+
+	// 不存在的卡号按校验位区分输入错误
+	valid := format.Generate()
+	mistyped := []byte(valid)
+	mistyped[0] = map[bool]byte{true: 'B', false: 'A'}[mistyped[0] == 'A']
+	cases := map[string]error{valid: ErrCardNotFound, string(mistyped): ErrCardNoMistyped}
+	for cardNo, want := range cases {
+		if _, err := service.ActivateCard(model.ActivateCardRequest{CardNo: cardNo, CardKey: "x", DeviceID: app.Name}, app, ""); !errors.Is(err, want) {
+			t.Errorf("卡号 %s 激活返回 %v，期望 %v", cardNo, err, want)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package device
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	cardmodel "github.com/skyle1995/DevE-Server/apps/card/model"
+	"github.com/skyle1995/DevE-Server/apps/device/model"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/middleware"
+	"github.com/skyle1995/DevE-Server/utils/response"
+)
+
+// Controller 设备控制器
+type Controller struct {
+	service *Service
+}
+
+// NewController 创建一个新的设备控制器实例
+func NewController() *Controller {
+	return &Controller{
+		service: NewService(),
+	}
+}
+
+// currentUser 获取当前用户ID以及是否可以管理所有设备，拥有该权限的用户可以管理所有用户应用下的设备
+func currentUser(ctx *gin.Context) (int, bool, bool) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.FailWithMessage("未找到用户信息", ctx)
+		return 0, false, false
+	}
+	isAdmin := middleware.HasPermission(ctx, dbmodel.PermissionDeviceManageAll)
+	return int(userID.(uint)), isAdmin, true
+}
+
+// deviceID 解析路径中的设备ID
+func deviceID(ctx *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		response.FailWithMessage("设备ID格式错误", ctx)
+		return 0, false
+	}
+	return id, true
+}
+
+// GetDevices 获取设备列表
+// @Summary 获取设备列表
+// @Description 获取当前用户应用下的设备，拥有管理所有设备权限的用户可以查看所有用户的设备
+// @Tags 用户API
+// @Accept json
+// @Produce json
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页数量，默认20，最大100"
+// @Param app_id query int false "应用ID"
+// @Param user_id query int false "应用所有者ID，仅拥有管理所有设备权限时有效"
+// @Param status query int false "状态：1-正常，0-禁用"
+// @Param online query bool false "是否在线"
+// @Param active_after query string false "最后活跃时间起，格式 2006-01-02 15:04:05"
+// @Param active_before query string false "最后活跃时间止，格式 2006-01-02 15:04:05"
+// @Param ip query string false "IP地址，模糊查询"
+// @Param keyword query string false "设备ID或设备名称，模糊查询"
+// @Success 200 {object} response.Response{data=model.DeviceListResponse} "成功"
+// @Router /api/v1/devices [get]
+func (c *Controller) GetDevices(ctx *gin.Context) {
+	userID, isAdmin, ok := currentUser(ctx)
+	if !ok {
+		return
+	}
+
+	var req model.GetDeviceListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), ctx)
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PageSize < 1 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+
+	items, total, err := c.service.GetDeviceList(req, userID, isAdmin)
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	response.OkWithData(model.DeviceListResponse{
+		Total: int(total),
+		Items: items,
+	}, ctx)
+}
+
+// GetDevice 获取设备详情
+// @Summary 获取设备详情
+// @Description 获取设备信息、当前绑定的卡密和最近的卡密事件
+// @Tags 用户API
+// @Accept json
+// @Produce json
+// @Param id path int true "设备ID"
+// @Success 200 {object} response.Response{data=model.DeviceDetailResponse} "成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Router /api/v1/devices/{id} [get]
+func (c *Controller) GetDevice(ctx *gin.Context) {
+	userID, isAdmin, ok := currentUser(ctx)
+	if !ok {
+		return
+	}
+	id, ok := deviceID(ctx)
+	if !ok {
+		return
+	}
+
+	detail, err := c.service.GetDevice(id, userID, isAdmin)
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	response.OkWithData(detail, ctx)
+}
+
+// GetDeviceEvents 获取设备事件
+// @Summary 获取设备事件
+// @Description 按时间倒序获取设备上发生的卡密事件，包括已解绑的卡密
+// @Tags 用户API
+// @Accept json
+// @Produce json
+// @Param id path int true "设备ID"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页数量，默认20，最大100"
+// @Param type query string false "事件类型"
+// @Success 200 {object} response.Response{data=cardmodel.CardEventListResponse} "成功"
+// @Router /api/v1/devices/{id}/events [get]
+func (c *Controller) GetDeviceEvents(ctx *gin.Context) {
+	userID, isAdmin, ok := currentUser(ctx)
+	if !ok {
+		return
+	}
+	id, ok := deviceID(ctx)
+	if !ok {
+		return
+	}
+
+	var req model.GetDeviceEventListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		response.FailWithMessage("参数错误: "+err.Error(), ctx)
+		return
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PageSize < 1 || req.PageSize > 100 {
+		req.PageSize = 20
+	}
+
+	events, total, err := c.service.GetDeviceEvents(id, req, userID, isAdmin)
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	items := make([]cardmodel.CardEventResponse, len(events))
+	for i, event := range events {
+		items[i] = cardmodel.FromCardEvent(event)
+	}
+	response.OkWithData(cardmodel.CardEventListResponse{
+		Total: int(total),
+		Items: items,
+	}, ctx)
+}
+
+// UpdateDevice 修改设备名称
+// @Summary 修改设备名称
+// @Description 修改设备的备注名称，名称为空时清除
+// @Tags 用户API
+// @Accept json
+// @Produce json
+// @Param id path int true "设备ID"
+// @Param request body model.UpdateDeviceRequest true "更新设备请求"
+// @Success 200 {object} response.Response{data=model.DeviceResponse} "更新成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Router /api/v1/devices/{id} [put]
+func (c *Controller) UpdateDevice(ctx *gin.Context) {
+	userID, isAdmin, ok := currentUser(ctx)
+	if !ok {
+		return
+	}
+	id, ok := deviceID(ctx)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateDeviceRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("请求参数错误: "+err.Error(), ctx)
+		return
+	}
+
+	device, err := c.service.UpdateDevice(id, req, userID, isAdmin)
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	response.OkWithDetailed(device, "更新成功", ctx)
+}
+
+// UpdateDeviceStatus 启用或禁用设备
+// @Summary 启用或禁用设备
+// @Description 禁用后设备无法激活、验证、心跳或作为换绑目标，设备上的卡密设为离线
+// @Tags 用户API
+// @Accept json
+// @Produce json
+// @Param id path int true "设备ID"
+// @Param request body model.UpdateDeviceStatusRequest true "设备状态"
+// @Success 200 {object} response.Response{data=model.DeviceResponse} "更新成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Router /api/v1/devices/{id}/status [put]
+func (c *Controller) UpdateDeviceStatus(ctx *gin.Context) {
+	userID, isAdmin, ok := currentUser(ctx)
+	if !ok {
+		return
+	}
+	id, ok := deviceID(ctx)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateDeviceStatusRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.FailWithMessage("请求参数错误: "+err.Error(), ctx)
+		return
+	}
+
+	device, err := c.service.UpdateDeviceStatus(id, *req.Status, userID, isAdmin)
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	message := "设备已启用"
+	if device.Status != 1 {
+		message = "设备已禁用"
+	}
+	response.OkWithDetailed(device, message, ctx)
+}
+
+// UnbindDevice 强制解绑设备
+// @Summary 强制解绑设备
+// @Description 解除设备上所有卡密的绑定，不计入卡密的解绑次数
+// @Tags 用户API
+// @Accept json
+// @Produce json
+// @Param id path int true "设备ID"
+// @Success 200 {object} response.Response{data=model.DeviceUnbindResponse} "解绑成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Router /api/v1/devices/{id}/unbind [post]
+func (c *Controller) UnbindDevice(ctx *gin.Context) {
+	userID, isAdmin, ok := currentUser(ctx)
+	if !ok {
+		return
+	}
+	id, ok := deviceID(ctx)
+	if !ok {
+		return
+	}
+
+	count, err := c.service.UnbindDevice(id, userID, isAdmin, ctx.ClientIP())
+	if err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	response.OkWithDetailed(model.DeviceUnbindResponse{Count: count}, "解绑成功", ctx)
+}
+
+// DeleteDevice 删除设备
+// @Summary 删除设备
+// @Description 删除设备并解绑设备上的所有卡密，同一设备再次激活时重新登记
+// @Tags 用户API
+// @Accept json
+// @Produce json
+// @Param id path int true "设备ID"
+// @Success 200 {object} response.Response "删除成功"
+// @Failure 400 {object} response.Response "请求参数错误"
+// @Router /api/v1/devices/{id} [delete]
+func (c *Controller) DeleteDevice(ctx *gin.Context) {
+	userID, isAdmin, ok := currentUser(ctx)
+	if !ok {
+		return
+	}
+	id, ok := deviceID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.DeleteDevice(id, userID, isAdmin, ctx.ClientIP()); err != nil {
+		response.FailWithMessage(err.Error(), ctx)
+		return
+	}
+
+	response.OkWithMessage("删除成功", ctx)
+}
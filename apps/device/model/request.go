@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// GetDeviceListRequest 获取设备列表请求
+type GetDeviceListRequest struct {
+	Page         int        `form:"page" json:"page"`                                                     // 页码
+	PageSize     int        `form:"page_size" json:"page_size"`                                           // 每页数量
+	AppID        uint       `form:"app_id" json:"app_id"`                                                 // 应用ID，可选
+	UserID       uint       `form:"user_id" json:"user_id"`                                               // 应用所有者ID，可选，仅管理员有效
+	Status       *int       `form:"status" json:"status" binding:"omitempty,oneof=0 1"`                   // 状态，可选：1-正常，0-禁用
+	Online       *bool      `form:"online" json:"online"`                                                 // 是否在线，可选，按绑定卡密的在线状态判断
+	ActiveAfter  *time.Time `form:"active_after" json:"active_after" time_format:"2006-01-02 15:04:05"`   // 最后活跃时间起，可选
+	ActiveBefore *time.Time `form:"active_before" json:"active_before" time_format:"2006-01-02 15:04:05"` // 最后活跃时间止，可选
+	IP           string     `form:"ip" json:"ip"`                                                         // IP地址，可选，模糊查询
+	Keyword      string     `form:"keyword" json:"keyword"`                                               // 设备ID或设备名称，可选，模糊查询
+}
+
+// UpdateDeviceRequest 更新设备请求
+type UpdateDeviceRequest struct {
+	DeviceName string `json:"device_name" binding:"max=100"` // 设备名称，为空时清除
+}
+
+// UpdateDeviceStatusRequest 启用/禁用设备请求
+type UpdateDeviceStatusRequest struct {
+	Status *int `json:"status" binding:"required,oneof=0 1"` // 状态：1-正常，0-禁用
+}
+
+// GetDeviceEventListRequest 获取设备事件请求
+type GetDeviceEventListRequest struct {
+	Page     int    `form:"page" json:"page"`           // 页码
+	PageSize int    `form:"page_size" json:"page_size"` // 每页数量
+	Type     string `form:"type" json:"type"`           // 事件类型，可选
+}
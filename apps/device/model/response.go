@@ -0,0 +1,98 @@
+package model
+
+import (
+	"time"
+
+	cardmodel "github.com/skyle1995/DevE-Server/apps/card/model"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+)
+
+// DeviceResponse 设备响应
+type DeviceResponse struct {
+	ID         uint                   `json:"id"`
+	DeviceID   string                 `json:"device_id"`   // 设备唯一标识
+	DeviceName string                 `json:"device_name"` // 设备名称
+	DeviceType string                 `json:"device_type"` // 设备类型
+	DeviceOS   string                 `json:"device_os"`   // 操作系统
+	DeviceIP   string                 `json:"device_ip"`   // IP地址
+	DeviceInfo map[string]interface{} `json:"device_info"` // 客户端上报的设备信息
+	LastActive time.Time              `json:"last_active"` // 最后活跃时间
+	Status     int                    `json:"status"`      // 状态：1-正常，0-禁用
+	AppID      uint                   `json:"app_id"`
+	AppName    string                 `json:"app_name,omitempty"`
+	Online     bool                   `json:"online"`     // 是否有在线的绑定卡密
+	CardCount  int                    `json:"card_count"` // 绑定的卡密数量
+	CreatedAt  time.Time              `json:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at"`
+}
+
+// DeviceListResponse 设备列表响应
+type DeviceListResponse struct {
+	Total int              `json:"total"`
+	Items []DeviceResponse `json:"items"`
+}
+
+// DeviceCardResponse 设备绑定的卡密
+type DeviceCardResponse struct {
+	ID            uint       `json:"id"`
+	CardNo        string     `json:"card_no"`
+	TypeID        uint       `json:"type_id"`
+	TypeName      string     `json:"type_name"`
+	Status        int        `json:"status"`
+	IsOnline      int        `json:"is_online"`
+	ActivateAt    *time.Time `json:"activate_at"`
+	ExpireAt      *time.Time `json:"expire_at"`
+	LastHeartbeat *time.Time `json:"last_heartbeat"`
+}
+
+// DeviceDetailResponse 设备详情响应
+type DeviceDetailResponse struct {
+	DeviceResponse
+	Cards  []DeviceCardResponse          `json:"cards"`  // 当前绑定的卡密
+	Events []cardmodel.CardEventResponse `json:"events"` // 最近的卡密事件
+}
+
+// DeviceUnbindResponse 强制解绑响应
+type DeviceUnbindResponse struct {
+	Count int `json:"count"` // 解绑的卡密数量
+}
+
+// FromDevice 将数据库设备模型转换为响应模型
+func FromDevice(device dbmodel.Device) DeviceResponse {
+	response := DeviceResponse{
+		ID:         device.ID,
+		DeviceID:   device.DeviceID,
+		DeviceName: device.DeviceName,
+		DeviceType: device.DeviceType,
+		DeviceOS:   device.DeviceOS,
+		DeviceIP:   device.DeviceIP,
+		DeviceInfo: device.DeviceInfo,
+		LastActive: device.LastActive,
+		Status:     device.Status,
+		AppID:      device.AppID,
+		CreatedAt:  device.CreatedAt,
+		UpdatedAt:  device.UpdatedAt,
+	}
+
+	// 如果有应用信息，添加应用名称
+	if device.Application.ID > 0 {
+		response.AppName = device.Application.Name
+	}
+
+	return response
+}
+
+// FromDeviceCard 将数据库卡密模型转换为设备绑定的卡密
+func FromDeviceCard(card dbmodel.Card) DeviceCardResponse {
+	return DeviceCardResponse{
+		ID:            card.ID,
+		CardNo:        card.CardNo,
+		TypeID:        card.TypeID,
+		TypeName:      card.CardType.Name,
+		Status:        card.Status,
+		IsOnline:      card.IsOnline,
+		ActivateAt:    card.ActivateAt,
+		ExpireAt:      card.ExpireAt,
+		LastHeartbeat: card.LastHeartbeat,
+	}
+}
@@ -0,0 +1,28 @@
+package device
+
+import (
+	"github.com/gin-gonic/gin"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/middleware"
+)
+
+// SetupDeviceRoutes 设置设备管理路由
+func SetupDeviceRoutes(r *gin.Engine) {
+	deviceController := NewController()
+
+	// 用户API路由组，普通用户管理自己应用下的设备，拥有管理所有设备权限的用户可以管理所有设备
+	deviceGroup := r.Group("/api/v1/devices")
+	deviceGroup.Use(middleware.JWTAuthMiddleware())
+	{
+		view := middleware.PermissionMiddleware(dbmodel.PermissionDeviceView)
+		manage := middleware.PermissionMiddleware(dbmodel.PermissionDeviceManage)
+
+		deviceGroup.GET("", view, deviceController.GetDevices)                                                           // 获取设备列表
+		deviceGroup.GET("/:id", view, deviceController.GetDevice)                                                        // 获取设备详情
+		deviceGroup.GET("/:id/events", view, deviceController.GetDeviceEvents)                                           // 获取设备事件
+		deviceGroup.PUT("/:id", manage, deviceController.UpdateDevice)                                                   // 修改设备名称
+		deviceGroup.PUT("/:id/status", manage, middleware.OperationLogMiddleware(), deviceController.UpdateDeviceStatus) // 启用/禁用设备
+		deviceGroup.POST("/:id/unbind", manage, middleware.OperationLogMiddleware(), deviceController.UnbindDevice)      // 强制解绑设备
+		deviceGroup.DELETE("/:id", manage, middleware.OperationLogMiddleware(), deviceController.DeleteDevice)           // 删除设备
+	}
+}
@@ -0,0 +1,369 @@
+package device
+
+import (
+	"errors"
+
+	cardmodel "github.com/skyle1995/DevE-Server/apps/card/model"
+	"github.com/skyle1995/DevE-Server/apps/device/model"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"gorm.io/gorm"
+)
+
+// recentEventCount 设备详情中返回的最近事件数量
+const recentEventCount = 20
+
+// onlineCondition 设备有在线的绑定卡密
+const onlineCondition = "EXISTS (SELECT 1 FROM cards WHERE cards.device_id = devices.device_id AND cards.app_id = devices.app_id AND cards.is_online = 1 AND cards.deleted_at IS NULL)"
+
+// Service 设备服务
+type Service struct{}
+
+// NewService 创建一个新的设备服务实例
+func NewService() *Service {
+	return &Service{}
+}
+
+// deviceScope 返回当前用户可以管理的设备查询，普通用户只能管理自己应用下的设备，拥有管理所有设备权限的用户可以管理所有设备
+// @param userID 当前用户ID
+// @param isAdmin 是否可以管理所有设备
+// @return 设备查询
+func deviceScope(userID int, isAdmin bool) *gorm.DB {
+	query := database.DB.Model(&dbmodel.Device{})
+	if !isAdmin {
+		query = query.Where("app_id IN (?)", database.DB.Model(&dbmodel.App{}).Select("id").Where("user_id = ?", userID))
+	}
+	return query
+}
+
+// getDevice 获取当前用户可以管理的设备
+func getDevice(id int, userID int, isAdmin bool) (*dbmodel.Device, error) {
+	var device dbmodel.Device
+	result := deviceScope(userID, isAdmin).Preload("Application").Where("id = ?", id).First(&device)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("设备不存在或无权限操作")
+		}
+		return nil, errors.New("查询设备失败: " + result.Error.Error())
+	}
+	return &device, nil
+}
+
+// boundCards 返回绑定到设备的卡密查询，设备ID只在所属应用内唯一
+func boundCards(tx *gorm.DB, device dbmodel.Device) *gorm.DB {
+	return tx.Model(&dbmodel.Card{}).Where("device_id = ? AND app_id = ?", device.DeviceID, device.AppID)
+}
+
+// deviceResponse 转换设备响应并统计设备绑定的卡密数量和在线状态
+func deviceResponse(device dbmodel.Device) (*model.DeviceResponse, error) {
+	var stat struct {
+		Count  int
+		Online int
+	}
+	err := boundCards(database.DB, device).Select("COUNT(*) AS count, COALESCE(MAX(is_online), 0) AS online").Scan(&stat).Error
+	if err != nil {
+		return nil, errors.New("统计设备卡密失败: " + err.Error())
+	}
+
+	response := model.FromDevice(device)
+	response.CardCount = stat.Count
+	response.Online = stat.Online == 1
+	return &response, nil
+}
+
+// unbindCards 解除设备上所有卡密的绑定并记录解绑事件，返回解绑的卡密数量
+// 所有者解绑不计入卡密的解绑次数，并使读取了旧绑定的客户端换绑、解绑失效
+func unbindCards(tx *gorm.DB, device dbmodel.Device, userID int, ip, message string) (int, error) {
+	var cards []dbmodel.Card
+	if err := boundCards(tx, device).Find(&cards).Error; err != nil {
+		return 0, err
+	}
+	if len(cards) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]uint, len(cards))
+	events := make([]dbmodel.CardEvent, len(cards))
+	for i, card := range cards {
+		ids[i] = card.ID
+		events[i] = dbmodel.NewCardEvent(card, dbmodel.CardEventUnbind, dbmodel.CardActorUser, uint(userID), ip).
+			WithChange(map[string]interface{}{"device_id": device.DeviceID}, map[string]interface{}{"device_id": nil})
+		events[i].Message = message
+	}
+
+	result := tx.Model(&dbmodel.Card{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"device_id":    nil,
+		"binding_info": "",
+		"is_online":    0,
+		"version":      gorm.Expr("version + 1"),
+	})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if err := tx.Create(&events).Error; err != nil {
+		return 0, err
+	}
+	return len(cards), nil
+}
+
+// GetDeviceList 获取设备列表
+// @param req 获取设备列表请求
+// @param userID 当前用户ID
+// @param isAdmin 是否可以管理所有设备，可以时可按应用所有者筛选
+// @return 设备列表、总数和错误信息
+func (s *Service) GetDeviceList(req model.GetDeviceListRequest, userID int, isAdmin bool) ([]model.DeviceResponse, int64, error) {
+	query := deviceScope(userID, isAdmin)
+
+	// 应用筛选条件
+	if req.AppID > 0 {
+		query = query.Where("app_id = ?", req.AppID)
+	}
+	if isAdmin && req.UserID > 0 {
+		query = query.Where("app_id IN (?)", database.DB.Model(&dbmodel.App{}).Select("id").Where("user_id = ?", req.UserID))
+	}
+	if req.Status != nil {
+		query = query.Where("status = ?", *req.Status)
+	}
+	if req.Online != nil {
+		if *req.Online {
+			query = query.Where(onlineCondition)
+		} else {
+			query = query.Not(onlineCondition)
+		}
+	}
+	if req.ActiveAfter != nil {
+		query = query.Where("last_active >= ?", *req.ActiveAfter)
+	}
+	if req.ActiveBefore != nil {
+		query = query.Where("last_active <= ?", *req.ActiveBefore)
+	}
+	if req.IP != "" {
+		query = query.Where("device_ip LIKE ?", "%"+req.IP+"%")
+	}
+	if req.Keyword != "" {
+		query = query.Where("(device_id LIKE ? OR device_name LIKE ?)", "%"+req.Keyword+"%", "%"+req.Keyword+"%")
+	}
+
+	// 获取总数
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("获取设备总数失败: " + err.Error())
+	}
+
+	// 查询设备列表
+	var devices []dbmodel.Device
+	offset := (req.Page - 1) * req.PageSize
+	if err := query.Preload("Application").Order("last_active DESC").Offset(offset).Limit(req.PageSize).Find(&devices).Error; err != nil {
+		return nil, 0, errors.New("获取设备列表失败: " + err.Error())
+	}
+	if len(devices) == 0 {
+		return []model.DeviceResponse{}, total, nil
+	}
+
+	// 统计当前页设备绑定的卡密数量和在线状态
+	deviceIDs := make([]string, len(devices))
+	for i, device := range devices {
+		deviceIDs[i] = device.DeviceID
+	}
+	var stats []struct {
+		AppID    uint
+		DeviceID string
+		Count    int
+		Online   int
+	}
+	err := database.DB.Model(&dbmodel.Card{}).
+		Select("app_id, device_id, COUNT(*) AS count, MAX(is_online) AS online").
+		Where("device_id IN ?", deviceIDs).
+		Group("app_id, device_id").
+		Scan(&stats).Error
+	if err != nil {
+		return nil, 0, errors.New("统计设备卡密失败: " + err.Error())
+	}
+
+	type statKey struct {
+		appID    uint
+		deviceID string
+	}
+	byDevice := make(map[statKey]int, len(stats))
+	for i, stat := range stats {
+		byDevice[statKey{stat.AppID, stat.DeviceID}] = i
+	}
+
+	items := make([]model.DeviceResponse, len(devices))
+	for i, device := range devices {
+		items[i] = model.FromDevice(device)
+		if j, ok := byDevice[statKey{device.AppID, device.DeviceID}]; ok {
+			items[i].CardCount = stats[j].Count
+			items[i].Online = stats[j].Online == 1
+		}
+	}
+	return items, total, nil
+}
+
+// GetDevice 获取设备详情，包括当前绑定的卡密和最近的卡密事件
+// @param id 设备ID
+// @param userID 当前用户ID
+// @param isAdmin 是否可以管理所有设备
+// @return 设备详情和错误信息
+func (s *Service) GetDevice(id int, userID int, isAdmin bool) (*model.DeviceDetailResponse, error) {
+	device, err := getDevice(id, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	var cards []dbmodel.Card
+	if err := boundCards(database.DB, *device).Preload("CardType").Order("id DESC").Find(&cards).Error; err != nil {
+		return nil, errors.New("查询设备卡密失败: " + err.Error())
+	}
+
+	var events []dbmodel.CardEvent
+	err = database.DB.Where("device_id = ? AND app_id = ?", device.DeviceID, device.AppID).
+		Order("id DESC").Limit(recentEventCount).Find(&events).Error
+	if err != nil {
+		return nil, errors.New("查询设备事件失败: " + err.Error())
+	}
+
+	detail := &model.DeviceDetailResponse{
+		DeviceResponse: model.FromDevice(*device),
+		Cards:          make([]model.DeviceCardResponse, len(cards)),
+		Events:         make([]cardmodel.CardEventResponse, len(events)),
+	}
+	detail.CardCount = len(cards)
+	for i, card := range cards {
+		detail.Cards[i] = model.FromDeviceCard(card)
+		if card.IsOnline == 1 {
+			detail.Online = true
+		}
+	}
+	for i, event := range events {
+		detail.Events[i] = cardmodel.FromCardEvent(event)
+	}
+	return detail, nil
+}
+
+// GetDeviceEvents 获取设备上发生的卡密事件，包括已解绑或已删除的卡密
+// @param id 设备ID
+// @param req 获取设备事件请求
+// @param userID 当前用户ID
+// @param isAdmin 是否可以管理所有设备
+// @return 事件列表、总数和错误信息
+func (s *Service) GetDeviceEvents(id int, req model.GetDeviceEventListRequest, userID int, isAdmin bool) ([]dbmodel.CardEvent, int64, error) {
+	device, err := getDevice(id, userID, isAdmin)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := database.DB.Model(&dbmodel.CardEvent{}).Where("device_id = ? AND app_id = ?", device.DeviceID, device.AppID)
+	if req.Type != "" {
+		query = query.Where("type = ?", req.Type)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("查询设备事件失败: " + err.Error())
+	}
+
+	var events []dbmodel.CardEvent
+	offset := (req.Page - 1) * req.PageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(req.PageSize).Find(&events).Error; err != nil {
+		return nil, 0, errors.New("查询设备事件失败: " + err.Error())
+	}
+	return events, total, nil
+}
+
+// UpdateDevice 修改设备名称
+// @param id 设备ID
+// @param req 更新设备请求
+// @param userID 当前用户ID
+// @param isAdmin 是否可以管理所有设备
+// @return 更新后的设备和错误信息
+func (s *Service) UpdateDevice(id int, req model.UpdateDeviceRequest, userID int, isAdmin bool) (*model.DeviceResponse, error) {
+	device, err := getDevice(id, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	device.DeviceName = req.DeviceName
+	if err := database.DB.Model(device).Update("device_name", device.DeviceName).Error; err != nil {
+		return nil, errors.New("更新设备失败: " + err.Error())
+	}
+	return deviceResponse(*device)
+}
+
+// UpdateDeviceStatus 启用或禁用设备
+// 禁用后设备无法激活、验证、心跳或作为换绑目标，设备上的卡密同时设为离线
+// @param id 设备ID
+// @param status 状态：1-正常，0-禁用
+// @param userID 当前用户ID
+// @param isAdmin 是否可以管理所有设备
+// @return 更新后的设备和错误信息
+func (s *Service) UpdateDeviceStatus(id int, status int, userID int, isAdmin bool) (*model.DeviceResponse, error) {
+	device, err := getDevice(id, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	device.Status = status
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(device).Update("status", status).Error; err != nil {
+			return err
+		}
+		if status != 1 {
+			return boundCards(tx, *device).Update("is_online", 0).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.New("更新设备状态失败: " + err.Error())
+	}
+	return deviceResponse(*device)
+}
+
+// UnbindDevice 强制解除设备上所有卡密的绑定
+// 解绑后卡密仍为原状态，任意设备调用激活接口即可重新绑定
+// @param id 设备ID
+// @param userID 当前用户ID
+// @param isAdmin 是否可以管理所有设备
+// @param ip 操作IP
+// @return 解绑的卡密数量和错误信息
+func (s *Service) UnbindDevice(id int, userID int, isAdmin bool, ip string) (int, error) {
+	device, err := getDevice(id, userID, isAdmin)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = database.Transaction(func(tx *gorm.DB) error {
+		count, err = unbindCards(tx, *device, userID, ip, "所有者强制解绑设备")
+		return err
+	})
+	if err != nil {
+		return 0, errors.New("解绑设备失败: " + err.Error())
+	}
+	return count, nil
+}
+
+// DeleteDevice 删除设备，设备上的卡密同时解绑
+// 设备记录直接从数据库删除，同一设备再次激活时重新登记
+// @param id 设备ID
+// @param userID 当前用户ID
+// @param isAdmin 是否可以管理所有设备
+// @param ip 操作IP
+// @return 错误信息
+func (s *Service) DeleteDevice(id int, userID int, isAdmin bool, ip string) error {
+	device, err := getDevice(id, userID, isAdmin)
+	if err != nil {
+		return err
+	}
+
+	err = database.Transaction(func(tx *gorm.DB) error {
+		if _, err := unbindCards(tx, *device, userID, ip, "删除设备时解绑"); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(device).Error
+	})
+	if err != nil {
+		return errors.New("删除设备失败: " + err.Error())
+	}
+	return nil
+}
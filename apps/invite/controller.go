@@ -0,0 +1,171 @@
+package invite
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skyle1995/DevE-Server/apps/invite/model"
+	"github.com/skyle1995/DevE-Server/utils/response"
+)
+
+// Controller 邀请码控制器
+type Controller struct {
+	service *Service
+}
+
+// NewController 创建一个新的邀请码控制器实例
+func NewController() *Controller {
+	return &Controller{
+		service: NewService(),
+	}
+}
+
+// parseID 解析路径中的ID参数
+func parseID(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		response.BadRequest(ctx, "无效的邀请码ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// GetInviteCodeList 获取邀请码列表（仅管理员可用）
+func (c *Controller) GetInviteCodeList(ctx *gin.Context) {
+	var query model.InviteCodeListQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	codes, total, err := c.service.GetInviteCodeList(query)
+	if err != nil {
+		response.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	page, pageSize := pageParams(query.Page, query.PageSize)
+	response.SuccessWithPagination(ctx, "获取邀请码列表成功", codes, page, pageSize, total)
+}
+
+// CreateInviteCodes 创建邀请码（仅管理员可用）
+func (c *Controller) CreateInviteCodes(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	var req model.CreateInviteCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	codes, err := c.service.CreateInviteCodes(req, userID.(uint))
+	if err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, "邀请码创建成功", inviteCodeResponses(codes))
+}
+
+// UpdateInviteCode 更新邀请码（仅管理员可用）
+func (c *Controller) UpdateInviteCode(ctx *gin.Context) {
+	id, ok := parseID(ctx)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateInviteCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	code, err := c.service.UpdateInviteCode(id, req, userID.(uint))
+	if err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, "邀请码更新成功", code)
+}
+
+// DeleteInviteCode 删除邀请码（仅管理员可用）
+func (c *Controller) DeleteInviteCode(ctx *gin.Context) {
+	id, ok := parseID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.DeleteInviteCode(id); err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, "邀请码删除成功", nil)
+}
+
+// GetInviteCodeUsages 获取邀请码使用记录（仅管理员可用）
+func (c *Controller) GetInviteCodeUsages(ctx *gin.Context) {
+	id, ok := parseID(ctx)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", strconv.Itoa(defaultListPageSize)))
+	usages, total, err := c.service.GetInviteCodeUsages(id, page, pageSize)
+	if err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	page, pageSize = pageParams(page, pageSize)
+	response.SuccessWithPagination(ctx, "获取使用记录成功", usages, page, pageSize, total)
+}
+
+// GetMyInviteCodes 获取当前用户作为邀请人的邀请码
+func (c *Controller) GetMyInviteCodes(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	codes, err := c.service.GetUserInviteCodes(userID.(uint))
+	if err != nil {
+		response.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, "获取邀请码成功", codes)
+}
+
+// GetMyInvitees 获取当前用户邀请注册的用户
+func (c *Controller) GetMyInvitees(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(ctx.DefaultQuery("page_size", strconv.Itoa(defaultListPageSize)))
+	invitees, total, err := c.service.GetInvitees(userID.(uint), page, pageSize)
+	if err != nil {
+		response.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	page, pageSize = pageParams(page, pageSize)
+	response.SuccessWithPagination(ctx, "获取邀请用户成功", invitees, page, pageSize, total)
+}
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// CreateInviteCodeRequest 创建邀请码请求
+type CreateInviteCodeRequest struct {
+	Code      string     `json:"code" binding:"omitempty,alphanum,min=4,max=32"` // 自定义邀请码，为空时随机生成，只能在数量为1时指定
+	Count     int        `json:"count" binding:"omitempty,min=1,max=100"`        // 生成数量，默认1
+	CreatorID uint       `json:"creator_id"`                                     // 邀请人用户ID，为空时为当前管理员
+	RoleID    uint       `json:"role_id" binding:"required"`                     // 注册后分配的角色ID，不能为管理员角色
+	MaxUses   int        `json:"max_uses" binding:"min=0"`                       // 最大使用次数，0表示不限
+	ExpiresAt *time.Time `json:"expires_at"`                                     // 过期时间，为空表示永不过期
+	Remark    string     `json:"remark" binding:"max=255"`                       // 备注
+}
+
+// UpdateInviteCodeRequest 更新邀请码请求，未传的字段不修改
+type UpdateInviteCodeRequest struct {
+	RoleID       *uint      `json:"role_id" binding:"omitempty,min=1"`    // 注册后分配的角色ID
+	MaxUses      *int       `json:"max_uses" binding:"omitempty,min=0"`   // 最大使用次数，0表示不限
+	ExpiresAt    *time.Time `json:"expires_at"`                           // 过期时间
+	ClearExpires bool       `json:"clear_expires"`                        // 是否清除过期时间，改为永不过期
+	Status       *int       `json:"status" binding:"omitempty,oneof=0 1"` // 状态：1-启用, 0-禁用
+	Remark       *string    `json:"remark" binding:"omitempty,max=255"`   // 备注
+}
+
+// InviteCodeListQuery 邀请码列表查询参数
+type InviteCodeListQuery struct {
+	Page      int    `form:"page"`                                 // 页码
+	PageSize  int    `form:"page_size"`                            // 每页数量
+	Code      string `form:"code"`                                 // 邀请码，模糊查询
+	CreatorID uint   `form:"creator_id"`                           // 邀请人用户ID
+	Status    *int   `form:"status" binding:"omitempty,oneof=0 1"` // 状态
+}
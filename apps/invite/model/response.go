@@ -0,0 +1,65 @@
+package model
+
+import (
+	"time"
+
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+)
+
+// InviteCodeResponse 邀请码响应
+type InviteCodeResponse struct {
+	ID              uint       `json:"id"`
+	Code            string     `json:"code"`             // 邀请码
+	CreatorID       uint       `json:"creator_id"`       // 邀请人用户ID
+	CreatorUsername string     `json:"creator_username"` // 邀请人用户名
+	RoleID          uint       `json:"role_id"`          // 注册后分配的角色ID
+	MaxUses         int        `json:"max_uses"`         // 最大使用次数，0表示不限
+	UsedCount       int        `json:"used_count"`       // 已使用次数
+	ExpiresAt       *time.Time `json:"expires_at"`       // 过期时间
+	Status          int        `json:"status"`           // 状态：1-启用, 0-禁用
+	Available       bool       `json:"available"`        // 当前是否可用于注册
+	Remark          string     `json:"remark"`           // 备注
+	CreatedAt       time.Time  `json:"created_at"`       // 创建时间
+	UpdatedAt       time.Time  `json:"updated_at"`       // 更新时间
+}
+
+// NewInviteCodeResponse 从数据库模型创建邀请码响应
+func NewInviteCodeResponse(code dbmodel.InviteCode, creatorUsername string) InviteCodeResponse {
+	return InviteCodeResponse{
+		ID:              code.ID,
+		Code:            code.Code,
+		CreatorID:       code.CreatorID,
+		CreatorUsername: creatorUsername,
+		RoleID:          code.RoleID,
+		MaxUses:         code.MaxUses,
+		UsedCount:       code.UsedCount,
+		ExpiresAt:       code.ExpiresAt,
+		Status:          code.Status,
+		Available:       code.Check(time.Now()) == nil,
+		Remark:          code.Remark,
+		CreatedAt:       code.CreatedAt,
+		UpdatedAt:       code.UpdatedAt,
+	}
+}
+
+// InviteCodeUsageResponse 邀请码使用记录响应
+type InviteCodeUsageResponse struct {
+	ID           uint      `json:"id"`
+	InviteCodeID uint      `json:"invite_code_id"` // 邀请码ID
+	Code         string    `json:"code"`           // 邀请码
+	InviterID    uint      `json:"inviter_id"`     // 邀请人ID
+	UserID       uint      `json:"user_id"`        // 注册用户ID
+	Username     string    `json:"username"`       // 注册用户名，用户已删除时为空
+	RoleID       uint      `json:"role_id"`        // 注册用户当前角色ID
+	IP           string    `json:"ip"`             // 注册IP
+	CreatedAt    time.Time `json:"created_at"`     // 注册时间
+}
+
+// InviteeResponse 当前用户邀请的用户
+type InviteeResponse struct {
+	UserID    uint      `json:"user_id"`    // 用户ID
+	Username  string    `json:"username"`   // 用户名，用户已删除时为空
+	Nickname  string    `json:"nickname"`   // 昵称
+	Code      string    `json:"code"`       // 使用的邀请码
+	CreatedAt time.Time `json:"created_at"` // 注册时间
+}
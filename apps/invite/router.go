@@ -0,0 +1,35 @@
+package invite
+
+import (
+	"github.com/gin-gonic/gin"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/middleware"
+)
+
+// SetupInviteRoutes 设置邀请码相关路由
+func SetupInviteRoutes(r *gin.Engine) {
+	controller := NewController()
+
+	// 需要认证的路由组
+	protected := r.Group("/api/v1")
+	protected.Use(middleware.JWTAuthMiddleware())
+	{
+		// 当前用户的邀请（所有已登录用户可访问）
+		invites := protected.Group("/invites")
+		{
+			invites.GET("/codes", controller.GetMyInviteCodes) // 获取我的邀请码
+			invites.GET("/invitees", controller.GetMyInvitees) // 获取我邀请的用户
+		}
+
+		// 邀请码管理（需要邀请码管理权限）
+		codes := protected.Group("/admin/invite-codes")
+		codes.Use(middleware.PermissionMiddleware(dbmodel.PermissionInviteManage))
+		{
+			codes.GET("", controller.GetInviteCodeList)                                            // 获取邀请码列表
+			codes.POST("", middleware.OperationLogMiddleware(), controller.CreateInviteCodes)      // 创建邀请码
+			codes.PUT("/:id", middleware.OperationLogMiddleware(), controller.UpdateInviteCode)    // 更新邀请码
+			codes.DELETE("/:id", middleware.OperationLogMiddleware(), controller.DeleteInviteCode) // 删除邀请码
+			codes.GET("/:id/usages", controller.GetInviteCodeUsages)                               // 获取使用记录
+		}
+	}
+}
@@ -0,0 +1,415 @@
+package invite
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/skyle1995/DevE-Server/apps/invite/model"
+	"github.com/skyle1995/DevE-Server/apps/role"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/utils/random"
+	"gorm.io/gorm"
+)
+
+// 邀请码生成参数
+const (
+	inviteCodeLength    = 12
+	inviteCodeCharset   = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789" // 去除易混淆的字符
+	generateMaxRetries  = 5                                  // 邀请码重复时的最大重试次数
+	defaultListPageSize = 20
+	maxListPageSize     = 100
+)
+
+// ErrInviteCodeInvalid 邀请码不存在
+var ErrInviteCodeInvalid = errors.New("邀请码无效")
+
+// Service 邀请码服务
+type Service struct{}
+
+// NewService 创建一个新的邀请码服务实例
+func NewService() *Service {
+	return &Service{}
+}
+
+// normalizeCode 邀请码不区分大小写，统一为大写
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// pageParams 修正分页参数
+func pageParams(page, pageSize int) (int, int) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = defaultListPageSize
+	} else if pageSize > maxListPageSize {
+		pageSize = maxListPageSize
+	}
+	return page, pageSize
+}
+
+// CheckInviteCode 检查邀请码是否可用于注册
+// @param code 邀请码
+// @return 邀请码和错误信息
+func (s *Service) CheckInviteCode(code string) (dbmodel.InviteCode, error) {
+	var invite dbmodel.InviteCode
+	result := database.DB.Where("code = ?", normalizeCode(code)).First(&invite)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return invite, ErrInviteCodeInvalid
+		}
+		return invite, errors.New("查询邀请码失败: " + result.Error.Error())
+	}
+	return invite, invite.Check(time.Now())
+}
+
+// RedeemInviteCode 在注册事务中使用邀请码，并记录使用情况
+// 使用次数按条件原子递增，并发注册时不会超过最大使用次数
+// @param tx 注册事务
+// @param code 邀请码
+// @param userID 注册用户ID
+// @param ip 注册IP
+// @return 邀请码和错误信息
+func (s *Service) RedeemInviteCode(tx *gorm.DB, code string, userID uint, ip string) (dbmodel.InviteCode, error) {
+	var invite dbmodel.InviteCode
+	result := tx.Where("code = ?", normalizeCode(code)).First(&invite)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return invite, ErrInviteCodeInvalid
+		}
+		return invite, errors.New("查询邀请码失败: " + result.Error.Error())
+	}
+	now := time.Now()
+	if err := invite.Check(now); err != nil {
+		return invite, err
+	}
+
+	result = tx.Model(&dbmodel.InviteCode{}).
+		Where("id = ? AND status = ? AND (expires_at IS NULL OR expires_at > ?) AND (max_uses = 0 OR used_count < max_uses)",
+			invite.ID, dbmodel.InviteCodeStatusEnabled, now).
+		Update("used_count", gorm.Expr("used_count + 1"))
+	if result.Error != nil {
+		return invite, errors.New("使用邀请码失败: " + result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return invite, errors.New("邀请码已达到使用次数上限")
+	}
+	invite.UsedCount++
+
+	usage := dbmodel.InviteCodeUsage{
+		InviteCodeID: invite.ID,
+		InviterID:    invite.CreatorID,
+		UserID:       userID,
+		IP:           ip,
+	}
+	if err := tx.Create(&usage).Error; err != nil {
+		return invite, errors.New("记录邀请码使用失败: " + err.Error())
+	}
+	return invite, nil
+}
+
+// ReleaseInviteCode 撤销用户对邀请码的使用，删除使用记录并恢复使用次数，用于注册失败时回退已创建的用户
+// @param tx 事务
+// @param userID 注册用户ID
+// @return 错误信息
+func (s *Service) ReleaseInviteCode(tx *gorm.DB, userID uint) error {
+	var usage dbmodel.InviteCodeUsage
+	result := tx.Where("user_id = ?", userID).Limit(1).Find(&usage)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+	if err := tx.Delete(&usage).Error; err != nil {
+		return err
+	}
+	return tx.Model(&dbmodel.InviteCode{}).
+		Where("id = ? AND used_count > 0", usage.InviteCodeID).
+		Update("used_count", gorm.Expr("used_count - 1")).Error
+}
+
+// checkRole 检查邀请码分配的角色是否存在，邀请码不能分配管理员角色，
+// 且角色的权限必须都在操作人自己的权限范围内，避免通过邀请码注册获得更高的权限
+// @param roleID 角色ID
+// @param operatorID 当前操作人ID
+// @return 错误信息
+func checkRole(roleID, operatorID uint) error {
+	var assigned dbmodel.Role
+	if err := database.DB.Where("id = ?", roleID).First(&assigned).Error; err != nil {
+		return errors.New("角色不存在")
+	}
+	if assigned.Code == dbmodel.RoleCodeAdmin {
+		return errors.New("邀请码不能分配管理员角色")
+	}
+	return role.CheckAssignable(operatorID, assigned)
+}
+
+// CreateInviteCodes 创建邀请码
+// @param req 创建邀请码请求
+// @param operatorID 当前管理员ID，未指定邀请人时作为邀请人
+// @return 创建的邀请码和错误信息
+func (s *Service) CreateInviteCodes(req model.CreateInviteCodeRequest, operatorID uint) ([]dbmodel.InviteCode, error) {
+	count := req.Count
+	if count == 0 {
+		count = 1
+	}
+	if req.Code != "" && count > 1 {
+		return nil, errors.New("指定邀请码时只能创建一个")
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("过期时间必须晚于当前时间")
+	}
+
+	creatorID := req.CreatorID
+	if creatorID == 0 {
+		creatorID = operatorID
+	}
+	var creatorCount int64
+	database.DB.Model(&dbmodel.User{}).Where("id = ?", creatorID).Count(&creatorCount)
+	if creatorCount == 0 {
+		return nil, errors.New("邀请人不存在")
+	}
+	if err := checkRole(req.RoleID, operatorID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]dbmodel.InviteCode, 0, count)
+	err := database.Transaction(func(tx *gorm.DB) error {
+		for i := 0; i < count; i++ {
+			invite := dbmodel.InviteCode{
+				Code:      normalizeCode(req.Code),
+				CreatorID: creatorID,
+				RoleID:    req.RoleID,
+				MaxUses:   req.MaxUses,
+				ExpiresAt: req.ExpiresAt,
+				Status:    dbmodel.InviteCodeStatusEnabled,
+				Remark:    req.Remark,
+			}
+			if err := createInviteCode(tx, &invite); err != nil {
+				return err
+			}
+			codes = append(codes, invite)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// createInviteCode 保存邀请码，未指定邀请码时随机生成，重复时重新生成
+func createInviteCode(tx *gorm.DB, invite *dbmodel.InviteCode) error {
+	custom := invite.Code != ""
+	for retry := 0; retry < generateMaxRetries; retry++ {
+		if !custom {
+			invite.Code = random.String(inviteCodeLength, inviteCodeCharset)
+		}
+
+		// 包括已删除的邀请码，避免唯一索引冲突
+		var count int64
+		if err := tx.Unscoped().Model(&dbmodel.InviteCode{}).Where("code = ?", invite.Code).Count(&count).Error; err != nil {
+			return errors.New("查询邀请码失败: " + err.Error())
+		}
+		if count > 0 {
+			if custom {
+				return errors.New("邀请码已存在")
+			}
+			continue
+		}
+
+		if err := tx.Create(invite).Error; err != nil {
+			return errors.New("创建邀请码失败: " + err.Error())
+		}
+		return nil
+	}
+	return errors.New("生成邀请码失败，请重试")
+}
+
+// GetInviteCodeList 获取邀请码列表
+// @param query 查询参数
+// @return 邀请码列表、总数和错误信息
+func (s *Service) GetInviteCodeList(query model.InviteCodeListQuery) ([]model.InviteCodeResponse, int64, error) {
+	page, pageSize := pageParams(query.Page, query.PageSize)
+
+	db := database.DB.Model(&dbmodel.InviteCode{})
+	if query.Code != "" {
+		db = db.Where("code LIKE ?", "%"+normalizeCode(query.Code)+"%")
+	}
+	if query.CreatorID > 0 {
+		db = db.Where("creator_id = ?", query.CreatorID)
+	}
+	if query.Status != nil {
+		db = db.Where("status = ?", *query.Status)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("获取邀请码总数失败: " + err.Error())
+	}
+
+	var codes []dbmodel.InviteCode
+	if err := db.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&codes).Error; err != nil {
+		return nil, 0, errors.New("获取邀请码列表失败: " + err.Error())
+	}
+
+	return inviteCodeResponses(codes), total, nil
+}
+
+// GetUserInviteCodes 获取用户作为邀请人的邀请码
+// @param userID 当前用户ID
+// @return 邀请码列表和错误信息
+func (s *Service) GetUserInviteCodes(userID uint) ([]model.InviteCodeResponse, error) {
+	var codes []dbmodel.InviteCode
+	if err := database.DB.Where("creator_id = ?", userID).Order("id DESC").Find(&codes).Error; err != nil {
+		return nil, errors.New("获取邀请码列表失败: " + err.Error())
+	}
+	return inviteCodeResponses(codes), nil
+}
+
+// inviteCodeResponses 转换邀请码列表，并查询邀请人用户名
+func inviteCodeResponses(codes []dbmodel.InviteCode) []model.InviteCodeResponse {
+	creatorIDs := make([]uint, 0, len(codes))
+	for _, code := range codes {
+		creatorIDs = append(creatorIDs, code.CreatorID)
+	}
+	usernames := make(map[uint]string)
+	if len(creatorIDs) > 0 {
+		var users []dbmodel.User
+		database.DB.Unscoped().Select("id, username").Where("id IN ?", creatorIDs).Find(&users)
+		for _, user := range users {
+			usernames[user.ID] = user.Username
+		}
+	}
+
+	responses := make([]model.InviteCodeResponse, 0, len(codes))
+	for _, code := range codes {
+		responses = append(responses, model.NewInviteCodeResponse(code, usernames[code.CreatorID]))
+	}
+	return responses
+}
+
+// UpdateInviteCode 更新邀请码
+// @param id 邀请码ID
+// @param req 更新邀请码请求
+// @param operatorID 当前操作人ID
+// @return 更新后的邀请码和错误信息
+func (s *Service) UpdateInviteCode(id uint, req model.UpdateInviteCodeRequest, operatorID uint) (*model.InviteCodeResponse, error) {
+	var invite dbmodel.InviteCode
+	result := database.DB.Where("id = ?", id).First(&invite)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("邀请码不存在")
+		}
+		return nil, errors.New("查询邀请码失败: " + result.Error.Error())
+	}
+
+	updates := map[string]interface{}{}
+	if req.RoleID != nil {
+		if err := checkRole(*req.RoleID, operatorID); err != nil {
+			return nil, err
+		}
+		updates["role_id"] = *req.RoleID
+	}
+	if req.MaxUses != nil {
+		updates["max_uses"] = *req.MaxUses
+	}
+	if req.ClearExpires {
+		updates["expires_at"] = nil
+	} else if req.ExpiresAt != nil {
+		updates["expires_at"] = *req.ExpiresAt
+	}
+	if req.Status != nil {
+		updates["status"] = *req.Status
+	}
+	if req.Remark != nil {
+		updates["remark"] = *req.Remark
+	}
+	if len(updates) > 0 {
+		if err := database.DB.Model(&invite).Updates(updates).Error; err != nil {
+			return nil, errors.New("更新邀请码失败: " + err.Error())
+		}
+	}
+
+	database.DB.First(&invite, invite.ID)
+	response := inviteCodeResponses([]dbmodel.InviteCode{invite})[0]
+	return &response, nil
+}
+
+// DeleteInviteCode 删除邀请码，已注册用户的使用记录保留
+// @param id 邀请码ID
+// @return 错误信息
+func (s *Service) DeleteInviteCode(id uint) error {
+	result := database.DB.Where("id = ?", id).Delete(&dbmodel.InviteCode{})
+	if result.Error != nil {
+		return errors.New("删除邀请码失败: " + result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("邀请码不存在")
+	}
+	return nil
+}
+
+// GetInviteCodeUsages 获取邀请码的使用记录
+// @param id 邀请码ID
+// @param page 页码
+// @param pageSize 每页数量
+// @return 使用记录、总数和错误信息
+func (s *Service) GetInviteCodeUsages(id uint, page, pageSize int) ([]model.InviteCodeUsageResponse, int64, error) {
+	var invite dbmodel.InviteCode
+	if err := database.DB.Unscoped().Where("id = ?", id).First(&invite).Error; err != nil {
+		return nil, 0, errors.New("邀请码不存在")
+	}
+	page, pageSize = pageParams(page, pageSize)
+
+	db := database.DB.Table("invite_code_usages AS u").
+		Joins("LEFT JOIN users ON users.id = u.user_id AND users.deleted_at IS NULL").
+		Where("u.invite_code_id = ?", id)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("获取使用记录总数失败: " + err.Error())
+	}
+
+	var usages []model.InviteCodeUsageResponse
+	err := db.Select("u.id, u.invite_code_id, u.inviter_id, u.user_id, u.ip, u.created_at, users.username, users.role_id").
+		Order("u.id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Scan(&usages).Error
+	if err != nil {
+		return nil, 0, errors.New("获取使用记录失败: " + err.Error())
+	}
+	for i := range usages {
+		usages[i].Code = invite.Code
+	}
+	return usages, total, nil
+}
+
+// GetInvitees 获取用户邀请注册的用户
+// @param userID 当前用户ID
+// @param page 页码
+// @param pageSize 每页数量
+// @return 被邀请的用户、总数和错误信息
+func (s *Service) GetInvitees(userID uint, page, pageSize int) ([]model.InviteeResponse, int64, error) {
+	page, pageSize = pageParams(page, pageSize)
+
+	db := database.DB.Table("invite_code_usages AS u").
+		Joins("LEFT JOIN invite_codes ON invite_codes.id = u.invite_code_id").
+		Joins("LEFT JOIN users ON users.id = u.user_id AND users.deleted_at IS NULL").
+		Where("u.inviter_id = ?", userID)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, errors.New("获取邀请用户总数失败: " + err.Error())
+	}
+
+	var invitees []model.InviteeResponse
+	err := db.Select("u.user_id, users.username, users.nickname, invite_codes.code, u.created_at").
+		Order("u.id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Scan(&invitees).Error
+	if err != nil {
+		return nil, 0, errors.New("获取邀请用户失败: " + err.Error())
+	}
+	return invitees, total, nil
+}
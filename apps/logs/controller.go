@@ -61,6 +61,14 @@ func (c *Controller) GetLogs(ctx *gin.Context) {
 		}
 	}
 
+	// 访问令牌ID筛选
+	if tokenID := ctx.Query("access_token_id"); tokenID != "" {
+		tokenIDInt, err := strconv.ParseUint(tokenID, 10, 32)
+		if err == nil {
+			query = query.Where("access_token_id = ?", uint(tokenIDInt))
+		}
+	}
+
 	// 查询总数
 	result := query.Count(&total)
 	if result.Error != nil {
@@ -122,6 +130,14 @@ func (c *Controller) GetMyLogs(ctx *gin.Context) {
 		}
 	}
 
+	// 访问令牌ID筛选
+	if tokenID := ctx.Query("access_token_id"); tokenID != "" {
+		tokenIDInt, err := strconv.ParseUint(tokenID, 10, 32)
+		if err == nil {
+			query = query.Where("access_token_id = ?", uint(tokenIDInt))
+		}
+	}
+
 	// 查询总数
 	result := query.Count(&total)
 	if result.Error != nil {
@@ -2,6 +2,7 @@ package logs
 
 import (
 	"github.com/gin-gonic/gin"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
 	"github.com/skyle1995/DevE-Server/middleware"
 )
 
@@ -13,11 +14,11 @@ func SetupLogsRoutes(router *gin.RouterGroup) {
 	// 日志管理路由组
 	logsGroup := router.Group("/logs")
 	{
-		// 获取日志列表 - 管理员可查看所有日志
-		logsGroup.GET("", middleware.AdminAuthMiddleware(), controller.GetLogs)
-		// 清空日志 - 仅管理员可操作
-		logsGroup.DELETE("", middleware.AdminAuthMiddleware(), controller.ClearLogs)
-		// 获取用户自己的日志 - 普通用户可查看自己的日志
+		// 获取日志列表 - 需要查看所有日志权限
+		logsGroup.GET("", middleware.JWTAuthMiddleware(), middleware.PermissionMiddleware(dbmodel.PermissionLogsViewAll), controller.GetLogs)
+		// 清空日志 - 需要清空日志权限
+		logsGroup.DELETE("", middleware.JWTAuthMiddleware(), middleware.PermissionMiddleware(dbmodel.PermissionLogsClear), controller.ClearLogs)
+		// 获取用户自己的日志 - 所有已登录用户可查看自己的日志
 		logsGroup.GET("/my", middleware.JWTAuthMiddleware(), controller.GetMyLogs)
 	}
 }
@@ -2,6 +2,7 @@ package notice
 
 import (
 	"github.com/gin-gonic/gin"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
 	"github.com/skyle1995/DevE-Server/middleware"
 )
 
@@ -19,13 +20,16 @@ func SetupNoticeRoutes(router *gin.RouterGroup) {
 	// 需要认证的接口
 	noticeGroup.Use(middleware.JWTAuthMiddleware())
 	{
-		// 管理员接口
-		noticeGroup.POST("", controller.CreateNotice)                 // 创建通知
-		noticeGroup.PUT("/:id", controller.UpdateNotice)              // 更新通知
-		noticeGroup.DELETE("/:id", controller.DeleteNotice)           // 删除通知
-		noticeGroup.GET("/:id", controller.GetNotice)                 // 获取通知详情
-		noticeGroup.GET("", controller.GetNoticeList)                 // 获取通知列表
-		noticeGroup.GET("/active", controller.GetActiveNoticeList)    // 获取活动通知列表
-		noticeGroup.PUT("/:id/status", controller.UpdateNoticeStatus) // 更新通知状态
+		// 发布通知接口，发布者可以修改自己的通知，拥有管理所有通知权限的用户可以修改所有通知
+		publish := middleware.PermissionMiddleware(dbmodel.PermissionNoticePublish)
+		noticeGroup.POST("", publish, controller.CreateNotice)                 // 创建通知
+		noticeGroup.PUT("/:id", publish, controller.UpdateNotice)              // 更新通知
+		noticeGroup.DELETE("/:id", publish, controller.DeleteNotice)           // 删除通知
+		noticeGroup.PUT("/:id/status", publish, controller.UpdateNoticeStatus) // 更新通知状态
+
+		// 查看通知接口（所有已登录用户可访问）
+		noticeGroup.GET("/:id", controller.GetNotice)              // 获取通知详情
+		noticeGroup.GET("", controller.GetNoticeList)              // 获取通知列表
+		noticeGroup.GET("/active", controller.GetActiveNoticeList) // 获取活动通知列表
 	}
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/skyle1995/DevE-Server/apps/notice/model"
 	"github.com/skyle1995/DevE-Server/database"
 	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/middleware"
 	"gorm.io/gorm"
 )
 
@@ -59,12 +60,12 @@ func (s *Service) UpdateNotice(id uint, req model.UpdateNoticeRequest, userID ui
 		return nil, errors.New("查询通知失败: " + result.Error.Error())
 	}
 
-	// 检查权限（只有管理员或发布者可以更新）
+	// 检查权限（只有发布者或拥有管理所有通知权限的用户可以更新）
 	if notice.UserID != userID {
-		// 检查当前用户是否为管理员
+		// 检查当前用户是否可以管理所有通知
 		var user dbmodel.User
 		userResult := database.DB.Where("id = ?", userID).First(&user)
-		if userResult.Error != nil || user.Role != 1 {
+		if userResult.Error != nil || !middleware.RoleHasPermission(user.RoleID, dbmodel.PermissionNoticeManage) {
 			return nil, errors.New("无权限更新此通知")
 		}
 	}
@@ -113,12 +114,12 @@ func (s *Service) DeleteNotice(id uint, userID uint) error {
 		return errors.New("查询通知失败: " + result.Error.Error())
 	}
 
-	// 检查权限（只有管理员或发布者可以删除）
+	// 检查权限（只有发布者或拥有管理所有通知权限的用户可以删除）
 	if notice.UserID != userID {
-		// 检查当前用户是否为管理员
+		// 检查当前用户是否可以管理所有通知
 		var user dbmodel.User
 		userResult := database.DB.Where("id = ?", userID).First(&user)
-		if userResult.Error != nil || user.Role != 1 {
+		if userResult.Error != nil || !middleware.RoleHasPermission(user.RoleID, dbmodel.PermissionNoticeManage) {
 			return errors.New("无权限删除此通知")
 		}
 	}
@@ -212,12 +213,12 @@ func (s *Service) UpdateNoticeStatus(id uint, status int, userID uint) (*dbmodel
 		return nil, errors.New("查询通知失败: " + result.Error.Error())
 	}
 
-	// 检查权限（只有管理员或发布者可以更新状态）
+	// 检查权限（只有发布者或拥有管理所有通知权限的用户可以更新状态）
 	if notice.UserID != userID {
-		// 检查当前用户是否为管理员
+		// 检查当前用户是否可以管理所有通知
 		var user dbmodel.User
 		userResult := database.DB.Where("id = ?", userID).First(&user)
-		if userResult.Error != nil || user.Role != 1 {
+		if userResult.Error != nil || !middleware.RoleHasPermission(user.RoleID, dbmodel.PermissionNoticeManage) {
 			return nil, errors.New("无权限更新此通知状态")
 		}
 	}
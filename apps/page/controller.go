@@ -19,7 +19,7 @@ func NewController() *Controller {
 
 // GetMenu 获取菜单数据
 // @Summary 获取菜单数据
-// @Description 根据用户角色获取对应的菜单数据
+// @Description 根据用户角色的权限过滤菜单数据
 // @Tags 页面管理
 // @Accept json
 // @Produce json
@@ -30,20 +30,20 @@ func NewController() *Controller {
 // @Router /api/v1/menu [get]
 func (c *Controller) GetMenu(ctx *gin.Context) {
 	// 从上下文中获取用户角色
-	roleValue, exists := ctx.Get("role")
+	roleValue, exists := ctx.Get("role_id")
 	if !exists {
 		response.FailWithMessage("获取用户角色失败", ctx)
 		return
 	}
 
-	role, ok := roleValue.(int)
+	roleID, ok := roleValue.(uint)
 	if !ok {
 		response.FailWithMessage("用户角色类型错误", ctx)
 		return
 	}
 
 	// 获取菜单数据
-	menuResp, err := c.service.GetMenu(role)
+	menuResp, err := c.service.GetMenu(roleID)
 	if err != nil {
 		response.FailWithMessage("获取菜单数据失败", ctx)
 		return
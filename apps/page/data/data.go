@@ -2,8 +2,7 @@ package data
 
 import "embed"
 
-//go:embed menu-admin.json
-var MenuAdmin embed.FS
-
-//go:embed menu-user.json
-var MenuUser embed.FS
+// Menu 菜单数据，菜单项的 permission 字段为访问该菜单需要的权限，为空表示所有已登录用户可见
+//
+//go:embed menu.json
+var Menu embed.FS
@@ -1,6 +1,16 @@
 package model
 
+// MenuItem 菜单项
+type MenuItem struct {
+	ID         string     `json:"id"`                   // 菜单路由或链接
+	Icon       string     `json:"icon"`                 // 图标
+	Title      string     `json:"title"`                // 标题
+	Type       string     `json:"type,omitempty"`       // 打开方式：modal-弹层, blank-新窗口
+	Permission string     `json:"permission,omitempty"` // 访问需要的权限，为空表示所有已登录用户可见
+	Children   []MenuItem `json:"children,omitempty"`   // 子菜单
+}
+
 // MenuResponse 菜单响应结构体
 type MenuResponse struct {
-	Menu []byte `json:"menu"`
+	Menu []MenuItem `json:"menu"`
 }
@@ -1,8 +1,11 @@
 package page
 
 import (
+	"encoding/json"
+
 	"github.com/skyle1995/DevE-Server/apps/page/data"
 	"github.com/skyle1995/DevE-Server/apps/page/model"
+	"github.com/skyle1995/DevE-Server/middleware"
 )
 
 // Service 页面服务结构体
@@ -13,23 +16,39 @@ func NewService() *Service {
 	return &Service{}
 }
 
-// GetMenu 根据用户角色获取菜单数据
-func (s *Service) GetMenu(role int) (*model.MenuResponse, error) {
-	var menuData []byte
-	var err error
-
-	// 根据角色返回不同的菜单数据
-	if role == 0 { // 管理员
-		menuData, err = data.MenuAdmin.ReadFile("menu-admin.json")
-	} else { // 普通会员或VIP会员
-		menuData, err = data.MenuUser.ReadFile("menu-user.json")
+// GetMenu 根据用户角色的权限获取菜单数据
+func (s *Service) GetMenu(roleID uint) (*model.MenuResponse, error) {
+	menuData, err := data.Menu.ReadFile("menu.json")
+	if err != nil {
+		return nil, err
 	}
 
-	if err != nil {
+	var menu []model.MenuItem
+	if err := json.Unmarshal(menuData, &menu); err != nil {
 		return nil, err
 	}
 
 	return &model.MenuResponse{
-		Menu: menuData,
+		Menu: filterMenu(menu, func(permission string) bool {
+			return middleware.RoleHasPermission(roleID, permission)
+		}),
 	}, nil
 }
+
+// filterMenu 过滤没有权限访问的菜单项，子菜单全部被过滤的菜单项同时移除
+func filterMenu(items []model.MenuItem, allowed func(permission string) bool) []model.MenuItem {
+	result := make([]model.MenuItem, 0, len(items))
+	for _, item := range items {
+		if item.Permission != "" && !allowed(item.Permission) {
+			continue
+		}
+		if len(item.Children) > 0 {
+			item.Children = filterMenu(item.Children, allowed)
+			if len(item.Children) == 0 {
+				continue
+			}
+		}
+		result = append(result, item)
+	}
+	return result
+}
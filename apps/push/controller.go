@@ -0,0 +1,140 @@
+package push
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	"github.com/skyle1995/DevE-Server/apps/push/model"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/middleware"
+	"github.com/skyle1995/DevE-Server/utils/random"
+	"github.com/skyle1995/DevE-Server/utils/response"
+)
+
+// upgrader WebSocket协议升级器
+// 客户端为原生应用且已通过签名认证，因此不校验Origin
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// Controller 推送控制器
+type Controller struct {
+	service *Service
+}
+
+// NewController 创建推送控制器
+func NewController() *Controller {
+	return &Controller{
+		service: NewService(),
+	}
+}
+
+// canPushAll 判断当前用户是否可以访问所有用户应用的在线客户端
+func canPushAll(ctx *gin.Context) bool {
+	return middleware.HasPermission(ctx, dbmodel.PermissionAppPushAll)
+}
+
+// Connect 客户端建立WebSocket长连接
+func (c *Controller) Connect(ctx *gin.Context) {
+	var req model.ConnectRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		response.FailWithError(response.NewCodeError(response.CodeParamError, "参数错误: "+err.Error()), ctx)
+		return
+	}
+
+	// 从上下文中获取应用信息
+	value, exists := ctx.Get("app")
+	app, ok := value.(dbmodel.App)
+	if !exists || !ok {
+		response.FailWithError(response.NewCodeError(response.CodeUnauthorized, "应用信息获取失败"), ctx)
+		return
+	}
+
+	// 长连接消息不经过ClientCryptoMiddleware，开启加密的应用拒绝升级，客户端改用加密的HTTP心跳
+	if app.EncryptionType != middleware.EncryptionNone {
+		response.FailWithError(response.NewCodeError(response.CodeCryptoError, "应用已开启加密，不支持长连接，请使用HTTP心跳"), ctx)
+		return
+	}
+
+	// 升级协议前先校验卡密和设备，校验失败时以普通HTTP响应返回错误
+	welcome, err := c.service.Authorize(app, req, ctx.ClientIP())
+	if err != nil {
+		response.FailWithError(err, ctx)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		log.Warnf("WebSocket协议升级失败: %v", err)
+		return
+	}
+
+	client := newClient(random.UUID(), conn, app.ID, req.CardNo, req.DeviceID, ctx.ClientIP())
+	c.service.Serve(app, client, welcome)
+}
+
+// GetClients 获取应用的在线客户端列表
+func (c *Controller) GetClients(ctx *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	// 获取应用ID
+	appID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(ctx, "无效的应用ID")
+		return
+	}
+
+	// 调用服务层获取在线客户端
+	res, err := c.service.GetClients(userID.(uint), uint(appID), canPushAll(ctx))
+	if err != nil {
+		response.NotFound(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, "获取在线客户端成功", res)
+}
+
+// Push 向在线客户端推送指令
+func (c *Controller) Push(ctx *gin.Context) {
+	// 从上下文中获取用户ID
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		response.Unauthorized(ctx, "未授权")
+		return
+	}
+
+	// 获取应用ID
+	appID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(ctx, "无效的应用ID")
+		return
+	}
+
+	// 绑定请求参数
+	var req model.PushRequest
+	if bindErr := ctx.ShouldBindJSON(&req); bindErr != nil {
+		response.BadRequest(ctx, "请求参数错误: "+bindErr.Error())
+		return
+	}
+
+	// 调用服务层推送指令
+	res, err := c.service.Push(userID.(uint), uint(appID), canPushAll(ctx), req, ctx.ClientIP())
+	if err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, "推送成功", res)
+}
@@ -0,0 +1,236 @@
+package push
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	"github.com/skyle1995/DevE-Server/apps/push/model"
+)
+
+const (
+	writeWait      = 10 * time.Second    // 写消息超时时间
+	pongWait       = 60 * time.Second    // 等待客户端pong的超时时间
+	pingPeriod     = (pongWait * 9) / 10 // 服务端发送ping的间隔，需小于pongWait
+	maxMessageSize = 4096                // 客户端消息的最大长度
+	sendBufferSize = 16                  // 发送队列长度
+)
+
+// outbound 待发送的消息
+type outbound struct {
+	data  []byte // 消息内容
+	close bool   // 发送后是否关闭连接
+}
+
+// Client 客户端长连接
+type Client struct {
+	ID          string    // 连接ID
+	AppID       uint      // 应用ID
+	CardNo      string    // 卡号
+	DeviceID    string    // 设备ID
+	IP          string    // 客户端IP
+	ConnectedAt time.Time // 连接时间
+
+	conn     *websocket.Conn
+	send     chan outbound
+	done     chan struct{}
+	once     sync.Once
+	mu       sync.RWMutex
+	lastSeen time.Time
+}
+
+// newClient 创建客户端长连接
+func newClient(id string, conn *websocket.Conn, appID uint, cardNo, deviceID, ip string) *Client {
+	now := time.Now()
+	return &Client{
+		ID:          id,
+		AppID:       appID,
+		CardNo:      cardNo,
+		DeviceID:    deviceID,
+		IP:          ip,
+		ConnectedAt: now,
+		conn:        conn,
+		send:        make(chan outbound, sendBufferSize),
+		done:        make(chan struct{}),
+		lastSeen:    now,
+	}
+}
+
+// Info 获取连接信息
+func (c *Client) Info() model.ClientInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return model.ClientInfo{
+		ConnID:      c.ID,
+		AppID:       c.AppID,
+		CardNo:      c.CardNo,
+		DeviceID:    c.DeviceID,
+		IP:          c.IP,
+		ConnectedAt: c.ConnectedAt,
+		LastSeen:    c.lastSeen,
+	}
+}
+
+// touch 更新最后活跃时间
+func (c *Client) touch() {
+	c.mu.Lock()
+	c.lastSeen = time.Now()
+	c.mu.Unlock()
+}
+
+// Send 发送消息，发送队列已满或连接已关闭时返回false
+// closeAfter: 消息发送后是否关闭连接
+func (c *Client) Send(msgType string, data interface{}, closeAfter bool) bool {
+	payload, err := json.Marshal(model.Message{
+		Type:      msgType,
+		Data:      data,
+		Timestamp: time.Now().Unix(),
+	})
+	if err != nil {
+		log.Errorf("推送消息序列化失败: %v", err)
+		return false
+	}
+
+	select {
+	case <-c.done:
+		return false
+	case c.send <- outbound{data: payload, close: closeAfter}:
+		return true
+	default:
+		// 发送队列已满，说明客户端处理过慢，直接断开
+		log.Warnf("客户端 %s 发送队列已满，断开连接", c.ID)
+		c.Close()
+		return false
+	}
+}
+
+// Close 关闭连接
+func (c *Client) Close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// readPump 读取客户端消息，连接断开时返回
+func (c *Client) readPump(handle func(*Client, model.ClientMessage)) {
+	defer c.Close()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.touch()
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var msg model.ClientMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Debugf("客户端 %s 连接异常断开: %v", c.ID, err)
+			}
+			return
+		}
+		c.touch()
+		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		handle(c, msg)
+	}
+}
+
+// writePump 向客户端发送消息和定时ping，连接断开时返回
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.Close()
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg.data); err != nil {
+				return
+			}
+			if msg.close {
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Hub 在线连接管理器，按应用维护所有客户端长连接
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[uint]map[string]*Client // 应用ID -> 连接ID -> 连接
+}
+
+// hub 全局连接管理器
+var hub = NewHub()
+
+// NewHub 创建连接管理器
+func NewHub() *Hub {
+	return &Hub{
+		clients: make(map[uint]map[string]*Client),
+	}
+}
+
+// Register 注册连接
+func (h *Hub) Register(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.clients[c.AppID] == nil {
+		h.clients[c.AppID] = make(map[string]*Client)
+	}
+	h.clients[c.AppID][c.ID] = c
+}
+
+// Unregister 注销连接，返回该卡密是否仍有其他在线连接
+func (h *Hub) Unregister(c *Client) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	conns := h.clients[c.AppID]
+	delete(conns, c.ID)
+	if len(conns) == 0 {
+		delete(h.clients, c.AppID)
+		return false
+	}
+
+	for _, other := range conns {
+		if other.CardNo == c.CardNo {
+			return true
+		}
+	}
+	return false
+}
+
+// Clients 获取应用的在线连接
+// connID、cardNo不为空时按条件筛选
+func (h *Hub) Clients(appID uint, connID, cardNo string) []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var list []*Client
+	for _, c := range h.clients[appID] {
+		if connID != "" && c.ID != connID {
+			continue
+		}
+		if cardNo != "" && c.CardNo != cardNo {
+			continue
+		}
+		list = append(list, c)
+	}
+	return list
+}
@@ -0,0 +1,22 @@
+package model
+
+// ConnectRequest 客户端建立长连接请求（通过URL查询参数传递，参与签名）
+type ConnectRequest struct {
+	CardNo   string `form:"card_no" binding:"required"`   // 卡号
+	DeviceID string `form:"device_id" binding:"required"` // 设备ID
+}
+
+// ClientMessage 客户端上行消息
+type ClientMessage struct {
+	Type string `json:"type"` // 消息类型：heartbeat-心跳
+}
+
+// PushRequest 推送指令请求
+type PushRequest struct {
+	Command     string `json:"command" binding:"required,oneof=kick disable_card message force_update"` // 指令类型：kick-踢下线，disable_card-禁用卡密，message-显示消息，force_update-强制更新
+	ConnID      string `json:"conn_id"`                                                                 // 连接ID，指定时仅推送到该连接
+	CardNo      string `json:"card_no"`                                                                 // 卡号，指定时仅推送到该卡密的连接
+	Message     string `json:"message"`                                                                 // 消息内容
+	Version     string `json:"version"`                                                                 // 强制更新的版本号，为空时使用应用当前版本
+	DownloadUrl string `json:"download_url"`                                                            // 强制更新的下载地址，为空时使用应用当前下载地址
+}
@@ -0,0 +1,67 @@
+package model
+
+import "time"
+
+// 推送消息类型
+const (
+	MessageConnected   = "connected"    // 连接成功
+	MessageHeartbeat   = "heartbeat"    // 心跳响应
+	MessageError       = "error"        // 错误通知
+	MessageKick        = "kick"         // 踢下线
+	MessageDisableCard = "disable_card" // 卡密已禁用
+	MessageText        = "message"      // 显示消息
+	MessageForceUpdate = "force_update" // 强制更新
+)
+
+// Message 下行推送消息
+type Message struct {
+	Type      string      `json:"type"`           // 消息类型
+	Data      interface{} `json:"data,omitempty"` // 消息数据
+	Timestamp int64       `json:"timestamp"`      // 时间戳
+}
+
+// ConnectedData 连接成功通知数据
+type ConnectedData struct {
+	ConnID    string      `json:"conn_id"`   // 连接ID
+	Heartbeat interface{} `json:"heartbeat"` // 卡密状态，与心跳接口的响应一致
+}
+
+// ErrorData 错误通知数据
+type ErrorData struct {
+	Code    int    `json:"code"`    // 业务状态码
+	Message string `json:"message"` // 错误信息
+}
+
+// TextData 消息通知数据
+type TextData struct {
+	Message string `json:"message"` // 消息内容
+}
+
+// ForceUpdateData 强制更新通知数据
+type ForceUpdateData struct {
+	Version     string `json:"version"`           // 版本号
+	DownloadUrl string `json:"download_url"`      // 下载地址
+	Message     string `json:"message,omitempty"` // 更新说明
+}
+
+// ClientInfo 在线客户端信息
+type ClientInfo struct {
+	ConnID      string    `json:"conn_id"`      // 连接ID
+	AppID       uint      `json:"app_id"`       // 应用ID
+	CardNo      string    `json:"card_no"`      // 卡号
+	DeviceID    string    `json:"device_id"`    // 设备ID
+	IP          string    `json:"ip"`           // 客户端IP
+	ConnectedAt time.Time `json:"connected_at"` // 连接时间
+	LastSeen    time.Time `json:"last_seen"`    // 最后活跃时间
+}
+
+// ClientListResponse 在线客户端列表响应
+type ClientListResponse struct {
+	Total   int          `json:"total"`   // 在线连接数
+	Clients []ClientInfo `json:"clients"` // 连接列表
+}
+
+// PushResponse 推送指令响应
+type PushResponse struct {
+	Sent int `json:"sent"` // 成功推送的连接数
+}
@@ -0,0 +1,32 @@
+package push
+
+import (
+	"github.com/gin-gonic/gin"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/middleware"
+)
+
+// SetupPushRoutes 设置推送路由
+func SetupPushRoutes(r *gin.Engine) {
+	// 创建控制器实例
+	controller := NewController()
+
+	// 客户端长连接路由，认证方式与v2客户端API一致
+	// 卡号和设备ID通过URL查询参数传递，并参与签名
+	clientV2 := r.Group("/api/v2/client")
+	clientV2.Use(middleware.ClientAuthV2Middleware())
+	{
+		clientV2.GET("/ws", controller.Connect)
+	}
+
+	// 管理端路由
+	protected := r.Group("/api/v1")
+	protected.Use(middleware.JWTAuthMiddleware())
+	{
+		apps := protected.Group("/apps")
+		{
+			apps.GET("/:id/clients", middleware.PermissionMiddleware(dbmodel.PermissionAppPush), controller.GetClients)                                      // 获取在线客户端列表
+			apps.POST("/:id/clients/push", middleware.PermissionMiddleware(dbmodel.PermissionAppPush), middleware.OperationLogMiddleware(), controller.Push) // 推送指令
+		}
+	}
+}
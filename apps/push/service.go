@@ -0,0 +1,221 @@
+package push
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skyle1995/DevE-Server/apps/client"
+	clientmodel "github.com/skyle1995/DevE-Server/apps/client/model"
+	"github.com/skyle1995/DevE-Server/apps/push/model"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/utils/response"
+	"gorm.io/gorm"
+)
+
+// Service 推送服务
+type Service struct {
+	db     *gorm.DB
+	client *client.Service
+}
+
+// NewService 创建推送服务
+func NewService() *Service {
+	return &Service{
+		db:     database.DB,
+		client: client.NewService(),
+	}
+}
+
+// Authorize 校验建立长连接的卡密和设备，与心跳接口的校验规则一致
+func (s *Service) Authorize(app dbmodel.App, req model.ConnectRequest, ip string) (*clientmodel.HeartbeatResponse, error) {
+	return s.client.Heartbeat(clientmodel.HeartbeatRequest{
+		CardNo:    req.CardNo,
+		DeviceID:  req.DeviceID,
+		AppKey:    app.AppKey,
+		Timestamp: time.Now().Unix(),
+	}, app, ip)
+}
+
+// Serve 注册连接并处理消息，直到连接断开
+func (s *Service) Serve(app dbmodel.App, c *Client, welcome *clientmodel.HeartbeatResponse) {
+	hub.Register(c)
+	log.Infof("客户端长连接已建立: app=%d card=%s device=%s conn=%s", c.AppID, c.CardNo, c.DeviceID, c.ID)
+
+	go c.writePump()
+	c.Send(model.MessageConnected, model.ConnectedData{
+		ConnID:    c.ID,
+		Heartbeat: welcome,
+	}, false)
+
+	c.readPump(func(c *Client, msg model.ClientMessage) {
+		s.handleMessage(app, c, msg)
+	})
+
+	// 连接断开，若该卡密已无其他在线连接则标记为离线
+	if !hub.Unregister(c) {
+		s.db.Model(&dbmodel.Card{}).
+			Where("card_no = ? AND app_id = ?", c.CardNo, c.AppID).
+			Update("is_online", 0)
+	}
+	log.Infof("客户端长连接已断开: app=%d card=%s conn=%s", c.AppID, c.CardNo, c.ID)
+}
+
+// handleMessage 处理客户端上行消息
+func (s *Service) handleMessage(app dbmodel.App, c *Client, msg model.ClientMessage) {
+	switch msg.Type {
+	case model.MessageHeartbeat:
+		res, err := s.Authorize(app, model.ConnectRequest{CardNo: c.CardNo, DeviceID: c.DeviceID}, c.IP)
+		if err != nil {
+			// 卡密已失效，通知客户端后断开连接
+			c.Send(model.MessageError, model.ErrorData{
+				Code:    response.CodeOf(err),
+				Message: err.Error(),
+			}, true)
+			return
+		}
+		c.Send(model.MessageHeartbeat, res, false)
+	default:
+		c.Send(model.MessageError, model.ErrorData{
+			Code:    response.CodeParamError,
+			Message: "不支持的消息类型",
+		}, false)
+	}
+}
+
+// appScope 返回当前用户可以推送的应用查询，普通用户只能访问自己的应用，拥有推送所有应用权限的用户可以访问所有应用
+// @param userID 当前用户ID
+// @param isAdmin 是否可以访问所有应用
+// @return 应用查询
+func (s *Service) appScope(userID uint, isAdmin bool) *gorm.DB {
+	query := s.db.Model(&dbmodel.App{})
+	if !isAdmin {
+		query = query.Where("user_id = ?", userID)
+	}
+	return query
+}
+
+// getApp 获取当前用户可以推送的应用
+func (s *Service) getApp(userID, appID uint, isAdmin bool) (*dbmodel.App, error) {
+	var app dbmodel.App
+	result := s.appScope(userID, isAdmin).Where("id = ?", appID).First(&app)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("应用不存在或无权访问")
+		}
+		return nil, result.Error
+	}
+	return &app, nil
+}
+
+// disableCard 按卡密状态机禁用卡密并记录事件，客户端接口按同一状态判断卡密已禁用
+func (s *Service) disableCard(app dbmodel.App, cardNo string, userID uint, ip string) error {
+	var card dbmodel.Card
+	if err := s.db.Where("card_no = ? AND app_id = ?", cardNo, app.ID).First(&card).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("卡密不存在")
+		}
+		return errors.New("查询卡密失败")
+	}
+	oldStatus := card.Status
+	if oldStatus != dbmodel.CardStatusDisabled {
+		if err := card.Transit(dbmodel.CardStatusDisabled); err != nil {
+			return err
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if oldStatus != dbmodel.CardStatusDisabled {
+			event := dbmodel.NewCardEvent(card, dbmodel.CardEventDisable, dbmodel.CardActorUser, userID, ip).
+				WithChange(map[string]interface{}{"status": oldStatus}, map[string]interface{}{"status": dbmodel.CardStatusDisabled})
+			event.Message = "推送禁用卡密指令"
+			if err := tx.Create(&event).Error; err != nil {
+				return errors.New("记录卡密事件失败")
+			}
+		}
+		err := tx.Model(&card).Updates(map[string]interface{}{"status": dbmodel.CardStatusDisabled, "is_online": 0}).Error
+		if err != nil {
+			return errors.New("禁用卡密失败")
+		}
+		return nil
+	})
+}
+
+// GetClients 获取应用的在线客户端列表
+func (s *Service) GetClients(userID, appID uint, isAdmin bool) (*model.ClientListResponse, error) {
+	if _, err := s.getApp(userID, appID, isAdmin); err != nil {
+		return nil, err
+	}
+
+	clients := hub.Clients(appID, "", "")
+	list := make([]model.ClientInfo, 0, len(clients))
+	for _, c := range clients {
+		list = append(list, c.Info())
+	}
+
+	return &model.ClientListResponse{
+		Total:   len(list),
+		Clients: list,
+	}, nil
+}
+
+// Push 向应用的在线客户端推送指令
+// 未指定连接ID和卡号时推送到该应用的所有在线客户端
+func (s *Service) Push(userID, appID uint, isAdmin bool, req model.PushRequest, ip string) (*model.PushResponse, error) {
+	app, err := s.getApp(userID, appID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		data       interface{}
+		closeAfter bool
+	)
+	switch req.Command {
+	case model.MessageKick:
+		data = model.TextData{Message: req.Message}
+		closeAfter = true
+	case model.MessageDisableCard:
+		if req.CardNo == "" {
+			return nil, errors.New("禁用卡密时必须指定卡号")
+		}
+		// 先禁用卡密，避免客户端重连后继续使用
+		if err := s.disableCard(*app, req.CardNo, userID, ip); err != nil {
+			return nil, err
+		}
+		data = model.TextData{Message: req.Message}
+		closeAfter = true
+	case model.MessageText:
+		if req.Message == "" {
+			return nil, errors.New("消息内容不能为空")
+		}
+		data = model.TextData{Message: req.Message}
+	case model.MessageForceUpdate:
+		update := model.ForceUpdateData{
+			Version:     req.Version,
+			DownloadUrl: req.DownloadUrl,
+			Message:     req.Message,
+		}
+		if update.Version == "" {
+			update.Version = app.Version
+		}
+		if update.DownloadUrl == "" {
+			update.DownloadUrl = app.DownloadUrl
+		}
+		data = update
+	default:
+		return nil, errors.New("不支持的指令类型")
+	}
+
+	sent := 0
+	for _, c := range hub.Clients(app.ID, req.ConnID, req.CardNo) {
+		if c.Send(req.Command, data, closeAfter) {
+			sent++
+		}
+	}
+
+	log.Infof("推送指令: app=%d command=%s conn=%s card=%s sent=%d", app.ID, req.Command, req.ConnID, req.CardNo, sent)
+
+	return &model.PushResponse{Sent: sent}, nil
+}
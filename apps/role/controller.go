@@ -0,0 +1,101 @@
+package role
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skyle1995/DevE-Server/apps/role/model"
+	"github.com/skyle1995/DevE-Server/utils/response"
+)
+
+// Controller 角色控制器
+type Controller struct {
+	service *Service
+}
+
+// NewController 创建一个新的角色控制器实例
+func NewController() *Controller {
+	return &Controller{
+		service: NewService(),
+	}
+}
+
+// parseID 解析路径中的ID参数
+func parseID(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		response.BadRequest(ctx, "无效的角色ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// GetRoles 获取角色列表
+func (c *Controller) GetRoles(ctx *gin.Context) {
+	roles, err := c.service.GetRoles()
+	if err != nil {
+		response.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, "获取角色列表成功", roles)
+}
+
+// GetPermissions 获取所有可分配的权限
+func (c *Controller) GetPermissions(ctx *gin.Context) {
+	response.Success(ctx, "获取权限列表成功", c.service.GetPermissions())
+}
+
+// CreateRole 创建角色
+func (c *Controller) CreateRole(ctx *gin.Context) {
+	var req model.CreateRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	role, err := c.service.CreateRole(req, ctx.GetUint("user_id"))
+	if err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, "角色创建成功", role)
+}
+
+// UpdateRole 更新角色
+func (c *Controller) UpdateRole(ctx *gin.Context) {
+	id, ok := parseID(ctx)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误: "+err.Error())
+		return
+	}
+
+	role, err := c.service.UpdateRole(id, req, ctx.GetUint("user_id"))
+	if err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, "角色更新成功", role)
+}
+
+// DeleteRole 删除角色
+func (c *Controller) DeleteRole(ctx *gin.Context) {
+	id, ok := parseID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := c.service.DeleteRole(id, ctx.GetUint("user_id")); err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+
+	response.Success(ctx, "角色删除成功", nil)
+}
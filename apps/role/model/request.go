@@ -0,0 +1,18 @@
+package model
+
+// CreateRoleRequest 创建角色请求
+type CreateRoleRequest struct {
+	Code        string   `json:"code" binding:"required,alphanum,min=2,max=50"` // 角色编码，创建后不能修改
+	Name        string   `json:"name" binding:"required,max=50"`                // 角色名称
+	Description string   `json:"description" binding:"max=255"`                 // 描述
+	Permissions []string `json:"permissions"`                                   // 权限列表
+	IsDefault   bool     `json:"is_default"`                                    // 是否设为注册用户的默认角色
+}
+
+// UpdateRoleRequest 更新角色请求，未传的字段不修改
+type UpdateRoleRequest struct {
+	Name        *string  `json:"name" binding:"omitempty,min=1,max=50"`   // 角色名称
+	Description *string  `json:"description" binding:"omitempty,max=255"` // 描述
+	Permissions []string `json:"permissions"`                             // 权限列表，管理员角色的权限不能修改
+	IsDefault   *bool    `json:"is_default"`                              // 是否设为注册用户的默认角色
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+)
+
+// RoleResponse 角色响应
+type RoleResponse struct {
+	ID          uint      `json:"id"`
+	Code        string    `json:"code"`        // 角色编码
+	Name        string    `json:"name"`        // 角色名称
+	Description string    `json:"description"` // 描述
+	Permissions []string  `json:"permissions"` // 权限列表，* 表示所有权限
+	IsSystem    bool      `json:"is_system"`   // 是否为内置角色
+	IsDefault   bool      `json:"is_default"`  // 是否为注册用户的默认角色
+	UserCount   int64     `json:"user_count"`  // 使用该角色的用户数量
+	CreatedAt   time.Time `json:"created_at"`  // 创建时间
+	UpdatedAt   time.Time `json:"updated_at"`  // 更新时间
+}
+
+// NewRoleResponse 从数据库模型创建角色响应
+func NewRoleResponse(role dbmodel.Role, userCount int64) RoleResponse {
+	permissions := role.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return RoleResponse{
+		ID:          role.ID,
+		Code:        role.Code,
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: permissions,
+		IsSystem:    role.IsSystem,
+		IsDefault:   role.IsDefault,
+		UserCount:   userCount,
+		CreatedAt:   role.CreatedAt,
+		UpdatedAt:   role.UpdatedAt,
+	}
+}
@@ -0,0 +1,27 @@
+package role
+
+import (
+	"github.com/gin-gonic/gin"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/middleware"
+)
+
+// SetupRoleRoutes 设置角色相关路由
+func SetupRoleRoutes(r *gin.Engine) {
+	controller := NewController()
+
+	// 角色管理（需要角色管理权限）
+	admin := r.Group("/api/v1/admin")
+	admin.Use(middleware.JWTAuthMiddleware(), middleware.PermissionMiddleware(dbmodel.PermissionRoleManage))
+	{
+		admin.GET("/permissions", controller.GetPermissions) // 获取可分配的权限
+
+		roles := admin.Group("/roles")
+		{
+			roles.GET("", controller.GetRoles)                                               // 获取角色列表
+			roles.POST("", middleware.OperationLogMiddleware(), controller.CreateRole)       // 创建角色
+			roles.PUT("/:id", middleware.OperationLogMiddleware(), controller.UpdateRole)    // 更新角色
+			roles.DELETE("/:id", middleware.OperationLogMiddleware(), controller.DeleteRole) // 删除角色
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package role
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/skyle1995/DevE-Server/apps/role/model"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/middleware"
+	"gorm.io/gorm"
+)
+
+// ErrRoleNotFound 角色不存在
+var ErrRoleNotFound = errors.New("角色不存在")
+
+// ErrRoleExceedsOperator 角色的权限超出当前操作人的权限
+var ErrRoleExceedsOperator = errors.New("不能分配或管理权限超出自己的角色")
+
+// Service 角色服务
+type Service struct{}
+
+// NewService 创建一个新的角色服务实例
+func NewService() *Service {
+	return &Service{}
+}
+
+// normalizePermissions 检查权限是否可分配，并去除重复的权限
+// @param permissions 权限列表
+// @return 去重后的权限列表和错误信息
+func normalizePermissions(permissions []string) ([]string, error) {
+	result := make([]string, 0, len(permissions))
+	seen := make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		permission = strings.TrimSpace(permission)
+		if !dbmodel.IsValidPermission(permission) {
+			return nil, errors.New("无效的权限: " + permission)
+		}
+		if !seen[permission] {
+			seen[permission] = true
+			result = append(result, permission)
+		}
+	}
+	return result, nil
+}
+
+// OperatorRole 获取操作人的角色
+// @param operatorID 当前操作人ID
+// @return 操作人的角色和错误信息
+func OperatorRole(operatorID uint) (dbmodel.Role, error) {
+	var user dbmodel.User
+	if err := database.DB.Select("id", "role_id").Where("id = ?", operatorID).First(&user).Error; err != nil {
+		return dbmodel.Role{}, errors.New("获取当前用户角色失败")
+	}
+	role, ok := middleware.GetRole(user.RoleID)
+	if !ok {
+		return dbmodel.Role{}, errors.New("获取当前用户角色失败")
+	}
+	return role, nil
+}
+
+// CheckAssignable 检查操作人能否将角色分配给用户或邀请码，避免通过分配角色获得超出自己的权限
+// @param operatorID 当前操作人ID
+// @param role 要分配的角色
+// @return 错误信息
+func CheckAssignable(operatorID uint, role dbmodel.Role) error {
+	operator, err := OperatorRole(operatorID)
+	if err != nil {
+		return err
+	}
+	if !operator.Covers(role) {
+		return ErrRoleExceedsOperator
+	}
+	return nil
+}
+
+// CheckUserManageable 检查操作人能否管理目标用户，目标用户的角色权限超出操作人时不能修改、删除或重置其账户
+// @param operatorID 当前操作人ID
+// @param target 目标用户
+// @return 错误信息
+func CheckUserManageable(operatorID uint, target dbmodel.User) error {
+	operator, err := OperatorRole(operatorID)
+	if err != nil {
+		return err
+	}
+	// 角色不存在的用户没有任何权限
+	if role, ok := middleware.GetRole(target.RoleID); ok && !operator.Covers(role) {
+		return errors.New("不能管理权限超出自己的用户")
+	}
+	return nil
+}
+
+// checkEditable 检查操作人能否修改或删除角色，非管理员不能修改自己的角色，也不能修改权限超出自己的角色
+// @param operatorID 当前操作人ID
+// @param role 要修改的角色
+// @return 操作人的角色和错误信息
+func checkEditable(operatorID uint, role dbmodel.Role) (dbmodel.Role, error) {
+	operator, err := OperatorRole(operatorID)
+	if err != nil {
+		return operator, err
+	}
+	if operator.Code == dbmodel.RoleCodeAdmin {
+		return operator, nil
+	}
+	if operator.ID == role.ID {
+		return operator, errors.New("不能修改自己的角色")
+	}
+	if !operator.Covers(role) {
+		return operator, ErrRoleExceedsOperator
+	}
+	return operator, nil
+}
+
+// GetRoles 获取所有角色及其用户数量
+// @return 角色列表和错误信息
+func (s *Service) GetRoles() ([]model.RoleResponse, error) {
+	var roles []dbmodel.Role
+	if err := database.DB.Order("id").Find(&roles).Error; err != nil {
+		return nil, errors.New("获取角色列表失败: " + err.Error())
+	}
+
+	var counts []struct {
+		RoleID uint
+		Count  int64
+	}
+	err := database.DB.Model(&dbmodel.User{}).Select("role_id, COUNT(*) AS count").Group("role_id").Scan(&counts).Error
+	if err != nil {
+		return nil, errors.New("统计角色用户数量失败: " + err.Error())
+	}
+	userCounts := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		userCounts[count.RoleID] = count.Count
+	}
+
+	responses := make([]model.RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		responses = append(responses, model.NewRoleResponse(role, userCounts[role.ID]))
+	}
+	return responses, nil
+}
+
+// GetPermissions 获取所有可分配的权限
+// @return 权限列表
+func (s *Service) GetPermissions() []dbmodel.PermissionDefinition {
+	return dbmodel.Permissions
+}
+
+// CreateRole 创建角色，角色的权限不能超出操作人自己的权限
+// @param req 创建角色请求
+// @param operatorID 当前操作人ID
+// @return 创建的角色和错误信息
+func (s *Service) CreateRole(req model.CreateRoleRequest, operatorID uint) (*model.RoleResponse, error) {
+	permissions, err := normalizePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	operator, err := OperatorRole(operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if !operator.Covers(dbmodel.Role{Permissions: permissions}) {
+		return nil, ErrRoleExceedsOperator
+	}
+
+	role := dbmodel.Role{
+		Code:        strings.ToLower(req.Code),
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Permissions: permissions,
+		IsDefault:   req.IsDefault,
+	}
+	if role.Name == "" {
+		return nil, errors.New("角色名称不能为空")
+	}
+
+	var count int64
+	database.DB.Model(&dbmodel.Role{}).Where("code = ?", role.Code).Count(&count)
+	if count > 0 {
+		return nil, errors.New("角色编码已存在")
+	}
+	database.DB.Model(&dbmodel.Role{}).Where("name = ?", role.Name).Count(&count)
+	if count > 0 {
+		return nil, errors.New("角色名称已存在")
+	}
+
+	err = database.Transaction(func(tx *gorm.DB) error {
+		// 默认角色只能有一个
+		if role.IsDefault {
+			if err := tx.Model(&dbmodel.Role{}).Where("is_default = ?", true).Update("is_default", false).Error; err != nil {
+				return errors.New("更新默认角色失败: " + err.Error())
+			}
+		}
+		if err := tx.Create(&role).Error; err != nil {
+			return errors.New("创建角色失败: " + err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	middleware.ClearRoleCache()
+
+	response := model.NewRoleResponse(role, 0)
+	return &response, nil
+}
+
+// UpdateRole 更新角色，管理员角色的权限不能修改，管理员角色不能设为默认角色
+// 非管理员不能修改自己的角色和权限超出自己的角色，修改后的权限也不能超出自己的权限
+// @param id 角色ID
+// @param req 更新角色请求
+// @param operatorID 当前操作人ID
+// @return 更新后的角色和错误信息
+func (s *Service) UpdateRole(id uint, req model.UpdateRoleRequest, operatorID uint) (*model.RoleResponse, error) {
+	var role dbmodel.Role
+	if err := database.DB.Where("id = ?", id).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, errors.New("查询角色失败: " + err.Error())
+	}
+	operator, err := checkEditable(operatorID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	// 权限字段需要序列化，按字段名更新角色
+	var columns []string
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, errors.New("角色名称不能为空")
+		}
+		var count int64
+		database.DB.Model(&dbmodel.Role{}).Where("name = ? AND id != ?", name, id).Count(&count)
+		if count > 0 {
+			return nil, errors.New("角色名称已存在")
+		}
+		role.Name = name
+		columns = append(columns, "name")
+	}
+	if req.Description != nil {
+		role.Description = *req.Description
+		columns = append(columns, "description")
+	}
+	if req.Permissions != nil {
+		if role.Code == dbmodel.RoleCodeAdmin {
+			return nil, errors.New("管理员角色的权限不能修改")
+		}
+		permissions, err := normalizePermissions(req.Permissions)
+		if err != nil {
+			return nil, err
+		}
+		if !operator.Covers(dbmodel.Role{Permissions: permissions}) {
+			return nil, ErrRoleExceedsOperator
+		}
+		role.Permissions = permissions
+		columns = append(columns, "permissions")
+	}
+	setDefault := false
+	if req.IsDefault != nil && *req.IsDefault != role.IsDefault {
+		if !*req.IsDefault {
+			return nil, errors.New("必须有一个默认角色，请将其他角色设为默认角色")
+		}
+		if role.Code == dbmodel.RoleCodeAdmin {
+			return nil, errors.New("管理员角色不能设为默认角色")
+		}
+		setDefault = true
+		role.IsDefault = true
+		columns = append(columns, "is_default")
+	}
+
+	if len(columns) > 0 {
+		err := database.Transaction(func(tx *gorm.DB) error {
+			if setDefault {
+				if err := tx.Model(&dbmodel.Role{}).Where("is_default = ?", true).Update("is_default", false).Error; err != nil {
+					return errors.New("更新默认角色失败: " + err.Error())
+				}
+			}
+			if err := tx.Model(&role).Select(columns).Updates(&role).Error; err != nil {
+				return errors.New("更新角色失败: " + err.Error())
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		middleware.ClearRoleCache()
+	}
+
+	var userCount int64
+	database.DB.Model(&dbmodel.User{}).Where("role_id = ?", role.ID).Count(&userCount)
+	response := model.NewRoleResponse(role, userCount)
+	return &response, nil
+}
+
+// DeleteRole 删除角色，内置角色、默认角色和正在使用的角色不能删除
+// @param id 角色ID
+// @param operatorID 当前操作人ID
+// @return 错误信息
+func (s *Service) DeleteRole(id uint, operatorID uint) error {
+	var role dbmodel.Role
+	if err := database.DB.Where("id = ?", id).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRoleNotFound
+		}
+		return errors.New("查询角色失败: " + err.Error())
+	}
+	if _, err := checkEditable(operatorID, role); err != nil {
+		return err
+	}
+	if role.IsSystem {
+		return errors.New("内置角色不能删除")
+	}
+	if role.IsDefault {
+		return errors.New("默认角色不能删除，请先将其他角色设为默认角色")
+	}
+
+	var count int64
+	database.DB.Model(&dbmodel.User{}).Where("role_id = ?", id).Count(&count)
+	if count > 0 {
+		return errors.New("角色正在被用户使用，不能删除")
+	}
+	database.DB.Model(&dbmodel.InviteCode{}).Where("role_id = ?", id).Count(&count)
+	if count > 0 {
+		return errors.New("角色正在被邀请码使用，不能删除")
+	}
+
+	if err := database.DB.Delete(&role).Error; err != nil {
+		return errors.New("删除角色失败: " + err.Error())
+	}
+	middleware.ClearRoleCache()
+	return nil
+}
@@ -0,0 +1,77 @@
+package setting
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/utils/cache"
+	"gorm.io/gorm/clause"
+)
+
+// 系统设置缓存参数
+const (
+	settingCacheKey = "values"
+	// settingCacheTTL 缓存有效期，多实例部署或直接修改数据库时，设置最多延迟该时间生效
+	settingCacheTTL = time.Minute
+)
+
+// settingCache 缓存全部系统设置的键值，通过本服务修改设置时清除
+var settingCache = cache.New(0, 0)
+
+// keyEquals 按键名查询设置，key为保留字，由gorm转义列名
+func keyEquals(key string) clause.Expression {
+	return clause.Eq{Column: clause.Column{Name: "key"}, Value: key}
+}
+
+// ClearCache 清除系统设置缓存，下次读取时重新从数据库加载
+func ClearCache() {
+	settingCache.Delete(settingCacheKey)
+}
+
+// values 返回全部系统设置的键值，缓存失效时从数据库加载
+// 查询失败时返回空值且不缓存，读取方使用默认值
+func (s *Service) values() map[string]string {
+	if cached, ok := settingCache.Get(settingCacheKey); ok {
+		return cached.(map[string]string)
+	}
+
+	var settings []dbmodel.SystemSetting
+	if err := database.DB.Find(&settings).Error; err != nil {
+		log.Errorf("加载系统设置失败: %v", err)
+		return map[string]string{}
+	}
+	values := make(map[string]string, len(settings))
+	for _, setting := range settings {
+		values[setting.Key] = setting.Value
+	}
+	settingCache.Set(settingCacheKey, values, settingCacheTTL)
+	return values
+}
+
+// GetString 读取字符串类型的设置，设置不存在时返回默认值
+func (s *Service) GetString(key, defaultValue string) string {
+	if value, ok := s.values()[key]; ok {
+		return value
+	}
+	return defaultValue
+}
+
+// GetInt 读取整数类型的设置，设置不存在或不是整数时返回默认值
+func (s *Service) GetInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(strings.TrimSpace(s.GetString(key, ""))); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+// GetBool 读取开关类型的设置，1表示开启，0表示关闭，设置不存在或无法识别时返回默认值
+func (s *Service) GetBool(key string, defaultValue bool) bool {
+	if value, err := strconv.ParseBool(strings.TrimSpace(s.GetString(key, ""))); err == nil {
+		return value
+	}
+	return defaultValue
+}
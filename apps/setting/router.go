@@ -2,6 +2,7 @@ package setting
 
 import (
 	"github.com/gin-gonic/gin"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
 	"github.com/skyle1995/DevE-Server/middleware"
 )
 
@@ -27,9 +28,9 @@ func SetupSettingRoutes(r *gin.Engine) {
 			settings.GET("", settingController.GetSettings) // 获取系统设置列表
 		}
 
-		// 管理员路由组（仅管理员可访问）
+		// 系统设置管理路由组（需要系统设置管理权限）
 		admin := protected.Group("/admin")
-		admin.Use(middleware.AdminAuthMiddleware())
+		admin.Use(middleware.PermissionMiddleware(dbmodel.PermissionSettingManage))
 		{
 			// 系统设置管理相关路由
 			adminSettings := admin.Group("/settings")
@@ -5,6 +5,7 @@ import (
 
 	"github.com/skyle1995/DevE-Server/database"
 	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/utils/mailer"
 	"gorm.io/gorm"
 )
 
@@ -21,9 +22,9 @@ func (s *Service) GetSettings(group string) ([]dbmodel.SystemSetting, error) {
 	var settings []dbmodel.SystemSetting
 	query := database.DB
 
-	// 如果指定了分组，则按分组筛选
+	// 如果指定了分组，则按分组筛选，group为保留字，使用结构体条件由gorm转义列名
 	if group != "" {
-		query = query.Where("group = ?", group)
+		query = query.Where(&dbmodel.SystemSetting{Group: group})
 	}
 
 	// 查询设置
@@ -38,7 +39,7 @@ func (s *Service) GetSettings(group string) ([]dbmodel.SystemSetting, error) {
 // GetSettingByKey 根据键名获取系统设置
 func (s *Service) GetSettingByKey(key string) (*dbmodel.SystemSetting, error) {
 	var setting dbmodel.SystemSetting
-	result := database.DB.Where("key = ?", key).First(&setting)
+	result := database.DB.Where(keyEquals(key)).First(&setting)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, errors.New("设置不存在")
@@ -53,7 +54,7 @@ func (s *Service) GetSettingByKey(key string) (*dbmodel.SystemSetting, error) {
 func (s *Service) UpdateSetting(key, value string) error {
 	// 检查设置是否存在
 	var setting dbmodel.SystemSetting
-	result := database.DB.Where("key = ?", key).First(&setting)
+	result := database.DB.Where(keyEquals(key)).First(&setting)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return errors.New("设置不存在")
@@ -67,6 +68,7 @@ func (s *Service) UpdateSetting(key, value string) error {
 	if result.Error != nil {
 		return result.Error
 	}
+	ClearCache()
 
 	return nil
 }
@@ -75,7 +77,7 @@ func (s *Service) UpdateSetting(key, value string) error {
 func (s *Service) CreateSetting(key, value, group, description string) error {
 	// 检查键名是否已存在
 	var count int64
-	database.DB.Model(&dbmodel.SystemSetting{}).Where("key = ?", key).Count(&count)
+	database.DB.Model(&dbmodel.SystemSetting{}).Where(keyEquals(key)).Count(&count)
 	if count > 0 {
 		return errors.New("键名已存在")
 	}
@@ -93,6 +95,7 @@ func (s *Service) CreateSetting(key, value, group, description string) error {
 	if result.Error != nil {
 		return result.Error
 	}
+	ClearCache()
 
 	return nil
 }
@@ -101,7 +104,7 @@ func (s *Service) CreateSetting(key, value, group, description string) error {
 func (s *Service) DeleteSetting(key string) error {
 	// 检查设置是否存在
 	var setting dbmodel.SystemSetting
-	result := database.DB.Where("key = ?", key).First(&setting)
+	result := database.DB.Where(keyEquals(key)).First(&setting)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return errors.New("设置不存在")
@@ -114,6 +117,7 @@ func (s *Service) DeleteSetting(key string) error {
 	if result.Error != nil {
 		return result.Error
 	}
+	ClearCache()
 
 	return nil
 }
@@ -139,3 +143,25 @@ func (s *Service) GetSiteInfo() (map[string]string, error) {
 
 	return siteInfo, nil
 }
+
+// GetMailConfig 获取邮件服务配置
+// 未启用邮件服务或未配置SMTP服务器地址时返回的配置不可用于发送邮件
+func (s *Service) GetMailConfig() mailer.Config {
+	values := s.values()
+
+	port := s.GetInt("mail_smtp_port", 25)
+	if port <= 0 {
+		port = 25
+	}
+
+	return mailer.Config{
+		Enable:    values["mail_enable"] == "1" && values["mail_smtp_host"] != "",
+		Host:      values["mail_smtp_host"],
+		Port:      port,
+		Secure:    values["mail_smtp_secure"] == "1",
+		Username:  values["mail_username"],
+		Password:  values["mail_password"],
+		FromEmail: values["mail_from_email"],
+		FromName:  values["mail_from_name"],
+	}
+}
@@ -2,8 +2,11 @@ package user
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	usermodel "github.com/skyle1995/DevE-Server/apps/user/model"
+	"github.com/skyle1995/DevE-Server/middleware"
 )
 
 // Controller 处理用户相关的HTTP请求
@@ -21,13 +24,13 @@ func NewController() *Controller {
 // GetUserList 获取用户列表（仅管理员可用）
 func (c *Controller) GetUserList(ctx *gin.Context) {
 	// 获取查询参数
-	role := ctx.DefaultQuery("role", "")
+	roleID := ctx.DefaultQuery("role_id", "")
 	status := ctx.DefaultQuery("status", "")
 	page := ctx.DefaultQuery("page", "1")
 	limit := ctx.DefaultQuery("limit", "20")
 
 	// 调用服务获取用户列表
-	users, total, err := c.service.GetUserList(role, status, page, limit)
+	users, total, err := c.service.GetUserList(roleID, status, page, limit)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{
 			"code":    500,
@@ -84,7 +87,7 @@ func (c *Controller) CreateUser(ctx *gin.Context) {
 		Username string `json:"username" binding:"required"`
 		Password string `json:"password" binding:"required"`
 		Email    string `json:"email" binding:"required,email"`
-		Role     string `json:"role" binding:"required"`
+		RoleID   string `json:"role_id" binding:"required"`
 		Status   string `json:"status" binding:"required"`
 	}
 
@@ -97,7 +100,7 @@ func (c *Controller) CreateUser(ctx *gin.Context) {
 	}
 
 	// 调用服务创建用户
-	err := c.service.CreateUser(req.Username, req.Password, req.Email, req.Role, req.Status)
+	err := c.service.CreateUser(req.Username, req.Password, req.Email, req.RoleID, req.Status, ctx.GetUint("user_id"))
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
@@ -128,7 +131,7 @@ func (c *Controller) UpdateUser(ctx *gin.Context) {
 	// 绑定请求参数
 	var req struct {
 		Email  string `json:"email" binding:"omitempty,email"`
-		Role   string `json:"role"`
+		RoleID string `json:"role_id"`
 		Status string `json:"status"`
 	}
 
@@ -141,7 +144,7 @@ func (c *Controller) UpdateUser(ctx *gin.Context) {
 	}
 
 	// 调用服务更新用户
-	err := c.service.UpdateUser(userID, req.Email, req.Role, req.Status)
+	err := c.service.UpdateUser(userID, req.Email, req.RoleID, req.Status, ctx.GetUint("user_id"))
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
@@ -170,7 +173,7 @@ func (c *Controller) DeleteUser(ctx *gin.Context) {
 	}
 
 	// 调用服务删除用户
-	err := c.service.DeleteUser(userID)
+	err := c.service.DeleteUser(userID, ctx.GetUint("user_id"))
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{
 			"code":    400,
@@ -186,6 +189,117 @@ func (c *Controller) DeleteUser(ctx *gin.Context) {
 	})
 }
 
+// ResetUserPassword 重置用户密码（仅管理员可用），用户下次登录时需要修改密码
+// 请求中包含密码，不使用日志中间件，由控制器在用户的登录日志中记录
+func (c *Controller) ResetUserPassword(ctx *gin.Context) {
+	startTime := time.Now()
+
+	var req usermodel.ResetUserPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	user, err := c.service.ResetUserPassword(ctx.Param("id"), req.Password, ctx.GetUint("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	content := "管理员 " + ctx.GetString("username") + " 重置了密码，下次登录时需要修改密码"
+	middleware.LoginLog(user.ID, content, ctx.ClientIP(), ctx.Request.UserAgent(), http.StatusOK, time.Since(startTime).Milliseconds())
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "密码已重置，用户下次登录时需要修改密码",
+	})
+}
+
+// ResetUserTwoFactor 重置用户的两步验证（仅管理员可用）
+func (c *Controller) ResetUserTwoFactor(ctx *gin.Context) {
+	// 获取用户ID
+	userID := ctx.Param("id")
+	if userID == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": "缺少用户ID",
+		})
+		return
+	}
+
+	// 调用服务重置两步验证
+	err := c.service.ResetUserTwoFactor(userID, ctx.GetUint("user_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 返回重置成功响应
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "两步验证已重置",
+	})
+}
+
+// GetUserSessions 获取用户的登录会话（仅管理员可用）
+func (c *Controller) GetUserSessions(ctx *gin.Context) {
+	sessions, err := c.service.GetUserSessions(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "获取成功",
+		"data":    sessions,
+	})
+}
+
+// RevokeUserSession 撤销用户的一个登录会话（仅管理员可用）
+func (c *Controller) RevokeUserSession(ctx *gin.Context) {
+	if err := c.service.RevokeUserSession(ctx.Param("id"), ctx.Param("session_id"), ctx.GetUint("user_id")); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "会话已撤销",
+	})
+}
+
+// RevokeUserSessions 撤销用户的所有登录会话（仅管理员可用）
+func (c *Controller) RevokeUserSessions(ctx *gin.Context) {
+	if err := c.service.RevokeUserSessions(ctx.Param("id"), ctx.GetUint("user_id")); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"code":    200,
+		"message": "已撤销该用户的所有会话",
+	})
+}
+
 // GetUserProfile 获取当前用户的个人资料
 func (c *Controller) GetUserProfile(ctx *gin.Context) {
 	// 从上下文中获取用户ID
@@ -11,10 +11,12 @@ type UserResponse struct {
 	ID        uint       `json:"id"`
 	Username  string     `json:"username"`
 	Email     string     `json:"email"`
-	Role      int        `json:"role"`
+	RoleID    uint       `json:"role_id"`
 	Status    int        `json:"status"`
 	CreatedAt time.Time  `json:"created_at"`
 	LastLogin *time.Time `json:"last_login"`
+
+	TwoFactorEnabled bool `json:"two_factor_enabled"` // 是否开启两步验证
 }
 
 // UserListResponse 用户列表响应
@@ -29,10 +31,12 @@ func FromUser(user *model.User) UserResponse {
 		ID:        user.ID,
 		Username:  user.Username,
 		Email:     user.Email,
-		Role:      user.Role,
+		RoleID:    user.RoleID,
 		Status:    user.Status,
 		CreatedAt: user.CreatedAt,
 		LastLogin: user.LastLogin,
+
+		TwoFactorEnabled: user.TwoFactorEnabled,
 	}
 }
 
@@ -47,10 +51,8 @@ func FromUsers(users []model.User) []UserResponse {
 
 // UserPermissionResponse 用户权限响应
 type UserPermissionResponse struct {
-	Role        int    `json:"role"`      // 角色：0-管理员, 1-普通会员, 2-VIP会员
-	RoleName    string `json:"role_name"` // 角色名称
-	Permissions struct {
-		CanManageUsers       bool `json:"can_manage_users"`        // 是否可以管理用户
-		CanAccessVipFeatures bool `json:"can_access_vip_features"` // 是否可以访问VIP功能
-	} `json:"permissions"`
+	RoleID      uint     `json:"role_id"`     // 角色ID
+	RoleCode    string   `json:"role_code"`   // 角色编码
+	RoleName    string   `json:"role_name"`   // 角色名称
+	Permissions []string `json:"permissions"` // 拥有的权限标识
 }
@@ -2,6 +2,7 @@ package user
 
 import (
 	"github.com/gin-gonic/gin"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
 	"github.com/skyle1995/DevE-Server/middleware"
 )
 
@@ -17,16 +18,16 @@ func SetupUserRoutes(r *gin.Engine) {
 		// 用户个人资料相关路由（所有已登录用户可访问）
 		profile := protected.Group("/profile")
 		{
-			profile.GET("", userController.GetUserProfile)    // 获取个人资料
-			profile.PUT("", userController.UpdateUserProfile) // 更新个人资料
+			profile.GET("", userController.GetUserProfile)                                        // 获取个人资料
+			profile.PUT("", middleware.SessionOnlyMiddleware(), userController.UpdateUserProfile) // 更新个人资料
 		}
 
 		// 用户权限相关路由（所有已登录用户可访问）
 		protected.GET("/permission", userController.GetUserPermission) // 获取当前用户权限
 
-		// 管理员路由组（仅管理员可访问）
+		// 用户管理路由组（需要用户管理权限）
 		admin := protected.Group("/admin")
-		admin.Use(middleware.AdminAuthMiddleware())
+		admin.Use(middleware.PermissionMiddleware(dbmodel.PermissionUserManage))
 		{
 			// 用户管理相关路由
 			users := admin.Group("/users")
@@ -36,6 +37,13 @@ func SetupUserRoutes(r *gin.Engine) {
 				users.POST("", userController.CreateUser)       // 创建用户
 				users.PUT("/:id", userController.UpdateUser)    // 更新用户
 				users.DELETE("/:id", userController.DeleteUser) // 删除用户
+
+				users.PUT("/:id/password", userController.ResetUserPassword)                                            // 重置密码，请求中包含密码，由控制器记录日志
+				users.DELETE("/:id/two-factor", middleware.OperationLogMiddleware(), userController.ResetUserTwoFactor) // 重置两步验证
+
+				users.GET("/:id/sessions", userController.GetUserSessions)                                                       // 获取登录会话
+				users.DELETE("/:id/sessions", middleware.OperationLogMiddleware(), userController.RevokeUserSessions)            // 撤销所有登录会话
+				users.DELETE("/:id/sessions/:session_id", middleware.OperationLogMiddleware(), userController.RevokeUserSession) // 撤销登录会话
 			}
 		}
 	}
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"strconv"
 
+	"github.com/skyle1995/DevE-Server/apps/auth"
 	authmodel "github.com/skyle1995/DevE-Server/apps/auth/model"
+	"github.com/skyle1995/DevE-Server/apps/role"
 	usermodel "github.com/skyle1995/DevE-Server/apps/user/model"
 	"github.com/skyle1995/DevE-Server/database"
 	dbmodel "github.com/skyle1995/DevE-Server/database/model"
@@ -20,15 +22,15 @@ func NewService() *Service {
 }
 
 // GetUserList 获取用户列表
-func (s *Service) GetUserList(roleStr, statusStr, pageStr, limitStr string) ([]dbmodel.User, int64, error) {
+func (s *Service) GetUserList(roleIDStr, statusStr, pageStr, limitStr string) ([]dbmodel.User, int64, error) {
 	// 构建查询
 	query := database.DB.Model(&dbmodel.User{})
 
 	// 应用过滤条件
-	if roleStr != "" {
-		role, err := strconv.Atoi(roleStr)
+	if roleIDStr != "" {
+		roleID, err := strconv.Atoi(roleIDStr)
 		if err == nil {
-			query = query.Where("role = ?", role)
+			query = query.Where("role_id = ?", roleID)
 		}
 	}
 
@@ -58,7 +60,7 @@ func (s *Service) GetUserList(roleStr, statusStr, pageStr, limitStr string) ([]d
 
 	// 查询用户列表
 	var users []dbmodel.User
-	result := query.Select("id, username, email, role, status, created_at, last_login").Offset(offset).Limit(limit).Find(&users)
+	result := query.Select("id, username, email, role_id, status, created_at, last_login").Offset(offset).Limit(limit).Find(&users)
 	if result.Error != nil {
 		return nil, 0, result.Error
 	}
@@ -76,7 +78,7 @@ func (s *Service) GetUserByID(userIDStr string) (*dbmodel.User, error) {
 
 	// 查询用户
 	var user dbmodel.User
-	result := database.DB.Select("id, username, email, role, status, created_at, last_login").Where("id = ?", userID).First(&user)
+	result := database.DB.Select("id, username, email, role_id, status, created_at, last_login").Where("id = ?", userID).First(&user)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, errors.New("用户不存在")
@@ -87,8 +89,24 @@ func (s *Service) GetUserByID(userIDStr string) (*dbmodel.User, error) {
 	return &user, nil
 }
 
-// CreateUser 创建新用户
-func (s *Service) CreateUser(username, password, email, roleStr, statusStr string) error {
+// parseRoleID 解析角色ID并检查角色是否存在，以及操作人能否分配该角色
+func parseRoleID(roleIDStr string, operatorID uint) (uint, error) {
+	roleID, err := strconv.ParseUint(roleIDStr, 10, 32)
+	if err != nil {
+		return 0, errors.New("无效的角色ID")
+	}
+	var assigned dbmodel.Role
+	if err := database.DB.Where("id = ?", roleID).First(&assigned).Error; err != nil {
+		return 0, errors.New("角色不存在")
+	}
+	if err := role.CheckAssignable(operatorID, assigned); err != nil {
+		return 0, err
+	}
+	return uint(roleID), nil
+}
+
+// CreateUser 创建新用户，只能分配权限不超出操作人的角色
+func (s *Service) CreateUser(username, password, email, roleIDStr, statusStr string, operatorID uint) error {
 	// 检查用户名是否已存在
 	var count int64
 	database.DB.Model(&dbmodel.User{}).Where("username = ?", username).Count(&count)
@@ -103,9 +121,9 @@ func (s *Service) CreateUser(username, password, email, roleStr, statusStr strin
 	}
 
 	// 转换角色和状态
-	role, err := strconv.Atoi(roleStr)
+	roleID, err := parseRoleID(roleIDStr, operatorID)
 	if err != nil {
-		return errors.New("无效的角色值")
+		return err
 	}
 
 	status, err := strconv.Atoi(statusStr)
@@ -113,18 +131,17 @@ func (s *Service) CreateUser(username, password, email, roleStr, statusStr strin
 		return errors.New("无效的状态值")
 	}
 
-	// 哈希密码
-	hashedPassword, err := authmodel.HashPassword(password)
-	if err != nil {
-		return errors.New("密码处理失败")
+	// 检查密码策略
+	if err := auth.NewService().CheckPasswordPolicy(password); err != nil {
+		return err
 	}
 
-	// 创建新用户
+	// 创建新用户，密码由模型钩子哈希
 	newUser := dbmodel.User{
 		Username: username,
-		Password: hashedPassword,
+		Password: password,
 		Email:    email,
-		Role:     role,
+		RoleID:   roleID,
 		Status:   status,
 	}
 
@@ -137,8 +154,8 @@ func (s *Service) CreateUser(username, password, email, roleStr, statusStr strin
 	return nil
 }
 
-// UpdateUser 更新用户信息
-func (s *Service) UpdateUser(userIDStr, email, roleStr, statusStr string) error {
+// UpdateUser 更新用户信息，不能修改权限超出操作人的用户，也不能分配权限超出操作人的角色
+func (s *Service) UpdateUser(userIDStr, email, roleIDStr, statusStr string, operatorID uint) error {
 	// 转换用户ID
 	userID, err := strconv.Atoi(userIDStr)
 	if err != nil {
@@ -154,6 +171,9 @@ func (s *Service) UpdateUser(userIDStr, email, roleStr, statusStr string) error
 		}
 		return result.Error
 	}
+	if err := role.CheckUserManageable(operatorID, user); err != nil {
+		return err
+	}
 
 	// 准备更新数据
 	updates := make(map[string]interface{})
@@ -170,12 +190,12 @@ func (s *Service) UpdateUser(userIDStr, email, roleStr, statusStr string) error
 	}
 
 	// 更新角色（如果提供）
-	if roleStr != "" {
-		role, err := strconv.Atoi(roleStr)
+	if roleIDStr != "" {
+		roleID, err := parseRoleID(roleIDStr, operatorID)
 		if err != nil {
-			return errors.New("无效的角色值")
+			return err
 		}
-		updates["role"] = role
+		updates["role_id"] = roleID
 	}
 
 	// 更新状态（如果提供）
@@ -198,11 +218,17 @@ func (s *Service) UpdateUser(userIDStr, email, roleStr, statusStr string) error
 		return errors.New("更新用户失败: " + result.Error.Error())
 	}
 
+	// 禁用用户或修改角色后撤销其所有会话，已签发的令牌立即失效
+	status, statusChanged := updates["status"]
+	if _, roleChanged := updates["role_id"]; roleChanged || (statusChanged && status != dbmodel.UserStatusActive) {
+		return auth.NewService().RevokeUserSessions(user.ID)
+	}
+
 	return nil
 }
 
-// DeleteUser 删除用户
-func (s *Service) DeleteUser(userIDStr string) error {
+// DeleteUser 删除用户，不能删除权限超出操作人的用户
+func (s *Service) DeleteUser(userIDStr string, operatorID uint) error {
 	// 转换用户ID
 	userID, err := strconv.Atoi(userIDStr)
 	if err != nil {
@@ -218,6 +244,9 @@ func (s *Service) DeleteUser(userIDStr string) error {
 		}
 		return result.Error
 	}
+	if err := role.CheckUserManageable(operatorID, user); err != nil {
+		return err
+	}
 
 	// 删除用户
 	result = database.DB.Delete(&user)
@@ -225,14 +254,116 @@ func (s *Service) DeleteUser(userIDStr string) error {
 		return errors.New("删除用户失败: " + result.Error.Error())
 	}
 
+	// 撤销已删除用户的所有会话
+	return auth.NewService().RevokeUserSessions(user.ID)
+}
+
+// ResetUserPassword 重置用户密码并撤销其所有会话，用户下次登录时需要修改密码
+func (s *Service) ResetUserPassword(userIDStr, password string, operatorID uint) (dbmodel.User, error) {
+	user, err := findManageableUser(userIDStr, operatorID)
+	if err != nil {
+		return user, err
+	}
+	return user, auth.NewService().SetUserPassword(user.ID, password, true)
+}
+
+// ResetUserTwoFactor 重置用户的两步验证，用于用户丢失验证器和恢复码的情况
+// 角色要求两步验证的用户下次登录时需要重新绑定验证器
+func (s *Service) ResetUserTwoFactor(userIDStr string, operatorID uint) error {
+	// 转换用户ID
+	userID, err := strconv.Atoi(userIDStr)
+	if err != nil {
+		return errors.New("无效的用户ID")
+	}
+
+	// 查询用户
+	var user dbmodel.User
+	result := database.DB.Where("id = ?", userID).First(&user)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return errors.New("用户不存在")
+		}
+		return result.Error
+	}
+	if err := role.CheckUserManageable(operatorID, user); err != nil {
+		return err
+	}
+	if !user.TwoFactorEnabled {
+		return errors.New("该用户未开启两步验证")
+	}
+
+	// 清除两步验证设置
+	result = database.DB.Model(&user).
+		Select("two_factor_enabled", "two_factor_secret", "two_factor_last_step", "two_factor_recovery_codes", "two_factor_enabled_at").
+		Updates(&dbmodel.User{})
+	if result.Error != nil {
+		return errors.New("重置两步验证失败: " + result.Error.Error())
+	}
+
 	return nil
 }
 
+// GetUserSessions 获取用户当前有效的登录会话
+func (s *Service) GetUserSessions(userIDStr string) ([]authmodel.SessionResponse, error) {
+	user, err := findUser(userIDStr)
+	if err != nil {
+		return nil, err
+	}
+	return auth.NewService().GetSessions(user.ID, "")
+}
+
+// RevokeUserSession 撤销用户的一个登录会话
+func (s *Service) RevokeUserSession(userIDStr, sessionIDStr string, operatorID uint) error {
+	user, err := findManageableUser(userIDStr, operatorID)
+	if err != nil {
+		return err
+	}
+	sessionID, err := strconv.Atoi(sessionIDStr)
+	if err != nil {
+		return errors.New("无效的会话ID")
+	}
+	return auth.NewService().RevokeSession(user.ID, uint(sessionID))
+}
+
+// RevokeUserSessions 撤销用户的所有登录会话，强制用户在所有设备上重新登录
+func (s *Service) RevokeUserSessions(userIDStr string, operatorID uint) error {
+	user, err := findManageableUser(userIDStr, operatorID)
+	if err != nil {
+		return err
+	}
+	return auth.NewService().RevokeUserSessions(user.ID)
+}
+
+// findManageableUser 查询操作人可以管理的用户，目标用户的权限超出操作人时返回错误
+func findManageableUser(userIDStr string, operatorID uint) (dbmodel.User, error) {
+	user, err := findUser(userIDStr)
+	if err != nil {
+		return user, err
+	}
+	return user, role.CheckUserManageable(operatorID, user)
+}
+
+// findUser 根据字符串形式的用户ID查询用户
+func findUser(userIDStr string) (dbmodel.User, error) {
+	var user dbmodel.User
+	userID, err := strconv.Atoi(userIDStr)
+	if err != nil {
+		return user, errors.New("无效的用户ID")
+	}
+	if err := database.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, errors.New("用户不存在")
+		}
+		return user, err
+	}
+	return user, nil
+}
+
 // GetUserProfile 获取用户个人资料
 func (s *Service) GetUserProfile(userID uint) (map[string]interface{}, error) {
 	// 查询用户
 	var user dbmodel.User
-	result := database.DB.Select("id, username, email, role, status, created_at, last_login").Where("id = ?", userID).First(&user)
+	result := database.DB.Select("id, username, email, role_id, status, created_at, last_login").Where("id = ?", userID).First(&user)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, errors.New("用户不存在")
@@ -245,7 +376,7 @@ func (s *Service) GetUserProfile(userID uint) (map[string]interface{}, error) {
 		"id":         user.ID,
 		"username":   user.Username,
 		"email":      user.Email,
-		"role":       user.Role,
+		"role_id":    user.RoleID,
 		"status":     user.Status,
 		"created_at": user.CreatedAt,
 		"last_login": user.LastLogin,
@@ -306,26 +437,24 @@ func (s *Service) GetUserPermission(userID uint) (*usermodel.UserPermissionRespo
 		return nil, result.Error
 	}
 
-	// 创建权限响应
-	permission := &usermodel.UserPermissionResponse{
-		Role: user.Role,
+	// 查询角色
+	var role dbmodel.Role
+	if err := database.DB.Where("id = ?", user.RoleID).First(&role).Error; err != nil {
+		return nil, errors.New("用户角色不存在")
 	}
 
-	// 设置角色名称
-	switch user.Role {
-	case 0:
-		permission.RoleName = "管理员"
-	case 1:
-		permission.RoleName = "普通会员"
-	case 2:
-		permission.RoleName = "VIP会员"
-	default:
-		permission.RoleName = "未知角色"
+	// 创建权限响应，拥有所有权限的角色返回全部权限标识
+	permission := &usermodel.UserPermissionResponse{
+		RoleID:      role.ID,
+		RoleCode:    role.Code,
+		RoleName:    role.Name,
+		Permissions: []string{},
+	}
+	for _, p := range dbmodel.Permissions {
+		if role.HasPermission(p.Key) {
+			permission.Permissions = append(permission.Permissions, p.Key)
+		}
 	}
 
-	// 设置权限
-	permission.Permissions.CanManageUsers = user.Role == 0                         // 只有管理员可以管理用户
-	permission.Permissions.CanAccessVipFeatures = user.Role == 0 || user.Role == 2 // 管理员和VIP会员可以访问VIP功能
-
 	return permission, nil
 }
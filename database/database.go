@@ -84,14 +84,28 @@ func initDefaultSystemSettings() {
 
 // initTables 初始化数据库表结构
 func initTables() {
+	// 迁移前修正旧版本的设备表结构
+	if err := migrateDeviceIdentity(DB); err != nil {
+		log.Errorf("迁移设备表结构失败: %v", err)
+	}
+
 	// 自动迁移表结构
 	models := []interface{}{
 		&model.User{},
+		&model.Role{},
 		&model.App{},
 		&model.CardType{},
 		&model.Card{},
 		&model.Device{},
 		&model.SystemSetting{},
+		&model.UserToken{},
+		&model.RefreshToken{},
+		&model.RevokedToken{},
+		&model.UserSession{},
+		&model.AccessToken{},
+		&model.PasswordHistory{},
+		&model.InviteCode{},
+		&model.InviteCodeUsage{},
 	}
 
 	for _, model := range models {
@@ -1,11 +1,19 @@
 package database
 
 import (
+	"strings"
+	"time"
+
 	log "github.com/sirupsen/logrus"
 	"github.com/skyle1995/DevE-Server/database/model"
 	"gorm.io/gorm"
 )
 
+// 一次性数据迁移的名称，执行后记录在 schema_migrations 表中，之后启动时不再执行
+const (
+	migrationNormalizeCardStatus = "normalize_card_status_v1"
+)
+
 // Migration 数据库迁移结构体
 type Migration struct {
 	db *gorm.DB
@@ -20,9 +28,15 @@ func NewMigration() *Migration {
 func (m *Migration) AutoMigrate() error {
 	log.Info("开始自动迁移数据库表结构...")
 
+	// 迁移前修正旧版本的设备表结构
+	if err := migrateDeviceIdentity(m.db); err != nil {
+		return err
+	}
+
 	// 添加所有需要迁移的模型
 	models := []interface{}{
 		&model.User{},
+		&model.Role{},
 		&model.App{},
 		&model.CardType{},
 		&model.Card{},
@@ -31,6 +45,20 @@ func (m *Migration) AutoMigrate() error {
 		&model.App{},
 		&model.Notice{},
 		&model.Logs{},
+		&model.CardExport{},
+		&model.CardExportItem{},
+		&model.CardTemplate{},
+		&model.CardBatch{},
+		&model.CardEvent{},
+		&model.UserToken{},
+		&model.RefreshToken{},
+		&model.RevokedToken{},
+		&model.UserSession{},
+		&model.AccessToken{},
+		&model.PasswordHistory{},
+		&model.InviteCode{},
+		&model.InviteCodeUsage{},
+		&model.SchemaMigration{},
 	}
 
 	for _, model := range models {
@@ -45,6 +73,42 @@ func (m *Migration) AutoMigrate() error {
 	return nil
 }
 
+// migrateDeviceIdentity 将设备标识从全局唯一的设备ID迁移为（应用ID, 设备ID）联合唯一
+// 旧版本的卡密模型将设备关联误识别为 devices.device_id 引用 cards.id 的外键，并为设备ID创建了全局唯一索引，
+// 同一设备使用多个应用时无法登记。需在自动迁移设备表之前删除该外键和索引，
+// 之后自动迁移会将 device_id 修正为字符串类型并创建联合唯一索引。旧索引保证了设备ID不重复，联合索引不会因已有数据创建失败
+func migrateDeviceIdentity(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable("devices") {
+		return nil
+	}
+
+	if migrator.HasConstraint("devices", "fk_cards_device") {
+		// MySQL 8.0.19 之前的版本和 MariaDB 不支持 DROP CONSTRAINT，外键需使用 DROP FOREIGN KEY 删除
+		var err error
+		if db.Dialector.Name() == "mysql" {
+			err = db.Exec("ALTER TABLE `devices` DROP FOREIGN KEY `fk_cards_device`").Error
+		} else {
+			err = migrator.DropConstraint("devices", "fk_cards_device")
+		}
+		if err != nil {
+			log.Errorf("删除设备表旧外键失败: %v", err)
+			return err
+		}
+		log.Info("已删除设备表旧外键 fk_cards_device")
+	}
+
+	if migrator.HasIndex("devices", "idx_devices_device_id") {
+		if err := migrator.DropIndex("devices", "idx_devices_device_id"); err != nil {
+			log.Errorf("删除设备ID全局唯一索引失败: %v", err)
+			return err
+		}
+		log.Info("已删除设备ID全局唯一索引 idx_devices_device_id")
+	}
+
+	return nil
+}
+
 // initAppSettings 初始化应用设置
 func (m *Migration) initAppSettings() error {
 	// 查询所有应用
@@ -84,11 +148,26 @@ func (m *Migration) InitDefaultData() error {
 		return err
 	}
 
+	// 初始化默认角色
+	if err := m.initRoles(); err != nil {
+		return err
+	}
+
+	// 将旧版本的角色编号迁移为角色ID
+	if err := m.migrateLegacyRoles(); err != nil {
+		return err
+	}
+
 	// 初始化管理员账户
 	if err := m.initAdminUser(); err != nil {
 		return err
 	}
 
+	// 规范化旧版本写入的卡密状态，只执行一次
+	if err := m.runOnce(migrationNormalizeCardStatus, normalizeCardStatus); err != nil {
+		return err
+	}
+
 	// 初始化应用设置
 	if err := m.initAppSettings(); err != nil {
 		return err
@@ -116,22 +195,135 @@ func (m *Migration) initSystemSettings() error {
 	return nil
 }
 
+// initRoles 初始化默认角色，已存在的角色不做修改
+func (m *Migration) initRoles() error {
+	for _, role := range model.DefaultRoles {
+		var count int64
+		m.db.Model(&model.Role{}).Where("code = ?", role.Code).Count(&count)
+		if count > 0 {
+			continue
+		}
+
+		if err := m.db.Create(&role).Error; err != nil {
+			log.Errorf("创建默认角色 %s 失败: %v", role.Code, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// legacyRoleCodes 旧版本的角色编号对应的角色编码
+var legacyRoleCodes = map[string]string{
+	"0": model.RoleCodeAdmin,
+	"1": model.RoleCodeMember,
+	"2": model.RoleCodeVIP,
+}
+
+// migrateLegacyRoles 将旧版本用户表和邀请码表中的角色编号迁移为角色ID，并删除旧的 role 字段
+// 要求开启两步验证的角色设置同时由角色编号改为角色编码
+func (m *Migration) migrateLegacyRoles() error {
+	migrator := m.db.Migrator()
+	roleIDs := make(map[string]uint)
+	for number, code := range legacyRoleCodes {
+		var role model.Role
+		if err := m.db.Where("code = ?", code).First(&role).Error; err != nil {
+			log.Errorf("查询默认角色 %s 失败: %v", code, err)
+			return err
+		}
+		roleIDs[number] = role.ID
+	}
+
+	tables := []struct {
+		name  string
+		model interface{}
+	}{
+		{"users", &model.User{}},
+		{"invite_codes", &model.InviteCode{}},
+	}
+	for _, item := range tables {
+		table := item.name
+		if !migrator.HasTable(table) || !migrator.HasColumn(table, "role") {
+			continue
+		}
+
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			for number, roleID := range roleIDs {
+				if err := tx.Table(table).Where("role = ?", number).Update("role_id", roleID).Error; err != nil {
+					return err
+				}
+			}
+			// 未知的角色编号按普通会员处理
+			if err := tx.Table(table).Where("role_id IS NULL OR role_id = 0").Update("role_id", roleIDs["1"]).Error; err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropColumn(item.model, "role"); err != nil {
+				return err
+			}
+			// SQLite删除字段时会重建数据表，需要重新创建索引
+			return tx.AutoMigrate(item.model)
+		})
+		if err != nil {
+			log.Errorf("迁移 %s 表的角色失败: %v", table, err)
+			return err
+		}
+		log.Infof("已将 %s 表的角色编号迁移为角色ID", table)
+
+		if table != "users" {
+			continue
+		}
+		var setting model.SystemSetting
+		if m.db.Where(&model.SystemSetting{Key: "security_two_factor_roles"}).First(&setting).Error != nil || setting.Value == "" {
+			continue
+		}
+		codes := make([]string, 0)
+		for _, number := range strings.Split(setting.Value, ",") {
+			if code, ok := legacyRoleCodes[strings.TrimSpace(number)]; ok {
+				codes = append(codes, code)
+			}
+		}
+		if err := m.db.Model(&setting).Update("value", strings.Join(codes, ",")).Error; err != nil {
+			log.Errorf("迁移两步验证角色设置失败: %v", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
 // initAdminUser 初始化管理员账户
 func (m *Migration) initAdminUser() error {
-	// 检查是否已存在管理员账户或同名用户
+	var adminRole model.Role
+	if err := m.db.Where("code = ?", model.RoleCodeAdmin).First(&adminRole).Error; err != nil {
+		log.Errorf("查询管理员角色失败: %v", err)
+		return err
+	}
+
+	// 检查是否已存在管理员账户
 	var count int64
-	m.db.Model(&model.User{}).Where("role = ? OR username = ?", 1, "admin").Count(&count)
+	m.db.Model(&model.User{}).Where("role_id = ?", adminRole.ID).Count(&count)
 	if count > 0 {
 		return nil
 	}
 
+	// 旧版本创建的默认管理员账户使用了普通会员的角色编号，将其设置为管理员
+	var admin model.User
+	if err := m.db.Where("username = ?", "admin").First(&admin).Error; err == nil {
+		if err := m.db.Model(&admin).Update("role_id", adminRole.ID).Error; err != nil {
+			log.Errorf("设置管理员账户角色失败: %v", err)
+			return err
+		}
+		log.Warn("没有管理员角色的用户，已将 admin 账户设置为管理员")
+		return nil
+	}
+
 	// 创建默认管理员账户
-	admin := model.User{
+	admin = model.User{
 		Username: "admin",
 		Password: "admin123", // 将在BeforeCreate钩子中自动哈希
 		Email:    "admin@example.com",
 		Nickname: "系统管理员",
-		Role:     1,
+		RoleID:   adminRole.ID,
 		Status:   1,
 	}
 
@@ -143,3 +335,82 @@ func (m *Migration) initAdminUser() error {
 
 	return nil
 }
+
+// runOnce 执行一次性数据迁移，迁移的修改和执行记录在同一事务中提交，已执行过的迁移直接跳过
+func (m *Migration) runOnce(name string, migrate func(tx *gorm.DB) error) error {
+	var count int64
+	if err := m.db.Model(&model.SchemaMigration{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		if err := migrate(tx); err != nil {
+			return err
+		}
+		return tx.Create(&model.SchemaMigration{Name: name, AppliedAt: time.Now()}).Error
+	})
+	if err != nil {
+		log.Errorf("执行数据迁移 %s 失败: %v", name, err)
+		return err
+	}
+	log.Infof("已执行数据迁移 %s", name)
+	return nil
+}
+
+// normalizeCardStatus 规范化旧版本写入的卡密状态
+// 旧版本中解绑会将卡密重置为未使用，部分接口将2视为已禁用，这里按激活和过期时间修正为统一的状态，每张修改的卡密记录一条系统事件
+func normalizeCardStatus(tx *gorm.DB) error {
+	now := time.Now()
+	rules := []struct {
+		name   string
+		where  string
+		args   []interface{}
+		status int
+	}{
+		{"未知状态", "status NOT IN ?", []interface{}{[]int{
+			model.CardStatusUnused, model.CardStatusActive, model.CardStatusExpired, model.CardStatusDisabled, model.CardStatusFrozen,
+		}}, model.CardStatusDisabled},
+		{"解绑后重置为未使用", "status = ? AND activate_at IS NOT NULL", []interface{}{model.CardStatusUnused}, model.CardStatusActive},
+		{"未过期却标记为已过期", "status = ? AND (activate_at IS NULL OR expire_at IS NULL OR expire_at > ?)", []interface{}{model.CardStatusExpired, now}, model.CardStatusDisabled},
+		{"缺少冻结记录且未激活", "status = ? AND frozen_at IS NULL AND activate_at IS NULL", []interface{}{model.CardStatusFrozen}, model.CardStatusUnused},
+		{"缺少冻结记录", "status = ? AND frozen_at IS NULL", []interface{}{model.CardStatusFrozen}, model.CardStatusActive},
+		{"已过期未更新状态", "status = ? AND expire_at <= ?", []interface{}{model.CardStatusActive, now}, model.CardStatusExpired},
+	}
+
+	for _, rule := range rules {
+		var cards []model.Card
+		var affected int64
+		result := tx.Where(rule.where, rule.args...).FindInBatches(&cards, 500, func(_ *gorm.DB, _ int) error {
+			ids := make([]uint, 0, len(cards))
+			events := make([]model.CardEvent, 0, len(cards))
+			for _, card := range cards {
+				ids = append(ids, card.ID)
+				event := model.NewCardEvent(card, model.CardEventEdit, model.CardActorSystem, 0, "").
+					WithChange(map[string]interface{}{"status": card.Status}, map[string]interface{}{"status": rule.status})
+				event.Message = "升级时修正卡密状态: " + rule.name
+				events = append(events, event)
+			}
+			// 同时增加版本号，使读取了旧状态的请求按版本保存时失败
+			if err := tx.Model(&model.Card{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+				"status":  rule.status,
+				"version": gorm.Expr("version + 1"),
+			}).Error; err != nil {
+				return err
+			}
+			affected += int64(len(ids))
+			return tx.Create(&events).Error
+		})
+		if result.Error != nil {
+			log.Errorf("规范化卡密状态失败（%s）: %v", rule.name, result.Error)
+			return result.Error
+		}
+		if affected > 0 {
+			log.Infof("规范化卡密状态（%s）: %d 张卡密修改为%s", rule.name, affected, model.CardStatusText[rule.status])
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/skyle1995/DevE-Server/database/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// legacyDeviceSchema 旧版本自动迁移生成的设备表结构
+// device_id 被误识别为引用 cards.id 的外键，并带有全局唯一索引
+var legacyDeviceSchema = []string{
+	"CREATE TABLE `devices` (`id` integer PRIMARY KEY AUTOINCREMENT,`device_id` integer NOT NULL,`device_name` text,`device_type` text,`device_os` text,`device_ip` text,`device_info` json,`last_active` datetime,`status` integer DEFAULT 1,`app_id` integer,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,CONSTRAINT `fk_cards_device` FOREIGN KEY (`device_id`) REFERENCES `cards`(`id`),CONSTRAINT `fk_devices_application` FOREIGN KEY (`app_id`) REFERENCES `apps`(`id`))",
+	"CREATE INDEX `idx_devices_deleted_at` ON `devices`(`deleted_at`)",
+	"CREATE UNIQUE INDEX `idx_devices_device_id` ON `devices`(`device_id`)",
+}
+
+func TestMigrateDeviceIdentity(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "legacy.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	// 设备表引用的应用表和卡密表结构未变，直接按当前模型创建
+	if err := db.AutoMigrate(&model.User{}, &model.App{}, &model.CardType{}, &model.Card{}); err != nil {
+		t.Fatalf("创建数据表失败: %v", err)
+	}
+	for _, sql := range legacyDeviceSchema {
+		if err := db.Exec(sql).Error; err != nil {
+			t.Fatalf("创建旧版设备表失败: %v", err)
+		}
+	}
+	// SQLite默认不检查外键，旧版本因此可以写入与卡密ID无关的设备ID
+	if err := db.Exec("INSERT INTO devices (device_id, device_name, status, app_id) VALUES (?, ?, 1, 1), (?, ?, 1, 2)",
+		"DEVICE_A", "设备A", "DEVICE_B", "设备B").Error; err != nil {
+		t.Fatalf("写入旧版设备数据失败: %v", err)
+	}
+
+	if err := (&Migration{db: db}).AutoMigrate(); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+	// 再次迁移应不做任何修改
+	if err := (&Migration{db: db}).AutoMigrate(); err != nil {
+		t.Fatalf("重复迁移失败: %v", err)
+	}
+
+	migrator := db.Migrator()
+	if migrator.HasConstraint("devices", "fk_cards_device") {
+		t.Error("旧外键 fk_cards_device 未删除")
+	}
+	if migrator.HasIndex("devices", "idx_devices_device_id") {
+		t.Error("设备ID全局唯一索引未删除")
+	}
+	if !migrator.HasIndex("devices", "idx_devices_app_device") {
+		t.Error("未创建（应用ID, 设备ID）联合唯一索引")
+	}
+
+	columns, err := migrator.ColumnTypes("devices")
+	if err != nil {
+		t.Fatalf("查询设备表字段失败: %v", err)
+	}
+	for _, column := range columns {
+		if column.Name() == "device_id" && column.DatabaseTypeName() == "integer" {
+			t.Error("device_id 字段仍为整数类型")
+		}
+	}
+
+	var devices []model.Device
+	if err := db.Order("id").Find(&devices).Error; err != nil {
+		t.Fatalf("查询设备失败: %v", err)
+	}
+	if len(devices) != 2 || devices[0].DeviceID != "DEVICE_A" || devices[1].DeviceID != "DEVICE_B" {
+		t.Fatalf("迁移后的设备数据不一致: %+v", devices)
+	}
+
+	// 同一设备可以在其他应用中登记，同一应用中不能重复登记
+	if err := db.Create(&model.Device{DeviceID: "DEVICE_A", AppID: 2, Status: 1}).Error; err != nil {
+		t.Errorf("同一设备在其他应用中登记失败: %v", err)
+	}
+	if err := db.Create(&model.Device{DeviceID: "DEVICE_A", AppID: 1, Status: 1}).Error; err == nil {
+		t.Error("同一设备在同一应用中重复登记成功，期望违反唯一索引")
+	}
+}
+
+func TestNormalizeCardStatusOnce(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "cards.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	migration := &Migration{db: db}
+	if err := migration.AutoMigrate(); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+	if err := migration.InitDefaultData(); err != nil {
+		t.Fatalf("初始化默认数据失败: %v", err)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	cards := []model.Card{
+		{CardNo: "EXPIRED_FUTURE", CardKey: "k1", Status: model.CardStatusExpired, ActivateAt: &past, ExpireAt: &future},
+		{CardNo: "FROZEN_ACTIVE", CardKey: "k2", Status: model.CardStatusFrozen, ActivateAt: &past},
+		{CardNo: "ACTIVE_OK", CardKey: "k3", Status: model.CardStatusActive, ActivateAt: &past, ExpireAt: &future},
+	}
+	if err := db.Create(&cards).Error; err != nil {
+		t.Fatalf("创建卡密失败: %v", err)
+	}
+
+	// 迁移已在初始化时执行，之后启动不再修改卡密
+	if err := migration.InitDefaultData(); err != nil {
+		t.Fatalf("重复初始化默认数据失败: %v", err)
+	}
+	var count int64
+	db.Model(&model.Card{}).Where("status = ?", model.CardStatusExpired).Count(&count)
+	if count != 1 {
+		t.Fatal("已执行的迁移不应再次修改卡密状态")
+	}
+
+	// 模拟从未执行过该迁移的旧版本数据库
+	db.Where("name = ?", migrationNormalizeCardStatus).Delete(&model.SchemaMigration{})
+	if err := migration.InitDefaultData(); err != nil {
+		t.Fatalf("初始化默认数据失败: %v", err)
+	}
+
+	expected := map[string]int{
+		"EXPIRED_FUTURE": model.CardStatusDisabled,
+		"FROZEN_ACTIVE":  model.CardStatusActive,
+		"ACTIVE_OK":      model.CardStatusActive,
+	}
+	for cardNo, status := range expected {
+		var card model.Card
+		db.Where("card_no = ?", cardNo).First(&card)
+		if card.Status != status {
+			t.Errorf("卡密 %s 的状态 = %d，期望 %d", cardNo, card.Status, status)
+		}
+		var events []model.CardEvent
+		db.Where("card_id = ?", card.ID).Find(&events)
+		changed := cardNo != "ACTIVE_OK"
+		if changed && (len(events) != 1 || events[0].ActorType != model.CardActorSystem || card.Version != 1) {
+			t.Errorf("卡密 %s 的事件 = %+v，版本号 = %d，期望一条系统事件并增加版本号", cardNo, events, card.Version)
+		}
+		if !changed && len(events) != 0 {
+			t.Errorf("未修改的卡密 %s 不应记录事件", cardNo)
+		}
+	}
+
+	db.Model(&model.SchemaMigration{}).Where("name = ?", migrationNormalizeCardStatus).Count(&count)
+	if count != 1 {
+		t.Errorf("迁移记录数量 = %d，期望 1", count)
+	}
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// AccessTokenPrefix 个人访问令牌的前缀，JWT认证中间件据此区分访问令牌和JWT
+const AccessTokenPrefix = "deve_"
+
+// AccessToken 个人访问令牌，用于脚本调用管理接口，只保存令牌的SHA256摘要
+// 令牌的权限为用户角色权限与令牌授权范围的交集，可以限定只能访问一个应用
+type AccessToken struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`                    // 主键ID
+	UserID     uint       `gorm:"index;not null" json:"user_id"`           // 用户ID
+	Name       string     `gorm:"size:50;not null" json:"name"`            // 令牌名称
+	Prefix     string     `gorm:"size:16;not null" json:"prefix"`          // 令牌开头的字符，用于识别令牌
+	TokenHash  string     `gorm:"size:64;uniqueIndex;not null" json:"-"`   // 令牌的SHA256摘要
+	Scopes     []string   `gorm:"type:json;serializer:json" json:"scopes"` // 授权范围，为权限标识列表
+	AppID      uint       `gorm:"not null;default:0" json:"app_id"`        // 限定访问的应用ID，0表示不限
+	ExpiresAt  *time.Time `json:"expires_at"`                              // 过期时间，为空表示永不过期
+	LastUsedAt *time.Time `json:"last_used_at"`                            // 最后使用时间
+	LastUsedIP string     `gorm:"size:50" json:"last_used_ip"`             // 最后使用IP
+	RevokedAt  *time.Time `gorm:"index" json:"revoked_at"`                 // 撤销时间
+	CreatedAt  time.Time  `json:"created_at"`                              // 创建时间
+}
+
+// TableName 指定表名
+func (AccessToken) TableName() string {
+	return "access_tokens"
+}
+
+// HasScope 判断令牌的授权范围是否包含指定权限
+func (t AccessToken) HasScope(permission string) bool {
+	for _, scope := range t.Scopes {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired 判断令牌在指定时间是否已过期
+func (t AccessToken) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
@@ -56,3 +56,16 @@ func (a *App) BeforeCreate(tx *gorm.DB) error {
 	// 这里暂时不实现，因为通常会在服务层生成这些值
 	return nil
 }
+
+// TrialEndTime 获取应用试用结束时间
+// 未开启试用或试用额度为0时返回false
+func (a *App) TrialEndTime() (time.Time, bool) {
+	if a.AllowTrial != 1 || a.TrialAmount <= 0 {
+		return time.Time{}, false
+	}
+
+	// 时长计费模式下试用额度为小时数
+	// 点数计费模式下，假设每个点数对应1小时的使用时间
+	trialDuration := time.Duration(a.TrialAmount) * time.Hour
+	return a.CreatedAt.Add(trialDuration), true
+}
@@ -1,30 +1,39 @@
 package model
 
 import (
+	"errors"
+	"fmt"
+	"regexp"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CardType 卡密类型模型
 type CardType struct {
-	ID                    uint           `gorm:"primaryKey" json:"id"`                      // 主键ID
-	Name                  string         `gorm:"size:50;not null" json:"name"`              // 类型名称
-	Description           string         `gorm:"size:255" json:"description"`               // 类型描述
-	Duration              int            `gorm:"not null" json:"duration"`                  // 有效时长
-	TimeUnit              string         `gorm:"size:10;default:'day'" json:"time_unit"`    // 时间单位（day, month, year）
-	ValidDays             int            `gorm:"default:0" json:"valid_days"`               // 有效天数
-	Price                 float64        `gorm:"type:decimal(10,2);not null" json:"price"`  // 价格
-	Status                int            `gorm:"default:1" json:"status"`                   // 状态：0-禁用，1-启用
-	AppID                 uint           `json:"app_id"`                                    // 所属应用ID
-	App                   App            `gorm:"foreignKey:AppID" json:"app"`               // 所属应用
-	UserID                int            `gorm:"not null" json:"user_id"`                   // 创建者ID
-	DefaultMaxRebindCount int            `gorm:"default:0" json:"default_max_rebind_count"` // 默认最大换绑次数
-	DefaultMaxUnbindCount int            `gorm:"default:0" json:"default_max_unbind_count"` // 默认最大解绑次数
-	MaxBindCount          int            `gorm:"default:0" json:"max_bind_count"`           // 最大换绑/解绑次数
-	CreatedAt             time.Time      `json:"created_at"`                                // 创建时间
-	UpdatedAt             time.Time      `json:"updated_at"`                                // 更新时间
-	DeletedAt             gorm.DeletedAt `gorm:"index" json:"-"`                            // 删除时间
+	ID                    uint           `gorm:"primaryKey" json:"id"`                          // 主键ID
+	Name                  string         `gorm:"size:50;not null" json:"name"`                  // 类型名称
+	Description           string         `gorm:"size:255" json:"description"`                   // 类型描述
+	Duration              int            `gorm:"not null" json:"duration"`                      // 有效时长
+	TimeUnit              string         `gorm:"size:10;default:'day'" json:"time_unit"`        // 时间单位（day, month, year）
+	ValidDays             int            `gorm:"default:0" json:"valid_days"`                   // 有效天数
+	Price                 float64        `gorm:"type:decimal(10,2);not null" json:"price"`      // 价格
+	Status                int            `gorm:"default:1" json:"status"`                       // 状态：0-禁用，1-启用
+	AppID                 uint           `json:"app_id"`                                        // 所属应用ID
+	App                   App            `gorm:"foreignKey:AppID" json:"app"`                   // 所属应用
+	UserID                int            `gorm:"not null" json:"user_id"`                       // 创建者ID
+	DefaultMaxRebindCount int            `gorm:"default:0" json:"default_max_rebind_count"`     // 默认最大换绑次数
+	DefaultMaxUnbindCount int            `gorm:"default:0" json:"default_max_unbind_count"`     // 默认最大解绑次数
+	MaxBindCount          int            `gorm:"default:0" json:"max_bind_count"`               // 最大换绑/解绑次数
+	CardNoTemplateID      *uint          `json:"card_no_template_id"`                           // 卡号生成模板ID，为空时使用用户默认模板
+	CardKeyTemplateID     *uint          `json:"card_key_template_id"`                          // 卡密生成模板ID，为空时使用用户默认模板
+	AllowClientFreeze     bool           `gorm:"default:false" json:"allow_client_freeze"`      // 是否允许终端用户通过客户端接口冻结
+	MaxFreezeCount        int            `gorm:"default:0" json:"max_freeze_count"`             // 每张卡密通过客户端接口冻结的最大次数，0为不限
+	Entitlements          Entitlements   `gorm:"type:json;serializer:json" json:"entitlements"` // 功能权益（JSON格式，如 {"export":true,"max_threads":8}）
+	CreatedAt             time.Time      `json:"created_at"`                                    // 创建时间
+	UpdatedAt             time.Time      `json:"updated_at"`                                    // 更新时间
+	DeletedAt             gorm.DeletedAt `gorm:"index" json:"-"`                                // 删除时间
 }
 
 // TableName 指定表名
@@ -32,6 +41,48 @@ func (CardType) TableName() string {
 	return "types"
 }
 
+// 功能权益限制
+const (
+	MaxEntitlementCount       = 50  // 每个卡密类型最多的权益数量
+	MaxEntitlementValueLength = 255 // 字符串类型权益值的最大长度
+)
+
+// entitlementKeyPattern 权益名称格式：字母开头，由字母、数字、下划线、点和中划线组成，最长64个字符
+var entitlementKeyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]{0,63}$`)
+
+// Entitlements 卡密类型的功能权益，名称对应布尔开关、数值限制或字符串配置
+type Entitlements map[string]interface{}
+
+// Validate 校验权益名称和值，值只允许布尔、数字或字符串
+func (e Entitlements) Validate() error {
+	if len(e) > MaxEntitlementCount {
+		return fmt.Errorf("功能权益最多%d项", MaxEntitlementCount)
+	}
+	for key, value := range e {
+		if !entitlementKeyPattern.MatchString(key) {
+			return fmt.Errorf("功能权益名称 %q 格式错误，需以字母开头，仅包含字母、数字、下划线、点和中划线，最长64个字符", key)
+		}
+		switch v := value.(type) {
+		case bool, float64, int, int64:
+		case string:
+			if len(v) > MaxEntitlementValueLength {
+				return fmt.Errorf("功能权益 %s 的值超过%d个字符", key, MaxEntitlementValueLength)
+			}
+		default:
+			return fmt.Errorf("功能权益 %s 的值只能是布尔、数字或字符串", key)
+		}
+	}
+	return nil
+}
+
+// OrEmpty 返回非nil的权益集合，保证序列化为 {} 而不是 null
+func (e Entitlements) OrEmpty() Entitlements {
+	if e == nil {
+		return Entitlements{}
+	}
+	return e
+}
+
 // Card 卡密模型
 type Card struct {
 	ID             uint           `gorm:"primaryKey" json:"id"`                          // 主键ID
@@ -42,9 +93,9 @@ type Card struct {
 	AppID          uint           `json:"app_id"`                                        // 所属应用ID
 	App            App            `gorm:"foreignKey:AppID" json:"app"`                   // 所属应用
 	UserID         int            `gorm:"not null" json:"user_id"`                       // 创建者ID
-	Status         int            `gorm:"default:0" json:"status"`                       // 状态：0-未使用, 1-已使用, 2-已过期, 3-已禁用
+	BatchID        *uint          `gorm:"index" json:"batch_id"`                         // 所属批次ID，导入的卡密为空
+	Status         int            `gorm:"default:0" json:"status"`                       // 状态：0-未使用, 1-已使用, 2-已过期, 3-已禁用, 4-已冻结
 	DeviceID       *string        `json:"device_id"`                                     // 使用设备ID
-	Device         *Device        `gorm:"foreignKey:DeviceID" json:"device,omitempty"`   // 使用设备
 	BindingInfo    string         `gorm:"type:text" json:"binding_info"`                 // 绑定信息（JSON格式，根据应用的绑定类型存储设备ID或IP地址）
 	BindCount      int            `gorm:"default:0" json:"bind_count"`                   // 换绑/解绑次数统计
 	MaxRebindCount int            `gorm:"default:0" json:"max_rebind_count"`             // 最大换绑次数
@@ -55,6 +106,11 @@ type Card struct {
 	ExpireAt       *time.Time     `json:"expire_at"`                                     // 过期时间
 	IsOnline       int            `gorm:"default:0" json:"is_online"`                    // 在线状态：0-离线，1-在线
 	LastHeartbeat  *time.Time     `json:"last_heartbeat"`                                // 最后心跳时间
+	FrozenAt       *time.Time     `json:"frozen_at"`                                     // 冻结时间
+	FrozenRemain   *int64         `json:"frozen_remain"`                                 // 冻结时的剩余有效秒数，永久卡密为空
+	FreezeSource   int            `gorm:"default:0" json:"freeze_source"`                // 冻结来源：1-卡密所有者，2-终端用户
+	FreezeCount    int            `gorm:"default:0" json:"freeze_count"`                 // 终端用户已冻结次数
+	Version        int            `gorm:"default:0;not null" json:"-"`                   // 版本号，每次按版本保存时加1，用于乐观锁
 	CreatedAt      time.Time      `json:"created_at"`                                    // 创建时间
 	UpdatedAt      time.Time      `json:"updated_at"`                                    // 更新时间
 	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`                                // 删除时间
@@ -72,19 +128,168 @@ func (c *Card) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
-// Activate 激活卡密
-func (c *Card) Activate(deviceID string) {
+// 卡密状态
+const (
+	CardStatusUnused   = 0 // 未使用，尚未激活
+	CardStatusActive   = 1 // 已使用，已激活且未过期，解绑后仍为已使用
+	CardStatusExpired  = 2 // 已过期
+	CardStatusDisabled = 3 // 已禁用
+	CardStatusFrozen   = 4 // 已冻结，保留剩余时长
+)
+
+// CardStatusText 卡密状态文本
+var CardStatusText = map[int]string{
+	CardStatusUnused:   "未使用",
+	CardStatusActive:   "已使用",
+	CardStatusExpired:  "已过期",
+	CardStatusDisabled: "已禁用",
+	CardStatusFrozen:   "已冻结",
+}
+
+// cardTransitions 卡密状态允许的转换
+var cardTransitions = map[int][]int{
+	CardStatusUnused:   {CardStatusActive, CardStatusDisabled},
+	CardStatusActive:   {CardStatusExpired, CardStatusDisabled, CardStatusFrozen},
+	CardStatusExpired:  {CardStatusActive, CardStatusDisabled},                                    // 续期后恢复为已使用
+	CardStatusDisabled: {CardStatusUnused, CardStatusActive, CardStatusExpired, CardStatusFrozen}, // 启用后恢复为禁用前的状态
+	CardStatusFrozen:   {CardStatusActive, CardStatusDisabled},
+}
+
+// CanTransitCardStatus 检查卡密状态能否从from转换为to
+func CanTransitCardStatus(from, to int) bool {
+	for _, status := range cardTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CardStatusesTo 返回可以转换为to的卡密状态，用于按状态批量更新时筛选
+func CardStatusesTo(to int) []int {
+	var statuses []int
+	for from := CardStatusUnused; from <= CardStatusFrozen; from++ {
+		if CanTransitCardStatus(from, to) {
+			statuses = append(statuses, from)
+		}
+	}
+	return statuses
+}
+
+// Transit 将卡密转换为目标状态，不允许的转换返回错误
+func (c *Card) Transit(to int) error {
+	if _, ok := CardStatusText[to]; !ok {
+		return fmt.Errorf("无效的卡密状态: %d", to)
+	}
+	if !CanTransitCardStatus(c.Status, to) {
+		return fmt.Errorf("卡密状态不能从%s变为%s", CardStatusText[c.Status], CardStatusText[to])
+	}
+	c.Status = to
+	if to != CardStatusActive {
+		c.IsOnline = 0
+	}
+	return nil
+}
+
+// EnabledStatus 返回启用已禁用的卡密后应恢复的状态
+func (c *Card) EnabledStatus() int {
+	switch {
+	case c.FrozenAt != nil:
+		return CardStatusFrozen
+	case c.ActivateAt == nil:
+		return CardStatusUnused
+	case c.IsExpired():
+		return CardStatusExpired
+	default:
+		return CardStatusActive
+	}
+}
+
+// Activate 激活卡密并绑定设备，过期时间按卡密类型的有效天数计算
+func (c *Card) Activate(deviceID string, validDays int) error {
+	if err := c.Transit(CardStatusActive); err != nil {
+		return err
+	}
 	now := time.Now()
-	c.Status = 1
 	c.DeviceID = &deviceID
 	c.ActivateAt = &now
 	c.RebindCount = 0 // 初始化换绑次数
 
 	// 计算过期时间
-	if c.CardType.ValidDays > 0 {
-		expireAt := now.AddDate(0, 0, c.CardType.ValidDays)
+	c.ExpireAt = nil
+	if validDays > 0 {
+		expireAt := now.AddDate(0, 0, validDays)
+		c.ExpireAt = &expireAt
+	}
+	return nil
+}
+
+// 卡密冻结来源
+const (
+	CardFreezeByOwner  = 1 // 卡密所有者
+	CardFreezeByClient = 2 // 终端用户
+)
+
+// Freeze 冻结卡密，记录剩余有效时长，冻结期间过期时间不再生效
+func (c *Card) Freeze(source int) error {
+	if err := c.Transit(CardStatusFrozen); err != nil {
+		return err
+	}
+	now := time.Now()
+	c.FrozenAt = &now
+	c.FreezeSource = source
+	c.FrozenRemain = nil
+	if c.ExpireAt != nil {
+		remain := int64(c.ExpireAt.Sub(now) / time.Second)
+		if remain < 0 {
+			remain = 0
+		}
+		c.FrozenRemain = &remain
+	}
+	if source == CardFreezeByClient {
+		c.FreezeCount++
+	}
+	return nil
+}
+
+// Unfreeze 解冻卡密，按冻结时的剩余时长重新计算过期时间
+func (c *Card) Unfreeze() error {
+	if err := c.Transit(CardStatusActive); err != nil {
+		return err
+	}
+	now := time.Now()
+	if c.FrozenRemain != nil {
+		expireAt := now.Add(time.Duration(*c.FrozenRemain) * time.Second)
 		c.ExpireAt = &expireAt
 	}
+	c.FrozenAt = nil
+	c.FrozenRemain = nil
+	c.FreezeSource = 0
+	return nil
+}
+
+// ErrCardVersionConflict 卡密在读取后已被其他请求修改
+var ErrCardVersionConflict = errors.New("卡密状态已变化，请重试")
+
+// SaveWithVersion 保存卡密的全部字段，只有卡密仍为读取时的状态和版本号才会保存
+// 状态条件用于防止覆盖不修改版本号的批量操作；保存失败时版本号保持不变
+// @param tx 数据库事务
+// @param fromStatus 读取时的卡密状态
+// @return 卡密已被其他请求修改时返回ErrCardVersionConflict
+func (c *Card) SaveWithVersion(tx *gorm.DB, fromStatus int) error {
+	version := c.Version
+	c.Version++
+	result := tx.Model(c).Where("status = ? AND version = ?", fromStatus, version).
+		Select("*").Omit("created_at", clause.Associations).Updates(c)
+	if result.Error != nil {
+		c.Version = version
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		c.Version = version
+		return ErrCardVersionConflict
+	}
+	return nil
 }
 
 // IsExpired 检查卡密是否已过期
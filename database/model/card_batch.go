@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 卡密批次状态
+const (
+	CardBatchStatusRevoked = 0 // 已撤销
+	CardBatchStatusNormal  = 1 // 正常
+)
+
+// CardBatch 卡密批次，每次生成卡密创建一个批次，用于追踪卡密的发放渠道
+type CardBatch struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`               // 主键ID
+	UserID    int            `gorm:"index;not null" json:"user_id"`      // 创建者ID
+	AppID     uint           `gorm:"index" json:"app_id"`                // 所属应用ID
+	TypeID    uint           `gorm:"index" json:"type_id"`               // 卡密类型ID
+	CardType  CardType       `gorm:"foreignKey:TypeID" json:"card_type"` // 卡密类型
+	Name      string         `gorm:"size:100;not null" json:"name"`      // 批次名称
+	Note      string         `gorm:"size:255" json:"note"`               // 备注
+	Channel   string         `gorm:"size:100;index" json:"channel"`      // 发放渠道，例如代理商名称
+	Count     int            `gorm:"default:0" json:"count"`             // 生成数量
+	Status    int            `gorm:"default:1" json:"status"`            // 状态：0-已撤销，1-正常
+	RevokedAt *time.Time     `json:"revoked_at"`                         // 撤销时间
+	CreatedAt time.Time      `json:"created_at"`                         // 创建时间
+	UpdatedAt time.Time      `json:"updated_at"`                         // 更新时间
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`                     // 删除时间
+}
+
+// TableName 指定表名
+func (CardBatch) TableName() string {
+	return "card_batches"
+}
@@ -0,0 +1,113 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/skyle1995/DevE-Server/utils/qqwry"
+	"gorm.io/gorm"
+)
+
+// 卡密事件类型
+const (
+	CardEventActivate     = "activate"      // 激活
+	CardEventVerifyFailed = "verify_failed" // 验证失败
+	CardEventRebind       = "rebind"        // 换绑
+	CardEventUnbind       = "unbind"        // 解绑
+	CardEventRecharge     = "recharge"      // 续期
+	CardEventEdit         = "edit"          // 修改
+	CardEventFreeze       = "freeze"        // 冻结
+	CardEventUnfreeze     = "unfreeze"      // 解冻
+	CardEventExpire       = "expire"        // 过期
+	CardEventDisable      = "disable"       // 禁用
+	CardEventEnable       = "enable"        // 启用
+	CardEventDelete       = "delete"        // 删除
+)
+
+// 卡密事件操作者类型
+const (
+	CardActorClient = "client" // 终端用户，通过客户端接口操作
+	CardActorUser   = "user"   // 卡密所有者，通过管理接口操作
+	CardActorSystem = "system" // 系统自动处理
+)
+
+// CardEvent 卡密事件，只允许追加，用于追溯卡密的使用和变更记录
+type CardEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`               // 主键ID
+	CardID    uint      `gorm:"index;not null" json:"card_id"`      // 卡密ID
+	CardNo    string    `gorm:"size:50" json:"card_no"`             // 卡号
+	AppID     uint      `gorm:"index" json:"app_id"`                // 所属应用ID
+	UserID    int       `gorm:"index" json:"user_id"`               // 卡密所有者ID
+	Type      string    `gorm:"size:30;index;not null" json:"type"` // 事件类型
+	ActorType string    `gorm:"size:20;not null" json:"actor_type"` // 操作者类型：client、user、system
+	ActorID   uint      `gorm:"default:0" json:"actor_id"`          // 操作者ID，所有者操作时为用户ID
+	DeviceID  string    `gorm:"size:100" json:"device_id"`          // 发生事件的设备ID
+	OldValue  string    `gorm:"type:text" json:"old_value"`         // 变更前的值（JSON格式）
+	NewValue  string    `gorm:"type:text" json:"new_value"`         // 变更后的值（JSON格式）
+	Message   string    `gorm:"size:255" json:"message"`            // 事件说明
+	IP        string    `gorm:"size:50" json:"ip"`                  // 请求IP
+	Location  string    `gorm:"size:100" json:"location"`           // IP归属地
+	CreatedAt time.Time `gorm:"index" json:"created_at"`            // 发生时间
+}
+
+// TableName 指定表名
+func (CardEvent) TableName() string {
+	return "card_events"
+}
+
+// NewCardEvent 创建卡密事件，卡密信息取自card
+func NewCardEvent(card Card, eventType, actorType string, actorID uint, ip string) CardEvent {
+	event := CardEvent{
+		CardID:    card.ID,
+		CardNo:    card.CardNo,
+		AppID:     card.AppID,
+		UserID:    card.UserID,
+		Type:      eventType,
+		ActorType: actorType,
+		ActorID:   actorID,
+		IP:        ip,
+	}
+	if card.DeviceID != nil {
+		event.DeviceID = *card.DeviceID
+	}
+	return event
+}
+
+// WithChange 以JSON格式记录变更前后的值，值为nil时不记录
+func (e CardEvent) WithChange(oldValue, newValue interface{}) CardEvent {
+	if oldValue != nil {
+		if data, err := json.Marshal(oldValue); err == nil {
+			e.OldValue = string(data)
+		}
+	}
+	if newValue != nil {
+		if data, err := json.Marshal(newValue); err == nil {
+			e.NewValue = string(data)
+		}
+	}
+	return e
+}
+
+// BeforeCreate 创建前根据IP查询归属地
+func (e *CardEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.Location == "" && e.IP != "" {
+		location := []rune(strings.TrimSpace(qqwry.GetIPLocation(e.IP)))
+		if len(location) > 100 {
+			location = location[:100]
+		}
+		e.Location = string(location)
+	}
+	return nil
+}
+
+// BeforeUpdate 卡密事件只允许追加，禁止修改
+func (e *CardEvent) BeforeUpdate(tx *gorm.DB) error {
+	return errors.New("卡密事件不允许修改")
+}
+
+// BeforeDelete 卡密事件只允许追加，禁止删除
+func (e *CardEvent) BeforeDelete(tx *gorm.DB) error {
+	return errors.New("卡密事件不允许删除")
+}
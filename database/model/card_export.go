@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+)
+
+// CardExport 卡密导出记录
+// 导出文件包含卡密密钥，因此记录每次导出的操作人、筛选条件和导出的卡密
+type CardExport struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`          // 主键ID
+	UserID    uint      `gorm:"index;not null" json:"user_id"` // 导出用户ID
+	Format    string    `gorm:"size:10" json:"format"`         // 导出格式：csv、txt、xlsx
+	Filter    string    `gorm:"type:text" json:"filter"`       // 筛选条件（JSON格式）
+	Count     int       `gorm:"default:0" json:"count"`        // 导出数量
+	IP        string    `gorm:"size:50" json:"ip"`             // 操作IP
+	UserAgent string    `gorm:"size:255" json:"user_agent"`    // 用户代理
+	CreatedAt time.Time `json:"created_at"`                    // 导出时间
+}
+
+// TableName 指定表名
+func (CardExport) TableName() string {
+	return "card_exports"
+}
+
+// CardExportItem 卡密导出明细
+type CardExportItem struct {
+	ID       uint `gorm:"primaryKey" json:"id"`            // 主键ID
+	ExportID uint `gorm:"index;not null" json:"export_id"` // 导出记录ID
+	CardID   uint `gorm:"index;not null" json:"card_id"`   // 卡密ID
+}
+
+// TableName 指定表名
+func (CardExportItem) TableName() string {
+	return "card_export_items"
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 卡密模板的默认用途
+const (
+	CardTemplateForCardNo  = "card_no"  // 卡号
+	CardTemplateForCardKey = "card_key" // 卡密
+)
+
+// CardTemplate 卡号/卡密生成模板
+// 模板属于用户，可设置为用户的默认模板，也可被卡密类型引用
+type CardTemplate struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`                                   // 主键ID
+	UserID     int            `gorm:"index;not null" json:"user_id"`                          // 创建者ID
+	Name       string         `gorm:"size:50;not null" json:"name"`                           // 模板名称
+	Layout     string         `gorm:"size:100;not null" json:"layout"`                        // 布局，X为随机字符，例如 XXXX-XXXX-XXXX-XXXX
+	Charset    string         `gorm:"size:20;default:'alnum'" json:"charset"`                 // 字符集：alnum、digits、letters、hex、unambiguous
+	Case       string         `gorm:"column:letter_case;size:10;default:'upper'" json:"case"` // 大小写：upper、lower、mixed
+	Checksum   bool           `gorm:"default:false" json:"checksum"`                          // 最后一个随机字符是否为校验位
+	DefaultFor string         `gorm:"size:20" json:"default_for"`                             // 作为用户默认模板的用途：card_no、card_key，空表示非默认
+	CreatedAt  time.Time      `json:"created_at"`                                             // 创建时间
+	UpdatedAt  time.Time      `json:"updated_at"`                                             // 更新时间
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`                                         // 删除时间
+}
+
+// TableName 指定表名
+func (CardTemplate) TableName() string {
+	return "card_templates"
+}
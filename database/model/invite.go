@@ -0,0 +1,64 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 邀请码状态
+const (
+	InviteCodeStatusDisabled = 0 // 禁用
+	InviteCodeStatusEnabled  = 1 // 启用
+)
+
+// InviteCode 注册邀请码模型
+type InviteCode struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`                     // 主键ID
+	Code      string         `gorm:"size:32;uniqueIndex;not null" json:"code"` // 邀请码，统一为大写
+	CreatorID uint           `gorm:"index;not null" json:"creator_id"`         // 创建者用户ID，使用该邀请码注册的用户计为其邀请
+	RoleID    uint           `gorm:"not null;default:0" json:"role_id"`        // 注册后分配的角色ID
+	MaxUses   int            `gorm:"not null" json:"max_uses"`                 // 最大使用次数，0表示不限
+	UsedCount int            `gorm:"not null" json:"used_count"`               // 已使用次数
+	ExpiresAt *time.Time     `json:"expires_at"`                               // 过期时间，为空表示永不过期
+	Status    int            `gorm:"not null" json:"status"`                   // 状态：1-启用, 0-禁用
+	Remark    string         `gorm:"size:255" json:"remark"`                   // 备注
+	CreatedAt time.Time      `json:"created_at"`                               // 创建时间
+	UpdatedAt time.Time      `json:"updated_at"`                               // 更新时间
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`                           // 删除时间
+}
+
+// TableName 指定表名
+func (InviteCode) TableName() string {
+	return "invite_codes"
+}
+
+// Check 检查邀请码在指定时间是否可用于注册
+func (c InviteCode) Check(now time.Time) error {
+	if c.Status != InviteCodeStatusEnabled {
+		return errors.New("邀请码已被禁用")
+	}
+	if c.ExpiresAt != nil && !now.Before(*c.ExpiresAt) {
+		return errors.New("邀请码已过期")
+	}
+	if c.MaxUses > 0 && c.UsedCount >= c.MaxUses {
+		return errors.New("邀请码已达到使用次数上限")
+	}
+	return nil
+}
+
+// InviteCodeUsage 邀请码使用记录，每个用户只能使用一次邀请码
+type InviteCodeUsage struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`                 // 主键ID
+	InviteCodeID uint      `gorm:"index;not null" json:"invite_code_id"` // 邀请码ID
+	InviterID    uint      `gorm:"index;not null" json:"inviter_id"`     // 邀请人ID，即邀请码创建者
+	UserID       uint      `gorm:"uniqueIndex;not null" json:"user_id"`  // 注册用户ID
+	IP           string    `gorm:"size:50" json:"ip"`                    // 注册IP
+	CreatedAt    time.Time `json:"created_at"`                           // 使用时间
+}
+
+// TableName 指定表名
+func (InviteCodeUsage) TableName() string {
+	return "invite_code_usages"
+}
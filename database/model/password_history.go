@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// PasswordHistory 用户使用过的密码，修改密码时保存旧密码的哈希，用于禁止重复使用最近的密码
+type PasswordHistory struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`          // 主键ID
+	UserID    uint      `gorm:"index;not null" json:"user_id"` // 用户ID
+	Password  string    `gorm:"size:100;not null" json:"-"`    // 旧密码的哈希
+	CreatedAt time.Time `json:"created_at"`                    // 停止使用该密码的时间
+}
+
+// TableName 指定表名
+func (PasswordHistory) TableName() string {
+	return "password_histories"
+}
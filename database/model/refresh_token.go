@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+)
+
+// RefreshToken 刷新令牌，只保存令牌的SHA256摘要
+// 每次刷新时轮换为新令牌，同一次登录轮换产生的令牌属于同一会话
+type RefreshToken struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`                     // 主键ID
+	UserID          uint       `gorm:"index;not null" json:"user_id"`            // 用户ID
+	SessionID       string     `gorm:"size:32;index;not null" json:"session_id"` // 会话ID，登录时生成，轮换后不变
+	TokenHash       string     `gorm:"size:64;uniqueIndex;not null" json:"-"`    // 令牌的SHA256摘要
+	AccessTokenID   string     `gorm:"size:32;index;not null" json:"-"`          // 同时签发的访问令牌ID（jti）
+	AccessExpiresAt time.Time  `gorm:"not null" json:"-"`                        // 同时签发的访问令牌的过期时间
+	Remember        bool       `gorm:"not null" json:"remember"`                 // 是否记住登录
+	IP              string     `gorm:"size:50" json:"ip"`                        // 签发时的IP
+	ExpiresAt       time.Time  `gorm:"index;not null" json:"expires_at"`         // 过期时间，轮换不延长会话的有效期
+	UsedAt          *time.Time `json:"used_at"`                                  // 轮换时间，已轮换的令牌再次使用时撤销整个会话
+	RevokedAt       *time.Time `gorm:"index" json:"revoked_at"`                  // 撤销时间
+	CreatedAt       time.Time  `json:"created_at"`                               // 创建时间
+}
+
+// TableName 指定表名
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// RevokedToken 已撤销的访问令牌，访问令牌过期后可以删除
+type RevokedToken struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`                  // 主键ID
+	TokenID   string    `gorm:"size:32;uniqueIndex;not null" json:"-"` // 访问令牌ID（jti）
+	UserID    uint      `gorm:"index;not null" json:"user_id"`         // 用户ID
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"`      // 访问令牌的过期时间
+	CreatedAt time.Time `json:"created_at"`                            // 撤销时间
+}
+
+// TableName 指定表名
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
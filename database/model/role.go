@@ -0,0 +1,158 @@
+package model
+
+import "time"
+
+// Role 角色模型，用户通过角色获得权限
+type Role struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`                         // 主键ID
+	Code        string    `gorm:"size:50;uniqueIndex;not null" json:"code"`     // 角色编码，唯一
+	Name        string    `gorm:"size:50;uniqueIndex;not null" json:"name"`     // 角色名称，唯一
+	Description string    `gorm:"size:255" json:"description"`                  // 描述
+	Permissions []string  `gorm:"type:json;serializer:json" json:"permissions"` // 权限列表，* 表示所有权限
+	IsSystem    bool      `gorm:"not null" json:"is_system"`                    // 是否为内置角色，内置角色不能删除
+	IsDefault   bool      `gorm:"not null" json:"is_default"`                   // 是否为注册用户的默认角色
+	CreatedAt   time.Time `json:"created_at"`                                   // 创建时间
+	UpdatedAt   time.Time `json:"updated_at"`                                   // 更新时间
+}
+
+// TableName 指定表名
+func (Role) TableName() string {
+	return "roles"
+}
+
+// HasPermission 判断角色是否拥有指定权限
+func (r Role) HasPermission(permission string) bool {
+	for _, p := range r.Permissions {
+		if p == PermissionAll || p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Covers 判断角色能否管理另一个角色：非管理员不能管理管理员角色，另一个角色的权限必须都在该角色的权限范围内
+func (r Role) Covers(other Role) bool {
+	if other.Code == RoleCodeAdmin && r.Code != RoleCodeAdmin {
+		return false
+	}
+	for _, permission := range other.Permissions {
+		if !r.HasPermission(permission) {
+			return false
+		}
+	}
+	return true
+}
+
+// 内置角色编码
+const (
+	RoleCodeAdmin  = "admin"  // 管理员
+	RoleCodeMember = "member" // 普通会员
+	RoleCodeVIP    = "vip"    // VIP会员
+)
+
+// 权限标识
+const (
+	PermissionAll = "*" // 所有权限，仅用于管理员角色
+
+	PermissionUserManage    = "user.manage"         // 管理用户、登录会话和登录锁定
+	PermissionRoleManage    = "role.manage"         // 管理角色和权限
+	PermissionInviteManage  = "invite.manage"       // 管理邀请码
+	PermissionSettingManage = "setting.manage"      // 管理系统设置
+	PermissionNoticePublish = "notice.publish"      // 发布通知，可修改自己发布的通知
+	PermissionNoticeManage  = "notice.manage"       // 管理所有用户发布的通知
+	PermissionLogsViewAll   = "logs.view_all"       // 查看所有用户的日志
+	PermissionLogsClear     = "logs.clear"          // 清空日志
+	PermissionLoginDisabled = "login.when_disabled" // 系统关闭登录时仍可登录
+
+	PermissionAppView    = "app.view"     // 查看应用
+	PermissionAppCreate  = "app.create"   // 创建应用
+	PermissionAppUpdate  = "app.update"   // 修改应用和重置应用密钥
+	PermissionAppDelete  = "app.delete"   // 删除应用
+	PermissionAppPush    = "app.push"     // 查看在线客户端并推送指令
+	PermissionAppPushAll = "app.push_all" // 查看所有用户应用的在线客户端并推送指令
+
+	PermissionCardView     = "card.view"     // 查看卡密、卡密类型、模板和批次
+	PermissionCardGenerate = "card.generate" // 生成和导入卡密
+	PermissionCardManage   = "card.manage"   // 修改、删除、冻结卡密，管理卡密类型、模板和批次
+	PermissionCardExport   = "card.export"   // 导出卡密
+
+	PermissionDeviceView      = "device.view"       // 查看设备
+	PermissionDeviceManage    = "device.manage"     // 修改、禁用、解绑和删除设备
+	PermissionDeviceManageAll = "device.manage_all" // 管理所有用户应用下的设备
+)
+
+// PermissionDefinition 权限定义
+type PermissionDefinition struct {
+	Key   string `json:"key"`   // 权限标识
+	Name  string `json:"name"`  // 权限名称
+	Group string `json:"group"` // 权限分组
+}
+
+// Permissions 所有可分配的权限，角色的权限必须在此列表中
+var Permissions = []PermissionDefinition{
+	{PermissionAppView, "查看应用", "应用"},
+	{PermissionAppCreate, "创建应用", "应用"},
+	{PermissionAppUpdate, "修改应用", "应用"},
+	{PermissionAppDelete, "删除应用", "应用"},
+	{PermissionAppPush, "推送指令", "应用"},
+	{PermissionAppPushAll, "向所有应用推送指令", "应用"},
+	{PermissionCardView, "查看卡密", "卡密"},
+	{PermissionCardGenerate, "生成卡密", "卡密"},
+	{PermissionCardManage, "管理卡密", "卡密"},
+	{PermissionCardExport, "导出卡密", "卡密"},
+	{PermissionDeviceView, "查看设备", "设备"},
+	{PermissionDeviceManage, "管理设备", "设备"},
+	{PermissionDeviceManageAll, "管理所有设备", "设备"},
+	{PermissionNoticePublish, "发布通知", "通知"},
+	{PermissionNoticeManage, "管理所有通知", "通知"},
+	{PermissionLogsViewAll, "查看所有日志", "日志"},
+	{PermissionLogsClear, "清空日志", "日志"},
+	{PermissionUserManage, "管理用户", "系统"},
+	{PermissionRoleManage, "管理角色", "系统"},
+	{PermissionInviteManage, "管理邀请码", "系统"},
+	{PermissionSettingManage, "管理系统设置", "系统"},
+	{PermissionLoginDisabled, "关闭登录时允许登录", "系统"},
+}
+
+// IsValidPermission 判断权限标识是否可分配
+func IsValidPermission(permission string) bool {
+	for _, p := range Permissions {
+		if p.Key == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// memberPermissions 会员角色的默认权限，可以管理自己的应用、卡密和设备
+var memberPermissions = []string{
+	PermissionAppView, PermissionAppCreate, PermissionAppUpdate, PermissionAppDelete, PermissionAppPush,
+	PermissionCardView, PermissionCardGenerate, PermissionCardManage, PermissionCardExport,
+	PermissionDeviceView, PermissionDeviceManage,
+}
+
+// DefaultRoles 默认角色，对应旧版本的角色编号：0-管理员, 1-普通会员, 2-VIP会员
+var DefaultRoles = []Role{
+	{
+		Code:        RoleCodeAdmin,
+		Name:        "管理员",
+		Description: "拥有所有权限",
+		Permissions: []string{PermissionAll},
+		IsSystem:    true,
+	},
+	{
+		Code:        RoleCodeMember,
+		Name:        "普通会员",
+		Description: "注册用户的默认角色",
+		Permissions: memberPermissions,
+		IsSystem:    true,
+		IsDefault:   true,
+	},
+	{
+		Code:        RoleCodeVIP,
+		Name:        "VIP会员",
+		Description: "VIP会员",
+		Permissions: memberPermissions,
+		IsSystem:    true,
+	},
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// SchemaMigration 已执行的一次性数据迁移，每个迁移按名称只执行一次
+type SchemaMigration struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`                      // 主键ID
+	Name      string    `gorm:"size:100;uniqueIndex;not null" json:"name"` // 迁移名称
+	AppliedAt time.Time `json:"applied_at"`                                // 执行时间
+}
+
+// TableName 指定表名
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
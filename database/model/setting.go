@@ -56,6 +56,12 @@ var DefaultSystemSettings = []SystemSetting{
 		Description: "网站版权信息",
 		Group:       "site",
 	},
+	{
+		Key:         "site_url",
+		Value:       "",
+		Description: "网站访问地址,用于生成邮件中的链接,如https://example.com(启用邮件功能时必须设置)",
+		Group:       "site",
+	},
 
 	// 用户设置
 	{
@@ -85,7 +91,7 @@ var DefaultSystemSettings = []SystemSetting{
 	{
 		Key:         "user_default_status",
 		Value:       "1",
-		Description: "用户默认状态(1-启用,0-禁用)",
+		Description: "用户默认状态(1-启用,0-等待管理员审核)",
 		Group:       "user",
 	},
 	{
@@ -158,6 +164,48 @@ var DefaultSystemSettings = []SystemSetting{
 		Description: "登录锁定时间(分钟)",
 		Group:       "security",
 	},
+	{
+		Key:         "security_captcha_attempts",
+		Value:       "3",
+		Description: "登录失败多少次后要求输入验证码(0表示不要求)",
+		Group:       "security",
+	},
+	{
+		Key:         "security_two_factor_roles",
+		Value:       "",
+		Description: "要求开启两步验证的角色编码(逗号分隔,如admin,vip;为空表示不要求)",
+		Group:       "security",
+	},
+	{
+		Key:         "security_password_min_length",
+		Value:       "8",
+		Description: "密码最小长度",
+		Group:       "security",
+	},
+	{
+		Key:         "security_password_char_types",
+		Value:       "2",
+		Description: "密码至少包含的字符种类数(大写字母、小写字母、数字、特殊字符,0-4)",
+		Group:       "security",
+	},
+	{
+		Key:         "security_password_history",
+		Value:       "3",
+		Description: "禁止重复使用最近几次的密码(0表示不限制)",
+		Group:       "security",
+	},
+	{
+		Key:         "security_password_max_age",
+		Value:       "0",
+		Description: "密码有效天数,过期后登录时要求修改密码(0表示永不过期)",
+		Group:       "security",
+	},
+	{
+		Key:         "security_password_denylist",
+		Value:       "1",
+		Description: "禁止使用常见弱密码(1-开启,0-关闭)",
+		Group:       "security",
+	},
 
 	// 应用配置
 	{
@@ -15,21 +15,43 @@ type User struct {
 	Email     string         `gorm:"size:100;uniqueIndex" json:"email"`            // 邮箱
 	Nickname  string         `gorm:"size:50" json:"nickname"`                      // 昵称
 	Avatar    string         `gorm:"size:255" json:"avatar"`                       // 头像URL
-	Role      int            `gorm:"default:1" json:"role"`                        // 角色：0-管理员, 1-普通会员, 2-VIP会员
-	Status    int            `gorm:"default:1" json:"status"`                      // 状态：1-启用, 0-禁用
+	RoleID    uint           `gorm:"index" json:"role_id"`                         // 角色ID
+	Status    int            `gorm:"default:1" json:"status"`                      // 状态：1-启用, 0-禁用, 2-待验证邮箱
 	LastLogin *time.Time     `json:"last_login"`                                   // 最后登录时间
 	CreatedAt time.Time      `json:"created_at"`                                   // 创建时间
 	UpdatedAt time.Time      `json:"updated_at"`                                   // 更新时间
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`                               // 删除时间
+
+	// 两步验证
+	TwoFactorEnabled       bool       `gorm:"default:false" json:"two_factor_enabled"` // 是否开启两步验证
+	TwoFactorSecret        string     `gorm:"size:64" json:"-"`                        // TOTP密钥（Base32编码，不返回给前端）
+	TwoFactorLastStep      int64      `gorm:"default:0" json:"-"`                      // 最近一次使用的验证码时间步，防止验证码重放
+	TwoFactorRecoveryCodes []string   `gorm:"type:json;serializer:json" json:"-"`      // 未使用的恢复码的SHA256摘要
+	TwoFactorEnabledAt     *time.Time `json:"two_factor_enabled_at"`                   // 开启两步验证的时间
+
+	// 密码策略
+	PasswordChangedAt      *time.Time `json:"password_changed_at"`                           // 最后修改密码的时间，为空时按创建时间计算密码有效期
+	PasswordChangeRequired bool       `gorm:"default:false" json:"password_change_required"` // 下次登录时是否需要修改密码
 }
 
+// 用户状态
+const (
+	UserStatusDisabled = 0 // 禁用
+	UserStatusActive   = 1 // 启用
+	UserStatusPending  = 2 // 待验证邮箱，注册后完成邮箱验证才能登录
+)
+
 // TableName 指定表名
 func (User) TableName() string {
 	return "users"
 }
 
-// BeforeCreate 创建前的钩子
+// BeforeCreate 创建前的钩子，记录设置密码的时间并哈希密码
 func (u *User) BeforeCreate(tx *gorm.DB) error {
+	if u.PasswordChangedAt == nil && u.Password != "" {
+		now := time.Now()
+		u.PasswordChangedAt = &now
+	}
 	return u.hashPassword()
 }
 
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+)
+
+// UserSession 用户登录会话，每次登录创建一个会话，刷新令牌轮换时会话不变
+// 会话记录当前访问令牌的ID，JWT认证中间件据此更新最后活动时间
+type UserSession struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`                  // 主键ID
+	UserID        uint       `gorm:"index;not null" json:"user_id"`         // 用户ID
+	SessionID     string     `gorm:"size:32;uniqueIndex;not null" json:"-"` // 会话ID，与刷新令牌的会话ID相同
+	AccessTokenID string     `gorm:"size:32;index;not null" json:"-"`       // 当前访问令牌ID（jti），刷新令牌时更新
+	UserAgent     string     `gorm:"size:255" json:"user_agent"`            // 登录时的浏览器或客户端标识
+	IP            string     `gorm:"size:50" json:"ip"`                     // 最后活动IP
+	Remember      bool       `gorm:"not null" json:"remember"`              // 是否记住登录
+	LastSeenAt    time.Time  `json:"last_seen_at"`                          // 最后活动时间
+	ExpiresAt     time.Time  `gorm:"index;not null" json:"expires_at"`      // 过期时间
+	RevokedAt     *time.Time `gorm:"index" json:"revoked_at"`               // 撤销时间
+	CreatedAt     time.Time  `json:"created_at"`                            // 登录时间
+}
+
+// TableName 指定表名
+func (UserSession) TableName() string {
+	return "user_sessions"
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+)
+
+// 用户令牌类型
+const (
+	UserTokenTypeEmailVerify   = "email_verify"   // 注册邮箱验证
+	UserTokenTypePasswordReset = "password_reset" // 找回密码
+)
+
+// UserToken 通过邮件发送给用户的一次性令牌，只保存令牌的SHA256摘要
+type UserToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`                  // 主键ID
+	UserID    uint       `gorm:"index;not null" json:"user_id"`         // 用户ID
+	Type      string     `gorm:"size:20;index;not null" json:"type"`    // 令牌类型
+	TokenHash string     `gorm:"size:64;uniqueIndex;not null" json:"-"` // 令牌的SHA256摘要
+	Email     string     `gorm:"size:100" json:"email"`                 // 发送令牌时的邮箱，邮箱变更后令牌失效
+	IP        string     `gorm:"size:50" json:"ip"`                     // 申请IP
+	ExpiresAt time.Time  `gorm:"index;not null" json:"expires_at"`      // 过期时间
+	UsedAt    *time.Time `json:"used_at"`                               // 使用时间，为空表示未使用
+	CreatedAt time.Time  `json:"created_at"`                            // 创建时间
+}
+
+// TableName 指定表名
+func (UserToken) TableName() string {
+	return "user_tokens"
+}
@@ -39,7 +39,9 @@ func initSQLite() (*gorm.DB, error) {
 	)
 
 	// 打开数据库连接
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
+	// 事务开始时即获取写锁，并在数据库被其他连接锁定时等待，避免并发事务在读取后升级写锁时失败
+	dsn := dbPath + "?_pragma=busy_timeout(5000)&_txlock=immediate"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
 		Logger: gormLogger,
 	})
 
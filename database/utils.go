@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 
 	"gorm.io/gorm"
 )
@@ -24,6 +25,18 @@ func Paginate(page, pageSize int) func(db *gorm.DB) *gorm.DB {
 	}
 }
 
+// IsDuplicateKeyError 判断错误是否为违反唯一索引，兼容SQLite和MySQL
+func IsDuplicateKeyError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "UNIQUE constraint failed") || strings.Contains(msg, "Error 1062")
+}
+
 // GetByID 通过ID获取记录
 func GetByID(model interface{}, id uint) error {
 	result := GetDB().First(model, id)
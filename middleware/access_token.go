@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/utils/crypto"
+)
+
+// 个人访问令牌相关的上下文键
+const (
+	accessTokenKey   = "access_token"    // 当前请求使用的访问令牌
+	accessTokenIDKey = "access_token_id" // 当前请求使用的访问令牌ID
+	appScopeKey      = "app_scope"       // 接口已通过应用范围检查
+	requestLoggedKey = "request_logged"  // 请求已由日志中间件记录
+)
+
+// IsAccessToken 判断令牌是否为个人访问令牌
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, dbmodel.AccessTokenPrefix)
+}
+
+// accessTokenAuth 使用个人访问令牌认证请求，并为每个请求记录操作日志
+func accessTokenAuth(c *gin.Context, tokenString string) {
+	now := time.Now()
+	var token dbmodel.AccessToken
+	err := database.DB.Where("token_hash = ? AND revoked_at IS NULL", crypto.SHA256(tokenString)).First(&token).Error
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "无效的访问令牌"})
+		c.Abort()
+		return
+	}
+	if token.Expired(now) {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "访问令牌已过期"})
+		c.Abort()
+		return
+	}
+
+	var user dbmodel.User
+	if err := database.DB.Where("id = ? AND status = ?", token.UserID, dbmodel.UserStatusActive).First(&user).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "用户不存在或已被禁用"})
+		c.Abort()
+		return
+	}
+
+	// 更新最后使用时间和IP，同一令牌在间隔内只更新一次
+	database.DB.Model(&dbmodel.AccessToken{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", token.ID, now.Add(-sessionTouchInterval)).
+		Updates(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": c.ClientIP(),
+		})
+
+	c.Set("user_id", user.ID)
+	c.Set("username", user.Username)
+	c.Set("role_id", user.RoleID)
+	c.Set(accessTokenKey, token)
+	c.Set(accessTokenIDKey, token.ID)
+
+	c.Next()
+
+	// 接口已使用日志中间件时由日志中间件记录，避免重复记录
+	if c.GetBool(requestLoggedKey) {
+		return
+	}
+	// 不记录请求体和响应体，避免保存其中的密码和密钥
+	log := dbmodel.Logs{
+		Type:          dbmodel.LogTypeOperation,
+		UserID:        user.ID,
+		AccessTokenID: token.ID,
+		Method:        c.Request.Method,
+		Path:          c.Request.URL.Path,
+		IP:            c.ClientIP(),
+		UserAgent:     c.Request.UserAgent(),
+		StatusCode:    c.Writer.Status(),
+		Latency:       time.Since(now).Milliseconds(),
+		Content:       "使用访问令牌: " + token.Name,
+	}
+	go func(log dbmodel.Logs) {
+		database.DB.Create(&log)
+	}(log)
+}
+
+// currentAccessToken 返回当前请求使用的个人访问令牌，使用JWT认证时返回false
+func currentAccessToken(c *gin.Context) (dbmodel.AccessToken, bool) {
+	value, exists := c.Get(accessTokenKey)
+	if !exists {
+		return dbmodel.AccessToken{}, false
+	}
+	token, ok := value.(dbmodel.AccessToken)
+	return token, ok
+}
+
+// TokenAppID 返回当前请求的访问令牌限定的应用ID，未使用访问令牌或不限定应用时返回0
+func TokenAppID(c *gin.Context) uint {
+	token, _ := currentAccessToken(c)
+	return token.AppID
+}
+
+// SessionOnlyMiddleware 拒绝使用个人访问令牌的请求，用于密码、两步验证、会话和令牌管理等账户安全接口
+// 需在JWTAuthMiddleware之后使用
+func SessionOnlyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := currentAccessToken(c); ok {
+			c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "该接口不支持使用访问令牌，请登录后操作"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// AppScopeMiddleware 检查限定应用的访问令牌是否在访问该应用，resolve 返回请求访问的应用ID，无法确定时返回0
+// 需在PermissionMiddleware之前使用；限定应用的访问令牌只能调用使用了该中间件的接口
+func AppScopeMiddleware(resolve func(c *gin.Context) uint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if appID := TokenAppID(c); appID != 0 && resolve(c) != appID {
+			c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "访问令牌无权访问该应用"})
+			c.Abort()
+			return
+		}
+		c.Set(appScopeKey, true)
+		c.Next()
+	}
+}
+
+// AppIDFromParam 从路径参数中读取应用ID，用于AppScopeMiddleware
+func AppIDFromParam(name string) func(c *gin.Context) uint {
+	return func(c *gin.Context) uint {
+		id, _ := strconv.ParseUint(c.Param(name), 10, 32)
+		return uint(id)
+	}
+}
+
+// AppIDFromQuery 从查询参数中读取应用ID，用于AppScopeMiddleware
+func AppIDFromQuery(name string) func(c *gin.Context) uint {
+	return func(c *gin.Context) uint {
+		id, _ := strconv.ParseUint(c.Query(name), 10, 32)
+		return uint(id)
+	}
+}
@@ -68,18 +68,7 @@ func AppTrialMiddleware() gin.HandlerFunc {
 		}
 
 		// 检查应用是否允许试用
-		if app.AllowTrial == 1 && app.TrialAmount > 0 {
-			createdTime := app.CreatedAt
-			// 根据计费模式处理试用额度
-			var trialDuration time.Duration
-			if app.BillingMode == 0 { // 时长计费模式
-				trialDuration = time.Duration(app.TrialAmount) * time.Hour
-			} else { // 点数计费模式
-				// 点数计费模式下，假设每个点数对应1小时的使用时间
-				trialDuration = time.Duration(app.TrialAmount) * time.Hour
-			}
-			trialEndTime := createdTime.Add(trialDuration)
-
+		if trialEndTime, hasTrial := app.TrialEndTime(); hasTrial {
 			if time.Now().After(trialEndTime) {
 				response.Forbidden(c, "应用试用期已过")
 				c.Abort()
@@ -7,11 +7,15 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/skyle1995/DevE-Server/apps/auth/model"
 	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
 	"github.com/skyle1995/DevE-Server/utils/jwt"
 	"github.com/spf13/viper"
 )
 
-// JWTAuthMiddleware 验证JWT令牌的中间件
+// sessionTouchInterval 更新会话最后活动时间的最小间隔，避免每个请求都写数据库
+const sessionTouchInterval = time.Minute
+
+// JWTAuthMiddleware 验证JWT令牌的中间件，同时接受个人访问令牌
 func JWTAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 创建JWT实例
@@ -61,6 +65,12 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// 个人访问令牌使用单独的认证流程
+		if IsAccessToken(tokenString) {
+			accessTokenAuth(c, tokenString)
+			return
+		}
+
 		// 解析和验证令牌
 		claims, err := jwtInstance.ParseToken(tokenString)
 		if err != nil {
@@ -76,20 +86,23 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// 检查令牌是否已撤销，没有令牌ID的旧令牌无法撤销，需要重新登录
+		if claims.ID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "认证令牌已失效，请重新登录"})
+			c.Abort()
+			return
+		}
+		var revoked int64
+		database.DB.Model(&dbmodel.RevokedToken{}).Where("token_id = ?", claims.ID).Count(&revoked)
+		if revoked > 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "认证令牌已失效，请重新登录"})
+			c.Abort()
+			return
+		}
+
 		// 从令牌中获取用户信息
 		userID := claims.UserID
 		username := claims.Username
-		roleStr := claims.Role
-
-		// 将角色字符串转换为整数
-		var role int
-		if roleStr == "0" {
-			role = 0 // 管理员
-		} else if roleStr == "2" {
-			role = 2 // VIP会员
-		} else {
-			role = 1 // 普通会员
-		}
 
 		// 查询用户是否存在且状态正常
 		var user model.User
@@ -100,33 +113,20 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// 更新会话的最后活动时间和IP，同一会话在间隔内只更新一次
+		now := time.Now()
+		database.DB.Model(&dbmodel.UserSession{}).
+			Where("access_token_id = ? AND last_seen_at < ?", claims.ID, now.Add(-sessionTouchInterval)).
+			Updates(map[string]interface{}{
+				"last_seen_at": now,
+				"ip":           c.ClientIP(),
+			})
+
 		// 将用户信息存储到上下文中
 		c.Set("user_id", userID)
 		c.Set("username", username)
-		c.Set("role", role)
-
-		c.Next()
-	}
-}
-
-// AdminAuthMiddleware 验证用户是否为管理员的中间件
-func AdminAuthMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// 获取JWTAuthMiddleware中设置的用户角色
-		role, exists := c.Get("role")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "未经授权的访问"})
-			c.Abort()
-			return
-		}
-
-		// 检查用户是否为管理员（角色值为0）
-		roleValue, ok := role.(int)
-		if !ok || roleValue != 0 {
-			c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "权限不足，需要管理员权限"})
-			c.Abort()
-			return
-		}
+		c.Set("role_id", user.RoleID)
+		c.Set("token_id", claims.ID)
 
 		c.Next()
 	}
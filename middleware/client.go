@@ -1,47 +1,80 @@
 package middleware
 
 import (
+	"bytes"
 	"errors"
+	"io"
+	"net/http"
+	"regexp"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/skyle1995/DevE-Server/database"
 	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/utils/cache"
 	"github.com/skyle1995/DevE-Server/utils/crypto"
 	"github.com/skyle1995/DevE-Server/utils/response"
 	"github.com/skyle1995/DevE-Server/utils/timeutil"
 	"github.com/spf13/viper"
 )
 
+// maxSignedBodySize v2客户端接口参与签名的请求体大小上限
+const maxSignedBodySize = 1 << 20
+
+// noncePattern v2客户端接口请求随机数的格式
+var noncePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{16,64}$`)
+
+// usedNonces 时间戳有效期内已使用的请求随机数，服务重启后清空
+var usedNonces = cache.New(0, time.Minute)
+
 // ClientAuthMiddleware 客户端认证中间件
 // 用于验证客户端请求的合法性
 // 客户端请求需要在Header中携带以下信息：
 // - App-Key: 应用的AppKey
 // - App-Sign: 请求签名，使用AppSecret对请求参数进行签名
 // - Timestamp: 请求时间戳，用于防止重放攻击
+// 认证失败时统一返回400状态码，供v1客户端接口使用
 func ClientAuthMiddleware() gin.HandlerFunc {
+	return clientAuthMiddleware(false)
+}
+
+// ClientAuthV2Middleware v2客户端认证中间件
+// 请求头需携带 App-Key、Timestamp、Nonce 和 App-Sign，签名覆盖请求方法、路径、查询参数、时间戳、随机数和请求体（见ValidateRequestSign），
+// 同一应用的随机数在时间戳有效期内只能使用一次，认证失败时返回细分的业务状态码
+func ClientAuthV2Middleware() gin.HandlerFunc {
+	return clientAuthMiddleware(true)
+}
+
+// clientAuthMiddleware 客户端认证中间件的具体实现
+// withCode: 认证失败时是否返回细分的业务状态码
+func clientAuthMiddleware(withCode bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// 认证失败时的响应处理
+		fail := func(code int, message string) {
+			if !withCode {
+				code = 400
+			}
+			response.FailWithCodeDetailed(code, gin.H{
+				"reload": true,
+			}, message, c)
+			c.Abort()
+		}
+
 		// 获取请求头中的认证信息
 		appKey := c.GetHeader("App-Key")
 		appSign := c.GetHeader("App-Sign")
 		timestamp := c.GetHeader("Timestamp")
 
-		// 检查必要的认证信息是否存在
-		if appKey == "" || appSign == "" || timestamp == "" {
-			response.FailWithDetailed(gin.H{
-				"reload": true,
-			}, "缺少必要的认证信息", c)
-			c.Abort()
+		// 检查必要的认证信息是否存在，v2接口还需携带随机数
+		if appKey == "" || appSign == "" || timestamp == "" || (withCode && c.GetHeader("Nonce") == "") {
+			fail(response.CodeUnauthorized, "缺少必要的认证信息")
 			return
 		}
 
 		// 验证时间戳
 		if err := validateTimestamp(timestamp); err != nil {
-			response.FailWithDetailed(gin.H{
-				"reload": true,
-			}, err.Error(), c)
-			c.Abort()
+			fail(response.CodeTimestampInvalid, err.Error())
 			return
 		}
 
@@ -49,34 +82,40 @@ func ClientAuthMiddleware() gin.HandlerFunc {
 		var app dbmodel.App
 		result := database.DB.Where("app_key = ?", appKey).First(&app)
 		if result.Error != nil {
-			response.FailWithDetailed(gin.H{
-				"reload": true,
-			}, "应用不存在或已被禁用", c)
-			c.Abort()
+			fail(response.CodeAppKeyInvalid, "应用不存在或已被禁用")
 			return
 		}
 
 		// 检查应用状态
 		if app.Status != 1 {
-			response.FailWithDetailed(gin.H{
-				"reload": true,
-			}, "应用已被禁用", c)
-			c.Abort()
+			fail(response.CodeAppDisabled, "应用已被禁用")
 			return
 		}
 
-		// 验证请求签名
-		err := ValidateAppSign(c, app)
+		// 验证请求签名，v1接口保留只对参数签名的旧规则
+		var err error
+		if withCode {
+			err = ValidateRequestSign(c, app)
+		} else {
+			err = ValidateAppSign(c, app)
+		}
 		if err != nil {
-			response.FailWithDetailed(gin.H{
-				"reload": true,
-			}, "签名验证失败: "+err.Error(), c)
-			c.Abort()
+			fail(response.CodeSignInvalid, "签名验证失败: "+err.Error())
 			return
 		}
 
+		// 签名通过后记录随机数，拒绝重放的请求
+		if withCode {
+			if _, used := usedNonces.GetOrSet(app.AppKey+":"+c.GetHeader("Nonce"), true, 2*timestampWindow()); used {
+				fail(response.CodeNonceReused, "请求重复，请重新生成随机数")
+				return
+			}
+		}
+
 		// 将应用信息存储到上下文中，供后续处理使用
 		c.Set("app", app)
+		c.Set("app_id", app.ID)
+		c.Set("app_name", app.Name)
 		c.Next()
 	}
 }
@@ -120,6 +159,44 @@ func ValidateAppSign(c *gin.Context, app dbmodel.App) error {
 	return nil
 }
 
+// ValidateRequestSign 验证v2客户端接口的请求签名
+// 签名规则见crypto.SignRequest，请求体读取后重新写回，供后续处理使用
+func ValidateRequestSign(c *gin.Context, app dbmodel.App) error {
+	sign := c.GetHeader("App-Sign")
+	nonce := c.GetHeader("Nonce")
+	if sign == "" {
+		return errors.New("缺少签名信息")
+	}
+	if !noncePattern.MatchString(nonce) {
+		return errors.New("随机数格式错误，需为16-64位字母、数字、下划线或中划线")
+	}
+
+	var body []byte
+	if c.Request.Body != nil {
+		var err error
+		body, err = io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxSignedBodySize))
+		if err != nil {
+			return errors.New("读取请求体失败")
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if !crypto.VerifyRequestSign(sign, app.AppSecret, c.Request.Method, c.Request.URL.Path, c.Request.URL.Query(),
+		c.GetHeader("Timestamp"), nonce, body) {
+		return errors.New("签名验证失败")
+	}
+	return nil
+}
+
+// timestampWindow 返回请求时间戳的有效期，默认5分钟
+func timestampWindow() time.Duration {
+	timestampExpire := viper.GetInt64("security.timestamp_expire")
+	if timestampExpire <= 0 {
+		timestampExpire = 300
+	}
+	return time.Duration(timestampExpire) * time.Second
+}
+
 // validateTimestamp 验证时间戳
 // timestamp: 请求时间戳（秒级Unix时间戳）
 func validateTimestamp(timestamp string) error {
@@ -136,11 +213,7 @@ func validateTimestamp(timestamp string) error {
 	timestampTime := time.Unix(timestampInt, 0)
 
 	// 获取配置的时间戳有效期（秒）
-	timestampExpire := viper.GetInt64("security.timestamp_expire")
-	if timestampExpire <= 0 {
-		// 默认5分钟
-		timestampExpire = 300
-	}
+	timestampExpire := int64(timestampWindow() / time.Second)
 
 	// 计算时间差
 	timeDiff := timeutil.DiffSeconds(now, timestampTime)
@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/gin-gonic/gin"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/utils/crypto"
+	"github.com/skyle1995/DevE-Server/utils/response"
+)
+
+// 应用加密类型
+const (
+	EncryptionNone = 0 // 不加密
+	EncryptionAES  = 1 // AES加密
+)
+
+// encryptedBody 加密后的请求/响应数据
+type encryptedBody struct {
+	Data string `json:"data"` // 加密后的base64字符串
+}
+
+// ClientCryptoMiddleware 客户端数据加密中间件
+// 需在ClientAuthV2Middleware之后使用，根据应用的加密类型处理请求和响应：
+// - 不加密：直接放行
+// - AES加密：请求体为 {"data": "密文"} 并携带请求头 Content-Encoding: encrypted，响应的data字段同样加密
+// - 其他加密类型暂未实现，返回加密/解密错误
+func ClientCryptoMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("app")
+		app, ok := value.(dbmodel.App)
+		if !ok || app.EncryptionType == EncryptionNone {
+			c.Next()
+			return
+		}
+
+		fail := func(message string) {
+			response.FailWithCode(response.CodeCryptoError, message, c)
+			c.Abort()
+		}
+
+		if app.EncryptionType != EncryptionAES {
+			fail("暂不支持该加密类型")
+			return
+		}
+
+		// 解密请求体
+		if c.Request.Body != nil && c.Request.ContentLength != 0 {
+			if c.GetHeader("Content-Encoding") != "encrypted" {
+				fail("应用已开启加密，请求数据必须加密")
+				return
+			}
+
+			var body encryptedBody
+			if err := json.NewDecoder(c.Request.Body).Decode(&body); err != nil {
+				fail("加密数据格式错误")
+				return
+			}
+
+			plain, err := crypto.AESDecrypt(body.Data, app.EncryptionKey)
+			if err != nil {
+				fail("请求数据" + err.Error())
+				return
+			}
+
+			c.Request.Body = io.NopCloser(bytes.NewReader(plain))
+			c.Request.ContentLength = int64(len(plain))
+			c.Request.Header.Del("Content-Encoding")
+		}
+
+		// 缓存响应体，处理完成后加密data字段
+		writer := &bufferedResponseWriter{body: &bytes.Buffer{}, ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		c.Writer = writer.ResponseWriter
+		if writer.body.Len() == 0 {
+			return
+		}
+		out, err := encryptResponse(writer.body.Bytes(), app.EncryptionKey)
+		if err != nil {
+			response.FailWithCode(response.CodeCryptoError, "响应数据加密失败", c)
+			return
+		}
+		c.Header("Content-Encoding", "encrypted")
+		c.Writer.Write(out)
+	}
+}
+
+// encryptResponse 加密统一响应结构体中的data字段
+func encryptResponse(raw []byte, key string) ([]byte, error) {
+	var res struct {
+		response.Response
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+
+	if len(res.Data) > 0 && string(res.Data) != "null" {
+		encrypted, err := crypto.AESEncrypt(res.Data, key)
+		if err != nil {
+			return nil, err
+		}
+		res.Response.Data = encrypted
+	}
+
+	return json.Marshal(res.Response)
+}
+
+// bufferedResponseWriter 缓存响应体，不直接写出
+type bufferedResponseWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+// Write 重写Write方法，缓存响应体
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// WriteString 重写WriteString方法，缓存响应体
+func (w *bufferedResponseWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
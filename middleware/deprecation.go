@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// DeprecationMiddleware 标记已废弃接口的中间件
+// 接口仍可正常访问，但会在响应头中提示客户端迁移到新接口
+// successor: 替代接口的路径前缀，例如 /api/v2/client
+func DeprecationMiddleware(successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", "<"+successor+">; rel=\"successor-version\"")
+
+		log.WithFields(log.Fields{
+			"path":      c.Request.URL.Path,
+			"ip":        c.ClientIP(),
+			"successor": successor,
+		}).Debug("访问已废弃的接口")
+
+		c.Next()
+	}
+}
@@ -28,6 +28,13 @@ func SystemLogMiddleware() gin.HandlerFunc {
 // LogMiddleware 通用日志中间件
 func LogMiddleware(logType int) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// 同一请求只记录一次
+		if c.GetBool(requestLoggedKey) {
+			c.Next()
+			return
+		}
+		c.Set(requestLoggedKey, true)
+
 		// 记录请求开始时间
 		startTime := time.Now()
 
@@ -81,6 +88,9 @@ func LogMiddleware(logType int) gin.HandlerFunc {
 				return // 如果没有用户ID，不记录日志
 			}
 			log.UserID = userID.(uint)
+			if tokenID, exists := c.Get(accessTokenIDKey); exists {
+				log.AccessTokenID = tokenID.(uint)
+			}
 		}
 
 		// 异步保存日志
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/utils/cache"
+)
+
+// 角色缓存参数
+const (
+	roleCacheKey = "roles"
+	// roleCacheTTL 缓存有效期，多实例部署或直接修改数据库时，角色权限最多延迟该时间生效
+	roleCacheTTL = time.Minute
+)
+
+// roleCache 缓存全部角色，通过角色管理接口修改角色时清除
+var roleCache = cache.New(0, 0)
+
+// ClearRoleCache 清除角色缓存，下次检查权限时重新从数据库加载
+func ClearRoleCache() {
+	roleCache.Delete(roleCacheKey)
+}
+
+// roles 返回全部角色，缓存失效时从数据库加载，查询失败时返回空值且不缓存
+func roles() map[uint]dbmodel.Role {
+	if cached, ok := roleCache.Get(roleCacheKey); ok {
+		return cached.(map[uint]dbmodel.Role)
+	}
+
+	var list []dbmodel.Role
+	if err := database.DB.Find(&list).Error; err != nil {
+		log.Errorf("加载角色失败: %v", err)
+		return map[uint]dbmodel.Role{}
+	}
+	result := make(map[uint]dbmodel.Role, len(list))
+	for _, role := range list {
+		result[role.ID] = role
+	}
+	roleCache.Set(roleCacheKey, result, roleCacheTTL)
+	return result
+}
+
+// GetRole 根据角色ID获取角色，角色不存在时返回false
+func GetRole(roleID uint) (dbmodel.Role, bool) {
+	role, ok := roles()[roleID]
+	return role, ok
+}
+
+// RoleHasPermission 判断角色是否拥有指定权限，角色不存在时没有任何权限
+func RoleHasPermission(roleID uint, permission string) bool {
+	role, ok := GetRole(roleID)
+	return ok && role.HasPermission(permission)
+}
+
+// HasPermission 判断当前登录用户是否拥有指定权限，需在JWTAuthMiddleware之后使用
+// 使用个人访问令牌时，权限还需在令牌的授权范围内
+func HasPermission(c *gin.Context, permission string) bool {
+	return roleHasPermission(c, permission) && tokenHasScope(c, permission)
+}
+
+// roleHasPermission 判断当前登录用户的角色是否拥有指定权限
+func roleHasPermission(c *gin.Context, permission string) bool {
+	roleID, exists := c.Get("role_id")
+	if !exists {
+		return false
+	}
+	id, ok := roleID.(uint)
+	return ok && RoleHasPermission(id, permission)
+}
+
+// tokenHasScope 判断当前请求的访问令牌是否授权了指定权限，未使用访问令牌时返回true
+func tokenHasScope(c *gin.Context, permission string) bool {
+	token, ok := currentAccessToken(c)
+	return !ok || token.HasScope(permission)
+}
+
+// PermissionMiddleware 验证当前登录用户是否拥有指定权限的中间件，需在JWTAuthMiddleware之后使用
+func PermissionMiddleware(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, exists := c.Get("role_id"); !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "未经授权的访问"})
+			c.Abort()
+			return
+		}
+
+		if !roleHasPermission(c, permission) {
+			c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "权限不足，需要" + permissionName(permission) + "权限"})
+			c.Abort()
+			return
+		}
+
+		// 访问令牌还需授权该权限，限定应用的访问令牌只能调用检查了应用范围的接口
+		if !tokenHasScope(c, permission) {
+			c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "访问令牌未授权" + permissionName(permission) + "权限"})
+			c.Abort()
+			return
+		}
+		if TokenAppID(c) != 0 && !c.GetBool(appScopeKey) {
+			c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "限定应用的访问令牌不能调用该接口"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// permissionName 返回权限名称，未定义的权限返回权限标识
+func permissionName(permission string) string {
+	for _, p := range dbmodel.Permissions {
+		if p.Key == permission {
+			return p.Name
+		}
+	}
+	return permission
+}
@@ -0,0 +1,129 @@
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/skyle1995/DevE-Server/apps/client/model"
+)
+
+// VerifyApp 验证应用，返回应用版本、公告、试用信息等
+func (c *Client) VerifyApp(ctx context.Context) (*model.VerifyAppResponse, error) {
+	req := model.VerifyAppRequest{
+		AppKey:    c.config.AppKey,
+		Timestamp: time.Now().Unix(),
+	}
+
+	var res model.VerifyAppResponse
+	if err := c.do(ctx, http.MethodPost, "/verify-app", req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// GetAppStatus 获取应用状态
+func (c *Client) GetAppStatus(ctx context.Context) (*model.AppStatusResponse, error) {
+	var res model.AppStatusResponse
+	if err := c.do(ctx, http.MethodGet, "/status", nil, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Activate 激活卡密并绑定当前设备
+// 未指定设备ID时使用配置中的设备ID，AppKey和时间戳由SDK自动填充
+func (c *Client) Activate(ctx context.Context, req model.ActivateCardRequest) (*model.ActivateCardResponse, error) {
+	req.DeviceID = c.deviceID(req.DeviceID)
+	req.AppKey = c.config.AppKey
+	req.Timestamp = time.Now().Unix()
+
+	var res model.ActivateCardResponse
+	if err := c.doOnce(ctx, http.MethodPost, "/activate", req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Verify 验证设备是否有权限使用应用
+func (c *Client) Verify(ctx context.Context, req model.ActivateCardRequest) (*model.VerifyDeviceResponse, error) {
+	req.DeviceID = c.deviceID(req.DeviceID)
+	req.AppKey = c.config.AppKey
+	req.Timestamp = time.Now().Unix()
+
+	var res model.VerifyDeviceResponse
+	if err := c.do(ctx, http.MethodPost, "/verify", req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Heartbeat 发送一次心跳
+func (c *Client) Heartbeat(ctx context.Context, req model.HeartbeatRequest) (*model.HeartbeatResponse, error) {
+	req.DeviceID = c.deviceID(req.DeviceID)
+	req.AppKey = c.config.AppKey
+	req.Timestamp = time.Now().Unix()
+
+	var res model.HeartbeatResponse
+	if err := c.do(ctx, http.MethodPost, "/heartbeat", req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Rebind 将卡密换绑到新设备
+// 未指定设备ID时换绑到配置中的设备ID
+func (c *Client) Rebind(ctx context.Context, req model.RebindCardRequest) (*model.RebindCardResponse, error) {
+	req.DeviceID = c.deviceID(req.DeviceID)
+	req.AppKey = c.config.AppKey
+	req.Timestamp = time.Now().Unix()
+
+	var res model.RebindCardResponse
+	if err := c.doOnce(ctx, http.MethodPost, "/rebind", req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Unbind 解除卡密与设备的绑定
+func (c *Client) Unbind(ctx context.Context, req model.UnbindCardRequest) (*model.UnbindCardResponse, error) {
+	req.AppKey = c.config.AppKey
+	req.Timestamp = time.Now().Unix()
+
+	var res model.UnbindCardResponse
+	if err := c.doOnce(ctx, http.MethodPost, "/unbind", req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// Freeze 冻结卡密，冻结期间剩余有效时长保留，需卡密类型允许客户端冻结
+func (c *Client) Freeze(ctx context.Context, req model.FreezeCardRequest) (*model.FreezeCardResponse, error) {
+	return c.changeFrozen(ctx, "/freeze", req)
+}
+
+// Unfreeze 解冻由终端用户冻结的卡密
+func (c *Client) Unfreeze(ctx context.Context, req model.FreezeCardRequest) (*model.FreezeCardResponse, error) {
+	return c.changeFrozen(ctx, "/unfreeze", req)
+}
+
+// changeFrozen 冻结或解冻卡密
+func (c *Client) changeFrozen(ctx context.Context, path string, req model.FreezeCardRequest) (*model.FreezeCardResponse, error) {
+	req.AppKey = c.config.AppKey
+	req.Timestamp = time.Now().Unix()
+	req.DeviceID = c.deviceID(req.DeviceID)
+
+	var res model.FreezeCardResponse
+	if err := c.doOnce(ctx, http.MethodPost, path, req, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// deviceID 获取请求使用的设备ID
+func (c *Client) deviceID(deviceID string) string {
+	if deviceID == "" {
+		return c.config.DeviceID
+	}
+	return deviceID
+}
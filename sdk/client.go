@@ -0,0 +1,216 @@
+// Package sdk 是DevE-Server客户端授权接口（/api/v2/client）的官方Go SDK
+// 负责请求签名、时间戳、数据加密、失败重试以及后台心跳
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skyle1995/DevE-Server/utils/crypto"
+	"github.com/skyle1995/DevE-Server/utils/random"
+)
+
+// 客户端接口路径前缀
+const apiPrefix = "/api/v2/client"
+
+// Config SDK配置
+type Config struct {
+	BaseURL           string        // 服务端地址，例如 https://example.com
+	AppKey            string        // 应用的AppKey
+	AppSecret         string        // 应用的AppSecret，仅用于本地签名，不会发送到服务端
+	EncryptionKey     string        // 应用的加密密钥，应用开启AES加密时必填
+	DeviceID          string        // 当前设备ID，请求中未指定设备ID时使用
+	HTTPClient        *http.Client  // 自定义HTTP客户端，默认超时10秒
+	MaxRetries        int           // 网络错误或服务端5xx错误时的最大重试次数，默认3次，小于0表示不重试，仅对验证、查询和心跳接口生效
+	RetryInterval     time.Duration // 重试间隔，默认1秒
+	HeartbeatInterval time.Duration // 后台心跳间隔，默认60秒
+}
+
+// Client 授权接口客户端，可在多个goroutine中并发使用
+type Client struct {
+	config Config
+	http   *http.Client
+}
+
+// envelope 服务端统一响应结构
+type envelope struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// New 创建授权接口客户端
+func New(config Config) (*Client, error) {
+	if config.BaseURL == "" {
+		return nil, errors.New("服务端地址不能为空")
+	}
+	if config.AppKey == "" || config.AppSecret == "" {
+		return nil, errors.New("AppKey和AppSecret不能为空")
+	}
+
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = 3
+	} else if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = time.Second
+	}
+	if config.HeartbeatInterval <= 0 {
+		config.HeartbeatInterval = 60 * time.Second
+	}
+
+	return &Client{
+		config: config,
+		http:   config.HTTPClient,
+	}, nil
+}
+
+// authHeaders 生成认证请求头
+// 签名规则与服务端ValidateRequestSign一致，请求方法、路径、URL查询参数、时间戳、随机数和实际发送的请求体参与签名
+func (c *Client) authHeaders(method, path string, query url.Values, body []byte) http.Header {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := random.RandomLettersDigits(32)
+
+	header := http.Header{}
+	header.Set("App-Key", c.config.AppKey)
+	header.Set("Timestamp", timestamp)
+	header.Set("Nonce", nonce)
+	header.Set("App-Sign", crypto.SignRequest(c.config.AppSecret, method, path, query, timestamp, nonce, body))
+	return header
+}
+
+// encodeBody 序列化请求体，开启加密时返回加密后的请求体
+func (c *Client) encodeBody(body interface{}) ([]byte, error) {
+	if body == nil {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	if c.config.EncryptionKey == "" {
+		return raw, nil
+	}
+
+	encrypted, err := crypto.AESEncrypt(raw, c.config.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]string{"data": encrypted})
+}
+
+// doOnce 发送请求并解析响应，失败时不重试
+// 用于激活、换绑等会改变卡密状态的接口，请求可能已被服务端处理，重试会导致重复执行
+func (c *Client) doOnce(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	payload, err := c.encodeBody(body)
+	if err != nil {
+		return err
+	}
+	_, err = c.send(ctx, method, path, payload, out)
+	return err
+}
+
+// do 发送请求并解析响应，仅用于重复执行无副作用的接口
+// 网络错误和服务端5xx错误会按配置重试，每次重试重新生成时间戳、随机数和签名
+func (c *Client) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	payload, err := c.encodeBody(body)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.config.RetryInterval):
+			}
+		}
+
+		var retry bool
+		retry, lastErr = c.send(ctx, method, path, payload, out)
+		if !retry {
+			return lastErr
+		}
+	}
+	return lastErr
+}
+
+// send 发送单次请求，返回是否可以重试
+func (c *Client) send(ctx context.Context, method, path string, payload []byte, out interface{}) (bool, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.config.BaseURL+apiPrefix+path, reader)
+	if err != nil {
+		return false, err
+	}
+	req.Header = c.authHeaders(method, req.URL.Path, req.URL.Query(), payload)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+		if c.config.EncryptionKey != "" {
+			req.Header.Set("Content-Encoding", "encrypted")
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// 上下文取消时不再重试
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return true, err
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return true, &APIError{Code: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+	}
+
+	var env envelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return false, errors.New("响应格式错误: " + err.Error())
+	}
+	if env.Code != http.StatusOK {
+		return false, &APIError{Code: env.Code, Message: env.Message}
+	}
+
+	data := []byte(env.Data)
+	if resp.Header.Get("Content-Encoding") == "encrypted" && len(data) > 0 && string(data) != "null" {
+		var encrypted string
+		if err := json.Unmarshal(data, &encrypted); err != nil {
+			return false, errors.New("加密响应格式错误")
+		}
+		if data, err = crypto.AESDecrypt(encrypted, c.config.EncryptionKey); err != nil {
+			return false, errors.New("响应数据" + err.Error())
+		}
+	}
+
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return false, errors.New("响应数据解析失败: " + err.Error())
+		}
+	}
+	return false, nil
+}
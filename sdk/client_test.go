@@ -0,0 +1,515 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/skyle1995/DevE-Server/apps/client/model"
+	"github.com/skyle1995/DevE-Server/database"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
+	"github.com/skyle1995/DevE-Server/server"
+	"github.com/skyle1995/DevE-Server/utils/jwt"
+	"github.com/skyle1995/DevE-Server/utils/response"
+	"github.com/spf13/viper"
+)
+
+var (
+	testServer   *httptest.Server
+	fixtureCount int32
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "deve-sdk-test")
+	if err != nil {
+		panic(err)
+	}
+
+	viper.Set("server.mode", "test")
+	viper.Set("security.jwt_secret", "sdk-test-secret")
+	viper.Set("database.type", "SQLite")
+	viper.Set("database.sqlite.path", dir+"/test.db")
+	database.Init()
+
+	migration := database.NewMigration()
+	if err := migration.AutoMigrate(); err != nil {
+		panic(err)
+	}
+	// 初始化默认管理员，用于调用管理端推送接口
+	if err := migration.InitDefaultData(); err != nil {
+		panic(err)
+	}
+
+	testServer = httptest.NewServer(server.SetupRouter())
+	code := m.Run()
+	testServer.Close()
+	database.Close()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// fixture 测试用的应用和卡密
+type fixture struct {
+	app   dbmodel.App
+	cards []string
+}
+
+// newFixture 创建应用、卡密类型和指定数量的未使用卡密
+func newFixture(t *testing.T, cards int, configure func(app *dbmodel.App)) *fixture {
+	t.Helper()
+
+	name := fmt.Sprintf("%s_%d", strings.ReplaceAll(t.Name(), "/", "_"), atomic.AddInt32(&fixtureCount, 1))
+	app := dbmodel.App{
+		Name:           name,
+		AppKey:         "key_" + name,
+		AppSecret:      "secret_" + name,
+		Status:         1,
+		UserID:         1,
+		BindPermission: 3,
+		Version:        "1.0.0",
+	}
+	if configure != nil {
+		configure(&app)
+	}
+	if err := database.DB.Create(&app).Error; err != nil {
+		t.Fatalf("创建应用失败: %v", err)
+	}
+
+	cardType := dbmodel.CardType{Name: name, Duration: 30, TimeUnit: "day", ValidDays: 30, AppID: app.ID, UserID: 1, Status: 1}
+	if err := database.DB.Create(&cardType).Error; err != nil {
+		t.Fatalf("创建卡密类型失败: %v", err)
+	}
+
+	f := &fixture{app: app}
+	for i := 0; i < cards; i++ {
+		card := dbmodel.Card{
+			CardNo:  fmt.Sprintf("%s_NO_%d", name, i),
+			CardKey: fmt.Sprintf("%s_KEY_%d", name, i),
+			TypeID:  cardType.ID,
+			AppID:   app.ID,
+			UserID:  1,
+		}
+		if err := database.DB.Create(&card).Error; err != nil {
+			t.Fatalf("创建卡密失败: %v", err)
+		}
+		f.cards = append(f.cards, card.CardNo)
+	}
+	return f
+}
+
+// newClient 创建指向测试服务端的SDK客户端
+func (f *fixture) newClient(t *testing.T, deviceID string, configure func(config *Config)) *Client {
+	t.Helper()
+
+	config := Config{
+		BaseURL:           testServer.URL,
+		AppKey:            f.app.AppKey,
+		AppSecret:         f.app.AppSecret,
+		DeviceID:          f.device(deviceID),
+		RetryInterval:     10 * time.Millisecond,
+		HeartbeatInterval: 50 * time.Millisecond,
+	}
+	if configure != nil {
+		configure(&config)
+	}
+
+	client, err := New(config)
+	if err != nil {
+		t.Fatalf("创建客户端失败: %v", err)
+	}
+	return client
+}
+
+// device 生成测试内唯一的设备ID
+func (f *fixture) device(id string) string {
+	return f.app.Name + "_" + id
+}
+
+// activate 激活第index张卡密
+func (f *fixture) activate(t *testing.T, client *Client, index int) {
+	t.Helper()
+
+	res, err := client.Activate(context.Background(), model.ActivateCardRequest{
+		CardNo:  f.cards[index],
+		CardKey: strings.Replace(f.cards[index], "_NO_", "_KEY_", 1),
+	})
+	if err != nil {
+		t.Fatalf("激活卡密失败: %v", err)
+	}
+	if !res.Activated || res.CardNo != f.cards[index] {
+		t.Fatalf("激活结果不正确: %+v", res)
+	}
+}
+
+// apiCode 获取错误的业务状态码
+func apiCode(err error) int {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return 0
+}
+
+func TestNewValidatesConfig(t *testing.T) {
+	if _, err := New(Config{AppKey: "k", AppSecret: "s"}); err == nil {
+		t.Fatal("缺少服务端地址时应返回错误")
+	}
+	if _, err := New(Config{BaseURL: "http://localhost"}); err == nil {
+		t.Fatal("缺少AppKey时应返回错误")
+	}
+}
+
+func TestVerifyApp(t *testing.T) {
+	f := newFixture(t, 0, nil)
+	client := f.newClient(t, "device-1", nil)
+
+	res, err := client.VerifyApp(context.Background())
+	if err != nil {
+		t.Fatalf("验证应用失败: %v", err)
+	}
+	if res.AppID != f.app.ID || res.Version != "1.0.0" {
+		t.Fatalf("验证应用结果不正确: %+v", res)
+	}
+
+	status, err := client.GetAppStatus(context.Background())
+	if err != nil {
+		t.Fatalf("获取应用状态失败: %v", err)
+	}
+	if status.Status != 1 {
+		t.Fatalf("应用状态不正确: %+v", status)
+	}
+}
+
+func TestLicenseLifecycle(t *testing.T) {
+	f := newFixture(t, 1, nil)
+	client := f.newClient(t, "device-1", nil)
+	ctx := context.Background()
+
+	f.activate(t, client, 0)
+
+	verify, err := client.Verify(ctx, model.ActivateCardRequest{
+		CardNo:  f.cards[0],
+		CardKey: strings.Replace(f.cards[0], "_NO_", "_KEY_", 1),
+	})
+	if err != nil {
+		t.Fatalf("验证设备失败: %v", err)
+	}
+	if !verify.Success || verify.RemainDays <= 0 {
+		t.Fatalf("验证设备结果不正确: %+v", verify)
+	}
+
+	heartbeat, err := client.Heartbeat(ctx, model.HeartbeatRequest{CardNo: f.cards[0]})
+	if err != nil {
+		t.Fatalf("心跳失败: %v", err)
+	}
+	if !heartbeat.IsOnline {
+		t.Fatalf("心跳结果不正确: %+v", heartbeat)
+	}
+
+	rebind, err := client.Rebind(ctx, model.RebindCardRequest{CardNo: f.cards[0], DeviceID: f.device("device-2")})
+	if err != nil {
+		t.Fatalf("换绑失败: %v", err)
+	}
+	if rebind.DeviceID != f.device("device-2") {
+		t.Fatalf("换绑结果不正确: %+v", rebind)
+	}
+
+	// 换绑后旧设备心跳失败
+	_, err = client.Heartbeat(ctx, model.HeartbeatRequest{CardNo: f.cards[0]})
+	if apiCode(err) != response.CodeCardDeviceMismatch {
+		t.Fatalf("换绑后旧设备心跳应返回设备不匹配，实际: %v", err)
+	}
+
+	if _, err := client.Unbind(ctx, model.UnbindCardRequest{CardNo: f.cards[0]}); err != nil {
+		t.Fatalf("解绑失败: %v", err)
+	}
+}
+
+func TestActivateDeviceInfo(t *testing.T) {
+	f := newFixture(t, 1, nil)
+	client := f.newClient(t, "device-1", nil)
+
+	_, err := client.Activate(context.Background(), model.ActivateCardRequest{
+		CardNo:     f.cards[0],
+		CardKey:    strings.Replace(f.cards[0], "_NO_", "_KEY_", 1),
+		DeviceInfo: map[string]interface{}{"model": "test-phone"},
+	})
+	if err != nil {
+		t.Fatalf("携带设备信息激活失败: %v", err)
+	}
+
+	var device dbmodel.Device
+	if err := database.DB.Where("device_id = ? AND app_id = ?", f.device("device-1"), f.app.ID).First(&device).Error; err != nil {
+		t.Fatalf("查询设备失败: %v", err)
+	}
+	if device.DeviceInfo["model"] != "test-phone" {
+		t.Fatalf("设备信息未保存: %+v", device.DeviceInfo)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	f := newFixture(t, 0, nil)
+	client := f.newClient(t, "device-1", nil)
+
+	_, err := client.Activate(context.Background(), model.ActivateCardRequest{CardNo: "missing", CardKey: "missing"})
+	if apiCode(err) != response.CodeCardNotFound {
+		t.Fatalf("激活不存在的卡密应返回卡密不存在，实际: %v", err)
+	}
+	if !IsLicenseInvalid(err) {
+		t.Fatal("卡密不存在应视为授权失效")
+	}
+
+	wrong := f.newClient(t, "device-1", func(config *Config) {
+		config.AppSecret = "wrong"
+	})
+	if _, err := wrong.VerifyApp(context.Background()); apiCode(err) != response.CodeSignInvalid {
+		t.Fatalf("错误的AppSecret应返回签名验证失败，实际: %v", err)
+	}
+}
+
+func TestRequestSignature(t *testing.T) {
+	f := newFixture(t, 0, nil)
+	client := f.newClient(t, "device-1", nil)
+
+	path := apiPrefix + "/verify-app"
+	body := []byte(fmt.Sprintf(`{"app_key":%q,"timestamp":%d}`, f.app.AppKey, time.Now().Unix()))
+	header := client.authHeaders(http.MethodPost, path, nil, body)
+	send := func(body []byte) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, testServer.URL+path, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header = header.Clone()
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		defer resp.Body.Close()
+		var env envelope
+		if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+			t.Fatalf("响应格式错误: %v", err)
+		}
+		return env.Code
+	}
+
+	// 请求体参与签名，修改后签名验证失败
+	tampered := bytes.Replace(body, []byte(f.app.AppKey), []byte("other"), 1)
+	if code := send(tampered); code != response.CodeSignInvalid {
+		t.Fatalf("修改请求体后应返回签名验证失败，实际: %d", code)
+	}
+	if code := send(body); code != http.StatusOK {
+		t.Fatalf("签名正确的请求应成功，实际: %d", code)
+	}
+	// 重放同一请求
+	if code := send(body); code != response.CodeNonceReused {
+		t.Fatalf("重放的请求应返回请求重复，实际: %d", code)
+	}
+}
+
+func TestEncryption(t *testing.T) {
+	f := newFixture(t, 1, func(app *dbmodel.App) {
+		app.EncryptionType = 1
+		app.EncryptionKey = "encryption-key"
+	})
+
+	plain := f.newClient(t, "device-1", nil)
+	if _, err := plain.VerifyApp(context.Background()); apiCode(err) != response.CodeCryptoError {
+		t.Fatalf("未加密的请求应返回加密错误，实际: %v", err)
+	}
+
+	client := f.newClient(t, "device-1", func(config *Config) {
+		config.EncryptionKey = "encryption-key"
+	})
+	f.activate(t, client, 0)
+
+	res, err := client.VerifyApp(context.Background())
+	if err != nil {
+		t.Fatalf("加密请求验证应用失败: %v", err)
+	}
+	if res.AppID != f.app.ID {
+		t.Fatalf("加密响应解析不正确: %+v", res)
+	}
+}
+
+// flakyTransport 前若干次请求返回网络错误
+type flakyTransport struct {
+	failures int32
+	calls    int32
+}
+
+func (t *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if atomic.AddInt32(&t.calls, 1) <= t.failures {
+		return nil, errors.New("模拟网络错误")
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestRetry(t *testing.T) {
+	f := newFixture(t, 0, nil)
+
+	transport := &flakyTransport{failures: 2}
+	client := f.newClient(t, "device-1", func(config *Config) {
+		config.HTTPClient = &http.Client{Transport: transport}
+	})
+	if _, err := client.VerifyApp(context.Background()); err != nil {
+		t.Fatalf("重试后应成功，实际: %v", err)
+	}
+	if transport.calls != 3 {
+		t.Fatalf("应请求3次，实际: %d", transport.calls)
+	}
+
+	transport = &flakyTransport{failures: 10}
+	client = f.newClient(t, "device-1", func(config *Config) {
+		config.HTTPClient = &http.Client{Transport: transport}
+		config.MaxRetries = 1
+	})
+	if _, err := client.VerifyApp(context.Background()); err == nil {
+		t.Fatal("超过最大重试次数后应返回错误")
+	}
+	if transport.calls != 2 {
+		t.Fatalf("应请求2次，实际: %d", transport.calls)
+	}
+
+	// 激活会改变卡密状态，失败时不重试
+	transport = &flakyTransport{failures: 1}
+	client = f.newClient(t, "device-1", func(config *Config) {
+		config.HTTPClient = &http.Client{Transport: transport}
+	})
+	if _, err := client.Activate(context.Background(), model.ActivateCardRequest{CardNo: "any"}); err == nil {
+		t.Fatal("激活请求失败时应直接返回错误")
+	}
+	if transport.calls != 1 {
+		t.Fatalf("激活请求不应重试，实际请求: %d次", transport.calls)
+	}
+}
+
+func TestPushDisableCard(t *testing.T) {
+	f := newFixture(t, 1, nil)
+	client := f.newClient(t, "device-1", nil)
+	f.activate(t, client, 0)
+
+	pushCommand(t, f.app.ID, fmt.Sprintf(`{"command":"disable_card","card_no":%q}`, f.cards[0]))
+
+	// 推送禁用的卡密在客户端接口中同样视为已禁用
+	_, err := client.Activate(context.Background(), model.ActivateCardRequest{
+		CardNo:  f.cards[0],
+		CardKey: strings.Replace(f.cards[0], "_NO_", "_KEY_", 1),
+	})
+	if apiCode(err) != response.CodeCardDisabled {
+		t.Fatalf("推送禁用后激活应返回卡密已禁用，实际: %v", err)
+	}
+
+	var count int64
+	database.DB.Model(&dbmodel.CardEvent{}).Where("card_no = ? AND type = ?", f.cards[0], dbmodel.CardEventDisable).Count(&count)
+	if count != 1 {
+		t.Fatalf("推送禁用应记录1条禁用事件，实际: %d", count)
+	}
+}
+
+func TestHeartbeatLoopKicked(t *testing.T) {
+	f := newFixture(t, 1, nil)
+	client := f.newClient(t, "device-1", nil)
+	f.activate(t, client, 0)
+
+	heartbeats := make(chan struct{}, 16)
+	messages := make(chan string, 1)
+	kicked := make(chan string, 1)
+	loop := client.StartHeartbeat(f.cards[0], Callbacks{
+		OnHeartbeat: func(res *model.HeartbeatResponse) {
+			select {
+			case heartbeats <- struct{}{}:
+			default:
+			}
+		},
+		OnMessage: func(message string) { messages <- message },
+		OnKicked:  func(command, message string) { kicked <- command + ":" + message },
+	})
+	defer loop.Stop()
+
+	waitFor(t, heartbeats, "心跳")
+	waitFor(t, heartbeats, "长连接心跳")
+
+	pushCommand(t, f.app.ID, `{"command":"message","message":"hello"}`)
+	if msg := waitFor(t, messages, "推送消息"); msg != "hello" {
+		t.Fatalf("推送消息内容不正确: %s", msg)
+	}
+
+	pushCommand(t, f.app.ID, `{"command":"kick","message":"bye"}`)
+	if msg := waitFor(t, kicked, "踢下线"); msg != "kick:bye" {
+		t.Fatalf("踢下线内容不正确: %s", msg)
+	}
+	waitFor(t, loop.Done(), "心跳停止")
+}
+
+func TestHeartbeatLoopExpired(t *testing.T) {
+	f := newFixture(t, 1, nil)
+	client := f.newClient(t, "device-1", nil)
+	f.activate(t, client, 0)
+
+	// 将卡密设置为已过期
+	database.DB.Model(&dbmodel.Card{}).Where("card_no = ?", f.cards[0]).Update("expire_at", time.Now().Add(-time.Hour))
+
+	expired := make(chan int, 1)
+	loop := client.StartHeartbeat(f.cards[0], Callbacks{
+		OnExpired: func(err *APIError) { expired <- err.Code },
+	})
+	defer loop.Stop()
+
+	if code := waitFor(t, expired, "授权失效"); code != response.CodeCardExpired {
+		t.Fatalf("授权失效状态码不正确: %d", code)
+	}
+	waitFor(t, loop.Done(), "心跳停止")
+}
+
+// waitFor 等待通道返回数据
+func waitFor[T any](t *testing.T, ch <-chan T, name string) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatalf("等待%s超时", name)
+	}
+	var zero T
+	return zero
+}
+
+// pushCommand 通过管理端接口向应用的在线客户端推送指令
+func pushCommand(t *testing.T, appID uint, body string) {
+	t.Helper()
+
+	config := jwt.DefaultConfig()
+	config.SigningKey = viper.GetString("security.jwt_secret")
+	token, err := jwt.New(config).CreateToken(1, "admin", "1")
+	if err != nil {
+		t.Fatalf("生成令牌失败: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/v1/apps/%d/clients/push", testServer.URL, appID), strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("推送指令失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var res struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil || res.Code != http.StatusOK {
+		t.Fatalf("推送指令失败: %d %s", res.Code, res.Message)
+	}
+}
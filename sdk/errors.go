@@ -0,0 +1,41 @@
+package sdk
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/skyle1995/DevE-Server/utils/response"
+)
+
+// APIError 服务端返回的业务错误
+type APIError struct {
+	Code    int    // 业务状态码，参见开发文档 12.2
+	Message string // 错误信息
+}
+
+// Error 实现error接口
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+// IsLicenseInvalid 判断错误是否表示授权已失效
+// 卡密不存在、未激活、已过期、已禁用、已冻结、设备不匹配或应用不可用时返回true，此时重试没有意义
+func IsLicenseInvalid(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	switch apiErr.Code {
+	case response.CodeCardNotFound,
+		response.CodeCardExpired,
+		response.CodeCardDisabled,
+		response.CodeCardFrozen,
+		response.CodeCardDeviceMismatch,
+		response.CodeCardNotActivated,
+		response.CodeAppKeyInvalid,
+		response.CodeAppDisabled:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/skyle1995/DevE-Server/apps/client/model"
+	pushmodel "github.com/skyle1995/DevE-Server/apps/push/model"
+)
+
+// Callbacks 后台心跳的回调函数，均为可选
+// 回调在心跳goroutine中同步执行，请勿在回调中长时间阻塞
+type Callbacks struct {
+	OnHeartbeat   func(res *model.HeartbeatResponse)     // 心跳成功
+	OnExpired     func(err *APIError)                    // 授权失效（过期、禁用等），回调后心跳停止
+	OnKicked      func(command, message string)          // 被服务端踢下线或禁用卡密，回调后心跳停止
+	OnMessage     func(message string)                   // 收到服务端推送的消息
+	OnForceUpdate func(update pushmodel.ForceUpdateData) // 收到服务端推送的强制更新
+	OnError       func(err error)                        // 网络等临时错误，心跳会继续
+}
+
+// HeartbeatLoop 后台心跳
+type HeartbeatLoop struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Stop 停止后台心跳并等待goroutine退出
+func (h *HeartbeatLoop) Stop() {
+	h.cancel()
+	<-h.done
+}
+
+// Done 返回心跳结束时关闭的通道，授权失效、被踢下线或调用Stop后关闭
+func (h *HeartbeatLoop) Done() <-chan struct{} {
+	return h.done
+}
+
+// StartHeartbeat 启动后台心跳
+// 优先通过WebSocket长连接保持在线并接收服务端推送的指令；
+// 长连接不可用或断开时改用HTTP心跳，并在下一个心跳周期重新建立长连接
+// 配置了EncryptionKey时服务端不提供长连接，只使用加密的HTTP心跳
+func (c *Client) StartHeartbeat(cardNo string, cb Callbacks) *HeartbeatLoop {
+	ctx, cancel := context.WithCancel(context.Background())
+	loop := &HeartbeatLoop{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		defer close(loop.done)
+		defer cancel()
+		c.runHeartbeat(ctx, cardNo, cb)
+	}()
+
+	return loop
+}
+
+// runHeartbeat 心跳主循环，授权失效、被踢下线或上下文取消时返回
+func (c *Client) runHeartbeat(ctx context.Context, cardNo string, cb Callbacks) {
+	for {
+		// 长连接不加密，开启加密的应用直接使用HTTP心跳
+		if c.config.EncryptionKey == "" {
+			if stop := c.listen(ctx, cardNo, cb); stop || ctx.Err() != nil {
+				return
+			}
+		}
+
+		// 长连接不可用，发送HTTP心跳
+		res, err := c.Heartbeat(ctx, model.HeartbeatRequest{CardNo: cardNo})
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			if c.handleError(err, cb) {
+				return
+			}
+		} else if cb.OnHeartbeat != nil {
+			cb.OnHeartbeat(res)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.config.HeartbeatInterval):
+		}
+	}
+}
+
+// handleError 处理心跳错误，授权失效时返回true
+func (c *Client) handleError(err error, cb Callbacks) bool {
+	var apiErr *APIError
+	if IsLicenseInvalid(err) && errors.As(err, &apiErr) {
+		if cb.OnExpired != nil {
+			cb.OnExpired(apiErr)
+		}
+		return true
+	}
+	if cb.OnError != nil {
+		cb.OnError(err)
+	}
+	return false
+}
+
+// wsURL 生成长连接地址和认证请求头
+func (c *Client) wsURL(cardNo string) (string, map[string][]string) {
+	query := url.Values{}
+	query.Set("card_no", cardNo)
+	query.Set("device_id", c.config.DeviceID)
+
+	address := c.config.BaseURL + apiPrefix + "/ws?" + query.Encode()
+	if strings.HasPrefix(address, "https://") {
+		address = "wss://" + strings.TrimPrefix(address, "https://")
+	} else {
+		address = "ws://" + strings.TrimPrefix(address, "http://")
+	}
+
+	// 签名使用请求的URL路径，服务端地址可能带有路径前缀
+	path := apiPrefix + "/ws"
+	if parsed, err := url.Parse(c.config.BaseURL + path); err == nil {
+		path = parsed.Path
+	}
+	return address, c.authHeaders(http.MethodGet, path, query, nil)
+}
+
+// wsMessage 长连接下行消息
+type wsMessage struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// listen 建立长连接并处理服务端推送，返回是否应停止心跳
+func (c *Client) listen(ctx context.Context, cardNo string, cb Callbacks) bool {
+	address, header := c.wsURL(cardNo)
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, address, header)
+	if err != nil {
+		if ctx.Err() != nil {
+			return true
+		}
+		// 握手被拒绝时服务端返回普通JSON响应，解析其中的业务错误
+		if resp != nil {
+			var env envelope
+			if json.NewDecoder(resp.Body).Decode(&env) == nil && env.Code != 0 && env.Code != 200 {
+				err = &APIError{Code: env.Code, Message: env.Message}
+			}
+		}
+		return c.handleError(err, cb)
+	}
+	defer conn.Close()
+
+	// 上下文取消时关闭连接，使读取循环退出
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	// 定时发送心跳消息
+	go func() {
+		ticker := time.NewTicker(c.config.HeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if conn.WriteJSON(pushmodel.ClientMessage{Type: pushmodel.MessageHeartbeat}) != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if ctx.Err() != nil {
+				return true
+			}
+			if cb.OnError != nil {
+				cb.OnError(err)
+			}
+			return false
+		}
+
+		if stop := c.dispatch(msg, cb); stop {
+			return true
+		}
+	}
+}
+
+// dispatch 分发服务端推送的消息，返回是否应停止心跳
+func (c *Client) dispatch(msg wsMessage, cb Callbacks) bool {
+	switch msg.Type {
+	case pushmodel.MessageConnected:
+		var data struct {
+			Heartbeat model.HeartbeatResponse `json:"heartbeat"`
+		}
+		if json.Unmarshal(msg.Data, &data) == nil && cb.OnHeartbeat != nil {
+			cb.OnHeartbeat(&data.Heartbeat)
+		}
+	case pushmodel.MessageHeartbeat:
+		var res model.HeartbeatResponse
+		if json.Unmarshal(msg.Data, &res) == nil && cb.OnHeartbeat != nil {
+			cb.OnHeartbeat(&res)
+		}
+	case pushmodel.MessageError:
+		var data pushmodel.ErrorData
+		json.Unmarshal(msg.Data, &data)
+		return c.handleError(&APIError{Code: data.Code, Message: data.Message}, cb)
+	case pushmodel.MessageKick, pushmodel.MessageDisableCard:
+		var data pushmodel.TextData
+		json.Unmarshal(msg.Data, &data)
+		if cb.OnKicked != nil {
+			cb.OnKicked(msg.Type, data.Message)
+		}
+		return true
+	case pushmodel.MessageText:
+		var data pushmodel.TextData
+		if json.Unmarshal(msg.Data, &data) == nil && cb.OnMessage != nil {
+			cb.OnMessage(data.Message)
+		}
+	case pushmodel.MessageForceUpdate:
+		var data pushmodel.ForceUpdateData
+		if json.Unmarshal(msg.Data, &data) == nil && cb.OnForceUpdate != nil {
+			cb.OnForceUpdate(data)
+		}
+	}
+	return false
+}
@@ -9,11 +9,16 @@ import (
 	"github.com/skyle1995/DevE-Server/apps/auth"
 	"github.com/skyle1995/DevE-Server/apps/card"
 	"github.com/skyle1995/DevE-Server/apps/client"
+	"github.com/skyle1995/DevE-Server/apps/device"
+	"github.com/skyle1995/DevE-Server/apps/invite"
 	"github.com/skyle1995/DevE-Server/apps/logs"
 	"github.com/skyle1995/DevE-Server/apps/notice"
 	"github.com/skyle1995/DevE-Server/apps/page"
+	"github.com/skyle1995/DevE-Server/apps/push"
+	"github.com/skyle1995/DevE-Server/apps/role"
 	"github.com/skyle1995/DevE-Server/apps/setting"
 	"github.com/skyle1995/DevE-Server/apps/user"
+	dbmodel "github.com/skyle1995/DevE-Server/database/model"
 	"github.com/skyle1995/DevE-Server/middleware"
 	"github.com/skyle1995/DevE-Server/public"
 	"github.com/spf13/viper"
@@ -100,11 +105,62 @@ func SetupRouter() *gin.Engine {
 		// 认证相关路由
 		auth := public.Group("/auth")
 		{
-			// 登录路由添加登录日志中间件
-			auth.POST("/login", middleware.LoginLogMiddleware(), authController.Login)
+			// 登录和刷新令牌路由，请求和响应中包含密码和令牌，由控制器记录登录日志
+			auth.POST("/login", authController.Login)
+			auth.POST("/refresh", authController.Refresh)
 			auth.POST("/register", authController.Register)
 			auth.GET("/captcha", authController.GenerateCaptcha)
 			auth.POST("/logout", authController.Logout)
+
+			// 登录两步验证路由，使用登录返回的挑战码，响应中包含令牌和恢复码，由控制器记录登录日志
+			auth.POST("/login/2fa", authController.LoginTwoFactor)
+			auth.POST("/login/2fa/setup", authController.SetupTwoFactorByChallenge)
+
+			// 登录时修改密码路由，密码过期或管理员要求修改密码时使用登录返回的挑战码，响应中包含令牌，由控制器记录登录日志
+			auth.POST("/login/password", authController.LoginChangePassword)
+
+			// 邮箱验证和找回密码路由，请求中包含令牌和新密码，由控制器记录日志
+			auth.GET("/verify-email", authController.VerifyEmail)
+			auth.POST("/verify-email/resend", authController.ResendVerification)
+			auth.POST("/password/forgot", authController.ForgotPassword)
+			auth.POST("/password/reset", authController.ResetPassword)
+			auth.GET("/password/policy", authController.GetPasswordPolicy) // 获取密码策略
+		}
+
+		// 两步验证管理路由（所有已登录用户可访问，不接受访问令牌），响应中包含密钥和恢复码，不使用记录响应体的日志中间件
+		twoFactor := public.Group("/auth/2fa")
+		twoFactor.Use(middleware.JWTAuthMiddleware(), middleware.SessionOnlyMiddleware())
+		{
+			twoFactor.GET("", authController.GetTwoFactorStatus)                      // 获取两步验证状态
+			twoFactor.POST("/setup", authController.SetupTwoFactor)                   // 绑定验证器
+			twoFactor.POST("/enable", authController.EnableTwoFactor)                 // 开启两步验证
+			twoFactor.POST("/recovery-codes", authController.RegenerateRecoveryCodes) // 重新生成恢复码
+			twoFactor.POST("/disable", authController.DisableTwoFactor)               // 关闭两步验证
+		}
+
+		// 登录会话管理路由（所有已登录用户可访问，不接受访问令牌）
+		sessions := public.Group("/auth/sessions")
+		sessions.Use(middleware.JWTAuthMiddleware(), middleware.SessionOnlyMiddleware())
+		{
+			sessions.GET("", authController.GetSessions)          // 获取登录会话
+			sessions.DELETE("/:id", authController.RevokeSession) // 撤销登录会话
+		}
+
+		// 个人访问令牌管理路由（所有已登录用户可访问，不接受访问令牌），创建时响应中包含令牌，由控制器记录日志
+		tokens := public.Group("/auth/tokens")
+		tokens.Use(middleware.JWTAuthMiddleware(), middleware.SessionOnlyMiddleware())
+		{
+			tokens.GET("", authController.GetAccessTokens)          // 获取访问令牌
+			tokens.POST("", authController.CreateAccessToken)       // 创建访问令牌
+			tokens.DELETE("/:id", authController.RevokeAccessToken) // 撤销访问令牌
+		}
+
+		// 登录锁定管理路由（需要用户管理权限）
+		loginLocks := public.Group("/admin/login-locks")
+		loginLocks.Use(middleware.JWTAuthMiddleware(), middleware.PermissionMiddleware(dbmodel.PermissionUserManage))
+		{
+			loginLocks.GET("", authController.GetLoginLocks)                                            // 获取登录锁定
+			loginLocks.POST("/unlock", middleware.OperationLogMiddleware(), authController.UnlockLogin) // 解除登录锁定
 		}
 	}
 
@@ -117,12 +173,24 @@ func SetupRouter() *gin.Engine {
 	// 设置用户路由
 	user.SetupUserRoutes(r)
 
+	// 设置邀请码路由
+	invite.SetupInviteRoutes(r)
+
+	// 设置角色路由
+	role.SetupRoleRoutes(r)
+
 	// 设置卡密路由
 	card.SetupCardRoutes(r)
 
 	// 设置客户端路由
 	client.SetupClientRoutes(r)
 
+	// 设置设备路由
+	device.SetupDeviceRoutes(r)
+
+	// 设置推送路由
+	push.SetupPushRoutes(r)
+
 	// 设置应用路由
 	apps.SetupAppsRoutes(r)
 
@@ -143,17 +211,10 @@ func SetupRouter() *gin.Engine {
 		user := protected.Group("/user")
 		{
 			user.GET("/info", authController.GetUserInfo)
-			user.POST("/password", authController.UpdatePassword)
+			user.POST("/password", middleware.SessionOnlyMiddleware(), authController.UpdatePassword)
 		}
 
 		// 页面相关路由已移至page模块的router.go文件中
-
-		// 管理员路由组
-		admin := protected.Group("/admin")
-		admin.Use(middleware.AdminAuthMiddleware())
-		{
-			// 这里可以添加管理员特有的路由，后期实现
-		}
 	}
 
 	return r
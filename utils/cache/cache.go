@@ -232,6 +232,35 @@ func (c *Cache) Increment(key string, n int64) (int64, error) {
 	return newValue, nil
 }
 
+// IncrementOrSet 增加整数缓存项的值，缓存项不存在或已过期时以n为初始值并设置过期时间
+// 已存在的缓存项保留原过期时间，适用于固定时间窗口内的计数
+func (c *Cache) IncrementOrSet(key string, n int64, duration time.Duration) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, found := c.items[key]
+	if found && (item.Expiration == 0 || time.Now().UnixNano() <= item.Expiration) {
+		if value, ok := item.Value.(int64); ok {
+			item.Value = value + n
+			c.items[key] = item
+			return value + n
+		}
+	}
+
+	if duration == 0 {
+		duration = c.DefaultExpiration
+	}
+	var expiration int64
+	if duration > 0 {
+		expiration = time.Now().Add(duration).UnixNano()
+	}
+	c.items[key] = Item{
+		Value:      n,
+		Expiration: expiration,
+	}
+	return n
+}
+
 // Decrement 减少整数缓存项的值
 func (c *Cache) Decrement(key string, n int64) (int64, error) {
 	return c.Increment(key, -n)
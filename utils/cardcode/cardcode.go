@@ -0,0 +1,198 @@
+// Package cardcode 根据模板生成和校验卡号、卡密
+// 模板布局中的X为随机字符占位符，其他字符原样保留，例如 XXXX-XXXX-XXXX-XXXX；
+// 开启校验位时最后一个占位符为Luhn mod N校验字符，可在查询数据库前发现输入错误
+package cardcode
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/skyle1995/DevE-Server/utils/random"
+)
+
+// Placeholder 布局中的随机字符占位符
+const Placeholder = 'X'
+
+// 字符集
+const (
+	CharsetAlnum       = "alnum"       // 字母和数字
+	CharsetDigits      = "digits"      // 纯数字
+	CharsetLetters     = "letters"     // 纯字母
+	CharsetHex         = "hex"         // 十六进制
+	CharsetUnambiguous = "unambiguous" // 去除易混淆字符（0、O、1、I、L等）的字母和数字
+)
+
+// 大小写
+const (
+	CaseUpper = "upper" // 大写
+	CaseLower = "lower" // 小写
+	CaseMixed = "mixed" // 大小写混合
+)
+
+// 各字符集的大写和小写字符
+var charsets = map[string][2]string{
+	CharsetAlnum:       {"0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ", "0123456789abcdefghijklmnopqrstuvwxyz"},
+	CharsetDigits:      {"0123456789", "0123456789"},
+	CharsetLetters:     {"ABCDEFGHIJKLMNOPQRSTUVWXYZ", "abcdefghijklmnopqrstuvwxyz"},
+	CharsetHex:         {"0123456789ABCDEF", "0123456789abcdef"},
+	CharsetUnambiguous: {"23456789ABCDEFGHJKMNPQRSTUVWXYZ", "23456789abcdefghjkmnpqrstuvwxyz"},
+}
+
+// Format 卡号/卡密格式
+type Format struct {
+	Layout   string // 布局，X为随机字符
+	Charset  string // 字符集
+	Case     string // 大小写
+	Checksum bool   // 最后一个随机字符是否为校验位
+}
+
+// Check 检查格式配置是否有效
+func (f Format) Check() error {
+	if _, ok := charsets[f.Charset]; !ok {
+		return errors.New("不支持的字符集: " + f.Charset)
+	}
+	if f.Case != CaseUpper && f.Case != CaseLower && f.Case != CaseMixed {
+		return errors.New("不支持的大小写: " + f.Case)
+	}
+	if strings.Count(f.Layout, string(Placeholder)) < 4 {
+		return errors.New("布局中至少需要4个随机字符X")
+	}
+	return nil
+}
+
+// alphabet 获取格式使用的字符表
+func (f Format) alphabet() string {
+	chars := charsets[f.Charset]
+	switch {
+	case f.Case == CaseLower:
+		return chars[1]
+	case f.Case == CaseMixed && chars[0] != chars[1]:
+		// 合并时去掉重复的数字
+		lower := strings.TrimLeft(chars[1], "0123456789")
+		return chars[0] + lower
+	default:
+		return chars[0]
+	}
+}
+
+// Generate 按格式生成随机字符串
+func (f Format) Generate() string {
+	alphabet := f.alphabet()
+	count := strings.Count(f.Layout, string(Placeholder))
+	if f.Checksum {
+		count--
+	}
+
+	chars := []byte(random.String(count, alphabet))
+	if f.Checksum {
+		chars = append(chars, alphabet[checkIndex(chars, alphabet)])
+	}
+
+	var b strings.Builder
+	i := 0
+	for _, c := range f.Layout {
+		if c == Placeholder {
+			b.WriteByte(chars[i])
+			i++
+		} else {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// Normalize 按格式的大小写规范化输入中的随机字符和校验位，布局中的固定字符保持不变
+// 输入长度与布局不一致时原样返回
+func (f Format) Normalize(value string) string {
+	if f.Case != CaseUpper && f.Case != CaseLower {
+		return value
+	}
+	layout := []rune(f.Layout)
+	input := []rune(value)
+	if len(layout) != len(input) {
+		return value
+	}
+	for i, c := range layout {
+		if c != Placeholder {
+			continue
+		}
+		if f.Case == CaseUpper {
+			input[i] = unicode.ToUpper(input[i])
+		} else {
+			input[i] = unicode.ToLower(input[i])
+		}
+	}
+	return string(input)
+}
+
+// Match 检查输入是否符合布局，不校验校验位
+// 输入会先按格式的大小写规范化，因此用户输错大小写也能匹配
+func (f Format) Match(value string) bool {
+	_, ok := f.extract(f.Normalize(value))
+	return ok
+}
+
+// Verify 检查输入是否符合布局且校验位正确，未开启校验位时等同于Match
+func (f Format) Verify(value string) bool {
+	chars, ok := f.extract(f.Normalize(value))
+	if !ok {
+		return false
+	}
+	if !f.Checksum {
+		return true
+	}
+	alphabet := f.alphabet()
+	return checkIndex(chars[:len(chars)-1], alphabet) == strings.IndexByte(alphabet, chars[len(chars)-1])
+}
+
+// extract 按布局取出随机字符
+func (f Format) extract(value string) ([]byte, bool) {
+	layout := []rune(f.Layout)
+	input := []rune(value)
+	if len(layout) != len(input) {
+		return nil, false
+	}
+
+	alphabet := f.alphabet()
+	chars := make([]byte, 0, len(input))
+	for i, c := range layout {
+		if c != Placeholder {
+			if input[i] != c {
+				return nil, false
+			}
+			continue
+		}
+		if input[i] > 127 || strings.IndexRune(alphabet, input[i]) < 0 {
+			return nil, false
+		}
+		chars = append(chars, byte(input[i]))
+	}
+	return chars, true
+}
+
+// checkIndex 计算Luhn mod N校验字符在字符表中的位置
+// 能发现任意单个字符错误和绝大多数相邻字符互换
+func checkIndex(chars []byte, alphabet string) int {
+	n := len(alphabet)
+	factor := 2
+	sum := 0
+	for i := len(chars) - 1; i >= 0; i-- {
+		sum += weigh(factor*strings.IndexByte(alphabet, chars[i]), n)
+		if factor == 2 {
+			factor = 1
+		} else {
+			factor = 2
+		}
+	}
+	return (n - sum%n) % n
+}
+
+// weigh 将加权后的字符位置映射回字符表范围内
+// 字符表长度为偶数时按Luhn的方式折叠，为奇数时折叠不再是一一映射，直接取模
+func weigh(addend, n int) int {
+	if n%2 == 0 {
+		return addend/n + addend%n
+	}
+	return addend % n
+}
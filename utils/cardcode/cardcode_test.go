@@ -0,0 +1,31 @@
+package cardcode
+
+import "testing"
+
+func TestNormalizeKeepsLiterals(t *testing.T) {
+	cases := []struct {
+		format Format
+		input  string
+	}{
+		{Format{Layout: "VIP-XXXX-XXXX", Charset: CharsetAlnum, Case: CaseLower}, "VIP-AB12-CD34"},
+		{Format{Layout: "vip-XXXX-XXXX", Charset: CharsetAlnum, Case: CaseUpper, Checksum: true}, ""},
+	}
+	for _, c := range cases {
+		if err := c.format.Check(); err != nil {
+			t.Fatalf("格式 %+v 无效: %v", c.format, err)
+		}
+		value := c.format.Generate()
+		if !c.format.Verify(value) {
+			t.Errorf("%s 生成的 %q 不能通过校验", c.format.Layout, value)
+		}
+		if c.input != "" && !c.format.Match(c.input) {
+			t.Errorf("%s 应匹配大小写不同的输入 %q", c.format.Layout, c.input)
+		}
+	}
+
+	// 固定字符的大小写需与布局一致
+	format := Format{Layout: "VIP-XXXX", Charset: CharsetAlnum, Case: CaseLower}
+	if format.Match("vip-ab12") {
+		t.Error("固定字符的大小写与布局不一致时不应匹配")
+	}
+}
@@ -1,11 +1,13 @@
 package crypto
 
 import (
+	"crypto/hmac"
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"net/url"
 	"sort"
 	"strings"
 )
@@ -73,3 +75,31 @@ func VerifySign(params map[string]string, sign string, secret string) bool {
 	// 比较签名
 	return calcSign == sign
 }
+
+// SignRequest 计算v2客户端接口的请求签名
+// 签名规则：
+// 1. 依次取请求方法（大写）、URL路径、按参数名排序并编码的URL查询参数、时间戳、随机数和请求体的SHA256摘要（十六进制小写）
+// 2. 用换行符连接上述内容，得到待签名字符串
+// 3. 使用密钥对待签名字符串计算HMAC-SHA256，以十六进制小写表示，得到签名
+// body为实际发送的请求体，开启加密时为加密后的请求体，没有请求体时为空
+func SignRequest(secret, method, path string, query url.Values, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	content := strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		query.Encode(),
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(content))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyRequestSign 验证v2客户端接口的请求签名，使用常量时间比较
+func VerifyRequestSign(sign, secret, method, path string, query url.Values, timestamp, nonce string, body []byte) bool {
+	expected := SignRequest(secret, method, path, query, timestamp, nonce, body)
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(sign)))
+}
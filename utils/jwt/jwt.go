@@ -7,8 +7,12 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/skyle1995/DevE-Server/utils/random"
 )
 
+// tokenIDLength 随机生成的令牌ID长度
+const tokenIDLength = 32
+
 // Claims 自定义JWT声明结构体
 type Claims struct {
 	UserID   uint   `json:"user_id"`
@@ -53,13 +57,19 @@ func New(config Config) *JWT {
 	}
 }
 
-// CreateToken 创建JWT Token
+// CreateToken 创建JWT Token，令牌ID随机生成
 func (j *JWT) CreateToken(userID uint, username, role string) (string, error) {
+	return j.CreateTokenWithID(userID, username, role, random.Hex(tokenIDLength))
+}
+
+// CreateTokenWithID 创建指定令牌ID（jti）的JWT Token，令牌ID用于服务端撤销令牌
+func (j *JWT) CreateTokenWithID(userID uint, username, role, id string) (string, error) {
 	claims := Claims{
 		UserID:   userID,
 		Username: username,
 		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
 			Issuer:    j.Config.Issuer,
 			Subject:   username,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.Config.ExpiresTime)),
@@ -0,0 +1,205 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrDisabled 邮件服务未启用
+var ErrDisabled = errors.New("邮件服务未启用")
+
+// Config SMTP配置
+type Config struct {
+	Enable    bool          // 是否启用邮件服务
+	Host      string        // SMTP服务器地址
+	Port      int           // SMTP服务器端口
+	Secure    bool          // 是否要求加密连接：465端口直接建立SSL/TLS连接，其他端口要求STARTTLS；不要求时若服务器支持仍使用STARTTLS
+	Username  string        // SMTP用户名，为空时不认证
+	Password  string        // SMTP密码
+	FromEmail string        // 发件人邮箱
+	FromName  string        // 发件人名称
+	Timeout   time.Duration // 连接超时时间，为0时使用10秒
+}
+
+// Message 邮件内容
+type Message struct {
+	To      []string // 收件人邮箱
+	Subject string   // 主题
+	Text    string   // 纯文本正文
+	HTML    string   // HTML正文，为空时只发送纯文本
+}
+
+// Mailer SMTP邮件发送器
+type Mailer struct {
+	config Config
+}
+
+// New 创建邮件发送器
+func New(config Config) *Mailer {
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	return &Mailer{config: config}
+}
+
+// Send 发送邮件
+func (m *Mailer) Send(msg Message) error {
+	if !m.config.Enable {
+		return ErrDisabled
+	}
+	if len(msg.To) == 0 {
+		return errors.New("收件人不能为空")
+	}
+	for _, to := range msg.To {
+		if _, err := mail.ParseAddress(to); err != nil || strings.ContainsAny(to, "\r\n") {
+			return fmt.Errorf("收件人邮箱格式错误: %s", to)
+		}
+	}
+
+	data, err := m.build(msg)
+	if err != nil {
+		return err
+	}
+
+	client, err := m.dial()
+	if err != nil {
+		return fmt.Errorf("连接SMTP服务器失败: %w", err)
+	}
+	defer client.Close()
+
+	if m.config.Username != "" {
+		if ok, _ := client.Extension("AUTH"); ok {
+			auth := smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+			if err := client.Auth(auth); err != nil {
+				return fmt.Errorf("SMTP认证失败: %w", err)
+			}
+		}
+	}
+
+	if err := client.Mail(m.config.FromEmail); err != nil {
+		return fmt.Errorf("设置发件人失败: %w", err)
+	}
+	for _, to := range msg.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("设置收件人失败: %w", err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("发送邮件内容失败: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("发送邮件内容失败: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("发送邮件内容失败: %w", err)
+	}
+	return client.Quit()
+}
+
+// dial 连接SMTP服务器，465端口直接建立TLS连接，其他端口在服务器支持时使用STARTTLS
+func (m *Mailer) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	tlsConfig := &tls.Config{ServerName: m.config.Host}
+	dialer := &net.Dialer{Timeout: m.config.Timeout}
+	implicitTLS := m.config.Secure && m.config.Port == 465
+
+	var conn net.Conn
+	var err error
+	if implicitTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(m.config.Timeout * 3))
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !implicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				client.Close()
+				return nil, err
+			}
+		} else if m.config.Secure {
+			client.Close()
+			return nil, errors.New("SMTP服务器不支持STARTTLS")
+		}
+	}
+	return client, nil
+}
+
+// build 生成邮件原文，同时包含纯文本和HTML正文时使用 multipart/alternative
+func (m *Mailer) build(msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+
+	from := mail.Address{Name: m.config.FromName, Address: m.config.FromEmail}
+	headers := []string{
+		"From: " + from.String(),
+		"To: " + strings.Join(msg.To, ", "),
+		"Subject: " + mime.BEncoding.Encode("UTF-8", msg.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+	}
+	for _, header := range headers {
+		buf.WriteString(header + "\r\n")
+	}
+
+	if msg.HTML == "" {
+		buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+		buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+		writeBase64(&buf, msg.Text)
+		return buf.Bytes(), nil
+	}
+
+	writer := multipart.NewWriter(&buf)
+	buf.WriteString("Content-Type: multipart/alternative; boundary=" + writer.Boundary() + "\r\n\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", msg.Text},
+		{"text/html; charset=UTF-8", msg.HTML},
+	} {
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		var body bytes.Buffer
+		writeBase64(&body, part.body)
+		w.Write(body.Bytes())
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64 按每行76个字符写入base64编码的正文
+func writeBase64(buf *bytes.Buffer, body string) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+}
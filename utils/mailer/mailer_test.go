@@ -0,0 +1,124 @@
+package mailer_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/skyle1995/DevE-Server/utils/mailer"
+	"github.com/skyle1995/DevE-Server/utils/mailer/mailertest"
+)
+
+func TestSend(t *testing.T) {
+	server := mailertest.NewServer(t)
+
+	err := mailer.New(server.Config()).Send(mailer.Message{
+		To:      []string{"alice@example.com"},
+		Subject: "测试邮件",
+		Text:    "纯文本正文",
+		HTML:    "<p>HTML正文</p>",
+	})
+	if err != nil {
+		t.Fatalf("发送邮件失败: %v", err)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("收到 %d 封邮件，期望 1 封", len(messages))
+	}
+	msg := messages[0]
+	if msg.From != "noreply@example.com" || len(msg.To) != 1 || msg.To[0] != "alice@example.com" {
+		t.Errorf("发件人或收件人错误: %s", msg)
+	}
+	if msg.Auth != "tester" {
+		t.Errorf("认证用户名 = %q，期望 tester", msg.Auth)
+	}
+	if got := msg.Subject(); got != "测试邮件" {
+		t.Errorf("主题 = %q", got)
+	}
+	if got := msg.Text(); got != "纯文本正文" {
+		t.Errorf("纯文本正文 = %q", got)
+	}
+	if got := msg.HTML(); got != "<p>HTML正文</p>" {
+		t.Errorf("HTML正文 = %q", got)
+	}
+}
+
+func TestSendTextOnly(t *testing.T) {
+	server := mailertest.NewServer(t)
+
+	body := strings.Repeat("长正文", 50) + "\n.以点号开头的行\n"
+	err := mailer.New(server.Config()).Send(mailer.Message{
+		To:      []string{"bob@example.com"},
+		Subject: "纯文本",
+		Text:    body,
+	})
+	if err != nil {
+		t.Fatalf("发送邮件失败: %v", err)
+	}
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("收到 %d 封邮件，期望 1 封", len(messages))
+	}
+	if got := messages[0].Text(); got != body {
+		t.Errorf("纯文本正文 = %q", got)
+	}
+	if got := messages[0].HTML(); got != "" {
+		t.Errorf("不应包含HTML正文: %q", got)
+	}
+}
+
+func TestSendRejected(t *testing.T) {
+	server := mailertest.NewServer(t)
+
+	config := server.Config()
+	config.Enable = false
+	if err := mailer.New(config).Send(mailer.Message{To: []string{"a@example.com"}}); !errors.Is(err, mailer.ErrDisabled) {
+		t.Errorf("未启用时应返回 ErrDisabled，实际为 %v", err)
+	}
+
+	config.Enable = true
+	for _, to := range []string{"", "not-an-email", "a@example.com\r\nBcc: b@example.com"} {
+		if err := mailer.New(config).Send(mailer.Message{To: []string{to}, Text: "x"}); err == nil {
+			t.Errorf("收件人 %q 应被拒绝", to)
+		}
+	}
+
+	// 要求加密连接但服务器不支持STARTTLS
+	config.Secure = true
+	if err := mailer.New(config).Send(mailer.Message{To: []string{"a@example.com"}, Text: "x"}); err == nil {
+		t.Error("服务器不支持STARTTLS时应拒绝发送")
+	}
+	if len(server.Messages()) != 0 {
+		t.Errorf("被拒绝的邮件不应发送")
+	}
+}
+
+func TestRender(t *testing.T) {
+	data := map[string]string{
+		"SiteName":  "DevE",
+		"Username":  "<alice>",
+		"Link":      "https://example.com/verify?token=abc&x=1",
+		"ExpiresIn": "24小时",
+	}
+	for _, name := range []string{mailer.TemplateVerifyEmail, mailer.TemplateResetPassword} {
+		msg, err := mailer.Render(name, data)
+		if err != nil {
+			t.Fatalf("渲染模板 %s 失败: %v", name, err)
+		}
+		if !strings.Contains(msg.Subject, "DevE") {
+			t.Errorf("%s 主题 = %q", name, msg.Subject)
+		}
+		// 纯文本正文不做HTML转义
+		if !strings.Contains(msg.Text, data["Link"]) || !strings.Contains(msg.Text, "<alice>") {
+			t.Errorf("%s 纯文本正文缺少链接或用户名: %q", name, msg.Text)
+		}
+		if !strings.Contains(msg.HTML, "&lt;alice&gt;") || !strings.Contains(msg.HTML, "token=abc&amp;x=1") {
+			t.Errorf("%s HTML正文未正确转义: %q", name, msg.HTML)
+		}
+	}
+
+	if _, err := mailer.Render("missing", data); err == nil {
+		t.Error("不存在的模板应返回错误")
+	}
+}
@@ -0,0 +1,245 @@
+// Package mailertest 提供用于测试的本地SMTP服务器，接收并保存邮件而不实际投递
+package mailertest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/skyle1995/DevE-Server/utils/mailer"
+)
+
+// Message 服务器收到的邮件
+type Message struct {
+	From string   // MAIL FROM 地址
+	To   []string // RCPT TO 地址
+	Auth string   // AUTH PLAIN 认证的用户名，未认证时为空
+	Data []byte   // 邮件原文
+}
+
+// Server 本地SMTP服务器，只支持明文连接和 AUTH PLAIN
+type Server struct {
+	listener net.Listener
+	mu       sync.Mutex
+	messages []Message
+	wg       sync.WaitGroup
+}
+
+// NewServer 在随机端口启动SMTP服务器，测试结束时自动关闭
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("启动SMTP测试服务器失败: %v", err)
+	}
+	s := &Server{listener: listener}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Config 返回连接到该服务器的邮件配置
+func (s *Server) Config() mailer.Config {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return mailer.Config{
+		Enable:    true,
+		Host:      addr.IP.String(),
+		Port:      addr.Port,
+		Username:  "tester",
+		Password:  "secret",
+		FromEmail: "noreply@example.com",
+		FromName:  "DevE",
+	}
+}
+
+// Messages 返回已收到邮件的副本
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Reset 清空已收到的邮件
+func (s *Server) Reset() {
+	s.mu.Lock()
+	s.messages = nil
+	s.mu.Unlock()
+}
+
+// Close 关闭服务器
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+// handle 处理单个SMTP会话
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		io.WriteString(conn, line+"\r\n")
+	}
+
+	var msg Message
+	reply("220 mailertest ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250-mailertest")
+			reply("250 AUTH PLAIN")
+		case strings.HasPrefix(cmd, "AUTH PLAIN"):
+			fields := strings.Fields(line)
+			if len(fields) == 3 {
+				if decoded, err := base64.StdEncoding.DecodeString(fields[2]); err == nil {
+					if parts := strings.Split(string(decoded), "\x00"); len(parts) == 3 {
+						msg.Auth = parts[1]
+					}
+				}
+			}
+			reply("235 2.7.0 Authentication successful")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			msg.From = trimAddress(line[len("MAIL FROM:"):])
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			msg.To = append(msg.To, trimAddress(line[len("RCPT TO:"):]))
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data bytes.Buffer
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				// 还原点号转义
+				data.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+			msg.Data = data.Bytes()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			msg = Message{Auth: msg.Auth}
+			reply("250 OK: queued")
+		case cmd == "RSET":
+			msg = Message{Auth: msg.Auth}
+			reply("250 OK")
+		case cmd == "NOOP":
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// trimAddress 去除地址两侧的尖括号和参数
+func trimAddress(addr string) string {
+	addr = strings.TrimSpace(addr)
+	if i := strings.Index(addr, ">"); i >= 0 {
+		addr = addr[:i]
+	}
+	return strings.TrimPrefix(addr, "<")
+}
+
+// Subject 返回解码后的邮件主题
+func (m Message) Subject() string {
+	parsed, err := mail.ReadMessage(bytes.NewReader(m.Data))
+	if err != nil {
+		return ""
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil {
+		return ""
+	}
+	return subject
+}
+
+// Text 返回解码后的纯文本正文
+func (m Message) Text() string {
+	return m.part("text/plain")
+}
+
+// HTML 返回解码后的HTML正文
+func (m Message) HTML() string {
+	return m.part("text/html")
+}
+
+// part 返回指定类型的正文，支持单一正文和 multipart/alternative
+func (m Message) part(contentType string) string {
+	parsed, err := mail.ReadMessage(bytes.NewReader(m.Data))
+	if err != nil {
+		return ""
+	}
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	if mediaType == contentType {
+		return decodeBody(parsed.Header.Get("Content-Transfer-Encoding"), parsed.Body)
+	}
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		return ""
+	}
+
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	for {
+		p, err := reader.NextRawPart()
+		if err != nil {
+			return ""
+		}
+		if partType, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type")); partType == contentType {
+			return decodeBody(p.Header.Get("Content-Transfer-Encoding"), p)
+		}
+	}
+}
+
+// decodeBody 按传输编码解码正文
+func decodeBody(encoding string, body io.Reader) string {
+	if strings.EqualFold(encoding, "base64") {
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	data, _ := io.ReadAll(body)
+	return string(data)
+}
+
+// String 返回邮件摘要，便于测试失败时输出
+func (m Message) String() string {
+	return "from=" + m.From + " to=" + strings.Join(m.To, ",") + " size=" + strconv.Itoa(len(m.Data))
+}
@@ -0,0 +1,53 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// 内置邮件模板名称
+const (
+	TemplateVerifyEmail   = "verify_email"   // 注册邮箱验证
+	TemplateResetPassword = "reset_password" // 找回密码
+)
+
+// templateFS 内置邮件模板
+// 每个模板由两个文件组成：{name}.txt 定义 subject 和 text 块，{name}.html 为HTML正文
+//
+//go:embed templates/*
+var templateFS embed.FS
+
+// Render 使用内置模板生成邮件内容，收件人需由调用方设置
+func Render(name string, data interface{}) (Message, error) {
+	var msg Message
+
+	text, err := texttemplate.ParseFS(templateFS, "templates/"+name+".txt")
+	if err != nil {
+		return msg, fmt.Errorf("邮件模板不存在: %s", name)
+	}
+	var subject, body bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return msg, fmt.Errorf("渲染邮件模板失败: %w", err)
+	}
+	if err := text.ExecuteTemplate(&body, "text", data); err != nil {
+		return msg, fmt.Errorf("渲染邮件模板失败: %w", err)
+	}
+	msg.Subject = strings.TrimSpace(subject.String())
+	msg.Text = strings.TrimSpace(body.String()) + "\n"
+
+	html, err := htmltemplate.ParseFS(templateFS, "templates/"+name+".html")
+	if err != nil {
+		// HTML正文可选
+		return msg, nil
+	}
+	var htmlBody bytes.Buffer
+	if err := html.Execute(&htmlBody, data); err != nil {
+		return msg, fmt.Errorf("渲染邮件模板失败: %w", err)
+	}
+	msg.HTML = htmlBody.String()
+	return msg, nil
+}
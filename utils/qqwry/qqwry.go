@@ -64,6 +64,11 @@ func QueryIP(queryIP string) (city string, isp string, err error) {
 		return cacheInfo.City, cacheInfo.ISP, nil
 	}
 
+	// IP数据库未加载时无法查询
+	if dataLen < 8 {
+		return "", "", errors.New("IP数据库未加载")
+	}
+
 	// 解析IP地址
 	ip := net.ParseIP(queryIP).To4()
 	if ip == nil {
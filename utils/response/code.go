@@ -0,0 +1,87 @@
+package response
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 业务状态码，与开发文档“12.2 状态码”保持一致
+const (
+	// 通用错误（1000-1999）
+	CodeParamError   = 1001 // 参数错误
+	CodeUnauthorized = 1002 // 未授权
+	CodeForbidden    = 1003 // 权限不足
+
+	// 用户相关错误（2000-2999）
+	CodeUserPasswordError = 2001 // 用户名或密码错误
+	CodeUserDisabled      = 2002 // 用户账号已禁用
+
+	// 卡密相关错误（3000-3999）
+	CodeCardNotFound       = 3001 // 卡密不存在
+	CodeCardUsed           = 3002 // 卡密已使用
+	CodeCardExpired        = 3003 // 卡密已过期
+	CodeCardDisabled       = 3004 // 卡密已禁用
+	CodeCardBindLimit      = 3005 // 卡密已达到最大换绑/解绑次数
+	CodeCardDeviceMismatch = 3006 // 卡密设备ID不匹配
+	CodeCardNotActivated   = 3007 // 卡密未激活
+	CodeCardFrozen         = 3008 // 卡密已冻结
+
+	// 应用相关错误（4000-4999）
+	CodeAppKeyInvalid        = 4001 // 应用密钥无效
+	CodeAppDisabled          = 4002 // 应用已禁用
+	CodeCardAppMismatch      = 4003 // 卡密不属于该应用
+	CodeHeartbeatFailed      = 4004 // 心跳验证失败
+	CodeBindRequired         = 4005 // 应用要求绑定验证
+	CodeCardBoundOther       = 4006 // 卡密已绑定其他设备或IP
+	CodeBindVerifyFailed     = 4007 // 设备或IP未通过绑定验证
+	CodeHeartbeatTooFrequent = 4013 // 心跳间隔时间过短
+	CodeBindNotAllowed       = 4014 // 应用不允许换绑/解绑
+
+	// 安全相关错误（4008-4099）
+	CodeSignInvalid      = 4008 // 签名验证失败
+	CodeCryptoError      = 4009 // 加密/解密错误
+	CodeTimestampInvalid = 4010 // 请求超时（时间戳验证失败）
+	CodeIPNotAllowed     = 4011 // IP不在白名单中
+	CodeRateLimited      = 4012 // 请求频率超限
+	CodeNonceReused      = 4015 // 请求重复（随机数已使用）
+
+	// 服务器错误（5000-5999）
+	CodeServerError = 5001 // 服务器内部错误
+)
+
+// CodeError 携带业务状态码的错误
+type CodeError struct {
+	Code    int    // 业务状态码
+	Message string // 错误信息
+}
+
+// NewCodeError 创建携带业务状态码的错误
+func NewCodeError(code int, message string) *CodeError {
+	return &CodeError{Code: code, Message: message}
+}
+
+// Error 实现error接口
+func (e *CodeError) Error() string {
+	return e.Message
+}
+
+// CodeOf 获取错误对应的业务状态码
+// 未携带状态码的错误视为服务器内部错误
+func CodeOf(err error) int {
+	var codeErr *CodeError
+	if errors.As(err, &codeErr) {
+		return codeErr.Code
+	}
+	return CodeServerError
+}
+
+// FailWithError 返回带业务状态码的失败响应
+func FailWithError(err error, c *gin.Context) {
+	Result(CodeOf(err), err.Error(), nil, c)
+}
+
+// FailWithCodeDetailed 返回带状态码、数据和消息的失败响应
+func FailWithCodeDetailed(code int, data interface{}, message string, c *gin.Context) {
+	Result(code, message, data, c)
+}
@@ -87,6 +87,21 @@ func AddYears(t time.Time, years int) time.Time {
 	return t.AddDate(years, 0, 0)
 }
 
+// AddUnits 按时间单位增加时长
+// t: 时间
+// n: 数量
+// unit: 时间单位（day, month, year），未知单位按天计算
+func AddUnits(t time.Time, n int, unit string) time.Time {
+	switch unit {
+	case "month":
+		return AddMonths(t, n)
+	case "year":
+		return AddYears(t, n)
+	default:
+		return AddDays(t, n)
+	}
+}
+
 // DiffSeconds 计算两个时间的秒数差
 // t1, t2: 两个时间
 // 返回t1-t2的秒数差
@@ -0,0 +1,27 @@
+package validator
+
+import (
+	_ "embed"
+	"strings"
+)
+
+// commonPasswordList 常见弱密码列表，每行一个，来自公开的泄露密码统计
+//
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+// commonPasswords 常见弱密码集合，统一为小写
+var commonPasswords = func() map[string]bool {
+	passwords := make(map[string]bool)
+	for _, line := range strings.Split(commonPasswordList, "\n") {
+		if password := strings.TrimSpace(line); password != "" {
+			passwords[strings.ToLower(password)] = true
+		}
+	}
+	return passwords
+}()
+
+// IsCommonPassword 判断密码是否为常见弱密码，不区分大小写
+func IsCommonPassword(password string) bool {
+	return commonPasswords[strings.ToLower(password)]
+}
@@ -93,14 +93,30 @@ func IsLengthBetween(str string, min, max int) bool {
 // 4. 包含至少一个数字
 // 5. 包含至少一个特殊字符
 func IsStrongPassword(password string) bool {
-	if len(password) < 8 {
-		return false
-	}
+	return len(password) >= 8 && PasswordCharTypes(password) == 4
+}
 
-	hasUppercase := regexp.MustCompile(`[A-Z]`).MatchString(password)
-	hasLowercase := regexp.MustCompile(`[a-z]`).MatchString(password)
-	hasDigit := regexp.MustCompile(`[0-9]`).MatchString(password)
-	hasSpecial := regexp.MustCompile(`[^a-zA-Z0-9]`).MatchString(password)
+// PasswordCharTypes 统计密码包含的字符种类数，种类为大写字母、小写字母、数字和特殊字符
+func PasswordCharTypes(password string) int {
+	var hasUppercase, hasLowercase, hasDigit, hasSpecial bool
+	for _, r := range password {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			hasUppercase = true
+		case r >= 'a' && r <= 'z':
+			hasLowercase = true
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		default:
+			hasSpecial = true
+		}
+	}
 
-	return hasUppercase && hasLowercase && hasDigit && hasSpecial
+	count := 0
+	for _, has := range []bool{hasUppercase, hasLowercase, hasDigit, hasSpecial} {
+		if has {
+			count++
+		}
+	}
+	return count
 }